@@ -9,9 +9,24 @@ import (
 )
 
 var (
-	baselineCwd       string
-	baselineRunFilter string
-	baselineAnalyze   bool
+	baselineCwd         string
+	baselineRunFilter   string
+	baselineAnalyze     bool
+	baselineShowBinding string
+	baselineShowAnalyze bool
+	baselinePctBinding  string
+	baselinePctSamples  int
+	baselineDiffBinding string
+	baselineDiffBefore  string
+	baselineDiffAfter   string
+	baselineAnBinding   string
+	baselineAnSuggest   bool
+	baselineStBinding   string
+	baselineStRuns      int
+	baselineUpThreshold float64
+	baselineUpDryRun    bool
+	baselineUpForce     bool
+	baselineUpAnalyze   bool
 
 	// baselineCmd represents the baseline command
 	baselineCmd = &cobra.Command{
@@ -43,12 +58,385 @@ captures actual buffer I/O counts for deterministic regression detection.`,
 			})
 		},
 	}
+
+	baselineShowCmd = &cobra.Command{
+		Use:   "show <query> [flags]",
+		Short: "Render a stored baseline plan as a tree",
+		Long: `Render the EXPLAIN plan stored in a baseline as an indented tree.
+
+The query is named by its SQL path without extension, plus the query name for
+files with several queries. Use --binding when the query has more than one
+baseline. --analyze adds actual times, rows and buffers for baselines created
+with 'regresql baseline --analyze'.
+
+Examples:
+  regresql baseline show orders/get_order
+  regresql baseline show orders/orders/by_id --binding 1
+  regresql baseline show orders/get_order --analyze`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselineShow(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselineDiffCmd = &cobra.Command{
+		Use:   "diff <query> [flags]",
+		Short: "Show how a query plan changed between two baselines",
+		Long: `Compare two versions of a query's baseline: the cost delta, the plan
+nodes added and removed, and the tables scanned differently.
+
+'regresql baseline' keeps a copy of each baseline it writes in
+regresql/baselines/.history/, named by the current snapshot tag or by the run
+time. --before and --after pick a version by snapshot tag, run time,
+'current', or date (YYYY-MM-DD or RFC 3339, the newest version recorded by
+then). --after defaults to the current baseline and --before to the version
+recorded before it.
+
+Examples:
+  regresql baseline diff orders/get_order
+  regresql baseline diff orders/orders/by_id --binding 1 --before v1.2
+  regresql baseline diff orders/get_order --before 2026-01-01 --after v1.3`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselineDiff(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselineAnalyzeCmd = &cobra.Command{
+		Use:   "analyze <query> [flags]",
+		Short: "Check a stored baseline plan for plan quality issues",
+		Long: `Run the plan quality checks of 'regresql test' (sequential scans, repeated
+sorts, nested loops over sequential scans) over the plan stored in a
+baseline, without running the query.
+
+--suggest-indexes adds CREATE INDEX statements for the sequential scans,
+derived from their filters: btree for equality and range predicates,
+trigram (pg_trgm) for LIKE and GIN for JSONB operators. Indexes that already
+exist in the database are left out.
+
+Examples:
+  regresql baseline analyze orders/get_order
+  regresql baseline analyze orders/orders/by_id --binding 1 --suggest-indexes`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselineAnalyze(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselinePercentileCmd = &cobra.Command{
+		Use:   "percentile <query> [flags]",
+		Short: "Record cost percentiles of a query",
+		Long: `Re-ANALYZE the database and plan the query --samples times, then store
+the p50, p95 and p99 of the planner cost in its baselines (cost_p50, cost_p95
+and cost_p99). Queries on small or skewed tables get different statistics,
+and so different costs, from one ANALYZE to the next; comparing against a
+percentile keeps that noise from failing cost checks.
+
+Missing baselines are created from the last sampled plan. Note that sampling
+replaces the database statistics, like 'regresql compare --stability'.
+
+Enable the comparison with 'regresql test --percentile 95' or
+analyze.cost_percentile: 95 in regress.yaml.
+
+Examples:
+  regresql baseline percentile orders/get_order
+  regresql baseline percentile orders/orders/by_id --binding 1 --samples 20`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselinePercentile(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselineStabilityCmd = &cobra.Command{
+		Use:   "stability <query> [flags]",
+		Short: "Check whether a query gets the same plan on every ANALYZE",
+		Long: `Re-ANALYZE the database and EXPLAIN the query --runs times, then compare
+the plan trees and record the verdict in its baselines:
+
+  STABLE    the same plan on every run
+  FLAKY     the plan varied, but the costs stayed within 10% (a cost tie)
+  UNSTABLE  the plan and its cost varied
+
+The command fails when a plan recorded as STABLE is no longer stable, which
+catches planner sensitivity to statistics before it shows up in production.
+Missing baselines are created from the last plan. Note that sampling
+replaces the database statistics, like 'regresql baseline percentile'.
+
+Examples:
+  regresql baseline stability orders/get_order
+  regresql baseline stability orders/orders/by_id --binding 1 --runs 10`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselineStability(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselineUpdateCmd = &cobra.Command{
+		Use:   "update [path...] [flags]",
+		Short: "Rewrite baselines whose plan cost changed",
+		Long: `Plan every query again and rewrite only the baselines whose cost moved
+more than --threshold percent, up or down, from the stored cost. Use it to
+accept a cost change once it is confirmed as expected; baselines within the
+threshold keep their history. Queries without a baseline get one.
+
+--threshold defaults to each query's cost_threshold, or the cost threshold
+of regress.yaml. --threshold 0 rewrites every baseline whose cost changed at
+all. --dry-run lists
+the baselines that would change without writing them, and --force rewrites
+every baseline.
+
+Examples:
+  regresql baseline update --threshold 20
+  regresql baseline update orders/ --dry-run
+  regresql baseline update --force`,
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselineUpdate(args, cmd.Flags().Changed("threshold")); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	baselinePurgeCmd = &cobra.Command{
+		Use:   "purge [flags]",
+		Short: "Delete baselines created by auto_baseline",
+		Long: `Delete the baselines that 'regresql test' recorded automatically with
+auto_baseline: true in regress.yaml. The next test run creates them again
+from the current plans. Baselines created with 'regresql baseline' are kept.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(baselineCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runBaselinePurge(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
 )
 
 func init() {
 	RootCmd.AddCommand(baselineCmd)
 
-	baselineCmd.Flags().StringVarP(&baselineCwd, "cwd", "C", ".", "Change to Directory")
+	baselineCmd.AddCommand(baselineShowCmd)
+	baselineCmd.AddCommand(baselineDiffCmd)
+	baselineCmd.AddCommand(baselineAnalyzeCmd)
+	baselineCmd.AddCommand(baselinePercentileCmd)
+	baselineCmd.AddCommand(baselineStabilityCmd)
+	baselineCmd.AddCommand(baselineUpdateCmd)
+	baselineCmd.AddCommand(baselinePurgeCmd)
+
+	baselineCmd.PersistentFlags().StringVarP(&baselineCwd, "cwd", "C", ".", "Change to Directory")
 	baselineCmd.Flags().StringVar(&baselineRunFilter, "run", "", "Run only queries matching regexp (matches file names and query names)")
 	baselineCmd.Flags().BoolVar(&baselineAnalyze, "analyze", false, "Use EXPLAIN (ANALYZE, BUFFERS) for baselines")
+
+	baselineShowCmd.Flags().StringVar(&baselineShowBinding, "binding", "", "Plan binding to show")
+	baselineShowCmd.Flags().BoolVar(&baselineShowAnalyze, "analyze", false, "Show actual times, rows and buffers")
+
+	baselineDiffCmd.Flags().StringVar(&baselineDiffBinding, "binding", "", "Plan binding to compare")
+	baselineDiffCmd.Flags().StringVar(&baselineDiffBefore, "before", "", "Older baseline version: snapshot tag, run time, 'current' or date (default: the version before --after)")
+	baselineDiffCmd.Flags().StringVar(&baselineDiffAfter, "after", "", "Newer baseline version: snapshot tag, run time, 'current' or date (default: current)")
+
+	baselineAnalyzeCmd.Flags().StringVar(&baselineAnBinding, "binding", "", "Plan binding to analyze")
+	baselineAnalyzeCmd.Flags().BoolVar(&baselineAnSuggest, "suggest-indexes", false, "Suggest indexes for sequential scans")
+
+	baselinePercentileCmd.Flags().StringVar(&baselinePctBinding, "binding", "", "Plan binding to sample (default: all bindings)")
+	baselinePercentileCmd.Flags().IntVar(&baselinePctSamples, "samples", regresql.DefaultCostSamples, "Number of re-ANALYZE and EXPLAIN runs")
+
+	baselineStabilityCmd.Flags().StringVar(&baselineStBinding, "binding", "", "Plan binding to check (default: all bindings)")
+	baselineStabilityCmd.Flags().IntVar(&baselineStRuns, "runs", regresql.DefaultStabilityRuns, "Number of re-ANALYZE and EXPLAIN runs")
+
+	baselineUpdateCmd.Flags().StringVar(&baselineRunFilter, "run", "", "Run only queries matching regexp (matches file names and query names)")
+	baselineUpdateCmd.Flags().Float64Var(&baselineUpThreshold, "threshold", 0, "Cost change in percent from which a baseline is rewritten (default: the query's cost_threshold, else analyze cost threshold)")
+	baselineUpdateCmd.Flags().BoolVar(&baselineUpDryRun, "dry-run", false, "Show which baselines would be updated without writing them")
+	baselineUpdateCmd.Flags().BoolVar(&baselineUpForce, "force", false, "Update all baselines regardless of the threshold")
+	baselineUpdateCmd.Flags().BoolVar(&baselineUpAnalyze, "analyze", false, "Use EXPLAIN (ANALYZE, BUFFERS) for baselines")
+}
+
+func runBaselineShow(ref string) error {
+	path, err := regresql.ResolveBaselinePath(baselineCwd, ref, baselineShowBinding)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := regresql.LoadBaseline(path)
+	if err != nil {
+		return err
+	}
+	if baseline.Explain == nil {
+		return fmt.Errorf("baseline %s has no stored plan, re-run 'regresql baseline' to record it", path)
+	}
+	if baselineShowAnalyze && !baseline.AnalyzeMode {
+		return fmt.Errorf("baseline %s was created without --analyze, no actuals to show", path)
+	}
+
+	fmt.Printf("%s (%s)\n\n", path, baseline.Timestamp)
+	fmt.Print(regresql.RenderPlanTree(baseline.Explain, regresql.PlanRenderOptions{
+		ShowActual:        baselineShowAnalyze,
+		ShowBuffers:       baselineShowAnalyze,
+		HighlightSeqScans: regresql.ShouldUseColor(rootColor, rootNoColor),
+	}))
+	return nil
+}
+
+func runBaselineDiff(ref string) error {
+	before, after, err := regresql.ResolveBaselineDiff(baselineCwd, ref, baselineDiffBinding, baselineDiffBefore, baselineDiffAfter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Before: %s (%s)\n", before.Label, before.Baseline.Timestamp)
+	fmt.Printf("After:  %s (%s)\n\n", after.Label, after.Baseline.Timestamp)
+	regresql.PrintBaselineDiff(os.Stdout, regresql.DiffBaselines(before.Baseline, after.Baseline), regresql.ShouldUseColor(rootColor, rootNoColor))
+	return nil
+}
+
+func runBaselineAnalyze(ref string) error {
+	analysis, err := regresql.AnalyzeBaseline(regresql.BaselineAnalyzeOptions{
+		Root:           baselineCwd,
+		Query:          ref,
+		Binding:        baselineAnBinding,
+		SuggestIndexes: baselineAnSuggest,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n\n", analysis.Path)
+	regresql.PrintBaselineAnalysis(os.Stdout, analysis, baselineAnSuggest)
+	return nil
+}
+
+func runBaselinePercentile(ref string) error {
+	written, err := regresql.RecordCostPercentiles(regresql.PercentileOptions{
+		Root:    baselineCwd,
+		Query:   ref,
+		Binding: baselinePctBinding,
+		Samples: baselinePctSamples,
+	})
+	for _, path := range written {
+		baseline, loadErr := regresql.LoadBaseline(path)
+		if loadErr != nil || baseline.CostPercentiles == nil {
+			continue
+		}
+		p := baseline.CostPercentiles
+		fmt.Printf("%s: p50 %.2f  p95 %.2f  p99 %.2f (%d samples)\n", path, p.CostP50, p.CostP95, p.CostP99, p.CostSamples)
+	}
+	return err
+}
+
+func runBaselineStability(ref string) error {
+	results, err := regresql.CheckPlanStability(regresql.StabilityOptions{
+		Root:    baselineCwd,
+		Query:   ref,
+		Binding: baselineStBinding,
+		Runs:    baselineStRuns,
+	})
+	unstable := 0
+	for _, r := range results {
+		rep := r.Report
+		fmt.Printf("%s: %s (%d runs, %d distinct plans, cost %.2f-%.2f)\n", r.Path, rep.Status, rep.Runs, rep.DistinctPlans, rep.MinCost, rep.MaxCost)
+		if r.BecameUnstable() {
+			fmt.Printf("  ALERT: plan was STABLE on %s, now %s\n", r.Previous.Timestamp, rep.Status)
+			unstable++
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if unstable > 0 {
+		return fmt.Errorf("%d previously stable plan(s) became unstable", unstable)
+	}
+	return nil
+}
+
+func runBaselinePurge() error {
+	removed, err := regresql.PurgeAutoBaselines(baselineCwd)
+	if err != nil {
+		return err
+	}
+	for _, path := range removed {
+		fmt.Printf("Removed baseline: %s\n", path)
+	}
+	fmt.Printf("Purged %d auto-created baseline(s)\n", len(removed))
+	return nil
+}
+
+func runBaselineUpdate(paths []string, thresholdSet bool) error {
+	if baselineUpThreshold < 0 {
+		return fmt.Errorf("--threshold must not be negative")
+	}
+	opts := regresql.BaselineUpdateOptions{
+		Root:      baselineCwd,
+		RunFilter: baselineRunFilter,
+		Paths:     paths,
+		Analyze:   baselineUpAnalyze,
+		DryRun:    baselineUpDryRun,
+		Force:     baselineUpForce,
+	}
+	if thresholdSet {
+		opts.Threshold = &baselineUpThreshold
+	}
+	updates, err := regresql.UpdateBaselines(opts)
+
+	verb := "Updated"
+	if baselineUpDryRun {
+		verb = "Would update"
+	}
+	changed := 0
+	for _, u := range updates {
+		if !u.Updated {
+			continue
+		}
+		changed++
+		if u.Created {
+			fmt.Printf("%s %s: new baseline, cost %.2f\n", verb, u.Path, u.NewCost)
+		} else {
+			fmt.Printf("%s %s: cost %.2f -> %.2f (%+.1f%%)\n", verb, u.Path, u.OldCost, u.NewCost, u.ChangePercent)
+		}
+	}
+	fmt.Printf("%s %d of %d baseline(s)\n", verb, changed, len(updates))
+	return err
 }
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchmarkCwd       string
+	benchmarkRunFilter string
+	benchmarkRuns      int
+	benchmarkWarmup    int
+	benchmarkCompare   string
+	benchmarkFactor    float64
+
+	benchmarkCmd = &cobra.Command{
+		Use:   "benchmark [flags]",
+		Short: "Collect execution time statistics across multiple runs",
+		Long: `Run every planned query binding with EXPLAIN ANALYZE --warmup times
+(discarded) and then --runs times, and report the mean, min, max, p50 and p95
+of the actual execution time. Each run is rolled back.
+
+Results are stored as JSON in regresql/benchmarks/. --compare reports the
+queries whose p50 grew by more than --factor against a previous benchmark
+and exits with status 1 when there are any.
+
+Examples:
+  regresql benchmark
+  regresql benchmark --runs 20 --warmup 5 --run orders
+  regresql benchmark --compare latest
+  regresql benchmark --compare regresql/benchmarks/20260101T120000Z.json --factor 2`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(benchmarkCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			code := regresql.Benchmark(regresql.BenchmarkOptions{
+				Root:      benchmarkCwd,
+				RunFilter: benchmarkRunFilter,
+				Runs:      benchmarkRuns,
+				Warmup:    benchmarkWarmup,
+				Compare:   benchmarkCompare,
+				Factor:    benchmarkFactor,
+			})
+			os.Exit(code)
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(benchmarkCmd)
+
+	benchmarkCmd.Flags().StringVarP(&benchmarkCwd, "cwd", "C", ".", "Change to Directory")
+	benchmarkCmd.Flags().StringVar(&benchmarkRunFilter, "run", "", "Run only queries matching regexp (matches file names and query names)")
+	benchmarkCmd.Flags().IntVar(&benchmarkRuns, "runs", regresql.DefaultBenchmarkRuns, "Measured runs per query binding")
+	benchmarkCmd.Flags().IntVar(&benchmarkWarmup, "warmup", regresql.DefaultBenchmarkWarmup, "Discarded runs before the measured ones")
+	benchmarkCmd.Flags().StringVar(&benchmarkCompare, "compare", "", "Previous benchmark JSON to compare against, or 'latest'")
+	benchmarkCmd.Flags().Float64Var(&benchmarkFactor, "factor", regresql.DefaultBenchmarkFactor, "p50 slowdown factor reported as a regression with --compare")
+}
@@ -1,16 +1,21 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
 	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configCwd      string
-	configTestConn bool
+	configCwd          string
+	configTestConn     bool
+	configSchemaOutput string
+	configMigrateTo    int
+	configMigrateDry   bool
 
 	configCmd = &cobra.Command{
 		Use:   "config",
@@ -25,7 +30,13 @@ Examples:
   regresql config set pguri "postgres://user@host/db" --test
 
   # Set pguri without testing
-  regresql config set pguri "postgres://user@host/db"`,
+  regresql config set pguri "postgres://user@host/db"
+
+  # Check regress.yaml against the config schema
+  regresql config validate
+
+  # Upgrade regress.yaml to the current config_version
+  regresql config migrate --dry-run`,
 	}
 
 	configGetCmd = &cobra.Command{
@@ -77,15 +88,87 @@ Examples:
 			}
 		},
 	}
+
+	configSchemaCmd = &cobra.Command{
+		Use:   "schema [flags]",
+		Short: "Print the JSON Schema of regress.yaml",
+		Long: `Print the JSON Schema (draft 2020-12) describing regress.yaml, for inline
+validation in editors.
+
+Examples:
+  regresql config schema --output regress-schema.json
+
+Then point the YAML language server at it, e.g. as the first line of
+regresql/regress.yaml:
+
+  # yaml-language-server: $schema=../regress-schema.json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runConfigSchema(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check regress.yaml against the config schema",
+		Long: `Check regress.yaml against the config schema and report unknown keys,
+values of the wrong type and invalid choices with their YAML path and line.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(configCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runConfigValidate(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	configMigrateCmd = &cobra.Command{
+		Use:   "migrate [flags]",
+		Short: "Upgrade regress.yaml to the current config_version",
+		Long: `Upgrade regress.yaml to a newer config_version, renaming and moving keys
+as the format changed. The previous file is kept as regress.yaml.bak;
+comments are not carried over.
+
+Examples:
+  # Show what would change
+  regresql config migrate --dry-run
+
+  # Rewrite regress.yaml
+  regresql config migrate`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(configCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runConfigMigrate(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
 )
 
 func init() {
 	RootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
 
 	configCmd.PersistentFlags().StringVarP(&configCwd, "cwd", "C", ".", "Change to directory")
 	configSetCmd.Flags().BoolVar(&configTestConn, "test", false, "Test connection before saving (for pguri)")
+	configSchemaCmd.Flags().StringVarP(&configSchemaOutput, "output", "o", "", "Output file path (default: stdout)")
+	configMigrateCmd.Flags().IntVar(&configMigrateTo, "to", regresql.CurrentConfigVersion, "Target config_version")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDry, "dry-run", false, "Show the changes without writing regress.yaml")
 }
 
 func runConfigGet(key string) error {
@@ -129,3 +212,48 @@ func runConfigSet(key, value string) error {
 
 	return nil
 }
+
+func runConfigSchema() error {
+	if configSchemaOutput == "" {
+		_, err := os.Stdout.Write(regresql.ConfigSchema())
+		return err
+	}
+	if err := os.WriteFile(configSchemaOutput, regresql.ConfigSchema(), 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("Wrote config schema to %s\n", configSchemaOutput)
+	return nil
+}
+
+func runConfigValidate() error {
+	errs, err := regresql.ValidateConfigFile(configCwd)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		fmt.Println("✓ regress.yaml matches the config schema")
+		return nil
+	}
+	for _, e := range errs {
+		fmt.Printf("✗ %s\n", e.Error())
+	}
+	return fmt.Errorf("%d problem(s) in regress.yaml", len(errs))
+}
+
+func runConfigMigrate() error {
+	before, after, err := regresql.MigrateConfigFile(configCwd, configMigrateTo, configMigrateDry)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(before, after) {
+		fmt.Printf("regress.yaml is already at config_version %d\n", configMigrateTo)
+		return nil
+	}
+	if configMigrateDry {
+		fmt.Print(regresql.DiffLines("regress.yaml", "regress.yaml (migrated)",
+			difflib.SplitLines(string(before)), difflib.SplitLines(string(after)), 3))
+		return nil
+	}
+	fmt.Printf("Migrated regress.yaml to config_version %d (previous file kept as regress.yaml.bak)\n", configMigrateTo)
+	return nil
+}
@@ -13,18 +13,25 @@ var (
 	coverageTaxonomy string
 	coverageFormat   string
 	coverageOutput   string
+	coverageMin      float64
 
 	coverageCmd = &cobra.Command{
-		Use:   "coverage --taxonomy <file>",
-		Short: "Report which planner-feature cells the corpus covers (and which it misses)",
-		Long: `Cross-reference each query's -- cell: tag against a taxonomy of planner-feature
-cells and report covered vs empty cells, tags not in the taxonomy, and untagged
-queries. Honest coverage accounting — the empty cells are the point.`,
+		Use:   "coverage [--taxonomy <file>]",
+		Short: "Report test coverage of SQL files, or which planner-feature cells the corpus covers",
+		Long: `Without --taxonomy, report for each SQL file how many queries have plans,
+expected files and cost baselines. --min-coverage fails the command when fewer
+queries (in percent) have plans, to make sure new SQL files get tests in CI.
+
+With --taxonomy, cross-reference each query's -- cell: tag against a taxonomy
+of planner-feature cells and report covered vs empty cells, tags not in the
+taxonomy, and untagged queries. Honest coverage accounting — the empty cells
+are the point.
+
+Examples:
+  regresql coverage
+  regresql coverage --min-coverage 80
+  regresql coverage --taxonomy taxonomy.json`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if coverageTaxonomy == "" {
-				fmt.Fprintln(os.Stderr, "--taxonomy is required")
-				os.Exit(2)
-			}
 			if err := checkDirectory(coverageCwd); err != nil {
 				fmt.Print(err.Error())
 				os.Exit(1)
@@ -34,6 +41,7 @@ queries. Honest coverage accounting — the empty cells are the point.`,
 				TaxonomyPath: coverageTaxonomy,
 				Format:       coverageFormat,
 				OutputPath:   coverageOutput,
+				MinCoverage:  coverageMin,
 			}))
 		},
 	}
@@ -44,6 +52,7 @@ func init() {
 
 	coverageCmd.Flags().StringVarP(&coverageCwd, "cwd", "C", ".", "Change to Directory")
 	coverageCmd.Flags().StringVar(&coverageTaxonomy, "taxonomy", "", "Path to the taxonomy JSON (axes -> cells)")
+	coverageCmd.Flags().Float64Var(&coverageMin, "min-coverage", 0, "Exit non-zero when fewer than this percentage of queries have plans")
 	coverageCmd.Flags().StringVar(&coverageFormat, "format", "console", "Output format: console, json")
 	coverageCmd.Flags().StringVarP(&coverageOutput, "output", "o", "", "Output file path (default: stdout)")
 }
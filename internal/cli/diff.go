@@ -53,55 +53,65 @@ func init() {
 }
 
 func runDiff() error {
-	snapshotsDir := regresql.GetSnapshotsDir(diffCwd)
+	fromInfo, toInfo, err := resolveSnapshotPair(diffCwd, diffFrom, diffTo)
+	if err != nil {
+		return err
+	}
+
+	if fromInfo.Hash == toInfo.Hash {
+		fmt.Printf("Both snapshots are identical (%s)\n", regresql.FormatSnapshotRef(fromInfo))
+		return nil
+	}
+
+	fmt.Printf("Comparing snapshots:\n")
+	fmt.Printf("  From: %s (%s)\n", regresql.FormatSnapshotRef(fromInfo), fromInfo.Path)
+	fmt.Printf("  To:   %s (%s)\n", regresql.FormatSnapshotRef(toInfo), toInfo.Path)
+	fmt.Println()
+
+	result, err := regresql.DiffSnapshots(diffCwd, fromInfo, toInfo, diffQuery, diffRunFilter)
+	if err != nil {
+		return err
+	}
+
+	printDiffResult(result, fromInfo, toInfo)
+
+	return nil
+}
+
+// resolveSnapshotPair resolves two snapshot references (tag, hash prefix or
+// "current" for the target) and checks that both snapshot files exist
+func resolveSnapshotPair(cwd, fromRef, toRef string) (*regresql.SnapshotInfo, *regresql.SnapshotInfo, error) {
+	snapshotsDir := regresql.GetSnapshotsDir(cwd)
 
 	metadata, err := regresql.ReadSnapshotMetadata(snapshotsDir)
 	if err != nil {
-		return fmt.Errorf("no snapshot metadata found: %w", err)
+		return nil, nil, fmt.Errorf("no snapshot metadata found: %w", err)
 	}
 
-	fromInfo, err := regresql.ResolveSnapshot(metadata, diffFrom)
+	fromInfo, err := regresql.ResolveSnapshot(metadata, fromRef)
 	if err != nil {
-		return fmt.Errorf("cannot resolve --from snapshot: %w", err)
+		return nil, nil, fmt.Errorf("cannot resolve source snapshot: %w", err)
 	}
 
-	toRef := diffTo
 	if toRef == "" || toRef == "current" {
 		if metadata.Current == nil {
-			return fmt.Errorf("no current snapshot")
+			return nil, nil, fmt.Errorf("no current snapshot")
 		}
 		toRef = metadata.Current.Hash
 	}
 	toInfo, err := regresql.ResolveSnapshot(metadata, toRef)
 	if err != nil {
-		return fmt.Errorf("cannot resolve --to snapshot: %w", err)
+		return nil, nil, fmt.Errorf("cannot resolve target snapshot: %w", err)
 	}
 
 	if !regresql.SnapshotExists(fromInfo) {
-		return fmt.Errorf("source snapshot file not found: %s", fromInfo.Path)
+		return nil, nil, fmt.Errorf("source snapshot file not found: %s", fromInfo.Path)
 	}
 	if !regresql.SnapshotExists(toInfo) {
-		return fmt.Errorf("target snapshot file not found: %s", toInfo.Path)
+		return nil, nil, fmt.Errorf("target snapshot file not found: %s", toInfo.Path)
 	}
 
-	if fromInfo.Hash == toInfo.Hash {
-		fmt.Printf("Both snapshots are identical (%s)\n", regresql.FormatSnapshotRef(fromInfo))
-		return nil
-	}
-
-	fmt.Printf("Comparing snapshots:\n")
-	fmt.Printf("  From: %s (%s)\n", regresql.FormatSnapshotRef(fromInfo), fromInfo.Path)
-	fmt.Printf("  To:   %s (%s)\n", regresql.FormatSnapshotRef(toInfo), toInfo.Path)
-	fmt.Println()
-
-	result, err := regresql.DiffSnapshots(diffCwd, fromInfo, toInfo, diffQuery, diffRunFilter)
-	if err != nil {
-		return err
-	}
-
-	printDiffResult(result, fromInfo, toInfo)
-
-	return nil
+	return fromInfo, toInfo, nil
 }
 
 func printDiffResult(result *regresql.SnapshotDiffResult, from, to *regresql.SnapshotInfo) {
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffRunsFormat string
+	diffRunsOutput string
+
+	diffRunsCmd = &cobra.Command{
+		Use:   "diff-runs <run1.json> <run2.json>",
+		Short: "Compare the results of two test runs",
+		Long: `Compare two test runs saved with 'regresql test --format json --output',
+e.g. from the main branch and a feature branch. Lists the tests that newly
+failed, the tests that were fixed, other status changes and the plan cost
+changes of cost tests.
+
+Examples:
+  regresql diff-runs main.json feature.json
+  regresql diff-runs main.json feature.json --format markdown -o pr-comment.md`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runDiffRuns(args[0], args[1]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(diffRunsCmd)
+
+	diffRunsCmd.Flags().StringVar(&diffRunsFormat, "format", "table", "Output format: table or markdown")
+	diffRunsCmd.Flags().StringVarP(&diffRunsOutput, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runDiffRuns(beforePath, afterPath string) error {
+	before, err := regresql.ReadTestRun(beforePath)
+	if err != nil {
+		return err
+	}
+	after, err := regresql.ReadTestRun(afterPath)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if diffRunsOutput != "" {
+		f, err := os.Create(diffRunsOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return regresql.DiffTestRuns(before, after).Render(w, diffRunsFormat)
+}
@@ -11,21 +11,31 @@ import (
 // Command Flags
 var (
 	initCwd string
-	pguri   string
+	initYes bool
 )
 
 // initCmd represents the init command
 var initCmd = &cobra.Command{
-	Use:   "init [flags] postgres://user@host/dbname",
+	Use:   "init [flags] [postgres://user@host/dbname]",
 	Short: "Initialize regresql for use in your project",
-	Args:  cobra.ExactArgs(1),
+	Long: `Create the regresql/ directory and the regress.yaml config.
+
+init detects how the project builds its schema: golang-migrate, goose, dbmate
+or atlas migrations, a plain migrations directory or a schema.sql file. It
+offers to configure 'regresql snapshot build' with them (--yes applies them
+without asking). Without a connection string, the postgres service of a
+compose file is used.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := checkDirectory(initCwd); err != nil {
 			fmt.Print(err.Error())
 			os.Exit(1)
 		}
-		pguri := args[0]
-		regresql.Init(initCwd, pguri)
+		opts := regresql.InitOptions{Root: initCwd, Yes: initYes}
+		if len(args) == 1 {
+			opts.PgUri = args[0]
+		}
+		regresql.Init(opts)
 	},
 }
 
@@ -42,4 +52,5 @@ func init() {
 	// is called directly, e.g.:
 	// initCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	initCmd.Flags().StringVarP(&initCwd, "cwd", "C", ".", "Change to Directory")
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Configure snapshots with the detected migrations or schema without asking")
 }
@@ -10,7 +10,8 @@ import (
 
 var (
 	// Command Flags
-	cwd string
+	cwd        string
+	listDetail bool
 
 	// listCmd represents the list command
 	listCmd = &cobra.Command{
@@ -21,7 +22,14 @@ var (
 				fmt.Print(err.Error())
 				os.Exit(1)
 			}
-			regresql.List(cwd)
+			if !listDetail {
+				regresql.List(cwd)
+				return
+			}
+			if err := regresql.ListDetail(cwd); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
 		},
 	}
 )
@@ -29,4 +37,5 @@ var (
 func init() {
 	RootCmd.AddCommand(listCmd)
 	listCmd.Flags().StringVarP(&cwd, "cwd", "C", ".", "Change to Directory")
+	listCmd.Flags().BoolVar(&listDetail, "detail", false, "Show each named query of the SQL files with its plan status")
 }
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/boringsql/regresql/v2/regresql"
 	"github.com/spf13/cobra"
@@ -12,10 +13,9 @@ var (
 	migrateCwd      string
 	migrateScript   string
 	migrateCommand  string
+	migrateEnv      []string
 	migrateKeepTemp bool
 	migrateVerbose  bool
-	migrateColor    bool
-	migrateNoColor  bool
 	migrateFullDiff bool
 	migrateNoDiff   bool
 
@@ -31,9 +31,13 @@ Workflow:
   4. Execute all queries -> capture "after" state
   5. Compare and report differences
 
+A --command that fails with a transient connection error is retried up to
+3 times with exponential backoff.
+
 Examples:
   regresql migrate --script migrations/002_add_status.sql
   regresql migrate --command "goose -dir migrations postgres \$PGURI up-to 002"
+  regresql migrate --command "migrate -path db -database {pguri} up" --env MIGRATE_LOCK=off
   regresql migrate --script migrations/002.sql --verbose`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := checkDirectory(migrateCwd); err != nil {
@@ -51,6 +55,17 @@ Examples:
 				os.Exit(1)
 			}
 
+			if migrateScript != "" && len(migrateEnv) > 0 {
+				fmt.Println("Error: --env requires --command")
+				os.Exit(1)
+			}
+			for _, kv := range migrateEnv {
+				if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+					fmt.Printf("Error: invalid --env %q (expected KEY=VALUE)\n", kv)
+					os.Exit(1)
+				}
+			}
+
 			// Validate script file exists if specified
 			if migrateScript != "" {
 				if _, err := os.Stat(migrateScript); os.IsNotExist(err) {
@@ -63,10 +78,11 @@ Examples:
 				Root:     migrateCwd,
 				Script:   migrateScript,
 				Command:  migrateCommand,
+				Env:      migrateEnv,
 				KeepTemp: migrateKeepTemp,
 				Verbose:  migrateVerbose,
-				Color:    migrateColor,
-				NoColor:  migrateNoColor,
+				Color:    rootColor,
+				NoColor:  rootNoColor,
 				FullDiff: migrateFullDiff,
 				NoDiff:   migrateNoDiff,
 			}
@@ -81,11 +97,10 @@ func init() {
 
 	migrateCmd.Flags().StringVarP(&migrateCwd, "cwd", "C", ".", "Change to Directory")
 	migrateCmd.Flags().StringVar(&migrateScript, "script", "", "Path to migration SQL script")
-	migrateCmd.Flags().StringVar(&migrateCommand, "command", "", "External migration command (receives $PGURI env var)")
+	migrateCmd.Flags().StringVar(&migrateCommand, "command", "", "External migration command (receives $PGURI and $DATABASE_URL env vars; {pguri} is replaced with the connection string)")
+	migrateCmd.Flags().StringArrayVar(&migrateEnv, "env", nil, "Extra KEY=VALUE environment variable for --command (repeatable)")
 	migrateCmd.Flags().BoolVar(&migrateKeepTemp, "keep-temp", false, "Preserve temporary before/after directories")
 	migrateCmd.Flags().BoolVarP(&migrateVerbose, "verbose", "v", false, "Verbose output")
-	migrateCmd.Flags().BoolVar(&migrateColor, "color", false, "Force colored output")
-	migrateCmd.Flags().BoolVar(&migrateNoColor, "no-color", false, "Disable colored output")
 	migrateCmd.Flags().BoolVar(&migrateFullDiff, "diff", false, "Show full diff output (no truncation)")
 	migrateCmd.Flags().BoolVar(&migrateNoDiff, "no-diff", false, "Suppress diff output entirely")
 }
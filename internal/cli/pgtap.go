@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pgtapCwd       string
+	pgtapDir       string
+	pgtapPattern   string
+	pgtapRunFilter string
+	pgtapFormat    string
+	pgtapOutput    string
+	pgtapVerbose   bool
+
+	pgtapCmd = &cobra.Command{
+		Use:   "pgtap [flags]",
+		Short: "Run pgTAP test files and report them like regression tests",
+		Long: `Discover SQL files that call pgTAP's plan() or no_plan(), run each one
+through psql and report the TAP results with the regular output formats
+(console, junit, json, html, ...).
+
+The directory defaults to pgtap_dir from regress.yaml, or sql/tests. With
+pgtap_dir set, 'regresql test' runs the pgTAP files as well and includes
+them in the same report.
+
+Examples:
+  regresql pgtap
+  regresql pgtap --dir db/tests --pattern 'test_*.sql'
+  regresql pgtap --format junit -o pgtap.xml`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(pgtapCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			regresql.PgTAP(regresql.PgTAPOptions{
+				Root:       pgtapCwd,
+				Dir:        pgtapDir,
+				Pattern:    pgtapPattern,
+				RunFilter:  pgtapRunFilter,
+				FormatName: pgtapFormat,
+				OutputPath: pgtapOutput,
+				Color:      rootColor,
+				NoColor:    rootNoColor,
+				Verbose:    pgtapVerbose,
+			})
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(pgtapCmd)
+
+	pgtapCmd.Flags().StringVarP(&pgtapCwd, "cwd", "C", ".", "Change to Directory")
+	pgtapCmd.Flags().StringVar(&pgtapDir, "dir", "", "Directory with pgTAP files (default: pgtap_dir from regress.yaml, or sql/tests)")
+	pgtapCmd.Flags().StringVar(&pgtapPattern, "pattern", regresql.DefaultPgTAPPattern, "File name pattern of pgTAP files")
+	pgtapCmd.Flags().StringVar(&pgtapRunFilter, "run", "", "Run only pgTAP files matching regexp")
+	pgtapCmd.Flags().StringVar(&pgtapFormat, "format", "console", "Output format: console, pgtap, junit, json, github-actions (alias github), html")
+	pgtapCmd.Flags().StringVarP(&pgtapOutput, "output", "o", "", "Output file path (default: stdout)")
+	pgtapCmd.Flags().BoolVarP(&pgtapVerbose, "verbose", "v", false, "Show each test with name, type, and duration")
+}
@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/boringsql/regresql/v2/regresql"
 	"github.com/spf13/cobra"
 )
 
 var (
+	planCwd         string
+	planShowBinding string
+	planShowAnalyze bool
+
 	planCmd = &cobra.Command{
 		Use:    "plan",
 		Short:  "Deprecated: use 'regresql add' instead",
@@ -24,8 +29,62 @@ See 'regresql add --help' for more information.`)
 			os.Exit(1)
 		},
 	}
+
+	planShowCmd = &cobra.Command{
+		Use:   "show <query> [flags]",
+		Short: "Render the EXPLAIN ANALYZE captured by capture_analyze",
+		Long: `Render the EXPLAIN (ANALYZE, BUFFERS) output that 'regresql test' saved
+in regresql/out/analyze/ for a query with the capture_analyze option:
+
+  -- name: get_order
+  -- regresql: capture_analyze
+  SELECT * FROM orders WHERE id = :id;
+
+The query is named by its SQL path without extension, plus the query name for
+files with several queries. Use --binding when the query has more than one
+binding, and --analyze to add actual times, rows and buffers to the tree.
+
+Examples:
+  regresql plan show orders/get_order --analyze
+  regresql plan show orders/orders/by_id --binding 1`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(planCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runPlanShow(args[0]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
 )
 
 func init() {
 	RootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planShowCmd)
+
+	planShowCmd.Flags().StringVarP(&planCwd, "cwd", "C", ".", "Change to Directory")
+	planShowCmd.Flags().StringVar(&planShowBinding, "binding", "", "Plan binding to show")
+	planShowCmd.Flags().BoolVar(&planShowAnalyze, "analyze", false, "Show actual times, rows and buffers")
+}
+
+func runPlanShow(ref string) error {
+	path, err := regresql.ResolveAnalyzePath(planCwd, ref, planShowBinding)
+	if err != nil {
+		return err
+	}
+	explain, err := regresql.LoadAnalyze(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n\n", path)
+	fmt.Print(regresql.RenderPlanTree(explain, regresql.PlanRenderOptions{
+		ShowActual:        planShowAnalyze,
+		ShowBuffers:       planShowAnalyze,
+		HighlightSeqScans: regresql.ShouldUseColor(rootColor, rootNoColor),
+	}))
+	return nil
 }
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resultsCwd   string
+	resultsSince string
+	resultsQuery string
+
+	resultsCmd = &cobra.Command{
+		Use:   "results",
+		Short: "Query test results stored in the result store",
+		Long: `Query the results 'regresql test' stores when result_store is configured
+in regress.yaml:
+
+  result_store:
+    pguri: $METRICS_DB
+    table: regresql_results   # default
+
+Every test run appends one row per test result to the table, which is
+created when missing.
+
+Examples:
+  regresql results query
+  regresql results query --since 7d --query orders/get_order.sql`,
+	}
+
+	resultsQueryCmd = &cobra.Command{
+		Use:   "query [flags]",
+		Short: "Show pass rate trends of stored test results",
+		Long: `Show the stored test results per day and per query binding: runs, tests,
+failures, pass rate, average duration and cost, and the latest status.
+
+--since accepts days (30d), weeks (2w) or a duration (12h). --query limits
+the results to one query file, relative to the project root.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(resultsCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			os.Exit(regresql.QueryStoredResults(regresql.ResultsQueryOptions{
+				Root:  resultsCwd,
+				Since: resultsSince,
+				Query: resultsQuery,
+			}))
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(resultsCmd)
+	resultsCmd.AddCommand(resultsQueryCmd)
+
+	resultsQueryCmd.Flags().StringVarP(&resultsCwd, "cwd", "C", ".", "Change to Directory")
+	resultsQueryCmd.Flags().StringVar(&resultsSince, "since", "30d", "Show results stored within this age (e.g. 30d, 2w, 12h)")
+	resultsQueryCmd.Flags().StringVar(&resultsQuery, "query", "", "Show results of this query file only")
+}
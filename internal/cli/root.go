@@ -7,6 +7,11 @@ import (
 var (
 	version = "dev" // Will be set via ldflags during build
 
+	// rootColor and rootNoColor override the NO_COLOR, CLICOLOR and
+	// CLICOLOR_FORCE environment variables for every command
+	rootColor   bool
+	rootNoColor bool
+
 	// RootCmd represents the base command when called without any subcommands
 	RootCmd = &cobra.Command{
 		Use:     "regresql",
@@ -15,6 +20,11 @@ var (
 	}
 )
 
+func init() {
+	RootCmd.PersistentFlags().BoolVar(&rootColor, "color", false, "Force colored output")
+	RootCmd.PersistentFlags().BoolVar(&rootNoColor, "no-color", false, "Disable colored output")
+}
+
 // Run executes the root command. Child commands register themselves via
 // init() in their respective files.
 func Run() error {
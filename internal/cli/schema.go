@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaCwd          string
+	schemaDiffFrom     string
+	schemaDiffTo       string
+	schemaDiffFormat   string
+	schemaDiffExitCode bool
+
+	schemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Inspect the database schema",
+		Long: `Inspect the database schema and compare it with the schema state
+captured at snapshot build.`,
+	}
+
+	schemaDiffCmd = &cobra.Command{
+		Use:   "diff [flags]",
+		Short: "Compare two schema states",
+		Long: `Compare two schema states and report added and removed tables,
+added, removed and changed columns (type and nullability), added and removed
+indexes, and added and removed foreign keys.
+
+A schema state is one of:
+  live       introspect the database at pguri
+  snapshot   the schema captured by the last 'regresql snapshot build'
+  <file>     a schema state JSON file, e.g. snapshots/v1.schema.json
+
+Set snapshot.schema_drift to warn or strict in regress.yaml to run the same
+check (snapshot against live) before 'regresql test'.
+
+Examples:
+  regresql schema diff                                  # snapshot -> live
+  regresql schema diff --from snapshots/v1.schema.json --to snapshot
+  regresql schema diff --format json > drift.json
+  regresql schema diff --exit-code                      # exit 1 on drift`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(schemaCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			drift, err := runSchemaDiff()
+			if err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if drift && schemaDiffExitCode {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaDiffCmd)
+
+	schemaCmd.PersistentFlags().StringVarP(&schemaCwd, "cwd", "C", ".", "Change to Directory")
+
+	schemaDiffCmd.Flags().StringVar(&schemaDiffFrom, "from", regresql.SchemaSourceSnapshot, "Schema to compare from: snapshot, live or a schema state file")
+	schemaDiffCmd.Flags().StringVar(&schemaDiffTo, "to", regresql.SchemaSourceLive, "Schema to compare to: live, snapshot or a schema state file")
+	schemaDiffCmd.Flags().StringVar(&schemaDiffFormat, "format", "console", "Output format: console or json")
+	schemaDiffCmd.Flags().BoolVar(&schemaDiffExitCode, "exit-code", false, "Exit with status 1 when the schemas differ")
+}
+
+func runSchemaDiff() (bool, error) {
+	if schemaDiffFormat != "console" && schemaDiffFormat != "json" {
+		return false, fmt.Errorf("unknown format %q (expected console or json)", schemaDiffFormat)
+	}
+
+	cfg, err := regresql.ReadConfig(schemaCwd)
+	if err != nil {
+		return false, err
+	}
+
+	from, err := regresql.LoadSchema(schemaCwd, cfg.PgUri, schemaDiffFrom)
+	if err != nil {
+		return false, fmt.Errorf("--from %s: %w", schemaDiffFrom, err)
+	}
+	to, err := regresql.LoadSchema(schemaCwd, cfg.PgUri, schemaDiffTo)
+	if err != nil {
+		return false, fmt.Errorf("--to %s: %w", schemaDiffTo, err)
+	}
+
+	diff := regresql.DiffSchemas(from, to)
+	diff.From, diff.To = schemaDiffFrom, schemaDiffTo
+
+	if schemaDiffFormat == "json" {
+		return diff.HasChanges(), regresql.WriteSchemaDiffJSON(os.Stdout, diff)
+	}
+	regresql.PrintSchemaDiff(os.Stdout, diff)
+	return diff.HasChanges(), nil
+}
@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/boringsql/regresql/v2/regresql"
@@ -11,24 +14,44 @@ import (
 )
 
 var (
-	snapshotCwd             string
-	snapshotOutput          string
-	snapshotOutputDir       string
-	snapshotFormat          string
-	snapshotSchemaOnly      bool
-	snapshotSection         string
-	snapshotSections        bool
-	snapshotInput           string
-	snapshotClean           bool
-	snapshotBuildFixtures          []string
-	snapshotBuildSchema            string
-	snapshotBuildMigrations        string
-	snapshotBuildVerbose           bool
-	snapshotBuildIgnoreSchemaErrs  bool
-	snapshotBuildDisableTriggers   bool
-	snapshotInfoCompare     bool
-	snapshotTagNote         string
-	snapshotTagArchive      string
+	snapshotCwd                   string
+	snapshotOutput                string
+	snapshotOutputDir             string
+	snapshotFormat                string
+	snapshotSchemaOnly            bool
+	snapshotSection               string
+	snapshotSections              bool
+	snapshotInput                 string
+	snapshotClean                 bool
+	snapshotRestoreProgress       bool
+	snapshotRestoreNoVerify       bool
+	snapshotBuildFixtures         []string
+	snapshotBuildSchema           string
+	snapshotBuildMigrations       string
+	snapshotBuildVerbose          bool
+	snapshotBuildIgnoreSchemaErrs bool
+	snapshotBuildDisableTriggers  bool
+	snapshotInfoCompare           bool
+	snapshotInfoVerbose           bool
+	snapshotTagNote               string
+	snapshotTagArchive            string
+	snapshotPruneKeep             int
+	snapshotPruneOlderThan        string
+	snapshotPruneDryRun           bool
+	snapshotBuildResetSeqs        bool
+	snapshotBuildCache            bool
+	snapshotBuildIncremental      bool
+	snapshotBuildTenant           string
+	snapshotBuildIncludeUndo      bool
+	snapshotResetSeqTables        []string
+	snapshotPullForce             bool
+	snapshotPullList              bool
+	snapshotPullDryRun            bool
+	snapshotPushTag               string
+	snapshotPushDryRun            bool
+	snapshotStorageType           string
+	snapshotHistoryLimit          int
+	snapshotHistoryFormat         string
 
 	snapshotCmd = &cobra.Command{
 		Use:   "snapshot",
@@ -87,6 +110,34 @@ Examples:
 		},
 	}
 
+	snapshotVerifyCmd = &cobra.Command{
+		Use:   "verify [flags]",
+		Short: "Check a snapshot is intact before restoring it",
+		Long: `Check a snapshot can be restored without restoring it.
+
+Custom and directory dumps must have a table of contents pg_restore can
+list, and directory dumps every data file it refers to. Plain SQL dumps must
+be valid UTF-8 and end with a complete statement. When the snapshot was
+captured by regresql, its hash must also match the recorded one.
+
+snapshot restore runs the same check first, unless --no-verify is given.
+
+Examples:
+  regresql snapshot verify
+  regresql snapshot verify --input snapshots/v1.dump`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotVerify(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
 	snapshotBuildCmd = &cobra.Command{
 		Use:   "build [flags]",
 		Short: "Build snapshot from fixtures",
@@ -96,7 +147,20 @@ Examples:
   regresql snapshot build
   regresql snapshot build --fixtures users,products,orders
   regresql snapshot build --schema schema.sql --fixtures seed_data
-  regresql snapshot build --output snapshots/test_data.dump --verbose`,
+  regresql snapshot build --output snapshots/test_data.dump --verbose
+  regresql snapshot build --cache
+  regresql snapshot build --incremental
+  regresql snapshot build --tenant-schema acme
+
+With --cache the build is skipped when the schema, migrations, fixture files
+and build options hash the same as for the current snapshot. The hashes are
+kept in snapshots/.regresql-fixture-cache.yaml. Builds using a
+migration_command always run.
+
+With --incremental, when only CSV fixtures changed, the current snapshot is
+restored into a temporary database and just the tables of the changed files
+(plus the tables with foreign keys into them) are truncated and reloaded.
+Any other change falls back to a full build.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := checkDirectory(snapshotCwd); err != nil {
 				fmt.Print(err.Error())
@@ -117,10 +181,12 @@ Examples:
 Shows the snapshot path, hash, size, creation time, server version, planner settings, and fixtures used.
 
 Use --compare to compare stored settings with current database settings.
+Use --verbose to show the exit code and output of the migration command.
 
 Examples:
   regresql snapshot info
-  regresql snapshot info --compare`,
+  regresql snapshot info --compare
+  regresql snapshot info --verbose`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := checkDirectory(snapshotCwd); err != nil {
 				fmt.Print(err.Error())
@@ -179,16 +245,170 @@ Examples:
 			}
 		},
 	}
+
+	snapshotHistoryCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Show the log of built and captured snapshots",
+		Long: `Show every recorded snapshot, newest first: tag, hash prefix, creation
+time, size and the fixtures it was built with.
+
+Building or capturing a snapshot moves a tagged current snapshot to the
+history, which keeps snapshot.history_max_entries entries (default 50).
+
+Examples:
+  regresql snapshot history
+  regresql snapshot history --limit 5 --format json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotHistory(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	snapshotPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old snapshot versions",
+		Long: `Remove snapshot history entries and their files according to a retention policy.
+
+--keep N retains the N most recent snapshots in history, --older-than removes
+snapshots created before the given age (e.g. 30d, 2w, 12h). When both are given
+a snapshot is removed if either rule applies. The current snapshot is never pruned.
+
+Examples:
+  regresql snapshot prune --keep 5
+  regresql snapshot prune --older-than 30d
+  regresql snapshot prune --keep 3 --older-than 90d --dry-run`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotPrune(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	snapshotPushCmd = &cobra.Command{
+		Use:   "push [flags]",
+		Short: "Upload the current snapshot to remote storage",
+		Long: `Upload the current snapshot, or the one tagged --tag, to the storage
+configured under snapshot.storage and record its remote_url in the snapshot
+metadata. Snapshots are stored under <hash>/<file name>, so every version
+keeps its own copy.
+
+snapshot capture and snapshot build push automatically when storage is
+configured; use push for snapshots created before that, or after a failed
+upload. Transfers that fail on the network are retried with exponential
+backoff. --dry-run prints what would be uploaded without connecting.
+
+Configuration (regress.yaml):
+  snapshot:
+    storage:
+      type: s3
+      bucket: my-team-snapshots
+      prefix: myapp
+      endpoint: http://localhost:9000   # S3-compatible service (MinIO, Ceph)
+      force_path_style: true
+
+Examples:
+  regresql snapshot push
+  regresql snapshot push --storage s3 --tag v1.0
+  regresql snapshot push --dry-run`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotPush(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	snapshotPullCmd = &cobra.Command{
+		Use:   "pull [tag] [flags]",
+		Short: "Download a snapshot from remote storage",
+		Long: `Download the current snapshot recorded in the snapshot metadata, or the
+one with the given tag, from the storage configured under snapshot.storage.
+An existing local file is kept unless --force is given. An older snapshot
+sharing the current snapshot's file name is saved with its tag in the name
+(default-v1.0.dump), so restore it with 'regresql snapshot restore --from'.
+
+snapshot restore, test and migrate download a missing current snapshot on
+their own; pull is useful to prefetch it, e.g. in a CI cache step.
+--dry-run prints what would be downloaded without connecting.
+
+Examples:
+  regresql snapshot pull
+  regresql snapshot pull v1.0
+  regresql snapshot pull --force
+  regresql snapshot pull --list`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			tag := ""
+			if len(args) > 0 {
+				tag = args[0]
+			}
+			if err := runSnapshotPull(tag); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	snapshotResetSequencesCmd = &cobra.Command{
+		Use:   "reset-sequences",
+		Short: "Move sequences past the rows loaded by fixtures",
+		Long: `Set every sequence owned by a table column (serial and identity columns) to
+the column's current maximum, so inserts after loading fixtures with explicit
+ids do not fail with duplicate keys. Runs against the configured database.
+
+Set snapshot.reset_sequences: true (or pass --reset-sequences to snapshot build)
+to do this automatically before the snapshot is captured.
+
+Examples:
+  regresql snapshot reset-sequences
+  regresql snapshot reset-sequences --table users --table billing.invoices`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotResetSequences(); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
 )
 
 func init() {
 	RootCmd.AddCommand(snapshotCmd)
 	snapshotCmd.AddCommand(snapshotCaptureCmd)
 	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotVerifyCmd)
 	snapshotCmd.AddCommand(snapshotBuildCmd)
 	snapshotCmd.AddCommand(snapshotInfoCmd)
 	snapshotCmd.AddCommand(snapshotTagCmd)
 	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotHistoryCmd)
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+	snapshotCmd.AddCommand(snapshotResetSequencesCmd)
+	snapshotCmd.AddCommand(snapshotPushCmd)
+	snapshotCmd.AddCommand(snapshotPullCmd)
 
 	snapshotCmd.PersistentFlags().StringVarP(&snapshotCwd, "cwd", "C", ".", "Change to directory")
 
@@ -202,20 +422,49 @@ func init() {
 	snapshotRestoreCmd.Flags().StringVar(&snapshotInput, "from", "", "Input file path")
 	snapshotRestoreCmd.Flags().StringVarP(&snapshotFormat, "format", "f", "", "Snapshot format: custom, plain, or directory")
 	snapshotRestoreCmd.Flags().BoolVar(&snapshotClean, "clean", false, "Drop existing objects before restore")
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotRestoreProgress, "progress", false, "Report restore progress, with a progress bar and ETA for snapshots over 100MB")
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotRestoreNoVerify, "no-verify", false, "Skip the snapshot integrity check before restoring")
+
+	snapshotVerifyCmd.Flags().StringVar(&snapshotInput, "input", "", "Snapshot file or directory (default: the configured snapshot)")
+	snapshotVerifyCmd.Flags().StringVarP(&snapshotFormat, "format", "f", "", "Snapshot format: custom, plain, or directory")
 
 	snapshotBuildCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "", "Output file path")
 	snapshotBuildCmd.Flags().StringVarP(&snapshotFormat, "format", "f", "", "Dump format: custom, plain, or directory")
 	snapshotBuildCmd.Flags().StringVar(&snapshotBuildSchema, "schema", "", "Schema file to apply before migrations")
 	snapshotBuildCmd.Flags().StringVar(&snapshotBuildMigrations, "migrations", "", "Directory of SQL migrations to apply")
-	snapshotBuildCmd.Flags().StringSliceVar(&snapshotBuildFixtures, "fixtures", nil, "Fixture names to apply")
+	snapshotBuildCmd.Flags().StringSliceVar(&snapshotBuildFixtures, "fixtures", nil, "Fixture files to apply (.sql executed, .csv loaded into the table named after the file)")
 	snapshotBuildCmd.Flags().BoolVarP(&snapshotBuildVerbose, "verbose", "v", false, "Print detailed progress")
 	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildIgnoreSchemaErrs, "ignore-schema-errors", false, "Continue on schema errors (e.g., missing roles)")
 	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildDisableTriggers, "disable-triggers", false, "Disable user triggers during fixture application (uses replica mode)")
+	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildResetSeqs, "reset-sequences", false, "Reset owned sequences to the loaded rows before capture")
+	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildCache, "cache", false, "Skip the build when no schema, migration or fixture changed")
+	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildIncremental, "incremental", false, "Reload only the tables of changed CSV fixtures into the current snapshot")
+	snapshotBuildCmd.Flags().StringVar(&snapshotBuildTenant, "tenant-schema", "", "Load fixtures into this tenant schema (search_path and unqualified CSV tables)")
+	snapshotBuildCmd.Flags().BoolVar(&snapshotBuildIncludeUndo, "include-undo", false, "Also apply Flyway undo (U) migrations, newest first, after the others")
 
 	snapshotInfoCmd.Flags().BoolVar(&snapshotInfoCompare, "compare", false, "Compare stored settings with current database")
+	snapshotInfoCmd.Flags().BoolVarP(&snapshotInfoVerbose, "verbose", "v", false, "Show migration command output")
 
 	snapshotTagCmd.Flags().StringVar(&snapshotTagNote, "note", "", "Note describing this snapshot version")
 	snapshotTagCmd.Flags().StringVar(&snapshotTagArchive, "archive", "", "Path to archive the snapshot file")
+
+	snapshotHistoryCmd.Flags().IntVar(&snapshotHistoryLimit, "limit", 10, "Show at most this many snapshots (0 = all)")
+	snapshotHistoryCmd.Flags().StringVar(&snapshotHistoryFormat, "format", "table", "Output format: table or json")
+
+	snapshotPruneCmd.Flags().IntVar(&snapshotPruneKeep, "keep", 0, "Number of most recent tagged snapshots to keep in history")
+	snapshotPruneCmd.Flags().StringVar(&snapshotPruneOlderThan, "older-than", "", "Remove snapshots older than this (e.g. 30d, 2w, 12h)")
+	snapshotPruneCmd.Flags().BoolVar(&snapshotPruneDryRun, "dry-run", false, "Show what would be removed without deleting anything")
+
+	snapshotResetSequencesCmd.Flags().StringSliceVar(&snapshotResetSeqTables, "table", nil, "Only reset sequences owned by these tables (default: all)")
+
+	snapshotPullCmd.Flags().BoolVar(&snapshotPullForce, "force", false, "Overwrite the local snapshot file")
+	snapshotPullCmd.Flags().BoolVar(&snapshotPullList, "list", false, "List snapshots in remote storage instead of downloading")
+	snapshotPullCmd.Flags().BoolVar(&snapshotPullDryRun, "dry-run", false, "Show what would be downloaded without connecting to the storage")
+	snapshotPullCmd.Flags().StringVar(&snapshotStorageType, "storage", "", "Storage backend, overriding snapshot.storage.type (s3)")
+
+	snapshotPushCmd.Flags().StringVar(&snapshotPushTag, "tag", "", "Push the snapshot with this tag instead of the current one")
+	snapshotPushCmd.Flags().BoolVar(&snapshotPushDryRun, "dry-run", false, "Show what would be uploaded without connecting to the storage")
+	snapshotPushCmd.Flags().StringVar(&snapshotStorageType, "storage", "", "Storage backend, overriding snapshot.storage.type (s3)")
 }
 
 func validateSnapshotPrereqs(pguri string) error {
@@ -236,6 +485,7 @@ func runSnapshotCapture() error {
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w (have you run 'regresql init'?)", err)
 	}
+	regresql.SetGlobalConfig(cfg)
 	if err := validateSnapshotPrereqs(cfg.PgUri); err != nil {
 		return err
 	}
@@ -254,11 +504,17 @@ func runSnapshotCapture() error {
 		format = regresql.GetSnapshotFormat(cfg.Snapshot)
 	}
 
+	storage, err := regresql.NewSnapshotStorage(cfg.Snapshot)
+	if err != nil {
+		return err
+	}
+
 	opts := regresql.SnapshotOptions{
 		OutputPath: outputPath,
 		Format:     format,
 		SchemaOnly: snapshotSchemaOnly,
 		Section:    snapshotSection,
+		Storage:    storage,
 	}
 
 	fmt.Printf("Capturing database snapshot...\n")
@@ -287,6 +543,9 @@ func runSnapshotCapture() error {
 	fmt.Printf("  Size: %s\n", regresql.FormatBytes(info.SizeBytes))
 	fmt.Printf("  Hash: %s\n", info.Hash)
 	fmt.Printf("  Time: %s\n", info.Created.Format("2006-01-02 15:04:05 UTC"))
+	if info.RemoteURL != "" {
+		fmt.Printf("  Remote: %s\n", info.RemoteURL)
+	}
 
 	return nil
 }
@@ -432,12 +691,22 @@ func runSnapshotRestore() error {
 		return err
 	}
 
+	storage, remoteKey, err := regresql.RemoteSnapshot(cfg.Snapshot, inputPath)
+	if err != nil {
+		return err
+	}
+
 	withStats := serverCtx != nil && serverCtx.MajorVersion() >= 18
 	opts := regresql.RestoreOptions{
 		InputPath:      inputPath,
 		Format:         format,
 		Clean:          snapshotClean,
 		WithStatistics: withStats,
+		Progress:       snapshotRestoreProgress,
+		Storage:        storage,
+		RemoteKey:      remoteKey,
+		Verify:         !snapshotRestoreNoVerify,
+		ExpectedHash:   recordedSnapshotHash(inputPath),
 	}
 
 	fmt.Printf("Restoring database snapshot...\n")
@@ -459,11 +728,66 @@ func runSnapshotRestore() error {
 	return nil
 }
 
+// recordedSnapshotHash returns the hash recorded at capture for the snapshot
+// at path, empty when regresql has no record of it
+func recordedSnapshotHash(path string) string {
+	metadata, err := regresql.ReadSnapshotMetadata(regresql.GetSnapshotsDir(snapshotCwd))
+	if err != nil {
+		return ""
+	}
+	info, err := regresql.GetSnapshotByPath(metadata, snapshotCwd, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s, the snapshot hash is not checked\n", err)
+		return ""
+	}
+	return info.Hash
+}
+
+func runSnapshotVerify() error {
+	cfg, err := regresql.ReadConfig(snapshotCwd)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w (have you run 'regresql init'?)", err)
+	}
+
+	inputPath := snapshotInput
+	if inputPath == "" {
+		inputPath = regresql.GetSnapshotPath(cfg.Snapshot, snapshotCwd)
+	} else if !filepath.IsAbs(inputPath) {
+		inputPath = filepath.Join(snapshotCwd, inputPath)
+	}
+	format := regresql.SnapshotFormat(snapshotFormat)
+	if format == "" {
+		format = regresql.DetectSnapshotFormat(inputPath)
+	}
+	if format != regresql.FormatPlain {
+		if err := regresql.CheckPgTool("pg_restore", snapshotCwd); err != nil {
+			return err
+		}
+	}
+
+	hash := recordedSnapshotHash(inputPath)
+	fmt.Printf("Verifying %s (%s)\n", inputPath, format)
+	result := regresql.VerifySnapshot(inputPath, format, hash)
+	if !result.Valid {
+		for _, e := range result.Errors {
+			fmt.Printf("  ✗ %s\n", e)
+		}
+		return fmt.Errorf("snapshot failed verification")
+	}
+	if hash == "" {
+		fmt.Println("  ✓ snapshot is readable (no recorded hash to compare)")
+	} else {
+		fmt.Printf("  ✓ snapshot is readable and matches %s\n", regresql.TruncateHash(hash))
+	}
+	return nil
+}
+
 func runSnapshotBuild() error {
 	cfg, err := regresql.ReadConfig(snapshotCwd)
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w (have you run 'regresql init'?)", err)
 	}
+	regresql.SetGlobalConfig(cfg)
 
 	if cfg.PgUri == "" {
 		return fmt.Errorf("pguri not configured in regress.yaml")
@@ -510,6 +834,11 @@ func runSnapshotBuild() error {
 
 	fixturize := regresql.GetSnapshotFixturize(cfg.Snapshot)
 
+	tenantSchema := snapshotBuildTenant
+	if tenantSchema == "" {
+		tenantSchema = regresql.GetSnapshotTenantSchema(cfg.Snapshot)
+	}
+
 	if len(fixtures) == 0 && len(fixturize) == 0 && schemaPath == "" && migrationsDir == "" && migrationCommand == "" {
 		return fmt.Errorf("no schema, migrations, or fixtures specified. Use flags or configure in regress.yaml")
 	}
@@ -539,6 +868,11 @@ func runSnapshotBuild() error {
 		format = regresql.GetSnapshotFormat(cfg.Snapshot)
 	}
 
+	storage, err := regresql.NewSnapshotStorage(cfg.Snapshot)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Building snapshot...\n")
 	fmt.Printf("  Database: %s\n", maskConnectionString(cfg.PgUri))
 	fmt.Printf("  Output:   %s\n", outputPath)
@@ -558,6 +892,12 @@ func runSnapshotBuild() error {
 	if len(fixturize) > 0 {
 		fmt.Printf("  Fixturize: %v\n", fixturize)
 	}
+	if tenantSchema != "" {
+		fmt.Printf("  Tenant:   %s\n", tenantSchema)
+	}
+	if masks := regresql.GetSnapshotMasks(cfg.Snapshot); len(masks) > 0 {
+		fmt.Printf("  Masks:    %d column(s)\n", len(masks))
+	}
 	fmt.Println()
 
 	result, err := regresql.BuildSnapshot(cfg.PgUri, snapshotCwd, regresql.SnapshotBuildOptions{
@@ -565,23 +905,51 @@ func runSnapshotBuild() error {
 		Format:             format,
 		SchemaPath:         schemaPath,
 		MigrationsDir:      migrationsDir,
+		MigrationsNaming:   regresql.GetSnapshotMigrationsNaming(cfg.Snapshot),
+		IncludeUndo:        snapshotBuildIncludeUndo,
 		MigrationCommand:   migrationCommand,
 		Fixtures:           fixtures,
 		Fixturize:          fixturize,
+		CSVNullValue:       regresql.GetSnapshotCSVNullValue(cfg.Snapshot),
+		Masks:              regresql.GetSnapshotMasks(cfg.Snapshot),
+		ResetSequences:     snapshotBuildResetSeqs || regresql.GetSnapshotResetSequences(cfg.Snapshot),
 		Verbose:            snapshotBuildVerbose,
 		IgnoreSchemaErrors: snapshotBuildIgnoreSchemaErrs,
 		DisableTriggers:    snapshotBuildDisableTriggers,
+		Storage:            storage,
+		Cache:              snapshotBuildCache,
+		Incremental:        snapshotBuildIncremental,
+		TenantSchema:       tenantSchema,
 	})
+	snapshotsDir := filepath.Dir(outputPath)
 	if err != nil {
+		var cmdErr *regresql.MigrationCommandError
+		if errors.As(err, &cmdErr) && cmdErr.Result != nil {
+			if recErr := regresql.RecordMigrationCommandFailure(snapshotsDir, cmdErr.Result); recErr == nil {
+				fmt.Printf("Migration command output recorded, see 'regresql snapshot info --verbose'\n")
+			}
+		}
 		return err
 	}
 
-	snapshotsDir := filepath.Dir(outputPath)
+	if result.Cached {
+		fmt.Printf("Snapshot is up to date, build skipped.\n")
+		fmt.Printf("  Hash:     %s\n", result.Info.Hash)
+		fmt.Printf("  Built:    %s\n", result.Info.Created.Format("2006-01-02 15:04:05 UTC"))
+		return nil
+	}
+
 	if err := regresql.WriteSnapshotMetadata(snapshotsDir, result.Info); err != nil {
 		fmt.Printf("Warning: failed to write snapshot metadata: %s\n", err)
 	}
 
 	fmt.Printf("Snapshot built successfully.\n")
+	if len(result.Changed) > 0 {
+		fmt.Printf("  Changed:  %s\n", strings.Join(result.Changed, ", "))
+	}
+	if len(result.Reloaded) > 0 {
+		fmt.Printf("  Reloaded: %s\n", strings.Join(result.Reloaded, ", "))
+	}
 	fmt.Printf("  Size:     %s\n", regresql.FormatBytes(result.Info.SizeBytes))
 	fmt.Printf("  Hash:     %s\n", result.Info.Hash)
 	fmt.Printf("  Duration: %s\n", result.Duration.Round(time.Millisecond))
@@ -603,6 +971,9 @@ func runSnapshotBuild() error {
 	if result.Info.Server != nil {
 		fmt.Printf("  Server:   PostgreSQL %d\n", result.Info.Server.MajorVersion())
 	}
+	if result.Info.RemoteURL != "" {
+		fmt.Printf("  Remote:   %s\n", result.Info.RemoteURL)
+	}
 
 	return nil
 }
@@ -615,6 +986,15 @@ func runSnapshotInfo() error {
 		return fmt.Errorf("no snapshot metadata found. Run 'regresql snapshot build' or 'regresql snapshot capture' first")
 	}
 
+	if failed := metadata.FailedMigrationCommand; failed != nil {
+		fmt.Printf("Last build failed: migration command exited with code %d (%s)\n",
+			failed.ExitCode, failed.Started.Format("2006-01-02 15:04:05"))
+		if snapshotInfoVerbose {
+			printMigrationCommandResult(failed)
+		}
+		fmt.Println()
+	}
+
 	if metadata.Current == nil {
 		return fmt.Errorf("snapshot metadata is empty")
 	}
@@ -626,6 +1006,9 @@ func runSnapshotInfo() error {
 	fmt.Printf("  Size:    %s\n", regresql.FormatBytes(info.SizeBytes))
 	fmt.Printf("  Created: %s\n", info.Created.Format("2006-01-02 15:04:05 UTC"))
 	fmt.Printf("  Hash:    %s\n", info.Hash)
+	if info.RemoteURL != "" {
+		fmt.Printf("  Remote:  %s\n", info.RemoteURL)
+	}
 
 	if info.SchemaPath != "" {
 		fmt.Println()
@@ -641,7 +1024,11 @@ func runSnapshotInfo() error {
 		fmt.Printf("  Hash: %s\n", info.MigrationsHash)
 		if len(info.MigrationsApplied) > 0 {
 			fmt.Println("  Applied:")
-			for _, m := range info.MigrationsApplied {
+			for i, m := range info.MigrationsApplied {
+				if i < len(info.MigrationChecksums) && info.MigrationChecksums[i].File == m {
+					fmt.Printf("    - %s (%s)\n", m, regresql.TruncateHash(info.MigrationChecksums[i].Hash))
+					continue
+				}
 				fmt.Printf("    - %s\n", m)
 			}
 		}
@@ -652,6 +1039,12 @@ func runSnapshotInfo() error {
 		fmt.Println("Migration command:")
 		fmt.Printf("  Command: %s\n", info.MigrationCommand)
 		fmt.Printf("  Hash:    %s\n", info.MigrationCommandHash)
+		if res := info.MigrationCommandResult; res != nil {
+			fmt.Printf("  Exit:    %d (%s)\n", res.ExitCode, res.Duration.Round(time.Millisecond))
+			if snapshotInfoVerbose {
+				printMigrationCommandResult(res)
+			}
+		}
 	}
 
 	if len(info.FixturesUsed) > 0 {
@@ -670,6 +1063,14 @@ func runSnapshotInfo() error {
 		}
 	}
 
+	if len(info.MasksApplied) > 0 {
+		fmt.Println()
+		fmt.Println("Masked columns:")
+		for _, m := range info.MasksApplied {
+			fmt.Printf("  - %s\n", m)
+		}
+	}
+
 	if info.Server != nil {
 		fmt.Println()
 		fmt.Printf("Server: PostgreSQL %s\n", info.Server.Version)
@@ -693,6 +1094,19 @@ func runSnapshotInfo() error {
 	return nil
 }
 
+func printMigrationCommandResult(res *regresql.MigrationCommandResult) {
+	fmt.Printf("  Command: %s\n", res.Command)
+	for _, stream := range []struct{ name, output string }{{"stdout", res.Stdout}, {"stderr", res.Stderr}} {
+		if strings.TrimSpace(stream.output) == "" {
+			continue
+		}
+		fmt.Printf("  %s:\n", stream.name)
+		for _, line := range strings.Split(strings.TrimRight(stream.output, "\n"), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
 func runSnapshotInfoCompare(info *regresql.SnapshotInfo) error {
 	if info.Server == nil {
 		fmt.Println()
@@ -811,3 +1225,291 @@ func runSnapshotList() error {
 
 	return nil
 }
+
+func runSnapshotHistory() error {
+	if snapshotHistoryFormat != "table" && snapshotHistoryFormat != "json" {
+		return fmt.Errorf("unknown format %q (expected table or json)", snapshotHistoryFormat)
+	}
+	metadata, err := regresql.ReadSnapshotMetadata(regresql.GetSnapshotsDir(snapshotCwd))
+	if err != nil {
+		return fmt.Errorf("no snapshot metadata found. Run 'regresql snapshot build' or 'regresql snapshot capture' first")
+	}
+	snapshots := regresql.SnapshotHistory(metadata)
+	if snapshotHistoryLimit > 0 && len(snapshots) > snapshotHistoryLimit {
+		snapshots = snapshots[:snapshotHistoryLimit]
+	}
+
+	if snapshotHistoryFormat == "json" {
+		type entry struct {
+			Tag       string    `json:"tag,omitempty"`
+			Hash      string    `json:"hash"`
+			Created   time.Time `json:"created"`
+			SizeBytes int64     `json:"size_bytes"`
+			Path      string    `json:"path,omitempty"`
+			Fixtures  []string  `json:"fixtures,omitempty"`
+			Note      string    `json:"note,omitempty"`
+		}
+		entries := make([]entry, len(snapshots))
+		for i, info := range snapshots {
+			entries[i] = entry{
+				Tag: info.Tag, Hash: info.Hash, Created: info.Created, SizeBytes: info.SizeBytes,
+				Path: info.Path, Fixtures: snapshotFixtures(info), Note: info.Note,
+			}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("%-12s %-20s %-20s %-10s %s\n", "TAG", "HASH", "CREATED", "SIZE", "FIXTURES")
+	fmt.Println("─────────────────────────────────────────────────────────────────────────────────────")
+
+	for _, info := range snapshots {
+		tag := info.Tag
+		if tag == "" {
+			tag = "(untagged)"
+		}
+		if info == metadata.Current {
+			tag = tag + "*"
+		}
+		fixtures := strings.Join(snapshotFixtures(info), ", ")
+		if fixtures == "" {
+			fixtures = "-"
+		}
+
+		fmt.Printf("%-12s %-20s %-20s %-10s %s\n", tag, regresql.TruncateHash(info.Hash),
+			info.Created.Format("2006-01-02 15:04:05"), regresql.FormatBytes(info.SizeBytes), fixtures)
+	}
+
+	if metadata.Current != nil {
+		fmt.Println()
+		fmt.Println("* = current snapshot")
+	}
+	return nil
+}
+
+// snapshotFixtures lists the fixture and fixturize files a snapshot was built with
+func snapshotFixtures(info *regresql.SnapshotInfo) []string {
+	return append(append([]string{}, info.FixturesUsed...), info.FixturizeUsed...)
+}
+
+func runSnapshotPrune() error {
+	snapshotsDir := regresql.GetSnapshotsDir(snapshotCwd)
+
+	opts := regresql.PruneOptions{
+		Keep:   snapshotPruneKeep,
+		DryRun: snapshotPruneDryRun,
+	}
+	if snapshotPruneOlderThan != "" {
+		d, err := regresql.ParseRetention(snapshotPruneOlderThan)
+		if err != nil {
+			return err
+		}
+		opts.OlderThan = d
+	}
+
+	if _, err := regresql.ReadSnapshotMetadata(snapshotsDir); err != nil {
+		return fmt.Errorf("no snapshot metadata found. Run 'regresql snapshot build' or 'regresql snapshot capture' first")
+	}
+
+	result, err := regresql.PruneSnapshots(snapshotsDir, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Removed) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+
+	verb := "Removed"
+	if snapshotPruneDryRun {
+		verb = "Would remove"
+	}
+	for _, info := range result.Removed {
+		fmt.Printf("%s %s (%s, %s)\n", verb, regresql.FormatSnapshotRef(info),
+			info.Created.Format("2006-01-02 15:04:05"), regresql.FormatBytes(info.SizeBytes))
+	}
+	fmt.Printf("\n%s %d snapshot(s), %s freed, %d kept in history\n",
+		verb, len(result.Removed), regresql.FormatBytes(result.FreedBytes), len(result.Kept))
+
+	return nil
+}
+
+func runSnapshotResetSequences() error {
+	cfg, err := regresql.ReadConfig(snapshotCwd)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	db, err := regresql.OpenDB(cfg.PgUri)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer db.Close()
+
+	reset, err := regresql.ResetSequences(db, snapshotResetSeqTables)
+	if err != nil {
+		return err
+	}
+
+	if len(reset) == 0 {
+		fmt.Println("No owned sequences found.")
+		return nil
+	}
+	for _, seq := range reset {
+		fmt.Printf("  %s\n", seq)
+	}
+	fmt.Printf("Reset %d sequence(s)\n", len(reset))
+	return nil
+}
+
+// snapshotStorage returns the configured storage backend, failing when none
+// is configured. --storage overrides the configured backend type.
+func snapshotStorage(cfg *regresql.SnapshotConfig) (regresql.SnapshotStorage, error) {
+	if snapshotStorageType != "" {
+		override := regresql.SnapshotConfig{Storage: &regresql.SnapshotStorageConfig{}}
+		if cfg != nil {
+			override = *cfg
+			if cfg.Storage != nil {
+				storage := *cfg.Storage
+				override.Storage = &storage
+			} else {
+				override.Storage = &regresql.SnapshotStorageConfig{}
+			}
+		}
+		override.Storage.Type = snapshotStorageType
+		cfg = &override
+	}
+
+	storage, err := regresql.NewSnapshotStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if storage == nil {
+		return nil, fmt.Errorf("no remote storage configured. Set snapshot.storage in regress.yaml")
+	}
+	return storage, nil
+}
+
+// storedSnapshot returns the snapshot metadata and the entry tagged tag, or
+// the current snapshot when tag is empty
+func storedSnapshot(snapshotsDir, tag string) (*regresql.SnapshotMetadata, *regresql.SnapshotInfo, error) {
+	metadata, err := regresql.ReadSnapshotMetadata(snapshotsDir)
+	if err != nil || metadata.Current == nil {
+		return nil, nil, fmt.Errorf("no snapshot metadata found. Run 'regresql snapshot build' or 'regresql snapshot capture' first")
+	}
+	if tag == "" {
+		return metadata, metadata.Current, nil
+	}
+	info, err := regresql.GetSnapshotByTag(metadata, tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata, info, nil
+}
+
+func runSnapshotPush() error {
+	cfg, err := regresql.ReadConfig(snapshotCwd)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	storage, err := snapshotStorage(cfg.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	snapshotsDir := regresql.GetSnapshotsDir(snapshotCwd)
+	metadata, info, err := storedSnapshot(snapshotsDir, snapshotPushTag)
+	if err != nil {
+		return err
+	}
+	localPath, err := regresql.LocalSnapshotPath(snapshotsDir, metadata, info)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("snapshot file not found: %s", localPath)
+	}
+
+	if snapshotPushDryRun {
+		fmt.Printf("Would upload %s (%s) to %s\n", localPath, regresql.FormatBytes(info.SizeBytes), storage.URL(regresql.SnapshotStorageKey(info)))
+		return nil
+	}
+
+	fmt.Printf("Uploading %s (%s)...\n", localPath, regresql.FormatBytes(info.SizeBytes))
+	if err := regresql.PushSnapshot(storage, localPath, info); err != nil {
+		return err
+	}
+	if err := regresql.WriteSnapshotMetadataFull(snapshotsDir, metadata); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot pushed to %s\n", info.RemoteURL)
+	return nil
+}
+
+func runSnapshotPull(tag string) error {
+	cfg, err := regresql.ReadConfig(snapshotCwd)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	storage, err := snapshotStorage(cfg.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	if snapshotPullList {
+		if snapshotPullDryRun {
+			return fmt.Errorf("--dry-run cannot be combined with --list")
+		}
+		entries, err := storage.List()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("No snapshots in remote storage.")
+			return nil
+		}
+		fmt.Printf("%-50s %-20s %s\n", "KEY", "MODIFIED", "SIZE")
+		for _, e := range entries {
+			fmt.Printf("%-50s %-20s %s\n", e.Key, e.LastModified.Format("2006-01-02 15:04:05"), regresql.FormatBytes(e.SizeBytes))
+		}
+		return nil
+	}
+
+	snapshotsDir := regresql.GetSnapshotsDir(snapshotCwd)
+	metadata, info, err := storedSnapshot(snapshotsDir, tag)
+	if err != nil {
+		return err
+	}
+	if info.RemoteURL == "" {
+		return fmt.Errorf("snapshot %s was never pushed. Run 'regresql snapshot push' where it was built", regresql.FormatSnapshotRef(info))
+	}
+
+	localPath, err := regresql.LocalSnapshotPath(snapshotsDir, metadata, info)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(localPath); err == nil && !snapshotPullForce {
+		fmt.Printf("Snapshot already present: %s (use --force to download again)\n", localPath)
+		return nil
+	}
+	if snapshotPullDryRun {
+		fmt.Printf("Would download %s (%s) to %s\n", info.RemoteURL, regresql.FormatBytes(info.SizeBytes), localPath)
+		return nil
+	}
+	fmt.Printf("Downloading %s...\n", info.RemoteURL)
+	if err := regresql.PullSnapshot(storage, localPath, info); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot pulled to %s\n", localPath)
+	if info != metadata.Current {
+		fmt.Printf("Restore it with: regresql snapshot restore --from %s\n", localPath)
+	}
+	return nil
+}
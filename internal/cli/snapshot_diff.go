@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotDiffQuery  string
+	snapshotDiffFormat string
+
+	snapshotDiffCmd = &cobra.Command{
+		Use:   "diff <from-ref> <to-ref>",
+		Short: "Compare query outputs between two tagged snapshots",
+		Long: `Compare query outputs between two snapshots.
+
+Both snapshots are restored to temporary databases and every query is run
+against each. References are tag names or hash prefixes; use "current" for
+the current snapshot. Queries are grouped into unchanged, row count changes
+and value changes.
+
+Use --format json for machine-readable output including the structured diff
+of each changed query. Progress messages go to stderr.
+
+Examples:
+  regresql snapshot diff v1 v2
+  regresql snapshot diff v1 current --query orders/get_order_total.sql
+  regresql snapshot diff a1b2c3 v2 --format json > diff.json`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(snapshotCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+			if err := runSnapshotDiff(args[0], args[1]); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+
+	snapshotDiffCmd.Flags().StringVar(&snapshotDiffQuery, "query", "", "Specific query file to compare (optional)")
+	snapshotDiffCmd.Flags().StringVar(&snapshotDiffFormat, "format", "console", "Output format: console or json")
+}
+
+func runSnapshotDiff(fromRef, toRef string) error {
+	if snapshotDiffFormat != "console" && snapshotDiffFormat != "json" {
+		return fmt.Errorf("unknown format %q (expected console or json)", snapshotDiffFormat)
+	}
+
+	fromInfo, toInfo, err := resolveSnapshotPair(snapshotCwd, fromRef, toRef)
+	if err != nil {
+		return err
+	}
+
+	if fromInfo.Hash == toInfo.Hash && snapshotDiffFormat == "console" {
+		fmt.Printf("Both snapshots are identical (%s)\n", regresql.FormatSnapshotRef(fromInfo))
+		return nil
+	}
+
+	result, err := regresql.DiffSnapshots(snapshotCwd, fromInfo, toInfo, snapshotDiffQuery, "")
+	if err != nil {
+		return err
+	}
+
+	if snapshotDiffFormat == "json" {
+		return regresql.WriteSnapshotDiffJSON(os.Stdout, result)
+	}
+
+	printSnapshotDiffSummary(result, fromInfo, toInfo)
+	return nil
+}
+
+func printSnapshotDiffSummary(result *regresql.SnapshotDiffResult, from, to *regresql.SnapshotInfo) {
+	rowCount := result.RowCountChanges()
+	values := result.ValueChanges()
+
+	fmt.Printf("%-40s %s\n", regresql.FormatSnapshotRef(from), regresql.FormatSnapshotRef(to))
+	fmt.Println()
+
+	if len(rowCount) > 0 {
+		fmt.Printf("ROW COUNT CHANGES (%d):\n", len(rowCount))
+		for _, d := range rowCount {
+			fmt.Printf("  %-38s %6d → %d rows\n", d.QueryPath, d.FromRows, d.ToRows)
+		}
+		fmt.Println()
+	}
+
+	if len(values) > 0 {
+		fmt.Printf("VALUE CHANGES (%d):\n", len(values))
+		for _, d := range values {
+			detail := fmt.Sprintf("%d rows", d.ToRows)
+			if sd := d.StructuredDiff; sd != nil {
+				detail = fmt.Sprintf("%d modified, %d added, %d removed of %d rows",
+					sd.ModifiedRows, sd.AddedRows, sd.RemovedRows, d.ToRows)
+			}
+			fmt.Printf("  %-38s %s\n", d.QueryPath, detail)
+		}
+		fmt.Println()
+	}
+
+	if len(result.Errors) > 0 {
+		fmt.Printf("ERRORS (%d):\n", len(result.Errors))
+		for _, e := range result.Errors {
+			fmt.Printf("  %s: %s\n", e.QueryPath, e.Error)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("SUMMARY:\n")
+	fmt.Printf("  Unchanged:         %d\n", len(result.Unchanged))
+	fmt.Printf("  Row count changes: %d\n", len(rowCount))
+	fmt.Printf("  Value changes:     %d\n", len(values))
+	if len(result.Errors) > 0 {
+		fmt.Printf("  Errors:            %d\n", len(result.Errors))
+	}
+}
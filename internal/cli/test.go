@@ -16,14 +16,18 @@ var (
 	testCommit        bool
 	testNoRestore     bool
 	testFailOnSkipped bool
-	testColor         bool
-	testNoColor       bool
 	testFullDiff      bool
 	testNoDiff        bool
-	testSnapshot  string
-	testStatsFile string
-	testVerbose   bool
-	testStrict    bool
+	testSnapshot      string
+	testStatsFile     string
+	testVerbose       bool
+	testStrict        bool
+	testCheckTypes    bool
+	testParallel      int
+	testInteractive   bool
+	testPercentile    int
+	testFailFast      bool
+	testStrictSchema  bool
 
 	testCmd = &cobra.Command{
 		Use:   "test [flags]",
@@ -33,22 +37,32 @@ var (
 				fmt.Print(err.Error())
 				os.Exit(1)
 			}
+			format := testFormat
+			if !cmd.Flags().Changed("format") && regresql.InGitHubActions() {
+				format = "github-actions"
+			}
 			opts := regresql.TestOptions{
 				Root:          testCwd,
 				RunFilter:     testRunFilter,
-				FormatName:    testFormat,
+				FormatName:    format,
 				OutputPath:    testOutputPath,
 				Commit:        testCommit,
 				NoRestore:     testNoRestore,
 				FailOnSkipped: testFailOnSkipped,
-				Color:         testColor,
-				NoColor:       testNoColor,
+				Color:         rootColor,
+				NoColor:       rootNoColor,
 				FullDiff:      testFullDiff,
 				NoDiff:        testNoDiff,
 				Snapshot:      testSnapshot,
 				Stats:         testStatsFile,
 				Verbose:       testVerbose,
 				Strict:        testStrict,
+				CheckTypes:    testCheckTypes,
+				Parallel:      testParallel,
+				FailFast:      testFailFast,
+				Interactive:   testInteractive,
+				Percentile:    testPercentile,
+				StrictSchema:  testStrictSchema,
 			}
 			regresql.Test(opts)
 		},
@@ -60,17 +74,21 @@ func init() {
 
 	testCmd.Flags().StringVarP(&testCwd, "cwd", "C", ".", "Change to Directory")
 	testCmd.Flags().StringVar(&testRunFilter, "run", "", "Run only queries matching regexp (matches file names and query names)")
-	testCmd.Flags().StringVar(&testFormat, "format", "console", "Output format: console, pgtap, junit, json, github-actions")
+	testCmd.Flags().StringVar(&testFormat, "format", "console", "Output format: console, pgtap, junit, json, github-actions (alias github), html; defaults to github-actions inside GitHub Actions")
 	testCmd.Flags().StringVarP(&testOutputPath, "output", "o", "", "Output file path (default: stdout)")
 	testCmd.Flags().BoolVar(&testCommit, "commit", false, "Commit transactions instead of rollback (use with caution)")
 	testCmd.Flags().BoolVar(&testNoRestore, "no-restore", false, "Skip snapshot restore before test")
 	testCmd.Flags().BoolVar(&testFailOnSkipped, "fail-on-skipped", false, "Exit with code 2 if skipped tests exist")
 	testCmd.Flags().BoolVar(&testStrict, "strict", false, "Exit with code 10 if any plan warning or regression is present (treats warnings as errors)")
-	testCmd.Flags().BoolVar(&testColor, "color", false, "Force colored output")
-	testCmd.Flags().BoolVar(&testNoColor, "no-color", false, "Disable colored output")
+	testCmd.Flags().BoolVar(&testStrictSchema, "strict-schema", false, "Fail when there is no snapshot metadata with a schema hash to check the schema against")
 	testCmd.Flags().BoolVar(&testFullDiff, "diff", false, "Show full diff output (no truncation)")
 	testCmd.Flags().BoolVar(&testNoDiff, "no-diff", false, "Suppress diff output entirely")
 	testCmd.Flags().StringVar(&testSnapshot, "snapshot", "", "Run tests against specific snapshot (tag or hash prefix)")
 	testCmd.Flags().StringVar(&testStatsFile, "stats", "", "SQL statistics file to apply instead of ANALYZE (requires PG18+)")
 	testCmd.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "Show each test with name, type, and duration")
+	testCmd.Flags().BoolVar(&testCheckTypes, "check-types", false, "Fail when result column types differ from the expected files")
+	testCmd.Flags().IntVar(&testParallel, "parallel", 1, "Run up to N queries concurrently, each in its own transaction")
+	testCmd.Flags().BoolVarP(&testFailFast, "fail-fast", "x", false, "Stop after the first failed test (cancels the queries still running with --parallel)")
+	testCmd.Flags().BoolVar(&testInteractive, "interactive", false, "Review failing output diffs and approve them into expected files (writes regresql/pending-approvals.json when not a terminal)")
+	testCmd.Flags().IntVar(&testPercentile, "percentile", 0, "Compare costs against the p50, p95 or p99 recorded by 'regresql baseline percentile' (overrides analyze.cost_percentile)")
 }
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/boringsql/regresql/v2/regresql/tracing"
+	"github.com/spf13/cobra"
+)
+
+var shutdownTracing func(context.Context) error
+
+func init() {
+	RootCmd.PersistentPreRun = startTracing
+	RootCmd.PersistentPostRun = stopTracing
+}
+
+// startTracing exports OpenTelemetry spans when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set or regress.yaml has tracing: true
+func startTracing(cmd *cobra.Command, args []string) {
+	if !tracing.Enabled() && !tracingConfigured(cmd) {
+		return
+	}
+	shutdown, err := tracing.Init(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %s\n", err)
+		return
+	}
+	shutdownTracing = shutdown
+}
+
+func stopTracing(cmd *cobra.Command, args []string) {
+	if shutdownTracing == nil {
+		return
+	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to flush traces: %s\n", err)
+	}
+}
+
+func tracingConfigured(cmd *cobra.Command) bool {
+	cwd, err := cmd.Flags().GetString("cwd")
+	if err != nil {
+		cwd = "."
+	}
+	cfg, err := regresql.ReadConfig(cwd)
+	return err == nil && cfg.Tracing
+}
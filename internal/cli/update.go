@@ -17,6 +17,11 @@ var (
 	updateInteractive bool
 	updateDryRun      bool
 	updateSnapshot    string
+	updateCheckTypes  bool
+	updateRole        string
+	updateQuery       string
+	updateBinding     string
+	updateFailed      bool
 
 	// updateCmd represents the update command
 	updateCmd = &cobra.Command{
@@ -33,7 +38,10 @@ Examples:
   regresql update orders/get_order.sql    # Update specific query
   regresql update --pending               # Only create missing baselines
   regresql update --dry-run               # Preview what would be updated
-  regresql update --interactive           # Review each change`,
+  regresql update --interactive           # Review each change
+  regresql update --role app_user         # Only queries run as app_user
+  regresql update orders/get_order.sql --query get_order --binding 1
+  regresql update --failed                # Only tests that failed in the last 'regresql test'`,
 		Args: cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := checkDirectory(updateCwd); err != nil {
@@ -50,6 +58,11 @@ Examples:
 				Interactive: updateInteractive,
 				DryRun:      updateDryRun,
 				Snapshot:    updateSnapshot,
+				CheckTypes:  updateCheckTypes,
+				Role:        updateRole,
+				Query:       updateQuery,
+				Binding:     updateBinding,
+				Failed:      updateFailed,
 			})
 		},
 	}
@@ -66,4 +79,9 @@ func init() {
 	updateCmd.Flags().BoolVar(&updateInteractive, "interactive", false, "Review and confirm each update")
 	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Show what would be updated without writing files")
 	updateCmd.Flags().StringVar(&updateSnapshot, "snapshot", "", "Update baselines against specific snapshot (tag or hash prefix)")
+	updateCmd.Flags().BoolVar(&updateCheckTypes, "check-types", false, "Record result column types in the expected files")
+	updateCmd.Flags().StringVar(&updateRole, "role", "", "Only update queries annotated with this role (-- regresql: role=...)")
+	updateCmd.Flags().StringVar(&updateQuery, "query", "", "Only update the query with this name")
+	updateCmd.Flags().StringVar(&updateBinding, "binding", "", "Only update this binding of queries with parameters")
+	updateCmd.Flags().BoolVar(&updateFailed, "failed", false, "Only update the output tests that failed in the last 'regresql test' run")
 }
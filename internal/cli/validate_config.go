@@ -9,7 +9,8 @@ import (
 )
 
 var (
-	validateConfigCwd string
+	validateConfigCwd    string
+	validateConfigSchema bool
 
 	validateConfigCmd = &cobra.Command{
 		Use:   "validate-config",
@@ -21,7 +22,11 @@ Validates:
   - Config file exists and is parseable
   - No deprecated 'fixtures:' or 'cleanup:' fields in plan files
   - All fixture files are valid
-  - Snapshot paths exist (if configured)`,
+  - Snapshot paths exist (if configured)
+
+With --schema, also connects to the database and checks CSV fixtures from
+snapshot.fixtures: target tables and columns exist, required (NOT NULL, no
+default) columns are provided and values parse as the column types.`,
 		Run: runValidateConfig,
 	}
 )
@@ -29,6 +34,7 @@ Validates:
 func init() {
 	RootCmd.AddCommand(validateConfigCmd)
 	validateConfigCmd.Flags().StringVarP(&validateConfigCwd, "cwd", "C", ".", "Change to Directory")
+	validateConfigCmd.Flags().BoolVar(&validateConfigSchema, "schema", false, "Validate CSV fixtures against the database schema")
 }
 
 func runValidateConfig(cmd *cobra.Command, args []string) {
@@ -51,6 +57,19 @@ func runValidateConfig(cmd *cobra.Command, args []string) {
 	printPlanIssues(result.PlanIssues)
 	printSnapshotIssues(result.SnapshotIssues)
 
+	if validateConfigSchema && result.ConfigValid {
+		issues, err := regresql.ValidateFixturesAgainstDatabase(validateConfigCwd)
+		if err != nil {
+			fmt.Printf("✗ Fixture schema check failed: %s\n", err)
+			result.Passed = false
+		} else if len(issues) > 0 {
+			printFixtureSchemaIssues(issues)
+			result.Passed = false
+		} else {
+			fmt.Println("✓ Fixtures match the database schema")
+		}
+	}
+
 	fmt.Println()
 	if result.Passed {
 		fmt.Println("✓ Ready for RegreSQL 2.0")
@@ -96,6 +115,13 @@ func printSnapshotIssues(issues []regresql.ValidationIssue) {
 	}
 }
 
+func printFixtureSchemaIssues(issues []regresql.ValidationIssue) {
+	fmt.Println("✗ Fixture schema issues:")
+	for _, issue := range issues {
+		fmt.Printf("  - %s: %s\n", issue.File, issue.Message)
+	}
+}
+
 func filterByField(issues []regresql.ValidationIssue, field string) []regresql.ValidationIssue {
 	var filtered []regresql.ValidationIssue
 	for _, issue := range issues {
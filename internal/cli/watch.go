@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchCwd       string
+	watchNoRestore bool
+	watchInterval  time.Duration
+	watchVerbose   bool
+
+	watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Re-run tests automatically when SQL or plan files change",
+		Long: `Runs the full test suite once, then watches *.sql files and plan files
+under regresql/plans. Each change re-runs only the affected queries: a SQL
+file change re-runs every query in that file, a plan change re-runs just
+that plan's query.
+
+Examples:
+  regresql watch
+  regresql watch --no-restore
+  regresql watch --interval 1s`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkDirectory(watchCwd); err != nil {
+				fmt.Print(err.Error())
+				os.Exit(1)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			err := regresql.Watch(ctx, regresql.WatchOptions{
+				Root:      watchCwd,
+				NoRestore: watchNoRestore,
+				Interval:  watchInterval,
+				Color:     rootColor,
+				NoColor:   rootNoColor,
+				Verbose:   watchVerbose,
+			})
+			if err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&watchCwd, "cwd", "C", ".", "Change to Directory")
+	watchCmd.Flags().BoolVar(&watchNoRestore, "no-restore", false, "Skip snapshot restore before the first run")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 500*time.Millisecond, "How often to poll for file changes")
+	watchCmd.Flags().BoolVarP(&watchVerbose, "verbose", "v", false, "Show each test with name, type, and duration")
+}
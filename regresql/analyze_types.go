@@ -51,6 +51,13 @@ type (
 		SortSpaceUsed int64    `json:"Sort Space Used,omitempty"`
 		SortSpaceType string   `json:"Sort Space Type,omitempty"`
 
+		// Hash fields (only present with ANALYZE true)
+		HashBuckets         int64 `json:"Hash Buckets,omitempty"`
+		OriginalHashBuckets int64 `json:"Original Hash Buckets,omitempty"`
+		HashBatches         int64 `json:"Hash Batches,omitempty"`
+		OriginalHashBatches int64 `json:"Original Hash Batches,omitempty"`
+		PeakMemoryUsage     int64 `json:"Peak Memory Usage,omitempty"`
+
 		// Parallel/Gather fields
 		WorkersPlanned  int  `json:"Workers Planned,omitempty"`
 		WorkersLaunched int  `json:"Workers Launched,omitempty"`
@@ -0,0 +1,122 @@
+package regresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/term"
+)
+
+// ApprovalManifestFile is written to the regresql directory when
+// `regresql test --interactive` cannot prompt (stdout is not a terminal or
+// the formatter is not interactive)
+const ApprovalManifestFile = "pending-approvals.json"
+
+type (
+	// Approval is a failed output test whose actual result can replace the
+	// expected file
+	Approval struct {
+		Name        string `json:"name"`
+		QueryFile   string `json:"query_file,omitempty"`
+		BindingName string `json:"binding,omitempty"`
+		Actual      string `json:"actual"`
+		Expected    string `json:"expected"`
+		Diff        string `json:"diff,omitempty"`
+	}
+
+	// interactiveFormatter is implemented by formatters that write to a
+	// terminal a user can answer prompts in
+	interactiveFormatter interface {
+		IsInteractive() bool
+	}
+)
+
+// IsInteractive reports whether prompts can be shown alongside console output
+func (f *ConsoleFormatter) IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+func isInteractiveFormatter(f OutputFormatter) bool {
+	i, ok := f.(interactiveFormatter)
+	return ok && i.IsInteractive()
+}
+
+// PendingApprovals collects failed output comparisons in result order
+func PendingApprovals(results []TestResult) []Approval {
+	var approvals []Approval
+	for _, r := range results {
+		if r.Type != "output" || r.Status != "failed" || r.ActualFile == "" || r.ExpectedFile == "" {
+			continue
+		}
+		approvals = append(approvals, Approval{
+			Name:        r.Name,
+			QueryFile:   r.QueryFile,
+			BindingName: r.BindingName,
+			Actual:      r.ActualFile,
+			Expected:    r.ExpectedFile,
+			Diff:        r.Diff,
+		})
+	}
+	return approvals
+}
+
+// Approve replaces the expected file with the actual output. An expected
+// file in the other result format is replaced by one in the actual's format.
+func (a Approval) Approve() error {
+	target := a.Expected
+	if isPsqlResultFile(a.Actual) != isPsqlResultFile(a.Expected) {
+		target = alternateResultFile(a.Expected)
+	}
+	if err := copyFile(a.Actual, target); err != nil {
+		return fmt.Errorf("failed to approve %s: %w", a.Name, err)
+	}
+	if target != a.Expected {
+		if err := os.Remove(a.Expected); err != nil {
+			return fmt.Errorf("failed to approve %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// approveInteractively prompts for each approval and returns how many were
+// approved. Quitting stops prompting but keeps earlier approvals.
+func approveInteractively(approvals []Approval, prompter *InteractivePrompter) (int, error) {
+	approved := 0
+	for i, a := range approvals {
+		fmt.Printf("\n(%d/%d)", i+1, len(approvals))
+		switch prompter.PromptApprove(a.Name, a.Diff) {
+		case "approve":
+			if err := a.Approve(); err != nil {
+				return approved, err
+			}
+			approved++
+			fmt.Printf("Approved: %s\n", a.Expected)
+		case "quit":
+			return approved, ErrUserQuit
+		}
+	}
+	return approved, nil
+}
+
+// writeApprovalManifest writes the pending approvals as JSON for offline
+// review; an empty list removes a stale manifest
+func writeApprovalManifest(regressDir string, approvals []Approval) (string, error) {
+	path := filepath.Join(regressDir, ApprovalManifestFile)
+	if len(approvals) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write approval manifest: %w", err)
+	}
+	return path, nil
+}
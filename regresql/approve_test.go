@@ -0,0 +1,60 @@
+package regresql
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPendingApprovals(t *testing.T) {
+	results := []TestResult{
+		{Name: "a.json", Type: "output", Status: "failed", ActualFile: "out/a.json", ExpectedFile: "expected/a.json"},
+		{Name: "b.json", Type: "output", Status: "passed", ActualFile: "out/b.json", ExpectedFile: "expected/b.json"},
+		{Name: "c.cost", Type: "cost", Status: "failed"},
+		{Name: "d.json", Type: "output", Status: "pending", ActualFile: "out/d.json", ExpectedFile: "expected/d.json"},
+	}
+
+	approvals := PendingApprovals(results)
+	if len(approvals) != 1 || approvals[0].Name != "a.json" {
+		t.Fatalf("PendingApprovals() = %+v, want only a.json", approvals)
+	}
+}
+
+func TestApproveAndManifest(t *testing.T) {
+	dir := t.TempDir()
+	actual := filepath.Join(dir, "out", "q.json")
+	expected := filepath.Join(dir, "expected", "q.json")
+	if err := os.MkdirAll(filepath.Dir(actual), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(actual, []byte(`{"rows":[[2]]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := Approval{Name: "q.json", Actual: actual, Expected: expected}
+	if err := a.Approve(); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if data, _ := os.ReadFile(expected); string(data) != `{"rows":[[2]]}` {
+		t.Errorf("expected file = %q", data)
+	}
+
+	path, err := writeApprovalManifest(dir, []Approval{a})
+	if err != nil {
+		t.Fatalf("writeApprovalManifest() error = %v", err)
+	}
+	var got []Approval
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &got); err != nil || len(got) != 1 || got[0].Expected != expected {
+		t.Errorf("manifest = %s (err %v)", data, err)
+	}
+
+	// no pending approvals removes a stale manifest
+	if _, err := writeApprovalManifest(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("stale manifest should be removed")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +26,19 @@ type (
 		AnalyzeMode   bool            `json:"analyze_mode,omitempty"`
 		Buffers       *BufferBaseline `json:"buffers,omitempty"`
 		Actuals       *ActualBaseline `json:"actuals,omitempty"`
+
+		// Explain is the full EXPLAIN output, kept for `baseline show`
+		Explain *ExplainOutput `json:"explain,omitempty"`
+
+		// AutoCreated marks baselines recorded by `regresql test` with
+		// auto_baseline enabled; `baseline purge` removes them
+		AutoCreated bool `json:"auto_created,omitempty"`
+
+		// CostPercentiles are recorded by `baseline percentile`
+		*CostPercentiles
+
+		// Stability is recorded by `baseline stability`
+		Stability *StabilityReport `json:"stability,omitempty"`
 	}
 
 	BufferBaseline struct {
@@ -90,6 +105,14 @@ func ExecuteExplain(ctx context.Context, q Querier, query string, args ...any) (
 	return ExecuteExplainWithOptions(ctx, q, query, DefaultExplainOptions(), args...)
 }
 
+// ExecuteExplainAnalyze runs EXPLAIN (ANALYZE) so the plan nodes carry their
+// actual times
+func ExecuteExplainAnalyze(ctx context.Context, q Querier, query string, args ...any) (*ExplainOutput, error) {
+	opts := DefaultExplainOptions()
+	opts.Analyze = true
+	return ExecuteExplainWithOptions(ctx, q, query, opts, args...)
+}
+
 // ExecuteExplainWithOptions runs EXPLAIN (FORMAT JSON) with configurable options
 func ExecuteExplainWithOptions(ctx context.Context, q Querier, query string, opts ExplainOptions, args ...any) (*ExplainOutput, error) {
 	explainQuery := fmt.Sprintf(
@@ -123,13 +146,14 @@ func ExecuteExplainWithOptions(ctx context.Context, q Querier, query string, opt
 }
 
 func (q *Query) CreateBaseline(ctx context.Context, baselineDir string, planDir string, db *sql.DB, useAnalyze bool) error {
-	var plan *Plan
-	var err error
-
+	plan, err := q.GetPlan(planDir)
 	if len(q.Args) == 0 {
-		plan = NewPlan(q, []TestCase{{Name: ""}})
+		// the plan file of a query without parameters only adds hooks
+		if err != nil {
+			plan = &Plan{Query: q}
+		}
+		plan = plan.withoutBindings()
 	} else {
-		plan, err = q.GetPlan(planDir)
 		if err != nil {
 			return fmt.Errorf("failed to load plan for query %s: %w (run 'regresql plan' first)", q.Name, err)
 		}
@@ -159,6 +183,23 @@ func (q *Query) CreateBaseline(ctx context.Context, baselineDir string, planDir
 }
 
 func writeBaselineFile(queryName, baselinePath string, filteredPlan map[string]any, fullExplainPlan *ExplainOutput, useAnalyze bool) error {
+	baseline := newBaseline(queryName, filteredPlan, fullExplainPlan, useAnalyze)
+	if existing, err := LoadBaseline(baselinePath); err == nil {
+		baseline.keepSampled(existing)
+	}
+	if err := saveBaseline(baselinePath, &baseline); err != nil {
+		return err
+	}
+
+	mode := ""
+	if useAnalyze {
+		mode = " [analyze]"
+	}
+	fmt.Printf("  Created baseline: %s%s\n", filepath.Base(baselinePath), mode)
+	return nil
+}
+
+func newBaseline(queryName string, filteredPlan map[string]any, fullExplainPlan *ExplainOutput, useAnalyze bool) Baseline {
 	var planSignature *PlanSignature
 	if fullExplainPlan != nil {
 		planSignature = ExtractPlanSignatureFromNode(&fullExplainPlan.Plan)
@@ -169,6 +210,7 @@ func writeBaselineFile(queryName, baselinePath string, filteredPlan map[string]a
 		Timestamp:     time.Now().UTC().Format(time.RFC3339),
 		Plan:          filteredPlan,
 		PlanSignature: planSignature,
+		Explain:       fullExplainPlan,
 	}
 
 	if useAnalyze && fullExplainPlan != nil {
@@ -194,7 +236,18 @@ func writeBaselineFile(queryName, baselinePath string, filteredPlan map[string]a
 			baseline.Actuals.WorstQErrorNode = qErrorNodeLabel(worst)
 		}
 	}
+	return baseline
+}
 
+// keepSampled carries over what was sampled into an existing baseline by
+// `baseline percentile` and `baseline stability`, which re-planning the
+// query does not recompute
+func (b *Baseline) keepSampled(existing *Baseline) {
+	b.CostPercentiles = existing.CostPercentiles
+	b.Stability = existing.Stability
+}
+
+func saveBaseline(baselinePath string, baseline *Baseline) error {
 	jsonBytes, err := json.MarshalIndent(baseline, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal baseline to JSON: %w", err)
@@ -203,13 +256,93 @@ func writeBaselineFile(queryName, baselinePath string, filteredPlan map[string]a
 	if err := os.WriteFile(baselinePath, jsonBytes, 0644); err != nil {
 		return fmt.Errorf("failed to write baseline JSON: %w", err)
 	}
+	return nil
+}
 
-	mode := ""
-	if useAnalyze {
-		mode = " [analyze]"
+// createAutoBaselines records baselines for a query that has none yet
+// (auto_baseline: true). The EXPLAIN runs in the test transaction; every
+// binding is reported as skipped since there is nothing to compare against.
+func (p *Plan) createAutoBaselines(ctx context.Context, baselineDir string, q Querier) []TestResult {
+	plan := p
+	if len(p.Query.Args) == 0 {
+		plan = p.withoutBindings()
+	}
+
+	useAnalyze := IsAnalyzeEnabled()
+	results := make([]TestResult, 0, len(plan.Names))
+	for i, name := range plan.Names {
+		start := time.Now()
+		baselinePath := getBaselinePath(p.Query, baselineDir, name)
+		result := TestResult{
+			Name:         strings.TrimSuffix(filepath.Base(baselinePath), ".json") + ".cost",
+			Type:         "cost",
+			Status:       "skipped",
+			Error:        "baseline created",
+			QueryFile:    p.Query.Path,
+			BindingsFile: p.Path,
+			BindingName:  name,
+		}
+		if len(p.Query.Args) > 0 {
+			result.Parameters = plan.Bindings[i]
+		}
+
+		filtered, fullPlan, err := plan.createSingleBaseline(ctx, q, i, useAnalyze)
+		if err == nil {
+			baseline := newBaseline(filtered.Query, filtered.Plan, fullPlan, useAnalyze)
+			baseline.AutoCreated = true
+			if err = ensureDir(baselineDir); err == nil {
+				err = saveBaseline(baselinePath, &baseline)
+			}
+		}
+		if err != nil {
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("failed to create baseline: %s", err.Error())
+		} else {
+			fmt.Fprintf(os.Stderr, "Created baseline: %s\n", baselinePath)
+		}
+
+		result.Duration = time.Since(start).Seconds()
+		results = append(results, result)
 	}
-	fmt.Printf("  Created baseline: %s%s\n", filepath.Base(baselinePath), mode)
-	return nil
+	return results
+}
+
+// PurgeAutoBaselines deletes the baselines created by auto_baseline below
+// the suite's baselines directory, so the next test run records them again.
+// Baselines created with 'regresql baseline' are kept. Returns the removed
+// paths.
+func PurgeAutoBaselines(root string) ([]string, error) {
+	baselineDir := filepath.Join(root, "regresql", "baselines")
+	var removed []string
+
+	err := filepath.WalkDir(baselineDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == baselineDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() && d.Name() == BaselineHistoryDir {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		baseline, err := LoadBaseline(path)
+		if err != nil {
+			return err
+		}
+		if !baseline.AutoCreated {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	return removed, err
 }
 
 type BaselineOptions struct {
@@ -271,6 +404,10 @@ func BaselineQueries(opts BaselineOptions) {
 	suite.SetPathFilters(opts.Paths)
 
 	baselineDirs := make(map[string]*lazyDir)
+	historyLabel := baselineHistoryLabel(opts.Root, time.Now())
+	archive := func(path string) error {
+		return archiveBaseline(baselineDir, historyLabel, path)
+	}
 
 	for _, pq := range plannedQueries {
 		fileName := filepath.Base(pq.SQLPath)
@@ -306,16 +443,19 @@ func BaselineQueries(opts BaselineOptions) {
 			os.Exit(11)
 		}
 
-		if err := createBaselineFromPlan(context.Background(), pq, dir.path, db, useAnalyze); err != nil {
+		if err := createBaselineFromPlan(context.Background(), pq, dir.path, db, useAnalyze, archive); err != nil {
 			fmt.Printf("  Error creating baseline for %s: %s\n", pq.Query.Name, err.Error())
 		}
 	}
 
 	fmt.Println("\nBaselines have been created successfully!")
 	fmt.Printf("Baseline files are stored in: %s\n", baselineDir)
+	fmt.Printf("Copies are kept for 'regresql baseline diff' in: %s\n", filepath.Join(baselineDir, BaselineHistoryDir, historyLabel))
 }
 
-func createBaselineFromPlan(ctx context.Context, pq *PlannedQuery, baselineDir string, db *sql.DB, useAnalyze bool) error {
+// createBaselineFromPlan writes the baselines of a planned query and hands
+// each written file to archive
+func createBaselineFromPlan(ctx context.Context, pq *PlannedQuery, baselineDir string, db *sql.DB, useAnalyze bool, archive func(path string) error) error {
 	q := pq.Query
 	plan := pq.Plan
 
@@ -325,7 +465,7 @@ func createBaselineFromPlan(ctx context.Context, pq *PlannedQuery, baselineDir s
 	}
 
 	if len(q.Args) == 0 {
-		plan = NewPlan(q, []TestCase{{Name: ""}})
+		plan = plan.withoutBindings()
 	}
 
 	baselines, fullPlans, err := plan.CreateBaselines(ctx, db, useAnalyze)
@@ -342,6 +482,9 @@ func createBaselineFromPlan(ctx context.Context, pq *PlannedQuery, baselineDir s
 		if err := writeBaselineFile(baseline.Query, baselinePath, baseline.Plan, fullPlan, useAnalyze); err != nil {
 			return err
 		}
+		if err := archive(baselinePath); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -362,6 +505,86 @@ func LoadBaseline(baselinePath string) (*Baseline, error) {
 	return &baseline, nil
 }
 
+// ResolveBaselinePath finds the baseline file of a query. ref names the query
+// like the regresqltest subtests: the SQL path without extension
+// (orders/get_order), plus the query name for multi-query files
+// (orders/orders/by_id). binding may be omitted when the query has a single
+// baseline.
+func ResolveBaselinePath(root, ref, binding string) (string, error) {
+	s, q, folderDir, err := resolveQueryRef(root, ref)
+	if err != nil {
+		return "", err
+	}
+	return findBaselineFile(q, filepath.Join(s.BaselineDir, folderDir), binding)
+}
+
+// resolveQueryRef finds the query named by ref (see ResolveBaselinePath) and
+// returns it with its suite and folder
+func resolveQueryRef(root, ref string) (*Suite, *Query, string, error) {
+	cfg, err := ReadConfig(root)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	ref = strings.TrimSuffix(filepath.ToSlash(ref), ".sql")
+
+	s := Walk(root, cfg.Ignore)
+	for _, folder := range s.Dirs {
+		for _, name := range folder.Files {
+			base := filepath.ToSlash(filepath.Join(folder.Dir, strings.TrimSuffix(name, filepath.Ext(name))))
+			if ref != base && !strings.HasPrefix(ref, base+"/") {
+				continue
+			}
+
+			parsed, err := parseQueryFile(filepath.Join(s.Root, folder.Dir, name))
+			if err != nil {
+				return nil, nil, "", err
+			}
+			for queryName, q := range parsed {
+				full := base
+				if filepath.Base(base) != queryName {
+					full = base + "/" + queryName
+				}
+				if full != ref && !(len(parsed) == 1 && ref == base) {
+					continue
+				}
+				return s, q, folder.Dir, nil
+			}
+		}
+	}
+	return nil, nil, "", fmt.Errorf("query %q not found", ref)
+}
+
+func findBaselineFile(q *Query, baselineDir, binding string) (string, error) {
+	return findQueryFile(q, baselineDir, binding, "baseline", "run 'regresql baseline' first")
+}
+
+// findQueryFile finds the per-binding file of q in dir, named like baselines;
+// what and hint describe the file in errors
+func findQueryFile(q *Query, dir, binding, what, hint string) (string, error) {
+	if binding != "" || len(q.Args) == 0 {
+		path := getBaselinePath(q, dir, binding)
+		if !fileExists(path) {
+			return "", fmt.Errorf("no %s for %s at %s (%s)", what, q.Name, path, hint)
+		}
+		return path, nil
+	}
+
+	matches := queryFiles(q, dir, ".json")
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s for %s (%s)", what, q.Name, hint)
+	case 1:
+		return matches[0], nil
+	}
+
+	prefix := strings.TrimSuffix(getBaselinePath(q, dir, ""), ".json") + "."
+	bindings := make([]string, len(matches))
+	for i, m := range matches {
+		bindings[i] = strings.TrimSuffix(strings.TrimPrefix(m, prefix), ".json")
+	}
+	return "", fmt.Errorf("query %s has several %ss, pick one with --binding: %s", q.Name, what, strings.Join(bindings, ", "))
+}
+
 // CompareCost compares actual cost against baseline with a threshold percentage
 // Returns (isOk bool, actual float64, baseline float64, percentage float64)
 func CompareCost(actualCost, baselineCost, thresholdPercent float64) (bool, float64) {
@@ -0,0 +1,109 @@
+package regresql
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+type (
+	BaselineAnalyzeOptions struct {
+		Root           string
+		Query          string // query ref, see ResolveBaselinePath
+		Binding        string
+		SuggestIndexes bool
+	}
+
+	// BaselineAnalysis holds the plan quality warnings of a stored baseline
+	// and, when asked for, the indexes suggested for its sequential scans
+	BaselineAnalysis struct {
+		Path        string
+		Warnings    []PlanWarning
+		Suggestions []IndexSuggestion
+
+		// SchemaErr is set when the database couldn't be introspected; the
+		// suggestions then don't account for existing indexes
+		SchemaErr error
+	}
+)
+
+// AnalyzeBaseline runs the plan quality checks of `regresql test` over the
+// plan stored in a baseline, without executing the query
+func AnalyzeBaseline(opts BaselineAnalyzeOptions) (*BaselineAnalysis, error) {
+	s, q, folderDir, err := resolveQueryRef(opts.Root, opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	path, err := findBaselineFile(q, filepath.Join(s.BaselineDir, folderDir), opts.Binding)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		return nil, err
+	}
+	if baseline.Explain == nil {
+		return nil, fmt.Errorf("baseline %s has no stored plan, re-run 'regresql baseline' to record it", path)
+	}
+
+	cfg, err := ReadConfig(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	SetGlobalConfig(cfg)
+
+	plan := &baseline.Explain.Plan
+	costInfo := PlanCostInfo{TotalCost: plan.TotalCost, TotalBuffers: -1}
+	if baseline.AnalyzeMode {
+		costInfo.TotalBuffers = plan.SharedHitBlocks + plan.SharedReadBlocks + plan.LocalHitBlocks + plan.LocalReadBlocks
+	}
+
+	analysis := &BaselineAnalysis{
+		Path:     path,
+		Warnings: DetectPlanQualityIssues(plan, q.GetRegressQLOptions(), GetIgnoredSeqScanTables(), GetCriticalTables(), costInfo),
+	}
+	if !opts.SuggestIndexes {
+		return analysis, nil
+	}
+
+	var schema *DatabaseSchema
+	db, err := OpenDB(cfg.PgUri)
+	if err == nil {
+		schema, err = IntrospectSchema(db)
+		db.Close()
+	}
+	analysis.SchemaErr = err
+	analysis.Suggestions = SuggestIndexes(ExtractPlanSignatureFromNode(plan), schema)
+	return analysis, nil
+}
+
+// PrintBaselineAnalysis writes the warnings and index suggestions of a
+// baseline analysis
+func PrintBaselineAnalysis(w io.Writer, analysis *BaselineAnalysis, suggest bool) {
+	if len(analysis.Warnings) == 0 {
+		fmt.Fprintln(w, "  No plan quality issues")
+	}
+	for _, warning := range analysis.Warnings {
+		fmt.Fprintf(w, "  %s  %s\n", GetSeveritySymbol(warning.Severity), warning.Message)
+		if warning.Suggestion != "" {
+			fmt.Fprintf(w, "    Suggestion: %s\n", warning.Suggestion)
+		}
+	}
+	if !suggest {
+		return
+	}
+
+	fmt.Fprintln(w)
+	if analysis.SchemaErr != nil {
+		fmt.Fprintf(w, "  Note: could not introspect the database (%v), existing indexes are not checked\n\n", analysis.SchemaErr)
+	}
+	if len(analysis.Suggestions) == 0 {
+		fmt.Fprintln(w, "  No index suggestions")
+		return
+	}
+	fmt.Fprintln(w, "  Index suggestions:")
+	for _, s := range analysis.Suggestions {
+		fmt.Fprintf(w, "    -- %s\n", s.Rationale)
+		fmt.Fprintf(w, "    %s\n", s.DDL)
+	}
+}
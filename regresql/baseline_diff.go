@@ -0,0 +1,249 @@
+package regresql
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type (
+	// BaselineDiff is the difference between two baselines of a query
+	BaselineDiff struct {
+		BeforeCost  float64
+		AfterCost   float64
+		CostDelta   float64 // AfterCost - BeforeCost
+		NodeChanges []PlanNodeChange
+		ScanChanges []ScanChange
+	}
+
+	// PlanNodeChange is a plan tree node present in only one of the baselines
+	PlanNodeChange struct {
+		Change string // "added" or "removed"
+		Depth  int    // 0 for the root node
+		Node   string // e.g. "Index Scan using orders_pkey on orders"
+	}
+
+	// ScanChange is a table scanned differently, or only in one baseline;
+	// Before or After is empty when the table isn't scanned there
+	ScanChange struct {
+		Table    string
+		Before   string
+		After    string
+		Severity string // "critical", "warning", "info"
+		Message  string
+	}
+)
+
+const (
+	NodeAdded   = "added"
+	NodeRemoved = "removed"
+)
+
+// DiffBaselines compares the cost, plan tree and table scans of two
+// baselines. Plan trees are only compared when both baselines stored their
+// EXPLAIN output.
+func DiffBaselines(before, after *Baseline) *BaselineDiff {
+	diff := &BaselineDiff{
+		BeforeCost: toFloat64(before.Plan["total_cost"]),
+		AfterCost:  toFloat64(after.Plan["total_cost"]),
+	}
+	diff.CostDelta = diff.AfterCost - diff.BeforeCost
+
+	if before.Explain != nil && after.Explain != nil {
+		diff.NodeChanges = diffPlanNodes(&before.Explain.Plan, &after.Explain.Plan)
+	}
+	diff.ScanChanges = diffScans(baselineSignature(before), baselineSignature(after))
+	return diff
+}
+
+// HasChanges reports whether the plans differ beyond their cost
+func (d *BaselineDiff) HasChanges() bool {
+	return len(d.NodeChanges) > 0 || len(d.ScanChanges) > 0
+}
+
+func baselineSignature(b *Baseline) *PlanSignature {
+	if b.PlanSignature != nil {
+		return b.PlanSignature
+	}
+	if b.Explain != nil {
+		return ExtractPlanSignatureFromNode(&b.Explain.Plan)
+	}
+	return &PlanSignature{}
+}
+
+type planNodeLine struct {
+	depth int
+	label string
+}
+
+func flattenPlanNodes(node *PlanNode, depth int, out []planNodeLine) []planNodeLine {
+	out = append(out, planNodeLine{depth: depth, label: planNodeLabel(node)})
+	for i := range node.Plans {
+		out = flattenPlanNodes(&node.Plans[i], depth+1, out)
+	}
+	return out
+}
+
+// diffPlanNodes matches the depth-first node lists of both trees and returns
+// the nodes only one of them has, in tree order
+func diffPlanNodes(before, after *PlanNode) []PlanNodeChange {
+	a := flattenPlanNodes(before, 0, nil)
+	b := flattenPlanNodes(after, 0, nil)
+	key := func(lines []planNodeLine) []string {
+		keys := make([]string, len(lines))
+		for i, l := range lines {
+			keys[i] = strings.Repeat("  ", l.depth) + l.label
+		}
+		return keys
+	}
+
+	var changes []PlanNodeChange
+	for _, op := range difflib.NewMatcher(key(a), key(b)).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		for _, l := range a[op.I1:op.I2] {
+			changes = append(changes, PlanNodeChange{Change: NodeRemoved, Depth: l.depth, Node: l.label})
+		}
+		for _, l := range b[op.J1:op.J2] {
+			changes = append(changes, PlanNodeChange{Change: NodeAdded, Depth: l.depth, Node: l.label})
+		}
+	}
+	return changes
+}
+
+// diffScans reports the tables whose scan changed, with the severity and
+// message the plan regression checks give the change
+func diffScans(before, after *PlanSignature) []ScanChange {
+	tables := make([]string, 0, len(before.Relations)+len(after.Relations))
+	for t := range before.Relations {
+		tables = append(tables, t)
+	}
+	for t := range after.Relations {
+		if _, ok := before.Relations[t]; !ok {
+			tables = append(tables, t)
+		}
+	}
+	slices.Sort(tables)
+
+	var changes []ScanChange
+	for _, table := range tables {
+		b, inBefore := before.Relations[table]
+		a, inAfter := after.Relations[table]
+		switch {
+		case !inAfter:
+			changes = append(changes, ScanChange{
+				Table: table, Before: FormatScanDescription(b), Severity: "info",
+				Message: fmt.Sprintf("Table '%s' is no longer scanned", table),
+			})
+		case !inBefore:
+			changes = append(changes, ScanChange{
+				Table: table, After: FormatScanDescription(a), Severity: "info",
+				Message: fmt.Sprintf("Table '%s' is now scanned", table),
+			})
+		case !CompareScans(b, a):
+			change := ScanChange{
+				Table: table, Before: FormatScanDescription(b), After: FormatScanDescription(a), Severity: "info",
+				Message: fmt.Sprintf("Table '%s' scan changed: %s → %s", table, FormatScanDescription(b), FormatScanDescription(a)),
+			}
+			if r := compareScanMethods(table, b, a); r != nil {
+				change.Severity = r.Severity
+				change.Message = r.Message
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// PrintBaselineDiff writes a diff in the layout of the plan regressions
+// `regresql test` reports: cost first, then the changed plan nodes (removed
+// in red, added in green) and the changed table scans
+func PrintBaselineDiff(w io.Writer, diff *BaselineDiff, color bool) {
+	paint := func(text, c string) string {
+		if !color {
+			return text
+		}
+		return c + text + colorReset
+	}
+
+	delta := fmt.Sprintf("%+.2f", diff.CostDelta)
+	if diff.BeforeCost > 0 {
+		delta += fmt.Sprintf(", %+.1f%%", diff.CostDelta/diff.BeforeCost*100)
+	}
+	switch {
+	case diff.CostDelta > 0:
+		delta = paint(delta, colorRed)
+	case diff.CostDelta < 0:
+		delta = paint(delta, colorGreen)
+	}
+	fmt.Fprintf(w, "  Cost: %.2f → %.2f (%s)\n", diff.BeforeCost, diff.AfterCost, delta)
+	fmt.Fprintln(w)
+
+	if !diff.HasChanges() {
+		fmt.Fprintln(w, "  Plan unchanged")
+		return
+	}
+
+	if len(diff.NodeChanges) > 0 {
+		fmt.Fprintln(w, "  Plan nodes:")
+		for _, c := range diff.NodeChanges {
+			line := "- " + strings.Repeat("  ", c.Depth) + c.Node
+			lineColor := colorRed
+			if c.Change == NodeAdded {
+				line = "+ " + strings.Repeat("  ", c.Depth) + c.Node
+				lineColor = colorGreen
+			}
+			fmt.Fprintf(w, "  %s\n", paint(line, lineColor))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(diff.ScanChanges) > 0 {
+		if slices.ContainsFunc(diff.ScanChanges, func(c ScanChange) bool { return c.Severity == "critical" }) {
+			fmt.Fprintln(w, "  ⚠️  PLAN REGRESSION DETECTED:")
+		}
+		for _, c := range diff.ScanChanges {
+			before, after := c.Before, c.After
+			if before == "" {
+				before = "(not scanned)"
+			}
+			if after == "" {
+				after = "(not scanned)"
+			}
+			fmt.Fprintf(w, "  %s Table '%s': %s → %s\n", GetSeveritySymbol(c.Severity), c.Table, before, after)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ResolveBaselineDiff loads the two versions of a query binding's baseline
+// that `baseline diff` compares. after defaults to the current baseline and
+// before to the newest version recorded before after; both accept a snapshot
+// tag, a history run time, "current" or a date (see FindBaselineVersion).
+func ResolveBaselineDiff(root, ref, binding, before, after string) (*BaselineVersion, *BaselineVersion, error) {
+	versions, err := ListBaselineVersions(root, ref, binding)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after == "" {
+		after = CurrentBaselineLabel
+	}
+	a, err := FindBaselineVersion(versions, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	var b *BaselineVersion
+	if before == "" {
+		b, err = previousBaselineVersion(versions, a)
+	} else {
+		b, err = FindBaselineVersion(versions, before)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, a, nil
+}
@@ -0,0 +1,150 @@
+package regresql
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func indexPlanBaseline() *Baseline {
+	return &Baseline{
+		Plan: map[string]any{"total_cost": 8.3},
+		Explain: &ExplainOutput{Plan: PlanNode{
+			NodeType: "Nested Loop", TotalCost: 8.3,
+			Plans: []PlanNode{
+				{NodeType: "Index Scan", IndexName: "orders_user_id_idx", RelationName: "orders", IndexCond: "(user_id = 1)"},
+				{NodeType: "Index Scan", IndexName: "users_pkey", RelationName: "users"},
+			},
+		}},
+	}
+}
+
+func seqPlanBaseline() *Baseline {
+	return &Baseline{
+		Plan: map[string]any{"total_cost": 41.5},
+		Explain: &ExplainOutput{Plan: PlanNode{
+			NodeType: "Hash Join", TotalCost: 41.5,
+			Plans: []PlanNode{
+				{NodeType: "Seq Scan", RelationName: "orders", Filter: "(user_id = 1)"},
+				{NodeType: "Hash", Plans: []PlanNode{
+					{NodeType: "Index Scan", IndexName: "users_pkey", RelationName: "users"},
+				}},
+			},
+		}},
+	}
+}
+
+func TestDiffBaselines(t *testing.T) {
+	diff := DiffBaselines(indexPlanBaseline(), seqPlanBaseline())
+
+	if diff.CostDelta != 41.5-8.3 {
+		t.Errorf("CostDelta = %v, want %v", diff.CostDelta, 41.5-8.3)
+	}
+
+	var nodes []string
+	for _, c := range diff.NodeChanges {
+		nodes = append(nodes, c.Change+" "+strings.Repeat(" ", c.Depth)+c.Node)
+	}
+	want := []string{
+		"removed Nested Loop",
+		"removed  Index Scan using orders_user_id_idx on orders",
+		"removed  Index Scan using users_pkey on users",
+		"added Hash Join",
+		"added  Seq Scan on orders",
+		"added  Hash",
+		"added   Index Scan using users_pkey on users",
+	}
+	if !equalStrings(nodes, want) {
+		t.Errorf("NodeChanges =\n%s\nwant\n%s", strings.Join(nodes, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(diff.ScanChanges) != 1 {
+		t.Fatalf("ScanChanges = %+v, want one change on orders", diff.ScanChanges)
+	}
+	c := diff.ScanChanges[0]
+	if c.Table != "orders" || c.Before != "Index Scan using orders_user_id_idx" || c.After != "Seq Scan" || c.Severity != "critical" {
+		t.Errorf("ScanChange = %+v", c)
+	}
+
+	var out bytes.Buffer
+	PrintBaselineDiff(&out, diff, false)
+	for _, s := range []string{
+		"Cost: 8.30 → 41.50 (+33.20, +400.0%)",
+		"- Nested Loop",
+		"+   Seq Scan on orders",
+		"PLAN REGRESSION DETECTED",
+		"✗ Table 'orders': Index Scan using orders_user_id_idx → Seq Scan",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output missing %q:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestDiffBaselinesUnchanged(t *testing.T) {
+	diff := DiffBaselines(indexPlanBaseline(), indexPlanBaseline())
+	if diff.HasChanges() || diff.CostDelta != 0 {
+		t.Errorf("identical baselines diff = %+v", diff)
+	}
+
+	// baselines recorded without the EXPLAIN output still compare scans
+	before, after := indexPlanBaseline(), seqPlanBaseline()
+	before.PlanSignature = ExtractPlanSignatureFromNode(&before.Explain.Plan)
+	before.Explain, after.Explain = nil, nil
+	after.PlanSignature = &PlanSignature{Relations: map[string]ScanInfo{"users": {ScanType: "Index Scan", IndexName: "users_pkey"}}}
+	diff = DiffBaselines(before, after)
+	if len(diff.NodeChanges) != 0 {
+		t.Errorf("NodeChanges without explain = %+v", diff.NodeChanges)
+	}
+	if len(diff.ScanChanges) != 1 || diff.ScanChanges[0].After != "" {
+		t.Errorf("ScanChanges = %+v, want orders no longer scanned", diff.ScanChanges)
+	}
+}
+
+func TestResolveBaselineDiff(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("regresql/regress.yaml", "pguri: postgres://localhost/test\n")
+	write("orders/get_order.sql", "-- name: get_order\nselect 1;\n")
+	write("regresql/baselines/orders/get_order.json", `{"timestamp": "2026-03-01T10:00:00Z"}`)
+	write("regresql/baselines/.history/v1/orders/get_order.json", `{"timestamp": "2026-01-01T10:00:00Z"}`)
+	write("regresql/baselines/.history/20260201T100000Z/orders/get_order.json", `{"timestamp": "2026-02-01T10:00:00Z"}`)
+	write("regresql/baselines/.history/20260301T100000Z/orders/get_order.json", `{"timestamp": "2026-03-01T10:00:00Z"}`)
+
+	tests := []struct {
+		before, after         string
+		wantBefore, wantAfter string
+	}{
+		{"", "", "20260201T100000Z", "current"},
+		{"v1", "", "v1", "current"},
+		{"2026-01-15", "20260201T100000Z", "v1", "20260201T100000Z"},
+		{"", "2026-02-15", "v1", "20260201T100000Z"},
+	}
+	for _, tt := range tests {
+		before, after, err := ResolveBaselineDiff(root, "orders/get_order", "", tt.before, tt.after)
+		if err != nil {
+			t.Errorf("ResolveBaselineDiff(%q, %q): %v", tt.before, tt.after, err)
+			continue
+		}
+		if before.Label != tt.wantBefore || after.Label != tt.wantAfter {
+			t.Errorf("ResolveBaselineDiff(%q, %q) = %s, %s; want %s, %s", tt.before, tt.after, before.Label, after.Label, tt.wantBefore, tt.wantAfter)
+		}
+	}
+
+	if _, _, err := ResolveBaselineDiff(root, "orders/get_order", "", "", "v1"); err == nil {
+		t.Error("expected error: nothing recorded before the oldest version")
+	}
+	if _, _, err := ResolveBaselineDiff(root, "orders/get_order", "", "v9", ""); err == nil {
+		t.Error("expected error for unknown tag")
+	}
+}
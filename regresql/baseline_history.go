@@ -0,0 +1,130 @@
+package regresql
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BaselineHistoryDir keeps a copy of every baseline written by
+// `regresql baseline`, one directory per run named by the snapshot tag the
+// baselines were recorded against, or by the run's time otherwise:
+//
+//	regresql/baselines/.history/v1.2/orders/get_order.json
+//	regresql/baselines/.history/20260101T120000Z/orders/get_order.json
+const BaselineHistoryDir = ".history"
+
+// CurrentBaselineLabel names the baseline in regresql/baselines/ itself
+const CurrentBaselineLabel = "current"
+
+// BaselineVersion is one recorded version of a baseline file
+type BaselineVersion struct {
+	Label    string // snapshot tag or run time; "current" for the live baseline
+	Path     string
+	Baseline *Baseline
+	Time     time.Time
+}
+
+// baselineHistoryLabel names the history directory of a `regresql baseline`
+// run: the tag of the current snapshot when it has one
+func baselineHistoryLabel(root string, now time.Time) string {
+	if metadata, err := ReadSnapshotMetadata(GetSnapshotsDir(root)); err == nil && metadata.Current != nil && metadata.Current.Tag != "" {
+		return metadata.Current.Tag
+	}
+	return now.UTC().Format("20060102T150405Z")
+}
+
+// archiveBaseline copies a baseline file below the history directory of a
+// run; baselinePath must be inside baselineDir
+func archiveBaseline(baselineDir, label, baselinePath string) error {
+	rel, err := filepath.Rel(baselineDir, baselinePath)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(baselineDir, BaselineHistoryDir, label, rel)
+	if err := ensureDir(filepath.Dir(target)); err != nil {
+		return fmt.Errorf("failed to create baseline history directory: %w", err)
+	}
+	if err := copyFile(baselinePath, target); err != nil {
+		return fmt.Errorf("failed to archive baseline '%s': %w", baselinePath, err)
+	}
+	return nil
+}
+
+// ListBaselineVersions returns the current baseline of a query binding (see
+// ResolveBaselinePath) and its archived versions, oldest first
+func ListBaselineVersions(root, ref, binding string) ([]BaselineVersion, error) {
+	current, err := ResolveBaselinePath(root, ref, binding)
+	if err != nil {
+		return nil, err
+	}
+	baselineDir := filepath.Join(root, "regresql", "baselines")
+	rel, err := filepath.Rel(baselineDir, current)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(baselineDir, BaselineHistoryDir, "*", rel))
+	versions := make([]BaselineVersion, 0, len(paths)+1)
+	for _, path := range append(paths, current) {
+		baseline, err := LoadBaseline(path)
+		if err != nil {
+			return nil, err
+		}
+		label := CurrentBaselineLabel
+		if path != current {
+			label = historyLabel(baselineDir, path)
+		}
+		ts, _ := time.Parse(time.RFC3339, baseline.Timestamp)
+		versions = append(versions, BaselineVersion{Label: label, Path: path, Baseline: baseline, Time: ts})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Time.Before(versions[j].Time)
+	})
+	return versions, nil
+}
+
+// historyLabel returns the run directory name of an archived baseline
+func historyLabel(baselineDir, path string) string {
+	rel, _ := filepath.Rel(filepath.Join(baselineDir, BaselineHistoryDir), path)
+	label, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return label
+}
+
+// FindBaselineVersion picks a version by label (a snapshot tag, a run time
+// or "current") or by date: the newest version recorded at or before an
+// RFC 3339 time or before the end of a YYYY-MM-DD day
+func FindBaselineVersion(versions []BaselineVersion, ref string) (*BaselineVersion, error) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Label == ref {
+			return &versions[i], nil
+		}
+	}
+
+	until, err := time.Parse(time.RFC3339, ref)
+	if err != nil {
+		day, dayErr := time.Parse(time.DateOnly, ref)
+		if dayErr != nil {
+			return nil, fmt.Errorf("no baseline version %q (expected a snapshot tag, %q, or a date)", ref, CurrentBaselineLabel)
+		}
+		until = day.Add(24*time.Hour - time.Nanosecond)
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].Time.After(until) {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no baseline version recorded before %s", ref)
+}
+
+// previousBaselineVersion returns the newest version older than after
+func previousBaselineVersion(versions []BaselineVersion, after *BaselineVersion) (*BaselineVersion, error) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Time.Before(after.Time) {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no baseline recorded before %s (%s); 'regresql baseline' keeps earlier versions in regresql/baselines/%s", after.Label, after.Baseline.Timestamp, BaselineHistoryDir)
+}
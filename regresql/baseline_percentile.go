@@ -0,0 +1,190 @@
+package regresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultCostSamples is how many times `baseline percentile` plans a query
+const DefaultCostSamples = 10
+
+type (
+	// CostPercentiles summarize the planner cost of a query over several
+	// re-ANALYZE runs. Small tables or skewed data make the sampled
+	// statistics, and so the cost, vary from one ANALYZE to the next;
+	// comparing against a high percentile keeps that noise from failing
+	// cost checks.
+	CostPercentiles struct {
+		CostSamples int     `json:"cost_samples"`
+		CostP50     float64 `json:"cost_p50"`
+		CostP95     float64 `json:"cost_p95"`
+		CostP99     float64 `json:"cost_p99"`
+	}
+
+	PercentileOptions struct {
+		Root    string
+		Query   string // query reference, as for `baseline show`
+		Binding string // only this binding; all bindings when empty
+		Samples int
+	}
+)
+
+// ValidateCostPercentile checks a --percentile / analyze.cost_percentile
+// value; 0 turns percentile comparison off
+func ValidateCostPercentile(p int) error {
+	switch p {
+	case 0, 50, 95, 99:
+		return nil
+	}
+	return fmt.Errorf("invalid cost percentile %d (expected 50, 95 or 99)", p)
+}
+
+// Cost returns the recorded cost at percentile p (50, 95 or 99)
+func (c *CostPercentiles) Cost(p int) (float64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	switch p {
+	case 50:
+		return c.CostP50, true
+	case 95:
+		return c.CostP95, true
+	case 99:
+		return c.CostP99, true
+	}
+	return 0, false
+}
+
+// costPercentiles computes nearest-rank percentiles of the sampled costs
+func costPercentiles(costs []float64) *CostPercentiles {
+	if len(costs) == 0 {
+		return nil
+	}
+	s := append([]float64{}, costs...)
+	sort.Float64s(s)
+	return &CostPercentiles{
+		CostSamples: len(s),
+		CostP50:     nearestRank(s, 50),
+		CostP95:     nearestRank(s, 95),
+		CostP99:     nearestRank(s, 99),
+	}
+}
+
+// sampleCosts re-ANALYZEs the database and plans the query n times, returning
+// the total cost of each plan. Like the compare stability pass this replaces
+// the database statistics.
+func sampleCosts(ctx context.Context, db *sql.DB, plan *Plan, bindings map[string]any, n int) ([]float64, *ExplainOutput, error) {
+	costs := make([]float64, 0, n)
+	var last *ExplainOutput
+	for range n {
+		if _, err := db.ExecContext(ctx, "ANALYZE"); err != nil {
+			return nil, nil, fmt.Errorf("failed to ANALYZE: %w", err)
+		}
+		explain, err := plan.runExplain(ctx, db, bindings)
+		if err != nil {
+			return nil, nil, err
+		}
+		costs = append(costs, explain.Plan.TotalCost)
+		last = explain
+	}
+	return costs, last, nil
+}
+
+// RecordCostPercentiles samples the cost of a query and stores its p50, p95
+// and p99 in the query's baselines, creating missing baselines from the last
+// sampled plan. It returns the baseline files written.
+func RecordCostPercentiles(opts PercentileOptions) ([]string, error) {
+	if opts.Samples < 1 {
+		return nil, fmt.Errorf("--samples must be at least 1")
+	}
+	s, q, folderDir, err := resolveQueryRef(opts.Root, opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ReadConfig(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := NewPlan(q, []TestCase{{Name: ""}})
+	if len(q.Args) > 0 {
+		plan, err = q.GetPlan(filepath.Join(s.PlanDir, folderDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plan for query %s: %w (run 'regresql plan' first)", q.Name, err)
+		}
+	}
+
+	db, err := OpenDB(cfg.PgUri)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	baselineDir := filepath.Join(s.BaselineDir, folderDir)
+	if err := ensureDir(baselineDir); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var written []string
+	for _, b := range iterateBindings(plan) {
+		if opts.Binding != "" && b.name != opts.Binding {
+			continue
+		}
+		costs, explain, err := sampleCosts(ctx, db, plan, b.bindings, opts.Samples)
+		if err != nil {
+			return written, fmt.Errorf("failed to sample %s: %w", q.Name, err)
+		}
+
+		path := getBaselinePath(q, baselineDir, b.name)
+		baseline, err := loadOrNewBaseline(path, q, explain)
+		if err != nil {
+			return written, err
+		}
+		baseline.CostPercentiles = costPercentiles(costs)
+		if err := saveBaseline(path, baseline); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	if len(written) == 0 {
+		return nil, fmt.Errorf("query %s has no binding %q", q.Name, opts.Binding)
+	}
+	return written, nil
+}
+
+// loadOrNewBaseline loads the baseline at path, or creates one from explain
+// when the query has none yet
+func loadOrNewBaseline(path string, q *Query, explain *ExplainOutput) (*Baseline, error) {
+	baseline, err := LoadBaseline(path)
+	if err == nil {
+		return baseline, nil
+	}
+	if fileExists(path) {
+		return nil, err
+	}
+	created := newBaseline(q.Name, map[string]any{
+		"startup_cost": explain.Plan.StartupCost,
+		"total_cost":   explain.Plan.TotalCost,
+		"plan_rows":    explain.Plan.PlanRows,
+	}, explain, false)
+	return &created, nil
+}
+
+// costBaseline returns the baseline cost a cost check compares against: the
+// configured percentile when the baseline recorded it, the plan cost otherwise
+func costBaseline(baseline *Baseline, baselinePath string) float64 {
+	p := GetCostPercentile()
+	if p == 0 {
+		return toFloat64(baseline.Plan["total_cost"])
+	}
+	if cost, ok := baseline.CostPercentiles.Cost(p); ok {
+		return cost
+	}
+	fmt.Fprintf(os.Stderr, "Warning: baseline '%s' has no cost percentiles (run 'regresql baseline percentile'), comparing against its plan cost\n", baselinePath)
+	return toFloat64(baseline.Plan["total_cost"])
+}
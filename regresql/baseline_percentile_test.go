@@ -0,0 +1,116 @@
+package regresql
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCostPercentiles(t *testing.T) {
+	costs := []float64{12, 10, 11, 10, 10, 15, 10, 11, 10, 40}
+	got := costPercentiles(costs)
+	want := &CostPercentiles{CostSamples: 10, CostP50: 10, CostP95: 40, CostP99: 40}
+	if *got != *want {
+		t.Errorf("costPercentiles = %+v, want %+v", got, want)
+	}
+	if costs[0] != 12 {
+		t.Error("costPercentiles sorted its input")
+	}
+
+	if got := costPercentiles([]float64{7}); got.CostP50 != 7 || got.CostP99 != 7 {
+		t.Errorf("single sample = %+v", got)
+	}
+	if costPercentiles(nil) != nil {
+		t.Error("no samples: want nil")
+	}
+}
+
+func TestCostPercentilesCost(t *testing.T) {
+	p := &CostPercentiles{CostP50: 1, CostP95: 2, CostP99: 3}
+	for pct, want := range map[int]float64{50: 1, 95: 2, 99: 3} {
+		if got, ok := p.Cost(pct); !ok || got != want {
+			t.Errorf("Cost(%d) = %v, %v", pct, got, ok)
+		}
+	}
+	if _, ok := p.Cost(90); ok {
+		t.Error("Cost(90): want not ok")
+	}
+	if _, ok := (*CostPercentiles)(nil).Cost(95); ok {
+		t.Error("nil Cost(95): want not ok")
+	}
+	if err := ValidateCostPercentile(90); err == nil {
+		t.Error("ValidateCostPercentile(90): expected error")
+	}
+}
+
+func TestBaselineCostPercentilesJSON(t *testing.T) {
+	b := Baseline{Query: "q", CostPercentiles: &CostPercentiles{CostSamples: 10, CostP50: 1.5, CostP95: 2, CostP99: 3}}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"cost_samples":10`, `"cost_p50":1.5`, `"cost_p95":2`, `"cost_p99":3`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("baseline JSON missing %s: %s", key, data)
+		}
+	}
+
+	var got Baseline
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CostPercentiles == nil || *got.CostPercentiles != *b.CostPercentiles {
+		t.Errorf("CostPercentiles after round trip = %+v", got.CostPercentiles)
+	}
+
+	data, _ = json.Marshal(Baseline{Query: "q"})
+	if strings.Contains(string(data), "cost_p") {
+		t.Errorf("baseline without percentiles has cost fields: %s", data)
+	}
+}
+
+func TestCostBaseline(t *testing.T) {
+	saved := cachedConfig
+	defer func() { cachedConfig = saved }()
+	b := &Baseline{Plan: map[string]any{"total_cost": 10.0}, CostPercentiles: &CostPercentiles{CostP95: 14}}
+
+	SetGlobalConfig(config{})
+	if got := costBaseline(b, "b.json"); got != 10 {
+		t.Errorf("without percentile = %v, want plan cost", got)
+	}
+	SetGlobalConfig(config{Analyze: &AnalyzeConfig{CostPercentile: 95}})
+	if got := costBaseline(b, "b.json"); got != 14 {
+		t.Errorf("p95 = %v, want 14", got)
+	}
+}
+
+func TestRebaselineKeepsSampledStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	old := Baseline{
+		Query:           "orders",
+		Plan:            map[string]any{"total_cost": 10.0},
+		CostPercentiles: &CostPercentiles{CostSamples: 5, CostP50: 10, CostP95: 12, CostP99: 14},
+		Stability:       &StabilityReport{Status: "STABLE", Runs: 5, DistinctPlans: 1},
+	}
+	if err := saveBaseline(path, &old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeBaselineFile("orders", path, map[string]any{"total_cost": 11.0}, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toFloat64(got.Plan["total_cost"]) != 11 {
+		t.Errorf("total_cost = %v, want the new plan cost", got.Plan["total_cost"])
+	}
+	if got.CostPercentiles == nil || *got.CostPercentiles != *old.CostPercentiles {
+		t.Errorf("CostPercentiles = %+v, want them kept from the previous baseline", got.CostPercentiles)
+	}
+	if got.Stability == nil || got.Stability.Status != "STABLE" {
+		t.Errorf("Stability = %+v, want it kept from the previous baseline", got.Stability)
+	}
+}
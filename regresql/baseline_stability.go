@@ -0,0 +1,159 @@
+package regresql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultStabilityRuns is how many times `baseline stability` plans a query
+const DefaultStabilityRuns = 5
+
+// Plan stability verdicts of `baseline stability`
+const (
+	StabilityStable   = "STABLE"   // the same plan on every run
+	StabilityFlaky    = "FLAKY"    // the plan varied between runs of similar cost
+	StabilityUnstable = "UNSTABLE" // the plan and its cost varied
+)
+
+// stabilityCostSpread is the largest spread between the cheapest and the
+// most expensive run for which varying plans count as a cost tie (FLAKY)
+const stabilityCostSpread = 0.10
+
+type (
+	// StabilityReport records how the plan of a query varied over several
+	// re-ANALYZE and EXPLAIN runs
+	StabilityReport struct {
+		Status        string  `json:"status"`
+		Runs          int     `json:"runs"`
+		DistinctPlans int     `json:"distinct_plans"`
+		MinCost       float64 `json:"min_cost"`
+		MaxCost       float64 `json:"max_cost"`
+		Timestamp     string  `json:"timestamp"`
+	}
+
+	StabilityOptions struct {
+		Root    string
+		Query   string // query reference, as for `baseline show`
+		Binding string // only this binding; all bindings when empty
+		Runs    int
+	}
+
+	// StabilityResult is the stability check of one baseline, with the
+	// report recorded by the previous check
+	StabilityResult struct {
+		Path     string
+		Report   *StabilityReport
+		Previous *StabilityReport
+	}
+)
+
+// BecameUnstable reports whether a plan that was stable on the previous
+// check varied on this one
+func (r StabilityResult) BecameUnstable() bool {
+	return r.Previous != nil && r.Previous.Status == StabilityStable && r.Report.Status != StabilityStable
+}
+
+// stabilityReport classifies the plan fingerprints and costs of the runs
+func stabilityReport(fingerprints []string, costs []float64) *StabilityReport {
+	report := &StabilityReport{
+		Status:        StabilityStable,
+		Runs:          len(fingerprints),
+		DistinctPlans: len(slices.Compact(slices.Sorted(slices.Values(fingerprints)))),
+		Timestamp:     time.Now().Format(time.RFC3339),
+	}
+	if len(costs) > 0 {
+		report.MinCost, report.MaxCost = slices.Min(costs), slices.Max(costs)
+	}
+	if report.DistinctPlans > 1 {
+		report.Status = StabilityUnstable
+		if report.MaxCost <= report.MinCost*(1+stabilityCostSpread) {
+			report.Status = StabilityFlaky
+		}
+	}
+	return report
+}
+
+// CheckPlanStability re-ANALYZEs the database and plans the query
+// opts.Runs times per binding, then records in the query's baselines
+// whether the plan tree stayed the same. Missing baselines are created from
+// the last plan. Like `baseline percentile` this replaces the database
+// statistics.
+func CheckPlanStability(opts StabilityOptions) ([]StabilityResult, error) {
+	if opts.Runs < 2 {
+		return nil, fmt.Errorf("--runs must be at least 2")
+	}
+	s, q, folderDir, err := resolveQueryRef(opts.Root, opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ReadConfig(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := NewPlan(q, []TestCase{{Name: ""}})
+	if len(q.Args) > 0 {
+		plan, err = q.GetPlan(filepath.Join(s.PlanDir, folderDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plan for query %s: %w (run 'regresql plan' first)", q.Name, err)
+		}
+	}
+
+	db, err := OpenDB(cfg.PgUri)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	baselineDir := filepath.Join(s.BaselineDir, folderDir)
+	if err := ensureDir(baselineDir); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var results []StabilityResult
+	for _, b := range iterateBindings(plan) {
+		if opts.Binding != "" && b.name != opts.Binding {
+			continue
+		}
+
+		var (
+			fingerprints []string
+			costs        []float64
+			explain      *ExplainOutput
+		)
+		for range opts.Runs {
+			if _, err := db.ExecContext(ctx, "ANALYZE"); err != nil {
+				return results, fmt.Errorf("failed to ANALYZE: %w", err)
+			}
+			explain, err = plan.runExplain(ctx, db, b.bindings)
+			if err != nil {
+				return results, fmt.Errorf("failed to plan %s: %w", q.Name, err)
+			}
+			var fp strings.Builder
+			planFingerprint(&explain.Plan, &fp)
+			fingerprints = append(fingerprints, fp.String())
+			costs = append(costs, explain.Plan.TotalCost)
+		}
+
+		path := getBaselinePath(q, baselineDir, b.name)
+		baseline, err := loadOrNewBaseline(path, q, explain)
+		if err != nil {
+			return results, err
+		}
+		result := StabilityResult{Path: path, Report: stabilityReport(fingerprints, costs), Previous: baseline.Stability}
+		baseline.Stability = result.Report
+		if err := saveBaseline(path, baseline); err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("query %s has no binding %q", q.Name, opts.Binding)
+	}
+	return results, nil
+}
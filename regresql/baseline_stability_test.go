@@ -0,0 +1,59 @@
+package regresql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStabilityReport(t *testing.T) {
+	tests := []struct {
+		name         string
+		fingerprints []string
+		costs        []float64
+		want         string
+		plans        int
+	}{
+		{"same plan", []string{"Seq Scan(t)", "Seq Scan(t)", "Seq Scan(t)"}, []float64{10, 12, 30}, StabilityStable, 1},
+		{"cost tie", []string{"Seq Scan(t)", "Index Scan(t)", "Seq Scan(t)"}, []float64{100, 104, 101}, StabilityFlaky, 2},
+		{"plan and cost vary", []string{"Seq Scan(t)", "Index Scan(t)", "Bitmap Heap Scan(t)"}, []float64{100, 8, 40}, StabilityUnstable, 3},
+	}
+	for _, tt := range tests {
+		got := stabilityReport(tt.fingerprints, tt.costs)
+		if got.Status != tt.want || got.DistinctPlans != tt.plans || got.Runs != len(tt.fingerprints) {
+			t.Errorf("%s: report = %+v, want %s with %d plans", tt.name, got, tt.want, tt.plans)
+		}
+	}
+	if tt := tests[2]; stabilityReport(tt.fingerprints, tt.costs).MinCost != 8 {
+		t.Error("MinCost should be the cheapest run")
+	}
+}
+
+func TestStabilityResultBecameUnstable(t *testing.T) {
+	stable := &StabilityReport{Status: StabilityStable}
+	flaky := &StabilityReport{Status: StabilityFlaky}
+
+	if !(StabilityResult{Report: flaky, Previous: stable}).BecameUnstable() {
+		t.Error("stable -> flaky should alert")
+	}
+	if (StabilityResult{Report: flaky, Previous: flaky}).BecameUnstable() {
+		t.Error("flaky -> flaky should not alert")
+	}
+	if (StabilityResult{Report: flaky}).BecameUnstable() {
+		t.Error("first check should not alert")
+	}
+}
+
+func TestBaselineStabilityJSON(t *testing.T) {
+	data, err := json.Marshal(Baseline{Query: "q", Stability: &StabilityReport{Status: StabilityFlaky, Runs: 5, DistinctPlans: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"stability":{"status":"FLAKY","runs":5,"distinct_plans":2`) {
+		t.Errorf("baseline JSON = %s", data)
+	}
+	data, _ = json.Marshal(Baseline{Query: "q"})
+	if strings.Contains(string(data), "stability") {
+		t.Errorf("baseline without stability check has the field: %s", data)
+	}
+}
@@ -0,0 +1,209 @@
+package regresql
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPurgeAutoBaselines(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "regresql", "baselines", "orders")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	manual := filepath.Join(dir, "by_id.json")
+	auto := filepath.Join(dir, "list.json")
+	plan := map[string]any{"total_cost": 1.0}
+	if err := saveBaseline(manual, &Baseline{Query: "by_id", Plan: plan}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveBaseline(auto, &Baseline{Query: "list", Plan: plan, AutoCreated: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PurgeAutoBaselines(root)
+	if err != nil {
+		t.Fatalf("PurgeAutoBaselines: %v", err)
+	}
+	if !equalStrings(removed, []string{auto}) {
+		t.Errorf("removed = %v, want [%s]", removed, auto)
+	}
+	if _, err := os.Stat(auto); !os.IsNotExist(err) {
+		t.Error("auto-created baseline still exists")
+	}
+	if _, err := os.Stat(manual); err != nil {
+		t.Errorf("manual baseline removed: %v", err)
+	}
+}
+
+func TestPurgeAutoBaselinesWithoutBaselines(t *testing.T) {
+	removed, err := PurgeAutoBaselines(t.TempDir())
+	if err != nil || len(removed) != 0 {
+		t.Errorf("PurgeAutoBaselines on empty suite = %v, %v", removed, err)
+	}
+}
+
+func TestShouldUpdateBaseline(t *testing.T) {
+	stored := &Baseline{Plan: map[string]any{"total_cost": 100.0}}
+	explain := func(cost float64) *ExplainOutput {
+		return &ExplainOutput{Plan: PlanNode{TotalCost: cost}}
+	}
+
+	tests := []struct {
+		name      string
+		existing  *Baseline
+		current   *ExplainOutput
+		threshold float64
+		want      bool
+	}{
+		{"within threshold", stored, explain(115), 20, false},
+		{"at threshold", stored, explain(120), 20, false},
+		{"cost increase", stored, explain(125), 20, true},
+		{"cost decrease", stored, explain(70), 20, true},
+		{"no baseline", nil, explain(10), 20, true},
+		{"no plan", stored, nil, 20, false},
+		{"zero stored cost", &Baseline{Plan: map[string]any{"total_cost": 0.0}}, explain(1), 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldUpdateBaseline(tt.existing, tt.current, tt.threshold); got != tt.want {
+				t.Errorf("ShouldUpdateBaseline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateBaselineDryRunAndForce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "by_id.json")
+	if err := saveBaseline(path, &Baseline{Query: "by_id", Plan: map[string]any{"total_cost": 100.0}}); err != nil {
+		t.Fatal(err)
+	}
+	q := testQuery(t, "by_id", "orders.sql")
+	current := &ExplainOutput{Plan: PlanNode{TotalCost: 150}}
+
+	u, err := updateBaseline(path, q, current, 20, false, BaselineUpdateOptions{DryRun: true})
+	if err != nil || !u.Updated || u.OldCost != 100 || u.ChangePercent != 50 {
+		t.Fatalf("dry run = %+v, %v", u, err)
+	}
+	if b, _ := LoadBaseline(path); toFloat64(b.Plan["total_cost"]) != 100 {
+		t.Error("dry run rewrote the baseline")
+	}
+
+	if u, _ := updateBaseline(path, q, &ExplainOutput{Plan: PlanNode{TotalCost: 105}}, 20, false, BaselineUpdateOptions{}); u.Updated {
+		t.Error("baseline within the threshold updated")
+	}
+
+	if _, err := updateBaseline(path, q, &ExplainOutput{Plan: PlanNode{TotalCost: 105}}, 20, false, BaselineUpdateOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := LoadBaseline(path); toFloat64(b.Plan["total_cost"]) != 105 {
+		t.Errorf("forced update stored cost %v, want 105", b.Plan["total_cost"])
+	}
+}
+
+func TestBaselineUpdateThreshold(t *testing.T) {
+	plain := queryWithMetadata(t, "-- name: q\nselect 1;\n")
+	hot := queryWithMetadata(t, "-- name: hot\n-- regresql: cost_threshold=5.0\nselect 1;\n")
+
+	if got := (BaselineUpdateOptions{}).threshold(plain); got != GetCostThreshold() {
+		t.Errorf("default threshold = %v, want the configured %v", got, GetCostThreshold())
+	}
+	if got := (BaselineUpdateOptions{}).threshold(hot); got != 5 {
+		t.Errorf("default threshold of a query with cost_threshold = %v, want 5", got)
+	}
+
+	zero := 0.0
+	if got := (BaselineUpdateOptions{Threshold: &zero}).threshold(hot); got != 0 {
+		t.Errorf("--threshold 0 = %v, want 0", got)
+	}
+	if !ShouldUpdateBaseline(&Baseline{Plan: map[string]any{"total_cost": 100.0}}, &ExplainOutput{Plan: PlanNode{TotalCost: 100.5}}, zero) {
+		t.Error("a zero threshold should rewrite a baseline whose cost changed")
+	}
+}
+
+func TestCreateBaselinesRunsHooks(t *testing.T) {
+	db, log := openRecordingDB(t)
+	q, err := NewQueryFromString("orders", "SELECT * FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := (&Plan{
+		Query:      q,
+		BeforeEach: []SQLSpec{{Inline: "SET enable_seqscan = off"}},
+		AfterEach:  []SQLSpec{{Inline: "RESET enable_seqscan"}},
+	}).withoutBindings()
+
+	baselines, _, err := plan.CreateBaselines(context.Background(), db, false)
+	if err != nil {
+		t.Fatalf("CreateBaselines() error = %v", err)
+	}
+	if len(baselines) != 1 || toFloat64(baselines[0].Plan["total_cost"]) != 5 {
+		t.Fatalf("CreateBaselines() = %+v", baselines)
+	}
+
+	got := log.Statements()
+	want := []string{"BEGIN", "SET enable_seqscan = off", "EXPLAIN", "RESET enable_seqscan", "ROLLBACK"}
+	if len(got) != len(want) {
+		t.Fatalf("statements = %q, want %q", got, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("statement %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCreateBaselinesAppliesRole(t *testing.T) {
+	db, log := openRecordingDB(t)
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: role=app_user\nSELECT * FROM orders;\n")
+	plan := (&Plan{Query: q}).withoutBindings()
+
+	if _, _, err := plan.CreateBaselines(context.Background(), db, false); err != nil {
+		t.Fatalf("CreateBaselines() error = %v", err)
+	}
+
+	got := log.Statements()
+	if len(got) != 4 || got[0] != "BEGIN" || got[1] != `SET LOCAL ROLE "app_user"` ||
+		!strings.HasPrefix(got[2], "EXPLAIN") || got[3] != "ROLLBACK" {
+		t.Errorf("statements = %q, want the EXPLAIN after SET LOCAL ROLE in one transaction", got)
+	}
+}
+
+func TestCreateBaselinesChainsKeysetCursors(t *testing.T) {
+	db, log := openRecordingDB(t)
+	log.Columns = []string{"id"}
+	log.Rows = [][]driver.Value{{int64(7)}, {int64(9)}}
+
+	q, err := NewQueryFromString("orders", "SELECT id FROM orders WHERE id > :__page_cursor ORDER BY id LIMIT :__page_size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("pagination: {type: keyset, cursor_col: id, seed: 0, page_size: 2, pages: 2}\n")
+	plan, err := parseYAMLPlan(data, "plans/orders.yaml", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := plan.CreateBaselines(context.Background(), db, false); err != nil {
+		t.Fatalf("CreateBaselines() error = %v", err)
+	}
+
+	var explains []int
+	for i, stmt := range log.Statements() {
+		if strings.HasPrefix(stmt, "EXPLAIN") {
+			explains = append(explains, i)
+		}
+	}
+	if len(explains) != 2 {
+		t.Fatalf("statements = %q, want an EXPLAIN per page", log.Statements())
+	}
+	if args := log.Args(explains[1]); !slices.Contains(args, driver.Value(int64(9))) {
+		t.Errorf("page_2 EXPLAIN args = %v, want the cursor 9 from page_1", args)
+	}
+}
@@ -0,0 +1,173 @@
+package regresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"time"
+)
+
+type (
+	BaselineUpdateOptions struct {
+		Root      string
+		RunFilter string
+		Paths     []string
+		Analyze   bool
+
+		// Threshold is the cost change, in percent either way, from which a
+		// baseline is rewritten; nil uses the cost_threshold of each query,
+		// or the configured cost threshold
+		Threshold *float64
+		DryRun    bool // report what would change without writing
+		Force     bool // rewrite every baseline regardless of Threshold
+	}
+
+	// BaselineUpdate is one baseline checked by UpdateBaselines
+	BaselineUpdate struct {
+		Path          string
+		OldCost       float64
+		NewCost       float64
+		ChangePercent float64
+		Created       bool // the query had no baseline yet
+		Updated       bool // rewritten, or would be on a dry run
+	}
+)
+
+// ShouldUpdateBaseline reports whether the current plan cost moved more than
+// threshold percent, up or down, from the cost stored in the baseline. A
+// missing baseline always needs writing.
+func ShouldUpdateBaseline(existing *Baseline, current *ExplainOutput, threshold float64) bool {
+	if current == nil {
+		return false
+	}
+	if existing == nil {
+		return true
+	}
+	return math.Abs(costChangePercent(toFloat64(existing.Plan["total_cost"]), current.Plan.TotalCost)) > threshold
+}
+
+func costChangePercent(oldCost, newCost float64) float64 {
+	if oldCost == 0 {
+		if newCost == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (newCost - oldCost) / oldCost * 100
+}
+
+// UpdateBaselines plans every query again and rewrites the baselines whose
+// cost changed by more than the threshold, the "accept regression" step
+// after a cost change was confirmed as expected. Rewritten baselines are
+// archived for `baseline diff` like those of `regresql baseline`.
+func UpdateBaselines(opts BaselineUpdateOptions) ([]BaselineUpdate, error) {
+	config, err := ReadConfig(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	SetGlobalConfig(config)
+	useAnalyze := opts.Analyze || IsAnalyzeEnabled()
+
+	db, err := OpenDB(config.PgUri)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	plannedQueries, err := WalkPlans(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	suite := Walk(opts.Root, config.Ignore)
+	suite.SetRunFilter(opts.RunFilter)
+	suite.SetPathFilters(opts.Paths)
+
+	baselineDir := filepath.Join(opts.Root, "regresql", "baselines")
+	historyLabel := baselineHistoryLabel(opts.Root, time.Now())
+
+	ctx := context.Background()
+	var updates []BaselineUpdate
+	var errs []error
+	for _, pq := range plannedQueries {
+		q := pq.Query
+		if !suite.matchesRunFilter(filepath.Base(pq.SQLPath), q.Name) || !suite.matchesPathFilter(pq.RelPath) {
+			continue
+		}
+		if qopts := q.GetRegressQLOptions(); qopts.NoTest || qopts.NoBaseline {
+			continue
+		}
+		plan := pq.Plan
+		if len(q.Args) == 0 {
+			plan = plan.withoutBindings()
+		} else if len(plan.Bindings) == 0 {
+			continue
+		}
+
+		_, fullPlans, err := plan.CreateBaselines(ctx, db, useAnalyze)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dir := filepath.Join(baselineDir, filepath.Dir(pq.RelPath))
+		for i, fullPlan := range fullPlans {
+			u, err := updateBaseline(getBaselinePath(q, dir, plan.Names[i]), q, fullPlan, opts.threshold(q), useAnalyze, opts)
+			if err == nil && u.Updated && !opts.DryRun {
+				err = archiveBaseline(baselineDir, historyLabel, u.Path)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", q.Name, err))
+				continue
+			}
+			updates = append(updates, u)
+		}
+	}
+	return updates, errors.Join(errs...)
+}
+
+// threshold returns the cost change from which the baselines of q are
+// rewritten: --threshold when given, even 0, otherwise the query's own
+// cost_threshold or the configured one
+func (opts BaselineUpdateOptions) threshold(q *Query) float64 {
+	if opts.Threshold != nil {
+		return *opts.Threshold
+	}
+	return q.GetRegressQLOptions().costThreshold(GetCostThreshold())
+}
+
+// updateBaseline compares one baseline with the current plan and rewrites it
+// when it changed enough
+func updateBaseline(path string, q *Query, current *ExplainOutput, threshold float64, useAnalyze bool, opts BaselineUpdateOptions) (BaselineUpdate, error) {
+	u := BaselineUpdate{Path: path, NewCost: current.Plan.TotalCost}
+
+	var existing *Baseline
+	if fileExists(path) {
+		b, err := LoadBaseline(path)
+		if err != nil {
+			return u, err
+		}
+		existing = b
+		u.OldCost = toFloat64(b.Plan["total_cost"])
+		u.ChangePercent = costChangePercent(u.OldCost, u.NewCost)
+	} else {
+		u.Created = true
+	}
+
+	u.Updated = opts.Force || ShouldUpdateBaseline(existing, current, threshold)
+	if !u.Updated || opts.DryRun {
+		return u, nil
+	}
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return u, err
+	}
+	baseline := newBaseline(q.Name, map[string]any{
+		"startup_cost": current.Plan.StartupCost,
+		"total_cost":   current.Plan.TotalCost,
+		"plan_rows":    current.Plan.PlanRows,
+	}, current, useAnalyze)
+	if existing != nil {
+		baseline.keepSampled(existing)
+	}
+	return u, saveBaseline(path, &baseline)
+}
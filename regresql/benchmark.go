@@ -0,0 +1,328 @@
+package regresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	DefaultBenchmarkRuns   = 10
+	DefaultBenchmarkWarmup = 2
+	DefaultBenchmarkFactor = 1.5
+
+	// BenchmarkLatest makes --compare pick the newest stored benchmark
+	BenchmarkLatest = "latest"
+)
+
+type (
+	BenchmarkOptions struct {
+		Root      string
+		RunFilter string
+		Runs      int     // measured EXPLAIN ANALYZE runs per binding
+		Warmup    int     // discarded runs before the measured ones
+		Compare   string  // previous benchmark JSON, or "latest"
+		Factor    float64 // p50 slowdown that counts as a regression
+	}
+
+	// BenchmarkRun is one `regresql benchmark` run, stored as JSON in
+	// regresql/benchmarks/
+	BenchmarkRun struct {
+		Timestamp string           `json:"timestamp"`
+		Runs      int              `json:"runs"`
+		Warmup    int              `json:"warmup"`
+		Results   []BenchmarkStats `json:"results"`
+	}
+
+	// BenchmarkStats are the execution times of one query binding, in ms
+	BenchmarkStats struct {
+		Query   string  `json:"query"`
+		Binding string  `json:"binding,omitempty"`
+		MeanMs  float64 `json:"mean_ms"`
+		MinMs   float64 `json:"min_ms"`
+		MaxMs   float64 `json:"max_ms"`
+		P50Ms   float64 `json:"p50_ms"`
+		P95Ms   float64 `json:"p95_ms"`
+		Error   string  `json:"error,omitempty"`
+	}
+
+	BenchmarkRegression struct {
+		Query         string
+		Binding       string
+		PreviousP50Ms float64
+		P50Ms         float64
+		Ratio         float64
+	}
+)
+
+// Benchmark runs every planned query binding Warmup+Runs times with EXPLAIN
+// ANALYZE, reports execution time statistics and stores them in
+// regresql/benchmarks/. With Compare set it also reports the bindings whose
+// p50 grew by more than Factor.
+// Returns exit code: 0 = ok, 1 = regressions found, 2 = error
+func Benchmark(opts BenchmarkOptions) int {
+	config, err := ReadConfig(opts.Root)
+	if err != nil {
+		fmt.Printf("Error reading config: %s\n", err.Error())
+		return 2
+	}
+	SetGlobalConfig(config)
+	if opts.Runs < 1 {
+		fmt.Println("Error: --runs must be at least 1")
+		return 2
+	}
+	if opts.Factor <= 1 {
+		fmt.Println("Error: --factor must be greater than 1")
+		return 2
+	}
+
+	benchmarkDir := filepath.Join(opts.Root, "regresql", "benchmarks")
+	var previous *BenchmarkRun
+	if opts.Compare != "" {
+		path := opts.Compare
+		if path == BenchmarkLatest {
+			if path, err = latestBenchmark(benchmarkDir); err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+				return 2
+			}
+		}
+		if previous, err = ReadBenchmark(path); err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			return 2
+		}
+		fmt.Printf("Comparing against %s\n", path)
+	}
+
+	if err := TestConnectionString(config.PgUri); err != nil {
+		fmt.Printf("Error connecting to database: %s\n", err.Error())
+		return 2
+	}
+	db, err := OpenDB(config.PgUri)
+	if err != nil {
+		fmt.Printf("Failed to open database connection: %s\n", err.Error())
+		return 2
+	}
+	defer db.Close()
+
+	plannedQueries, err := WalkPlans(opts.Root)
+	if err != nil {
+		fmt.Printf("Error walking plans: %s\n", err.Error())
+		return 2
+	}
+	suite := Walk(opts.Root, config.Ignore)
+	suite.SetRunFilter(opts.RunFilter)
+
+	run := &BenchmarkRun{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Runs:      opts.Runs,
+		Warmup:    opts.Warmup,
+	}
+	for _, pq := range plannedQueries {
+		if !suite.matchesRunFilter(filepath.Base(pq.SQLPath), pq.Query.Name) {
+			continue
+		}
+		if pq.Query.GetRegressQLOptions().NoTest {
+			continue
+		}
+		name := benchmarkQueryName(pq)
+		for _, b := range iterateBindings(pq.Plan) {
+			stats := BenchmarkStats{Query: name, Binding: b.name}
+			times, err := benchmarkBinding(context.Background(), db, pq.Query, b.bindings, opts.Warmup, opts.Runs)
+			if err != nil {
+				stats.Error = err.Error()
+			} else {
+				stats.setTimes(times)
+			}
+			run.Results = append(run.Results, stats)
+		}
+	}
+
+	PrintBenchmark(os.Stdout, run)
+
+	path, err := WriteBenchmark(benchmarkDir, run)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return 2
+	}
+	fmt.Printf("\nBenchmark stored in: %s\n", path)
+
+	if previous == nil {
+		return 0
+	}
+	regressions := CompareBenchmarks(previous, run, opts.Factor)
+	if len(regressions) == 0 {
+		fmt.Printf("No query slowed down by more than %.2fx\n", opts.Factor)
+		return 0
+	}
+	fmt.Printf("\n%d regression(s) slower than %.2fx:\n", len(regressions), opts.Factor)
+	for _, r := range regressions {
+		fmt.Printf("  %s: p50 %.3f ms -> %.3f ms (%.2fx)\n", benchmarkLabel(r.Query, r.Binding), r.PreviousP50Ms, r.P50Ms, r.Ratio)
+	}
+	return 1
+}
+
+// benchmarkBinding returns the actual total time of the measured runs. Each
+// run is rolled back, so writing queries leave the database unchanged.
+func benchmarkBinding(ctx context.Context, db *sql.DB, q *Query, bindings map[string]any, warmup, runs int) ([]float64, error) {
+	sqlText := q.OrdinalQuery
+	var args []any
+	if len(q.Args) > 0 {
+		sqlText, args = q.Prepare(bindings)
+	}
+	timeout := resolveCompareTimeout(q, 0)
+
+	times := make([]float64, 0, runs)
+	for i := 0; i < warmup+runs; i++ {
+		ms, err := func() (float64, error) {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return 0, err
+			}
+			defer tx.Rollback()
+			if err := applyStatementTimeout(ctx, tx, timeout); err != nil {
+				return 0, err
+			}
+			ex, err := ExecuteExplainAnalyze(ctx, tx, sqlText, args...)
+			if err != nil {
+				return 0, err
+			}
+			return ex.Plan.ActualTotalTime, nil
+		}()
+		if err != nil {
+			return nil, err
+		}
+		if i >= warmup {
+			times = append(times, ms)
+		}
+	}
+	return times, nil
+}
+
+func (s *BenchmarkStats) setTimes(times []float64) {
+	if len(times) == 0 {
+		return
+	}
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+	var sum float64
+	for _, t := range sorted {
+		sum += t
+	}
+	s.MeanMs = sum / float64(len(sorted))
+	s.MinMs = sorted[0]
+	s.MaxMs = sorted[len(sorted)-1]
+	s.P50Ms = nearestRank(sorted, 50)
+	s.P95Ms = nearestRank(sorted, 95)
+}
+
+// CompareBenchmarks returns the bindings of current whose p50 is more than
+// factor times their p50 in previous; bindings missing from either run or
+// that failed are skipped
+func CompareBenchmarks(previous, current *BenchmarkRun, factor float64) []BenchmarkRegression {
+	before := make(map[string]BenchmarkStats, len(previous.Results))
+	for _, s := range previous.Results {
+		before[bindingKey(s.Query, s.Binding)] = s
+	}
+
+	var regressions []BenchmarkRegression
+	for _, s := range current.Results {
+		prev, ok := before[bindingKey(s.Query, s.Binding)]
+		if !ok || s.Error != "" || prev.Error != "" || prev.P50Ms <= 0 {
+			continue
+		}
+		if ratio := s.P50Ms / prev.P50Ms; ratio > factor {
+			regressions = append(regressions, BenchmarkRegression{
+				Query:         s.Query,
+				Binding:       s.Binding,
+				PreviousP50Ms: prev.P50Ms,
+				P50Ms:         s.P50Ms,
+				Ratio:         ratio,
+			})
+		}
+	}
+	return regressions
+}
+
+// PrintBenchmark writes the statistics of a run as a table
+func PrintBenchmark(w io.Writer, run *BenchmarkRun) {
+	fmt.Fprintf(w, "Benchmark: %d run(s), %d warmup(s), times in ms\n\n", run.Runs, run.Warmup)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "query\tmean\tmin\tmax\tp50\tp95\t")
+	for _, s := range run.Results {
+		label := benchmarkLabel(s.Query, s.Binding)
+		if s.Error != "" {
+			fmt.Fprintf(tw, "%s\terror: %s\t\t\t\t\t\n", label, s.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t\n", label, s.MeanMs, s.MinMs, s.MaxMs, s.P50Ms, s.P95Ms)
+	}
+	tw.Flush()
+}
+
+// WriteBenchmark stores a run as <timestamp>.json in dir
+func WriteBenchmark(dir string, run *BenchmarkRun) (string, error) {
+	if err := ensureDir(dir); err != nil {
+		return "", fmt.Errorf("failed to create benchmarks directory: %w", err)
+	}
+	ts, err := time.Parse(time.RFC3339, run.Timestamp)
+	if err != nil {
+		return "", fmt.Errorf("invalid benchmark timestamp %q: %w", run.Timestamp, err)
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal benchmark: %w", err)
+	}
+	path := filepath.Join(dir, ts.Format("20060102T150405Z")+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write benchmark '%s': %w", path, err)
+	}
+	return path, nil
+}
+
+func ReadBenchmark(path string) (*BenchmarkRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read benchmark '%s': %w", path, err)
+	}
+	var run BenchmarkRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse benchmark '%s': %w", path, err)
+	}
+	return &run, nil
+}
+
+// latestBenchmark returns the newest benchmark in dir; the timestamped file
+// names sort in time order
+func latestBenchmark(dir string) (string, error) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no previous benchmark in %s", dir)
+	}
+	slices.Sort(matches)
+	return matches[len(matches)-1], nil
+}
+
+// benchmarkQueryName names a query like `baseline show` does: the SQL path
+// without extension, plus the query name for multi-query files
+func benchmarkQueryName(pq *PlannedQuery) string {
+	base := strings.TrimSuffix(filepath.ToSlash(pq.RelPath), filepath.Ext(pq.RelPath))
+	if filepath.Base(base) != pq.Query.Name {
+		base += "/" + pq.Query.Name
+	}
+	return base
+}
+
+func benchmarkLabel(query, binding string) string {
+	if binding == "" {
+		return query
+	}
+	return query + "." + binding
+}
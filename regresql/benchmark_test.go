@@ -0,0 +1,91 @@
+package regresql
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkStatsSetTimes(t *testing.T) {
+	var s BenchmarkStats
+	s.setTimes([]float64{4, 1, 3, 2, 10})
+	want := BenchmarkStats{MeanMs: 4, MinMs: 1, MaxMs: 10, P50Ms: 3, P95Ms: 10}
+	if s != want {
+		t.Errorf("setTimes = %+v, want %+v", s, want)
+	}
+}
+
+func TestCompareBenchmarks(t *testing.T) {
+	previous := &BenchmarkRun{Results: []BenchmarkStats{
+		{Query: "orders/get_order", Binding: "1", P50Ms: 2},
+		{Query: "orders/get_order", Binding: "2", P50Ms: 2},
+		{Query: "users/list", P50Ms: 1},
+		{Query: "users/broken", Error: "boom"},
+	}}
+	current := &BenchmarkRun{Results: []BenchmarkStats{
+		{Query: "orders/get_order", Binding: "1", P50Ms: 5},
+		{Query: "orders/get_order", Binding: "2", P50Ms: 2.5},
+		{Query: "users/list", P50Ms: 1.4},
+		{Query: "users/broken", P50Ms: 100},
+		{Query: "users/new", P50Ms: 100},
+	}}
+
+	got := CompareBenchmarks(previous, current, 1.5)
+	if len(got) != 1 {
+		t.Fatalf("regressions = %+v, want only orders/get_order.1", got)
+	}
+	if r := got[0]; r.Query != "orders/get_order" || r.Binding != "1" || r.Ratio != 2.5 {
+		t.Errorf("regression = %+v", r)
+	}
+}
+
+func TestWriteBenchmark(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "benchmarks")
+	older := &BenchmarkRun{Timestamp: "2026-01-01T10:00:00Z", Runs: 10, Results: []BenchmarkStats{{Query: "q", P50Ms: 1}}}
+	newer := &BenchmarkRun{Timestamp: "2026-01-02T09:00:00Z", Runs: 5, Results: []BenchmarkStats{{Query: "q", P50Ms: 2}}}
+	for _, run := range []*BenchmarkRun{newer, older} {
+		if _, err := WriteBenchmark(dir, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, err := latestBenchmark(dir)
+	if err != nil || filepath.Base(path) != "20260102T090000Z.json" {
+		t.Fatalf("latestBenchmark = %s, %v", path, err)
+	}
+	got, err := ReadBenchmark(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Runs != 5 || len(got.Results) != 1 || got.Results[0].P50Ms != 2 {
+		t.Errorf("ReadBenchmark = %+v", got)
+	}
+
+	if _, err := latestBenchmark(filepath.Join(t.TempDir(), "none")); err == nil {
+		t.Error("latestBenchmark without benchmarks: expected error")
+	}
+}
+
+func TestPrintBenchmark(t *testing.T) {
+	var out strings.Builder
+	PrintBenchmark(&out, &BenchmarkRun{Runs: 3, Warmup: 1, Results: []BenchmarkStats{
+		{Query: "orders/get_order", Binding: "1", MeanMs: 1.5, MinMs: 1, MaxMs: 2, P50Ms: 1.5, P95Ms: 2},
+		{Query: "users/broken", Error: "relation \"users\" does not exist"},
+	}})
+	for _, want := range []string{"3 run(s), 1 warmup(s)", "orders/get_order.1", "1.500", `error: relation "users" does not exist`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestBenchmarkQueryName(t *testing.T) {
+	single := &PlannedQuery{RelPath: "orders/get_order.sql", Query: testQuery(t, "get_order", "orders/get_order.sql")}
+	multi := &PlannedQuery{RelPath: "orders/orders.sql", Query: testQuery(t, "by_id", "orders/orders.sql")}
+	if got := benchmarkQueryName(single); got != "orders/get_order" {
+		t.Errorf("single = %s", got)
+	}
+	if got := benchmarkQueryName(multi); got != "orders/orders/by_id" {
+		t.Errorf("multi = %s", got)
+	}
+}
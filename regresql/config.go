@@ -11,10 +11,15 @@ import (
 
 type (
 	config struct {
+		ConfigVersion  int                   `yaml:"config_version,omitempty"` // format version, see CurrentConfigVersion
 		Extends        string                `yaml:"extends,omitempty"`
 		Root           string                `yaml:"root"`
 		PgUri          string                `yaml:"pguri"`
-		Timeout        string                `yaml:"timeout,omitempty"` // statement_timeout, e.g. "30s"
+		Timeout        string                `yaml:"timeout,omitempty"`         // statement_timeout, e.g. "30s"
+		MaxResultRows  int                   `yaml:"max_result_rows,omitempty"` // sample larger results (0 = keep all rows)
+		AutoBaseline   bool                  `yaml:"auto_baseline,omitempty"`   // create missing baselines during test
+		PgTAPDir       string                `yaml:"pgtap_dir,omitempty"`       // pgTAP files run alongside the queries
+		Tracing        bool                  `yaml:"tracing,omitempty"`         // export OpenTelemetry spans
 		Ignore         []string              `yaml:"ignore,omitempty"`
 		PlanQuality    *PlanQualityGlobal    `yaml:"plan_quality,omitempty"`
 		DiffComparison *DiffComparisonGlobal `yaml:"diff_comparison,omitempty"`
@@ -22,6 +27,8 @@ type (
 		Analyze        *AnalyzeConfig        `yaml:"analyze,omitempty"`
 		Stats          *StatsConfig          `yaml:"stats,omitempty"`
 		Policies       *PoliciesConfig       `yaml:"policies,omitempty"`
+		ResultStore    *ResultStoreConfig    `yaml:"result_store,omitempty"`
+		Notifications  *NotificationConfig   `yaml:"notifications,omitempty"`
 	}
 
 	StatsConfig struct {
@@ -36,6 +43,7 @@ type (
 		ImprovementThreshold float64 `yaml:"improvement_threshold,omitempty"` // default: 20.0
 		QErrorRatio          float64 `yaml:"qerror_ratio,omitempty"`          // default: 2.0 (x worse than baseline)
 		QErrorFloor          float64 `yaml:"qerror_floor,omitempty"`          // default: 10.0 (absolute q-error floor)
+		CostPercentile       int     `yaml:"cost_percentile,omitempty"`       // 50 | 95 | 99: compare against the recorded cost percentile
 	}
 
 	PlanQualityGlobal struct {
@@ -51,18 +59,65 @@ type (
 	DiffComparisonGlobal struct {
 		FloatTolerance float64 `yaml:"float_tolerance,omitempty"`
 		MaxSamples     int     `yaml:"max_samples,omitempty"`
+
+		// IgnoreColumnOrder compares result columns by name for every
+		// query, like the ignore_column_order annotation
+		IgnoreColumnOrder bool `yaml:"ignore_column_order,omitempty"`
 	}
 
 	SnapshotConfig struct {
-		Path             string   `yaml:"path,omitempty"`
-		Format           string   `yaml:"format,omitempty"`
-		Schema           string   `yaml:"schema,omitempty"`
-		Migrations       string   `yaml:"migrations,omitempty"`
-		MigrationCommand string   `yaml:"migration_command,omitempty"`
-		Fixtures         []string `yaml:"fixtures,omitempty"`
-		Fixturize        []string `yaml:"fixturize,omitempty"`
-		RestoreDatabase  string   `yaml:"restore_database,omitempty"`
-		ValidateSettings string   `yaml:"validate_settings,omitempty"`
+		Path              string                 `yaml:"path,omitempty"`
+		Format            string                 `yaml:"format,omitempty"`
+		Schema            string                 `yaml:"schema,omitempty"`
+		Migrations        string                 `yaml:"migrations,omitempty"`
+		MigrationsNaming  string                 `yaml:"migrations_naming,omitempty"` // golang-migrate, flyway, timestamp, dbmate or goose
+		MigrationCommand  string                 `yaml:"migration_command,omitempty"`
+		Fixtures          []string               `yaml:"fixtures,omitempty"`
+		Fixturize         []string               `yaml:"fixturize,omitempty"`
+		CSVNullValue      string                 `yaml:"csv_null_value,omitempty"`
+		Masks             map[string]string      `yaml:"masks,omitempty"` // table.column -> SQL expression
+		ResetSequences    bool                   `yaml:"reset_sequences,omitempty"`
+		RestoreDatabase   string                 `yaml:"restore_database,omitempty"`
+		ValidateSettings  string                 `yaml:"validate_settings,omitempty"`
+		SchemaDrift       string                 `yaml:"schema_drift,omitempty"` // warn, strict or ignore (default)
+		TenantSchema      string                 `yaml:"tenant_schema,omitempty"`
+		HistoryMaxEntries int                    `yaml:"history_max_entries,omitempty"` // default: 50
+		Storage           *SnapshotStorageConfig `yaml:"storage,omitempty"`
+	}
+
+	// SnapshotStorageConfig configures a remote backend that snapshots are
+	// pushed to after capture and pulled from when missing locally
+	SnapshotStorageConfig struct {
+		Type   string `yaml:"type"` // s3
+		Bucket string `yaml:"bucket,omitempty"`
+		Prefix string `yaml:"prefix,omitempty"`
+
+		// Endpoint of an S3-compatible service (MinIO, Ceph); MinIO
+		// usually needs ForcePathStyle, since it doesn't serve buckets
+		// as subdomains
+		Endpoint       string `yaml:"endpoint,omitempty"`
+		ForcePathStyle bool   `yaml:"force_path_style,omitempty"`
+	}
+
+	// ResultStoreConfig configures the PostgreSQL table `regresql test`
+	// appends its results to, so pass rates can be tracked across runs
+	ResultStoreConfig struct {
+		PgUri string `yaml:"pguri"`           // $VAR and ${VAR} are expanded
+		Table string `yaml:"table,omitempty"` // default: regresql_results
+	}
+
+	// NotificationConfig configures where `regresql test` reports critical
+	// plan regressions
+	NotificationConfig struct {
+		Webhook *WebhookConfig `yaml:"webhook,omitempty"`
+	}
+
+	// WebhookConfig is an HTTP endpoint critical plan regressions are POSTed
+	// to as JSON; $VAR and ${VAR} in URL and Secret are expanded
+	WebhookConfig struct {
+		URL                  string `yaml:"url"`
+		OnCriticalRegression bool   `yaml:"on_critical_regression,omitempty"`
+		Secret               string `yaml:"secret,omitempty"` // HMAC-SHA256 key of the signature header
 	}
 )
 
@@ -84,12 +139,14 @@ func (s *Suite) createRegressDir() error {
 	return nil
 }
 
-func (s *Suite) setupConfig(pguri string) {
+func (s *Suite) setupConfig(pguri string, snapshot *SnapshotConfig) {
 	configFile := s.getRegressConfigFile()
 
 	cfg := config{
-		Root:  s.Root,
-		PgUri: pguri,
+		ConfigVersion: CurrentConfigVersion,
+		Root:          s.Root,
+		PgUri:         pguri,
+		Snapshot:      snapshot,
 	}
 
 	data, err := yaml.Marshal(&cfg)
@@ -118,6 +175,7 @@ func ReadConfig(root string) (config, error) {
 	if err != nil {
 		return cfg, err
 	}
+	warnUnversioned(cfg, filepath.Join(root, "regresql", "regress.yaml"))
 	return withEnvOverride(cfg), nil
 }
 
@@ -146,6 +204,9 @@ func loadConfig(configFile string) (config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return cfg, fmt.Errorf("failed to parse config '%s': %w", configFile, err)
 	}
+	if cfg.ConfigVersion > CurrentConfigVersion {
+		return config{}, fmt.Errorf("config '%s' has config_version %d, this regresql reads up to %d; upgrade regresql", configFile, cfg.ConfigVersion, CurrentConfigVersion)
+	}
 
 	if cfg.Extends != "" {
 		base, err := resolveAndLoadPack(cfg.Extends, filepath.Dir(configFile))
@@ -214,6 +275,21 @@ func GetPoliciesConfig() *PoliciesConfig {
 	return cachedConfig.Policies
 }
 
+// GetMaxResultRows returns the global row limit above which query results are
+// sampled (0 = unlimited)
+func GetMaxResultRows() int {
+	if cachedConfig == nil {
+		return 0
+	}
+	return cachedConfig.MaxResultRows
+}
+
+// IsAutoBaselineEnabled reports whether regresql test records a baseline for
+// queries that have none yet
+func IsAutoBaselineEnabled() bool {
+	return cachedConfig != nil && cachedConfig.AutoBaseline
+}
+
 func GetDiffConfig() *DiffConfig {
 	cfg := DefaultDiffConfig()
 	if cachedConfig != nil && cachedConfig.DiffComparison != nil {
@@ -224,6 +300,7 @@ func GetDiffConfig() *DiffConfig {
 		if dc.MaxSamples > 0 {
 			cfg.MaxSamples = dc.MaxSamples
 		}
+		cfg.IgnoreColumnOrder = dc.IgnoreColumnOrder
 	}
 	return cfg
 }
@@ -249,6 +326,7 @@ func GetAnalyzeConfig() *AnalyzeConfig {
 		ImprovementThreshold: cfg.ImprovementThreshold,
 		QErrorRatio:          cfg.QErrorRatio,
 		QErrorFloor:          cfg.QErrorFloor,
+		CostPercentile:       cfg.CostPercentile,
 	}
 	if result.Comparison == "" {
 		result.Comparison = "auto"
@@ -273,6 +351,9 @@ func GetAnalyzeConfig() *AnalyzeConfig {
 
 func mergeConfig(base, over config) config {
 	out := base
+	if over.ConfigVersion != 0 {
+		out.ConfigVersion = over.ConfigVersion
+	}
 	if over.Root != "" {
 		out.Root = over.Root
 	}
@@ -282,6 +363,18 @@ func mergeConfig(base, over config) config {
 	if over.Timeout != "" {
 		out.Timeout = over.Timeout
 	}
+	if over.MaxResultRows != 0 {
+		out.MaxResultRows = over.MaxResultRows
+	}
+	if over.AutoBaseline {
+		out.AutoBaseline = true
+	}
+	if over.PgTAPDir != "" {
+		out.PgTAPDir = over.PgTAPDir
+	}
+	if over.Tracing {
+		out.Tracing = true
+	}
 	out.Ignore = mergeStringSlice(base.Ignore, over.Ignore)
 	out.PlanQuality = mergePlanQuality(base.PlanQuality, over.PlanQuality)
 	out.DiffComparison = mergeDiffComparison(base.DiffComparison, over.DiffComparison)
@@ -289,6 +382,8 @@ func mergeConfig(base, over config) config {
 	out.Analyze = mergeAnalyzeConfig(base.Analyze, over.Analyze)
 	out.Stats = mergeStatsConfig(base.Stats, over.Stats)
 	out.Policies = mergePoliciesConfig(base.Policies, over.Policies)
+	out.ResultStore = mergeResultStoreConfig(base.ResultStore, over.ResultStore)
+	out.Notifications = mergeNotificationConfig(base.Notifications, over.Notifications)
 	return out
 }
 
@@ -373,6 +468,9 @@ func mergeDiffComparison(a, b *DiffComparisonGlobal) *DiffComparisonGlobal {
 	if b.MaxSamples != 0 {
 		out.MaxSamples = b.MaxSamples
 	}
+	if b.IgnoreColumnOrder {
+		out.IgnoreColumnOrder = true
+	}
 	return &out
 }
 
@@ -396,17 +494,39 @@ func mergeSnapshotConfig(a, b *SnapshotConfig) *SnapshotConfig {
 	if b.Migrations != "" {
 		out.Migrations = b.Migrations
 	}
+	if b.MigrationsNaming != "" {
+		out.MigrationsNaming = b.MigrationsNaming
+	}
 	if b.MigrationCommand != "" {
 		out.MigrationCommand = b.MigrationCommand
 	}
 	out.Fixtures = mergeStringSlice(a.Fixtures, b.Fixtures)
 	out.Fixturize = mergeStringSlice(a.Fixturize, b.Fixturize)
+	if b.CSVNullValue != "" {
+		out.CSVNullValue = b.CSVNullValue
+	}
+	out.Masks = mergeStringMap(a.Masks, b.Masks)
+	if b.ResetSequences {
+		out.ResetSequences = true
+	}
 	if b.RestoreDatabase != "" {
 		out.RestoreDatabase = b.RestoreDatabase
 	}
 	if b.ValidateSettings != "" {
 		out.ValidateSettings = b.ValidateSettings
 	}
+	if b.SchemaDrift != "" {
+		out.SchemaDrift = b.SchemaDrift
+	}
+	if b.TenantSchema != "" {
+		out.TenantSchema = b.TenantSchema
+	}
+	if b.HistoryMaxEntries != 0 {
+		out.HistoryMaxEntries = b.HistoryMaxEntries
+	}
+	if b.Storage != nil {
+		out.Storage = b.Storage
+	}
 	return &out
 }
 
@@ -439,6 +559,9 @@ func mergeAnalyzeConfig(a, b *AnalyzeConfig) *AnalyzeConfig {
 	if b.QErrorFloor != 0 {
 		out.QErrorFloor = b.QErrorFloor
 	}
+	if b.CostPercentile != 0 {
+		out.CostPercentile = b.CostPercentile
+	}
 	return &out
 }
 
@@ -456,6 +579,73 @@ func mergeStatsConfig(a, b *StatsConfig) *StatsConfig {
 	return &out
 }
 
+func mergeResultStoreConfig(a, b *ResultStoreConfig) *ResultStoreConfig {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := *a
+	if b.PgUri != "" {
+		out.PgUri = b.PgUri
+	}
+	if b.Table != "" {
+		out.Table = b.Table
+	}
+	return &out
+}
+
+// GetSnapshotHistoryMaxEntries returns how many earlier snapshots the
+// snapshot metadata keeps (snapshot.history_max_entries)
+func GetSnapshotHistoryMaxEntries() int {
+	if cachedConfig == nil || cachedConfig.Snapshot == nil || cachedConfig.Snapshot.HistoryMaxEntries <= 0 {
+		return DefaultSnapshotHistoryMaxEntries
+	}
+	return cachedConfig.Snapshot.HistoryMaxEntries
+}
+
+// GetResultStoreConfig returns the result_store configuration with its pguri
+// environment variables expanded, nil when no pguri is configured
+func GetResultStoreConfig() *ResultStoreConfig {
+	if cachedConfig == nil || cachedConfig.ResultStore == nil || cachedConfig.ResultStore.PgUri == "" {
+		return nil
+	}
+	cfg := *cachedConfig.ResultStore
+	cfg.PgUri = os.ExpandEnv(cfg.PgUri)
+	return &cfg
+}
+
+func mergeNotificationConfig(a, b *NotificationConfig) *NotificationConfig {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := *a
+	if b.Webhook != nil {
+		out.Webhook = b.Webhook
+	}
+	return &out
+}
+
+// GetNotificationConfig returns the notifications configuration with the
+// webhook's environment variables expanded, nil when none is configured
+func GetNotificationConfig() *NotificationConfig {
+	if cachedConfig == nil || cachedConfig.Notifications == nil {
+		return nil
+	}
+	cfg := *cachedConfig.Notifications
+	if cfg.Webhook != nil {
+		webhook := *cfg.Webhook
+		webhook.URL = os.ExpandEnv(webhook.URL)
+		webhook.Secret = os.ExpandEnv(webhook.Secret)
+		cfg.Webhook = &webhook
+	}
+	return &cfg
+}
+
 // GetStatementTimeout returns the default statement_timeout (0 = none).
 func GetStatementTimeout() time.Duration {
 	if cachedConfig == nil || cachedConfig.Timeout == "" {
@@ -495,3 +685,9 @@ func GetQErrorRatio() float64 {
 func GetQErrorFloor() float64 {
 	return GetAnalyzeConfig().QErrorFloor
 }
+
+// GetCostPercentile returns the cost percentile cost checks compare against,
+// 0 to compare against the baseline plan cost
+func GetCostPercentile() int {
+	return GetAnalyzeConfig().CostPercentile
+}
@@ -0,0 +1,134 @@
+package regresql
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the regress.yaml format this regresql reads and
+// writes. Files without config_version predate versioning and are version 0.
+const CurrentConfigVersion = 1
+
+// configMigrations upgrade a regress.yaml, decoded as a map, by one format
+// version: configMigrations[n] turns version n into n+1. MigrateConfig
+// stamps config_version after each step.
+var configMigrations = []func(cfg map[string]any) error{
+	// 0 -> 1: unversioned files already have the version 1 layout, the
+	// step only records the version
+	func(map[string]any) error { return nil },
+}
+
+var warnUnversionedConfig sync.Once
+
+// MigrateConfig upgrades a decoded regress.yaml from fromVersion to
+// toVersion, running the migration of every version in between. The input
+// map is left untouched.
+func MigrateConfig(old map[string]any, fromVersion, toVersion int) (map[string]any, error) {
+	if fromVersion < 0 || toVersion > CurrentConfigVersion {
+		return nil, fmt.Errorf("cannot migrate config from version %d to %d (latest is %d)", fromVersion, toVersion, CurrentConfigVersion)
+	}
+	if fromVersion > toVersion {
+		return nil, fmt.Errorf("cannot migrate config from version %d down to %d", fromVersion, toVersion)
+	}
+
+	cfg := deepCopyConfig(old)
+	for v := fromVersion; v < toVersion; v++ {
+		if err := configMigrations[v](cfg); err != nil {
+			return nil, fmt.Errorf("migrating config from version %d to %d: %w", v, v+1, err)
+		}
+		cfg["config_version"] = v + 1
+	}
+	return cfg, nil
+}
+
+// deepCopyConfig copies the nested maps of a decoded config, so migrations
+// can rewrite sections in place
+func deepCopyConfig(m map[string]any) map[string]any {
+	out := maps.Clone(m)
+	if out == nil {
+		out = map[string]any{}
+	}
+	for k, v := range out {
+		if sub, ok := v.(map[string]any); ok {
+			out[k] = deepCopyConfig(sub)
+		}
+	}
+	return out
+}
+
+// configVersionOf returns the config_version of a decoded regress.yaml, 0
+// when it has none
+func configVersionOf(cfg map[string]any) (int, error) {
+	v, ok := cfg["config_version"]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("config_version must be an integer, got %v", v)
+	}
+	return n, nil
+}
+
+// MigrateConfigFile upgrades regresql/regress.yaml to toVersion and returns
+// the file before and after. Unless dryRun is set, the previous file is
+// kept as regress.yaml.bak and the upgraded one written in its place;
+// comments are not preserved.
+func MigrateConfigFile(root string, toVersion int, dryRun bool) (before, after []byte, err error) {
+	configFile := filepath.Join(root, "regresql", "regress.yaml")
+	before, err = os.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config '%s': %w", configFile, err)
+	}
+
+	var old map[string]any
+	if err := yaml.Unmarshal(before, &old); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config '%s': %w", configFile, err)
+	}
+	fromVersion, err := configVersionOf(old)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fromVersion == toVersion {
+		return before, before, nil
+	}
+
+	migrated, err := MigrateConfig(old, fromVersion, toVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(migrated); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	after = buf.Bytes()
+	if dryRun {
+		return before, after, nil
+	}
+
+	if err := os.WriteFile(configFile+".bak", before, 0644); err != nil {
+		return nil, nil, fmt.Errorf("failed to back up config: %w", err)
+	}
+	if err := os.WriteFile(configFile, after, 0644); err != nil {
+		return nil, nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	return before, after, nil
+}
+
+// warnUnversioned warns, once per run, that a config has no config_version
+func warnUnversioned(cfg config, configFile string) {
+	if cfg.ConfigVersion != 0 {
+		return
+	}
+	warnUnversionedConfig.Do(func() {
+		fmt.Fprintf(os.Stderr, "Warning: %s has no config_version, which is deprecated; run 'regresql config migrate' to add it\n", configFile)
+	})
+}
@@ -0,0 +1,121 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRegressYAML(t *testing.T, content string) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	regressDir := filepath.Join(tmpDir, "regresql")
+	if err := os.Mkdir(regressDir, 0755); err != nil {
+		t.Fatalf("failed to create regresql dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(regressDir, "regress.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return tmpDir
+}
+
+func TestMigrateConfig(t *testing.T) {
+	old := map[string]any{
+		"pguri":    "postgres://localhost/db",
+		"snapshot": map[string]any{"path": "snapshots/default.dump"},
+	}
+
+	got, err := MigrateConfig(old, 0, CurrentConfigVersion)
+	if err != nil {
+		t.Fatalf("MigrateConfig() error = %v", err)
+	}
+	if got["config_version"] != CurrentConfigVersion {
+		t.Errorf("config_version = %v, want %d", got["config_version"], CurrentConfigVersion)
+	}
+	if got["pguri"] != "postgres://localhost/db" {
+		t.Errorf("pguri = %v, want it kept", got["pguri"])
+	}
+
+	// the input must not change
+	if _, ok := old["config_version"]; ok {
+		t.Error("MigrateConfig() modified its input")
+	}
+	got["snapshot"].(map[string]any)["path"] = "changed"
+	if old["snapshot"].(map[string]any)["path"] != "snapshots/default.dump" {
+		t.Error("MigrateConfig() shares nested maps with its input")
+	}
+}
+
+func TestMigrateConfigInvalidVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+	}{
+		{"negative", -1, 1},
+		{"beyond latest", 0, CurrentConfigVersion + 1},
+		{"downgrade", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MigrateConfig(map[string]any{}, tt.from, tt.to); err == nil {
+				t.Errorf("MigrateConfig(%d, %d) expected error", tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	original := "root: .\npguri: postgres://localhost/db\n"
+	tmpDir := writeRegressYAML(t, original)
+	configFile := filepath.Join(tmpDir, "regresql", "regress.yaml")
+
+	before, after, err := MigrateConfigFile(tmpDir, CurrentConfigVersion, true)
+	if err != nil {
+		t.Fatalf("MigrateConfigFile(dry run) error = %v", err)
+	}
+	if string(before) != original {
+		t.Errorf("before = %q, want %q", before, original)
+	}
+	if !strings.Contains(string(after), "config_version: 1") {
+		t.Errorf("after = %q, want config_version: 1", after)
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != original {
+		t.Error("dry run rewrote regress.yaml")
+	}
+	if fileExists(configFile + ".bak") {
+		t.Error("dry run wrote a backup")
+	}
+
+	if _, _, err := MigrateConfigFile(tmpDir, CurrentConfigVersion, false); err != nil {
+		t.Fatalf("MigrateConfigFile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(configFile + ".bak"); string(data) != original {
+		t.Errorf("backup = %q, want the original file", data)
+	}
+	cfg, err := ReadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	if cfg.ConfigVersion != CurrentConfigVersion || cfg.PgUri != "postgres://localhost/db" {
+		t.Errorf("migrated config = %+v", cfg)
+	}
+
+	// already current: nothing changes
+	before, after, err = MigrateConfigFile(tmpDir, CurrentConfigVersion, false)
+	if err != nil {
+		t.Fatalf("MigrateConfigFile(current) error = %v", err)
+	}
+	if string(before) != string(after) {
+		t.Error("migrating a current config changed it")
+	}
+}
+
+func TestReadConfigRejectsNewerVersion(t *testing.T) {
+	tmpDir := writeRegressYAML(t, "config_version: 99\npguri: postgres://localhost/db\n")
+
+	_, err := ReadConfig(tmpDir)
+	if err == nil || !strings.Contains(err.Error(), "config_version 99") {
+		t.Errorf("ReadConfig() error = %v, want config_version error", err)
+	}
+}
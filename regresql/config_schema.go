@@ -0,0 +1,223 @@
+package regresql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchemaJSON is the JSON Schema (draft 2020-12) of regress.yaml, for
+// editors and `regresql config validate`
+//
+//go:embed config.schema.json
+var configSchemaJSON []byte
+
+type (
+	// ConfigValidationError is a regress.yaml value that does not match the
+	// config schema. Path is the YAML path of the value, e.g. snapshot.format.
+	ConfigValidationError struct {
+		Path    string
+		Line    int
+		Message string
+	}
+
+	// ConfigValidationErrors is returned by ReadValidatedConfig when the
+	// config file does not match the schema
+	ConfigValidationErrors []ConfigValidationError
+
+	// jsonSchema is the subset of JSON Schema used by config.schema.json
+	jsonSchema struct {
+		Type                 string                 `json:"type"`
+		Properties           map[string]*jsonSchema `json:"properties"`
+		AdditionalProperties *schemaOrBool          `json:"additionalProperties"`
+		Items                *jsonSchema            `json:"items"`
+		Required             []string               `json:"required"`
+		Enum                 []string               `json:"enum"`
+		Minimum              *float64               `json:"minimum"`
+	}
+
+	// schemaOrBool holds additionalProperties, which is either false (no
+	// other keys allowed) or a schema for the values of other keys
+	schemaOrBool struct {
+		Allowed bool
+		Schema  *jsonSchema
+	}
+)
+
+func (e ConfigValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d): %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func (errs ConfigValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (s *schemaOrBool) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Allowed); err == nil {
+		return nil
+	}
+	s.Allowed = true
+	return json.Unmarshal(data, &s.Schema)
+}
+
+// ConfigSchema returns the JSON Schema of regress.yaml
+func ConfigSchema() []byte {
+	return configSchemaJSON
+}
+
+// ValidateConfigData checks a regress.yaml document against the config
+// schema. The error is only set when the document cannot be parsed.
+func ValidateConfigData(data []byte) ([]ConfigValidationError, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(configSchemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse config schema: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var errs []ConfigValidationError
+	schema.validate(doc.Content[0], "", &errs)
+	return errs, nil
+}
+
+// ValidateConfigFile checks root's regresql/regress.yaml against the config
+// schema. Packs named in extends are not validated.
+func ValidateConfigFile(root string) ([]ConfigValidationError, error) {
+	configFile := filepath.Join(root, "regresql", "regress.yaml")
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config '%s': %w", configFile, err)
+	}
+	return ValidateConfigData(data)
+}
+
+// ReadValidatedConfig is ReadConfig that also fails with
+// ConfigValidationErrors when the file does not match the config schema
+func ReadValidatedConfig(root string) (config, error) {
+	errs, err := ValidateConfigFile(root)
+	if err != nil {
+		return config{}, err
+	}
+	if len(errs) > 0 {
+		return config{}, ConfigValidationErrors(errs)
+	}
+	return ReadConfig(root)
+}
+
+func (s *jsonSchema) validate(node *yaml.Node, path string, errs *[]ConfigValidationError) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	// an empty value leaves the setting unset
+	if node.Tag == "!!null" {
+		return
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ConfigValidationError{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping")
+			return
+		}
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if key == "<<" {
+				continue
+			}
+			seen[key] = true
+			keyPath := joinConfigPath(path, key)
+			switch {
+			case s.Properties[key] != nil:
+				s.Properties[key].validate(value, keyPath, errs)
+			case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+				s.AdditionalProperties.Schema.validate(value, keyPath, errs)
+			case s.AdditionalProperties != nil && !s.AdditionalProperties.Allowed:
+				*errs = append(*errs, ConfigValidationError{Path: keyPath, Line: node.Content[i].Line, Message: "unknown key"})
+			}
+		}
+		for _, key := range s.Required {
+			if !seen[key] {
+				fail("missing required key %q", key)
+			}
+		}
+		return
+
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			fail("expected a list")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range node.Content {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+		return
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		fail("expected a %s", s.Type)
+		return
+	}
+	switch s.Type {
+	case "string":
+		if node.Tag != "!!str" {
+			fail("expected a string, got %q", node.Value)
+			return
+		}
+	case "boolean":
+		if node.Tag != "!!bool" {
+			fail("expected true or false, got %q", node.Value)
+			return
+		}
+	case "integer":
+		if node.Tag != "!!int" {
+			fail("expected an integer, got %q", node.Value)
+			return
+		}
+	case "number":
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			fail("expected a number, got %q", node.Value)
+			return
+		}
+	}
+	if s.Minimum != nil {
+		if v, err := strconv.ParseFloat(node.Value, 64); err == nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) {
+		fail("must be one of %s, got %q", strings.Join(s.Enum, ", "), node.Value)
+	}
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
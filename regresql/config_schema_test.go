@@ -0,0 +1,106 @@
+package regresql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigData(t *testing.T) {
+	data := []byte(`pguri: postgres://localhost/app
+root: "."
+max_result_rows: many
+tracing: yes please
+ignore:
+  - vendor/
+  - 42
+snapshot:
+  format: tar
+  masks:
+    users.email: "'x'"
+  storage:
+    bucket: snaps
+  fixtures_dir: db/fixtures
+analyze:
+  buffer_threshold: -1
+`)
+	errs, err := ValidateConfigData(data)
+	if err != nil {
+		t.Fatalf("ValidateConfigData: %v", err)
+	}
+
+	want := map[string]int{
+		"max_result_rows":          3,
+		"tracing":                  4,
+		"ignore[1]":                7,
+		"snapshot.format":          9,
+		"snapshot.storage":         13,
+		"snapshot.fixtures_dir":    14,
+		"analyze.buffer_threshold": 16,
+	}
+	got := make(map[string]int)
+	for _, e := range errs {
+		got[e.Path] = e.Line
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v\nwant paths and lines %v", errs, want)
+	}
+}
+
+func TestValidateConfigDataValid(t *testing.T) {
+	data := []byte(`pguri: postgres://localhost/app
+root: .
+timeout: 30s
+auto_baseline: true
+diff_comparison:
+  float_tolerance: 0.01
+snapshot:
+  format: directory
+  validate_settings: strict
+  fixtures: [users.csv, orders.sql]
+  storage:
+    type: s3
+    bucket: snaps
+policies:
+  severity:
+    sequential_scan: critical
+stats:
+`)
+	errs, err := ValidateConfigData(data)
+	if err != nil || len(errs) != 0 {
+		t.Errorf("valid config reported %v, %v", errs, err)
+	}
+}
+
+// TestConfigSchemaCoversConfig keeps config.schema.json in sync with the
+// config structs: every yaml key must be described by the schema
+func TestConfigSchemaCoversConfig(t *testing.T) {
+	var schema jsonSchema
+	if err := json.Unmarshal(ConfigSchema(), &schema); err != nil {
+		t.Fatalf("config schema is not valid JSON: %v", err)
+	}
+
+	var check func(typ reflect.Type, s *jsonSchema, path string)
+	check = func(typ reflect.Type, s *jsonSchema, path string) {
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			key, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+			if key == "" || key == "-" {
+				continue
+			}
+			prop := s.Properties[key]
+			if prop == nil {
+				t.Errorf("config key %s%s is missing from config.schema.json", path, key)
+				continue
+			}
+			check(typ.Field(i).Type, prop, path+key+".")
+		}
+	}
+	check(reflect.TypeOf(config{}), &schema, "")
+}
@@ -13,9 +13,20 @@ import (
 type (
 	CoverageOptions struct {
 		Root         string
-		TaxonomyPath string
+		TaxonomyPath string // planner-feature coverage; without it, test file coverage
 		Format       string // console | json
 		OutputPath   string
+		MinCoverage  float64 // fail when fewer queries (percent) have plans
+	}
+
+	// QueryCoverageReport counts, per SQL file, the queries that have plans,
+	// expected files and cost baselines
+	QueryCoverageReport struct {
+		Files     []DiscoveryResult `json:"files"`
+		Queries   int               `json:"queries"`
+		Plans     int               `json:"plans"`
+		Expected  int               `json:"expected"`
+		Baselines int               `json:"baselines"`
 	}
 
 	// Taxonomy is the planner-feature coverage matrix: each axis lists the cells
@@ -49,6 +60,10 @@ func (t *Taxonomy) targetCells() []string {
 }
 
 func Coverage(opts CoverageOptions) int {
+	if opts.TaxonomyPath == "" {
+		return queryCoverage(opts)
+	}
+
 	tax, err := loadTaxonomy(opts.TaxonomyPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "taxonomy: %s\n", err)
@@ -148,3 +163,59 @@ func parseCell(sql string) string {
 	}
 	return ""
 }
+
+// queryCoverage reports which queries have plans, expected files and
+// baselines. Returns 1 when plan coverage is below opts.MinCoverage.
+func queryCoverage(opts CoverageOptions) int {
+	results, err := Discover(DiscoverOptions{Root: opts.Root})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 2
+	}
+	report := NewQueryCoverageReport(results)
+
+	w, closeFn, err := getWriter(opts.OutputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	defer closeFn()
+
+	if opts.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		PrintQueryCoverage(w, report)
+	}
+
+	if opts.MinCoverage > 0 && report.PlanCoverage() < opts.MinCoverage {
+		fmt.Fprintf(os.Stderr, "Plan coverage %.1f%% is below the minimum of %.1f%%\n", report.PlanCoverage(), opts.MinCoverage)
+		return 1
+	}
+	return 0
+}
+
+func NewQueryCoverageReport(results []DiscoveryResult) *QueryCoverageReport {
+	r := &QueryCoverageReport{Files: results}
+	for _, f := range results {
+		r.Queries += f.TotalQueries
+		r.Plans += f.AddedQueries
+		r.Expected += f.ExpectedQueries
+		r.Baselines += f.BaselineQueries
+	}
+	return r
+}
+
+// PlanCoverage is the percentage of queries with a plan file; a project
+// without queries is fully covered
+func (r *QueryCoverageReport) PlanCoverage() float64 {
+	return percentOf(r.Plans, r.Queries)
+}
+
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
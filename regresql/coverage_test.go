@@ -0,0 +1,109 @@
+package regresql
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQueryCoverageReport(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("sql/orders.sql", "-- name: by_id\nselect 1;\n\n-- name: recent\nselect 2;\n")
+	write("sql/users.sql", "-- name: users\nselect 3;\n")
+	write("regresql/plans/sql/orders_by_id.yaml", "\"1\": {}\n")
+	write("regresql/plans/sql/users.yaml", "\"1\": {}\n")
+	write("regresql/expected/sql/orders_by_id.json", "{}")
+	write("regresql/baselines/sql/users.json", "{}")
+
+	results, err := Discover(DiscoverOptions{Root: root})
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	report := NewQueryCoverageReport(results)
+
+	if report.Queries != 3 || report.Plans != 2 || report.Expected != 1 || report.Baselines != 1 {
+		t.Errorf("report = %d queries, %d plans, %d expected, %d baselines; want 3, 2, 1, 1",
+			report.Queries, report.Plans, report.Expected, report.Baselines)
+	}
+	if got := report.PlanCoverage(); got < 66.6 || got > 66.7 {
+		t.Errorf("PlanCoverage = %.2f, want 66.67", got)
+	}
+
+	var buf bytes.Buffer
+	PrintQueryCoverage(&buf, report)
+	out := buf.String()
+	for _, want := range []string{"sql/orders.sql", "baselines", "plans 2 (66.7%)", "expected 1 (33.3%)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPlanCoverageWithoutQueries(t *testing.T) {
+	if got := NewQueryCoverageReport(nil).PlanCoverage(); got != 100 {
+		t.Errorf("PlanCoverage of empty project = %v, want 100", got)
+	}
+}
+
+func TestDiscoverMultipleQueriesPerFile(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the unnamed query takes the file's name; the expected files of the
+	// named query must not count for it, nor by_id_full's for by_id
+	write("sql/orders.sql", "select 0;\n\n-- name: by_id\nselect 1;\n\n-- name: by_id_full\nselect 2;\n")
+	write("regresql/plans/sql/orders.yaml", "\"1\": {}\n")
+	write("regresql/plans/sql/orders_by_id_full.yaml", "\"1\": {}\n")
+	write("regresql/expected/sql/orders_by_id_full.1.json", "{}")
+	write("regresql/baselines/sql/orders_by_id_full.1.json", "{}")
+
+	results, err := Discover(DiscoverOptions{Root: root})
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Queries) != 3 {
+		t.Fatalf("results = %+v, want one file with 3 queries", results)
+	}
+	got := map[string]QueryStatus{}
+	for _, q := range results[0].Queries {
+		got[q.Name] = q
+	}
+	if q := got["orders"]; !q.HasPlan || q.HasExpected || q.HasBaseline {
+		t.Errorf("orders = %+v, want plan only", q)
+	}
+	if q := got["by_id"]; q.HasPlan || q.HasExpected || q.HasBaseline {
+		t.Errorf("by_id = %+v, want nothing", q)
+	}
+	if q := got["by_id_full"]; !q.HasPlan || !q.HasExpected || !q.HasBaseline {
+		t.Errorf("by_id_full = %+v, want plan, expected and baseline", q)
+	}
+
+	var buf bytes.Buffer
+	PrintQueryDetail(&buf, results)
+	out := buf.String()
+	for _, want := range []string{"sql/orders.sql (2/3 queries added)", "[ ] by_id       no plan", "[+] by_id_full  plan, expected, baseline", "[+] orders      plan"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package regresql
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"math"
 	"reflect"
+	"slices"
+	"sort"
 	"time"
 )
 
@@ -27,22 +30,57 @@ type (
 		ModifiedSamples []RowDiff
 
 		Columns []string
+
+		// TypeMismatches lists columns whose recorded type changed, only
+		// populated when DiffConfig.CheckTypes is set
+		TypeMismatches []string
+
+		// ColumnsReordered is set when the columns differ only in their
+		// order and the rows match once compared by column name, which
+		// DiffConfig.IgnoreColumnOrder would accept
+		ColumnsReordered bool
+
+		// Sampling of the expected and actual results, nil when not sampled
+		ExpectedSampling *SamplingMetadata
+		ActualSampling   *SamplingMetadata
 	}
 
 	RowDiff struct {
 		ExpectedRow []any
 		ActualRow   []any
+
+		// ColumnDiffs lists only the columns whose values differ
+		ColumnDiffs []ColumnDiff
+	}
+
+	// ColumnDiff is one changed value of a modified row, formatted for
+	// display
+	ColumnDiff struct {
+		Column   string
+		Expected string
+		Actual   string
 	}
 
 	DiffConfig struct {
 		FloatTolerance float64
 		MaxSamples     int
 
+		// ColumnTolerances: per-column float tolerance by column name,
+		// overriding FloatTolerance for that column.
+		ColumnTolerances map[string]float64
+
 		// IgnoreColumns: drop these column names from both sides before comparing.
 		IgnoreColumns []string
 
 		// IgnoreOrder: treat ordering-only differences as identical.
 		IgnoreOrder bool
+
+		// CheckTypes: compare column types when both sides recorded them.
+		CheckTypes bool
+
+		// IgnoreColumnOrder: compare columns by name, so a SELECT * picking
+		// up the columns in another order still matches.
+		IgnoreColumnOrder bool
 	}
 )
 
@@ -60,6 +98,19 @@ func DefaultDiffConfig() *DiffConfig {
 
 // CompareResultSets performs semantic comparison of two ResultSet objects
 func CompareResultSets(expected, actual *ResultSet, config *DiffConfig) *StructuredDiff {
+	diff := compareResultSets(expected, actual, config)
+	diff.ExpectedSampling = expected.Sampling
+	diff.ActualSampling = actual.Sampling
+
+	// identical samples can hide rows added or removed outside the sample
+	if diff.Identical && samplingChanged(expected, actual) {
+		diff.Identical = false
+		diff.Type = DiffTypeRowCount
+	}
+	return diff
+}
+
+func compareResultSets(expected, actual *ResultSet, config *DiffConfig) *StructuredDiff {
 	if config == nil {
 		config = DefaultDiffConfig()
 	}
@@ -67,6 +118,9 @@ func CompareResultSets(expected, actual *ResultSet, config *DiffConfig) *Structu
 	if len(config.IgnoreColumns) > 0 {
 		expected, actual = projectColumns(expected, actual, config.IgnoreColumns)
 	}
+	if config.IgnoreColumnOrder {
+		expected, actual = sortColumns(expected), sortColumns(actual)
+	}
 
 	diff := &StructuredDiff{
 		Identical:    true,
@@ -79,9 +133,23 @@ func CompareResultSets(expected, actual *ResultSet, config *DiffConfig) *Structu
 	if !columnsMatch(expected.Cols, actual.Cols) {
 		diff.Identical = false
 		diff.Type = DiffTypeValues
+		if !config.IgnoreColumnOrder && columnsMatch(sortedNames(expected.Cols), sortedNames(actual.Cols)) {
+			byName := *config
+			byName.IgnoreColumnOrder = true
+			diff.ColumnsReordered = compareResultSets(expected, actual, &byName).Identical
+		}
 		return diff
 	}
 
+	if config.CheckTypes {
+		if mismatches := columnTypeMismatches(expected, actual); len(mismatches) > 0 {
+			diff.Identical = false
+			diff.Type = DiffTypeValues
+			diff.TypeMismatches = mismatches
+			return diff
+		}
+	}
+
 	// Quick check: identical content in order
 	if len(expected.Rows) == len(actual.Rows) {
 		identical, modifiedIndices := compareRowsInOrder(expected, actual, config)
@@ -116,7 +184,7 @@ func CompareResultSets(expected, actual *ResultSet, config *DiffConfig) *Structu
 			diff.ModifiedRows = len(unmatchedExpected)
 
 			// Collect samples
-			diff.ModifiedSamples = collectModifiedSamples(expected, actual, unmatchedExpected, unmatchedActual, config.MaxSamples)
+			diff.ModifiedSamples = collectModifiedSamples(expected, actual, unmatchedExpected, unmatchedActual, config)
 
 			return diff
 		}
@@ -161,15 +229,54 @@ func projectColumns(expected, actual *ResultSet, ignore []string) (*ResultSet, *
 func projectByNames(rs *ResultSet, keep []string) *ResultSet {
 	idx := make([]int, 0, len(keep))
 	cols := make([]string, 0, len(keep))
+	var types []string
 	for _, name := range keep {
 		for i, c := range rs.Cols {
 			if c == name {
 				idx = append(idx, i)
 				cols = append(cols, c)
+				if i < len(rs.ColumnTypes) {
+					types = append(types, rs.ColumnTypes[i])
+				}
 				break
 			}
 		}
 	}
+	if len(types) != len(cols) {
+		types = nil
+	}
+	rows := make([][]any, len(rs.Rows))
+	for i, row := range rs.Rows {
+		nr := make([]any, len(idx))
+		for j, k := range idx {
+			if k < len(row) {
+				nr[j] = row[k]
+			}
+		}
+		rows[i] = nr
+	}
+	return &ResultSet{Cols: cols, ColumnTypes: types, Rows: rows, Filename: rs.Filename}
+}
+
+// sortColumns returns the result set with its columns in alphabetical order
+func sortColumns(rs *ResultSet) *ResultSet {
+	idx := make([]int, len(rs.Cols))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return rs.Cols[idx[a]] < rs.Cols[idx[b]] })
+
+	cols := make([]string, len(idx))
+	var types []string
+	if len(rs.ColumnTypes) == len(rs.Cols) {
+		types = make([]string, len(idx))
+	}
+	for j, k := range idx {
+		cols[j] = rs.Cols[k]
+		if types != nil {
+			types[j] = rs.ColumnTypes[k]
+		}
+	}
 	rows := make([][]any, len(rs.Rows))
 	for i, row := range rs.Rows {
 		nr := make([]any, len(idx))
@@ -180,7 +287,13 @@ func projectByNames(rs *ResultSet, keep []string) *ResultSet {
 		}
 		rows[i] = nr
 	}
-	return &ResultSet{Cols: cols, Rows: rows, Filename: rs.Filename}
+	return &ResultSet{Cols: cols, ColumnTypes: types, Rows: rows, Filename: rs.Filename}
+}
+
+func sortedNames(names []string) []string {
+	sorted := slices.Clone(names)
+	sort.Strings(sorted)
+	return sorted
 }
 
 // columnsMatch checks if two column lists are identical
@@ -196,24 +309,65 @@ func columnsMatch(expected, actual []string) bool {
 	return true
 }
 
+// columnTypeMismatches reports columns whose type differs; result sets
+// written without column types (older expected files) are never flagged.
+// Types are compared by their schema names, so expected files that recorded
+// int4 still match integer, and columns recorded only as ARRAY,
+// USER-DEFINED or an OID are skipped.
+func columnTypeMismatches(expected, actual *ResultSet) []string {
+	if len(expected.ColumnTypes) != len(expected.Cols) || len(actual.ColumnTypes) != len(actual.Cols) {
+		return nil
+	}
+	var mismatches []string
+	for i, col := range expected.Cols {
+		want, got := normalizeColumnType(expected.ColumnTypes[i]), normalizeColumnType(actual.ColumnTypes[i])
+		if !comparableColumnType(want) || !comparableColumnType(got) {
+			continue
+		}
+		if want != got {
+			mismatches = append(mismatches, fmt.Sprintf("column %s: expected type %s, got %s", col, want, got))
+		}
+	}
+	return mismatches
+}
+
 func compareRowsInOrder(expected, actual *ResultSet, config *DiffConfig) (bool, []int) {
+	tolerances := config.columnTolerances(expected.Cols)
 	var diffs []int
 	for i := range expected.Rows {
-		if !rowsEqual(expected.Rows[i], actual.Rows[i], config.FloatTolerance) {
+		if !rowsEqual(expected.Rows[i], actual.Rows[i], tolerances) {
 			diffs = append(diffs, i)
 		}
 	}
 	return len(diffs) == 0, diffs
 }
 
-// rowsEqual compares two rows for equality
-func rowsEqual(expectedRow, actualRow []any, floatTolerance float64) bool {
+// columnTolerances returns the float tolerance for each column position:
+// the column's entry in ColumnTolerances, or FloatTolerance
+func (c *DiffConfig) columnTolerances(cols []string) []float64 {
+	tolerances := make([]float64, len(cols))
+	for i, col := range cols {
+		tolerances[i] = c.FloatTolerance
+		if t, ok := c.ColumnTolerances[col]; ok {
+			tolerances[i] = t
+		}
+	}
+	return tolerances
+}
+
+// rowsEqual compares two rows for equality, using the tolerance of each
+// column position for numeric values
+func rowsEqual(expectedRow, actualRow []any, tolerances []float64) bool {
 	if len(expectedRow) != len(actualRow) {
 		return false
 	}
 
 	for i := range expectedRow {
-		if !valuesEqual(expectedRow[i], actualRow[i], floatTolerance) {
+		var tolerance float64
+		if i < len(tolerances) {
+			tolerance = tolerances[i]
+		}
+		if !valuesEqual(expectedRow[i], actualRow[i], tolerance) {
 			return false
 		}
 	}
@@ -326,6 +480,7 @@ func matchRowsUnordered(expected, actual *ResultSet, config *DiffConfig) (
 	matchedExpected, matchedActual, unmatchedExpected, unmatchedActual []int) {
 
 	used := make(map[int]bool, len(actual.Rows))
+	tolerances := config.columnTolerances(expected.Cols)
 
 	for ei, expRow := range expected.Rows {
 		found := false
@@ -333,7 +488,7 @@ func matchRowsUnordered(expected, actual *ResultSet, config *DiffConfig) (
 			if used[ai] {
 				continue
 			}
-			if rowsEqual(expRow, actRow, config.FloatTolerance) {
+			if rowsEqual(expRow, actRow, tolerances) {
 				matchedExpected = append(matchedExpected, ei)
 				matchedActual = append(matchedActual, ai)
 				used[ai] = true
@@ -368,21 +523,48 @@ func collectSamples(rs *ResultSet, indices []int, maxSamples int) [][]any {
 	return samples
 }
 
-func collectModifiedSamples(expected, actual *ResultSet, unmatchedExpected, unmatchedActual []int, maxSamples int) []RowDiff {
+func collectModifiedSamples(expected, actual *ResultSet, unmatchedExpected, unmatchedActual []int, config *DiffConfig) []RowDiff {
 	var samples []RowDiff
 	n := len(unmatchedExpected)
 	if len(unmatchedActual) < n {
 		n = len(unmatchedActual)
 	}
-	if n > maxSamples {
-		n = maxSamples
+	if n > config.MaxSamples {
+		n = config.MaxSamples
 	}
 
+	tolerances := config.columnTolerances(expected.Cols)
 	for i := 0; i < n; i++ {
+		expectedRow := expected.Rows[unmatchedExpected[i]]
+		actualRow := actual.Rows[unmatchedActual[i]]
 		samples = append(samples, RowDiff{
-			ExpectedRow: expected.Rows[unmatchedExpected[i]],
-			ActualRow:   actual.Rows[unmatchedActual[i]],
+			ExpectedRow: expectedRow,
+			ActualRow:   actualRow,
+			ColumnDiffs: columnDiffs(expected.Cols, expectedRow, actualRow, tolerances),
 		})
 	}
 	return samples
 }
+
+// columnDiffs compares two rows column by column and returns the columns
+// whose values differ
+func columnDiffs(cols []string, expectedRow, actualRow []any, tolerances []float64) []ColumnDiff {
+	var diffs []ColumnDiff
+	for i, col := range cols {
+		if i >= len(expectedRow) || i >= len(actualRow) {
+			break
+		}
+		var tolerance float64
+		if i < len(tolerances) {
+			tolerance = tolerances[i]
+		}
+		if !valuesEqual(expectedRow[i], actualRow[i], tolerance) {
+			diffs = append(diffs, ColumnDiff{
+				Column:   col,
+				Expected: formatValue(expectedRow[i]),
+				Actual:   formatValue(actualRow[i]),
+			})
+		}
+	}
+	return diffs
+}
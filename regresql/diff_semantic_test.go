@@ -1,6 +1,8 @@
 package regresql
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -134,6 +136,60 @@ func TestCompareResultSets_IgnoreOrder(t *testing.T) {
 	})
 }
 
+// TestCompareResultSets_IgnoreColumnOrder covers a SELECT * whose columns
+// come back in another order:
+//   - default fails as DiffTypeValues and flags that a reorder would match
+//   - IgnoreColumnOrder compares the columns by name
+func TestCompareResultSets_IgnoreColumnOrder(t *testing.T) {
+	expected := rs(
+		[]string{"id", "name"},
+		[][]any{{1, "a"}, {2, "b"}},
+	)
+	actual := rs(
+		[]string{"name", "id"},
+		[][]any{{"a", 1}, {"b", 2}},
+	)
+
+	t.Run("default flags the reorder", func(t *testing.T) {
+		got := CompareResultSets(expected, actual, nil)
+		if got.Identical || got.Type != DiffTypeValues {
+			t.Errorf("Identical = %v, Type = %q, want values diff", got.Identical, got.Type)
+		}
+		if !got.ColumnsReordered {
+			t.Error("ColumnsReordered = false, want true")
+		}
+	})
+
+	t.Run("IgnoreColumnOrder=true matches by name", func(t *testing.T) {
+		got := CompareResultSets(expected, actual, &DiffConfig{MaxSamples: 5, IgnoreColumnOrder: true})
+		if !got.Identical || got.Type != DiffTypeIdentical {
+			t.Errorf("Identical = %v, Type = %q, want identical", got.Identical, got.Type)
+		}
+	})
+
+	t.Run("reordered columns with different values", func(t *testing.T) {
+		changed := rs(
+			[]string{"name", "id"},
+			[][]any{{"a", 1}, {"c", 2}},
+		)
+		got := CompareResultSets(expected, changed, nil)
+		if got.ColumnsReordered {
+			t.Error("ColumnsReordered = true, want false when values differ too")
+		}
+		got = CompareResultSets(expected, changed, &DiffConfig{MaxSamples: 5, IgnoreColumnOrder: true})
+		if got.Type != DiffTypeValues || got.ModifiedRows != 1 {
+			t.Errorf("Type = %q, ModifiedRows = %d, want 1 modified row", got.Type, got.ModifiedRows)
+		}
+	})
+
+	t.Run("different columns are not a reorder", func(t *testing.T) {
+		other := rs([]string{"name", "email"}, [][]any{{"a", 1}, {"b", 2}})
+		if got := CompareResultSets(expected, other, nil); got.ColumnsReordered {
+			t.Error("ColumnsReordered = true, want false")
+		}
+	})
+}
+
 // TestCompareResultSets_IgnoreColumnsAndOrder combines both flags: rows are
 // permuted AND carry a non-deterministic column. With both flags set the
 // comparison should report identical.
@@ -193,6 +249,161 @@ func TestCompareResultSets_DefaultsUnchanged(t *testing.T) {
 	})
 }
 
+// TestCompareResultSets_ColumnTolerances covers per-column float tolerance:
+// a column listed in ColumnTolerances uses its own tolerance, every other
+// column falls back to FloatTolerance.
+func TestCompareResultSets_ColumnTolerances(t *testing.T) {
+	expected := rs([]string{"id", "amount", "score"}, [][]any{{1, 10.00, 0.123456}})
+	actual := rs([]string{"id", "amount", "score"}, [][]any{{1, 10.004, 0.12346}})
+
+	tests := []struct {
+		name   string
+		config *DiffConfig
+		wantOK bool
+	}{
+		{"exact by default", &DiffConfig{MaxSamples: 5}, false},
+		{"global tolerance too tight for amount", &DiffConfig{FloatTolerance: 0.0001, MaxSamples: 5}, false},
+		{
+			name: "per-column tolerances",
+			config: &DiffConfig{
+				FloatTolerance:   0.0001,
+				MaxSamples:       5,
+				ColumnTolerances: map[string]float64{"amount": 0.01},
+			},
+			wantOK: true,
+		},
+		{
+			name: "column tolerance tighter than global",
+			config: &DiffConfig{
+				FloatTolerance:   0.01,
+				MaxSamples:       5,
+				ColumnTolerances: map[string]float64{"score": 0.000001},
+			},
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CompareResultSets(expected, actual, tc.config)
+			if got.Identical != tc.wantOK {
+				t.Errorf("Identical = %v, want %v (Type=%q)", got.Identical, tc.wantOK, got.Type)
+			}
+		})
+	}
+
+	t.Run("unordered matching", func(t *testing.T) {
+		config := &DiffConfig{
+			MaxSamples:       5,
+			IgnoreOrder:      true,
+			ColumnTolerances: map[string]float64{"amount": 0.01},
+		}
+		a := rs([]string{"id", "amount"}, [][]any{{1, 1.00}, {2, 2.00}})
+		b := rs([]string{"id", "amount"}, [][]any{{2, 2.005}, {1, 0.999}})
+		if got := CompareResultSets(a, b, config); !got.Identical {
+			t.Errorf("Identical = false, want true (Type=%q)", got.Type)
+		}
+	})
+}
+
+// TestCompareResultSets_CheckTypes covers column type assertions: a type
+// change only fails when CheckTypes is set, and expected files written
+// without column types never trip it.
+func TestCompareResultSets_CheckTypes(t *testing.T) {
+	typed := func(types ...string) *ResultSet {
+		r := rs([]string{"id", "created"}, [][]any{{1, "2026-01-01"}})
+		r.ColumnTypes = types
+		return r
+	}
+
+	t.Run("type change is ignored by default", func(t *testing.T) {
+		got := CompareResultSets(typed("integer", "date"), typed("integer", "timestamp without time zone"), nil)
+		if !got.Identical {
+			t.Errorf("Identical = false, want true")
+		}
+	})
+
+	t.Run("type change fails with CheckTypes", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true}
+		got := CompareResultSets(typed("integer", "date"), typed("integer", "timestamp without time zone"), cfg)
+		if got.Identical || got.Type != DiffTypeValues {
+			t.Fatalf("got Type=%q Identical=%v, want values mismatch", got.Type, got.Identical)
+		}
+		want := []string{"column created: expected type date, got timestamp without time zone"}
+		if !equalStrings(got.TypeMismatches, want) {
+			t.Errorf("TypeMismatches = %v, want %v", got.TypeMismatches, want)
+		}
+	})
+
+	t.Run("expected file without types is not flagged", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true}
+		got := CompareResultSets(typed(), typed("integer", "timestamp without time zone"), cfg)
+		if !got.Identical {
+			t.Errorf("Identical = false, want true")
+		}
+	})
+
+	t.Run("driver type names match schema type names", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true}
+		got := CompareResultSets(typed("int4", "timestamptz"), typed("integer", "timestamp with time zone"), cfg)
+		if !got.Identical {
+			t.Errorf("TypeMismatches = %v, want none", got.TypeMismatches)
+		}
+	})
+
+	t.Run("array and enum element types are compared", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true}
+		got := CompareResultSets(typed("_int4", "mood"), typed("text[]", "color"), cfg)
+		want := []string{
+			"column id: expected type integer[], got text[]",
+			"column created: expected type mood, got color",
+		}
+		if !equalStrings(got.TypeMismatches, want) {
+			t.Errorf("TypeMismatches = %v, want %v", got.TypeMismatches, want)
+		}
+	})
+
+	t.Run("types recorded without element or name are not flagged", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true}
+		got := CompareResultSets(typed("ARRAY", "16394"), typed("text[]", "mood"), cfg)
+		if !got.Identical {
+			t.Errorf("TypeMismatches = %v, want none", got.TypeMismatches)
+		}
+	})
+
+	t.Run("ignored columns drop their types too", func(t *testing.T) {
+		cfg := &DiffConfig{MaxSamples: 5, CheckTypes: true, IgnoreColumns: []string{"created"}}
+		got := CompareResultSets(typed("integer", "date"), typed("integer", "timestamp without time zone"), cfg)
+		if !got.Identical {
+			t.Errorf("Identical = false, want true")
+		}
+	})
+}
+
+func TestResultSetProject(t *testing.T) {
+	rs := &ResultSet{
+		Cols:        []string{"id", "name", "created"},
+		ColumnTypes: []string{"int4", "text", "timestamp"},
+		Rows:        [][]any{{1, "a", "2026-01-01"}, {2, "b", "2026-01-02"}},
+		Sampling:    &SamplingMetadata{},
+	}
+
+	got, err := rs.Project([]string{"name", "id"})
+	if err != nil {
+		t.Fatalf("Project: %v", err)
+	}
+	if !equalStrings(got.Cols, []string{"name", "id"}) || !equalStrings(got.ColumnTypes, []string{"text", "int4"}) {
+		t.Errorf("Cols = %v, ColumnTypes = %v", got.Cols, got.ColumnTypes)
+	}
+	if got.Rows[1][0] != "b" || got.Rows[1][1] != 2 || got.Sampling != rs.Sampling {
+		t.Errorf("Rows = %v, Sampling = %v", got.Rows, got.Sampling)
+	}
+
+	if _, err := rs.Project([]string{"id", "missing"}); err == nil {
+		t.Error("Project accepted a missing column")
+	}
+}
+
 func equalStrings(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -204,3 +415,41 @@ func equalStrings(a, b []string) bool {
 	}
 	return true
 }
+
+// TestCompareResultSets_ColumnDiffs covers the per-column changes of modified
+// rows: only differing columns are listed, within their float tolerance, and
+// the console names each changed column.
+func TestCompareResultSets_ColumnDiffs(t *testing.T) {
+	expected := rs([]string{"id", "status", "amount", "note"}, [][]any{{1, "active", 10.0, nil}, {2, "active", 5.0, "x"}})
+	actual := rs([]string{"id", "status", "amount", "note"}, [][]any{{1, "inactive", 10.001, "late"}, {2, "active", 5.0, "x"}})
+
+	got := CompareResultSets(expected, actual, &DiffConfig{MaxSamples: 5, FloatTolerance: 0.01})
+	if got.Type != DiffTypeValues || len(got.ModifiedSamples) != 1 {
+		t.Fatalf("Type = %q with %d modified samples, want values with 1", got.Type, len(got.ModifiedSamples))
+	}
+	want := []ColumnDiff{
+		{Column: "status", Expected: `"active"`, Actual: `"inactive"`},
+		{Column: "note", Expected: "null", Actual: `"late"`},
+	}
+	diffs := got.ModifiedSamples[0].ColumnDiffs
+	if len(diffs) != len(want) {
+		t.Fatalf("ColumnDiffs = %+v, want %+v", diffs, want)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Errorf("ColumnDiffs[%d] = %+v, want %+v", i, diffs[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	(&ConsoleFormatter{}).printStructuredDiff(got, &buf)
+	out := buf.String()
+	for _, line := range []string{
+		`  Row #1: column "status" changed: "active" → "inactive"` + "\n",
+		`          column "note" changed: null → "late"` + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("console output missing %q:\n%s", line, out)
+		}
+	}
+}
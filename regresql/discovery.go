@@ -2,6 +2,7 @@ package regresql
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -10,16 +11,20 @@ import (
 
 type (
 	DiscoveryResult struct {
-		RelPath      string
-		Queries      []QueryStatus
-		TotalQueries int
-		AddedQueries int
+		RelPath         string        `json:"path"`
+		Queries         []QueryStatus `json:"queries"`
+		TotalQueries    int           `json:"total_queries"`
+		AddedQueries    int           `json:"plans"`
+		ExpectedQueries int           `json:"expected"`  // queries with expected result files
+		BaselineQueries int           `json:"baselines"` // queries with cost baselines
 	}
 
 	QueryStatus struct {
-		Name     string
-		HasPlan  bool
-		PlanPath string
+		Name        string `json:"name"`
+		HasPlan     bool   `json:"has_plan"`
+		PlanPath    string `json:"plan_path,omitempty"`
+		HasExpected bool   `json:"has_expected"`
+		HasBaseline bool   `json:"has_baseline"`
 	}
 
 	DiscoverOptions struct {
@@ -81,6 +86,8 @@ func Discover(opts DiscoverOptions) ([]DiscoveryResult, error) {
 
 	for _, folder := range suite.Dirs {
 		planDir := filepath.Join(suite.PlanDir, folder.Dir)
+		expectedDir := filepath.Join(suite.ExpectedDir, folder.Dir)
+		baselineDir := filepath.Join(suite.BaselineDir, folder.Dir)
 
 		for _, name := range folder.Files {
 			qfile := filepath.Join(suite.Root, folder.Dir, name)
@@ -107,15 +114,23 @@ func Discover(opts DiscoverOptions) ([]DiscoveryResult, error) {
 				planExists := hasPlan(planPath)
 
 				qs := QueryStatus{
-					Name:     qname,
-					HasPlan:  planExists,
-					PlanPath: planPath,
+					Name:        qname,
+					HasPlan:     planExists,
+					PlanPath:    planPath,
+					HasExpected: len(getResultSetFiles(q, expectedDir)) > 0,
+					HasBaseline: len(getBaselineFiles(q, baselineDir)) > 0,
 				}
 				result.Queries = append(result.Queries, qs)
 
 				if planExists {
 					result.AddedQueries++
 				}
+				if qs.HasExpected {
+					result.ExpectedQueries++
+				}
+				if qs.HasBaseline {
+					result.BaselineQueries++
+				}
 			}
 
 			// Sort queries by name for consistent output
@@ -178,6 +193,56 @@ func PrintDiscoveryResults(results []DiscoveryResult, showDetail bool, newOnly .
 	fmt.Printf("Summary: %d added, %d not added, %d partial\n", added, notAdded, partial)
 }
 
+// PrintQueryDetail prints every query of each SQL file: [+] when it has a
+// plan, followed by what else it has
+func PrintQueryDetail(w io.Writer, results []DiscoveryResult) {
+	width := 0
+	for _, r := range results {
+		for _, q := range r.Queries {
+			width = max(width, len(q.Name))
+		}
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%s %s\n", r.RelPath, r.StatusDetail())
+		for _, q := range r.Queries {
+			status, detail := " ", "no plan"
+			if q.HasPlan {
+				status, detail = "+", "plan"
+				if q.HasExpected {
+					detail += ", expected"
+				}
+				if q.HasBaseline {
+					detail += ", baseline"
+				}
+			}
+			fmt.Fprintf(w, "  [%s] %-*s  %s\n", status, width, q.Name, detail)
+		}
+	}
+}
+
+// PrintQueryCoverage prints the discovery results with a column for each
+// coverage dimension: plans, expected files and baselines
+func PrintQueryCoverage(w io.Writer, r *QueryCoverageReport) {
+	width := len("SQL files in project:")
+	for _, f := range r.Files {
+		width = max(width, len(f.RelPath)+6)
+	}
+
+	fmt.Fprintf(w, "%-*s %8s %6s %9s %10s\n", width, "SQL files in project:", "queries", "plans", "expected", "baselines")
+	for _, f := range r.Files {
+		fmt.Fprintf(w, "  %s %-*s %8d %6d %9d %10d\n", f.Status(), width-6, f.RelPath,
+			f.TotalQueries, f.AddedQueries, f.ExpectedQueries, f.BaselineQueries)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Summary: %d queries, plans %d (%.1f%%), expected %d (%.1f%%), baselines %d (%.1f%%)\n",
+		r.Queries,
+		r.Plans, r.PlanCoverage(),
+		r.Expected, percentOf(r.Expected, r.Queries),
+		r.Baselines, percentOf(r.Baselines, r.Queries))
+}
+
 // AddQueries adds SQL files to the test suite by creating plan files
 func AddQueries(opts AddOptions) error {
 	config, err := ReadConfig(opts.Root)
@@ -313,14 +378,10 @@ func RemoveQueries(opts RemoveOptions) error {
 
 			if opts.Clean {
 				// Expected result files
-				expectedPattern := getResultSetPathPattern(q, expectedDir)
-				matches, _ := filepath.Glob(expectedPattern)
-				filesToDelete = append(filesToDelete, matches...)
+				filesToDelete = append(filesToDelete, getResultSetFiles(q, expectedDir)...)
 
 				// Baseline files
-				baselinePattern := getBaselinePathPattern(q, baselineDir)
-				matches, _ = filepath.Glob(baselinePattern)
-				filesToDelete = append(filesToDelete, matches...)
+				filesToDelete = append(filesToDelete, getBaselineFiles(q, baselineDir)...)
 			}
 		}
 	}
@@ -447,22 +508,34 @@ func expandPaths(root string, paths []string, suite *Suite) ([]string, error) {
 	return result, nil
 }
 
-// getResultSetPathPattern returns a glob pattern for expected result files
-func getResultSetPathPattern(q *Query, expectedDir string) string {
-	basename := strings.TrimSuffix(filepath.Base(q.Path), filepath.Ext(q.Path))
-	// If query name matches file basename, don't duplicate it
-	if q.Name == basename {
-		return filepath.Join(expectedDir, basename+"*.json")
-	}
-	return filepath.Join(expectedDir, basename+"_"+q.Name+"*.json")
+// getResultSetFiles returns the expected result files of a query
+func getResultSetFiles(q *Query, expectedDir string) []string {
+	return queryFiles(q, expectedDir, resultFileExt(q))
+}
+
+// getBaselineFiles returns the baseline files of a query
+func getBaselineFiles(q *Query, baselineDir string) []string {
+	return queryFiles(q, baselineDir, ".json")
 }
 
-// getBaselinePathPattern returns a glob pattern for baseline files
-func getBaselinePathPattern(q *Query, baselineDir string) string {
+// queryFiles returns the files of a query in dir: <prefix><ext> and the
+// per-binding <prefix>.<binding><ext>, where the prefix is the file's base
+// name, followed by _<query name> unless the query is named after the file.
+// A plain prefix glob would also match the files of the other queries of
+// the file, orders*.json matching orders_by_id.1.json.
+func queryFiles(q *Query, dir, ext string) []string {
 	basename := strings.TrimSuffix(filepath.Base(q.Path), filepath.Ext(q.Path))
+	prefix := basename
 	// If query name matches file basename, don't duplicate it
-	if q.Name == basename {
-		return filepath.Join(baselineDir, basename+"*.json")
+	if q.Name != basename {
+		prefix += "_" + q.Name
+	}
+	prefix = filepath.Join(dir, prefix)
+
+	var files []string
+	if fileExists(prefix + ext) {
+		files = append(files, prefix+ext)
 	}
-	return filepath.Join(baselineDir, basename+"_"+q.Name+"*.json")
+	matches, _ := filepath.Glob(prefix + ".*" + ext)
+	return append(files, matches...)
 }
@@ -0,0 +1,197 @@
+package regresql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type (
+	// Fixture is test data built in Go with FixtureBuilder. Tables are
+	// inserted in the order they were first added to the builder.
+	Fixture struct {
+		Tables []*FixtureTable
+	}
+
+	// FixtureTable holds the rows inserted into one table. Columns missing
+	// from a row get their column default.
+	FixtureTable struct {
+		Name string // table name, optionally schema qualified
+		Rows []map[string]any
+
+		// OnConflict is what happens to a row whose key already exists:
+		// OnConflictError (default), OnConflictIgnore or OnConflictUpdate
+		OnConflict string
+	}
+
+	// FixtureBuilder builds a Fixture with a fluent API:
+	//
+	//	fb := regresql.NewFixtureBuilder()
+	//	fb.Table("users").Row(map[string]any{"id": 1, "role": "admin"}).Row(map[string]any{"id": 2, "role": "viewer"})
+	//	fb.Table("orders").Row(map[string]any{"user_id": 1, "total": 9.5})
+	//	err := regresql.ApplyFixtureData(ctx, tx, fb.Build())
+	FixtureBuilder struct {
+		tables []*FixtureTable
+	}
+
+	// FixtureTableBuilder adds rows to one table of a FixtureBuilder
+	FixtureTableBuilder struct {
+		table *FixtureTable
+	}
+)
+
+// OnConflict modes of a FixtureTable, so a fixture can be applied again to
+// a database that already holds its rows
+const (
+	OnConflictError  = "error"  // fail with a duplicate key error
+	OnConflictIgnore = "ignore" // keep the existing row
+	OnConflictUpdate = "update" // overwrite the existing row's non key columns
+)
+
+func NewFixtureBuilder() *FixtureBuilder {
+	return &FixtureBuilder{}
+}
+
+// Table returns the builder for the named table; calling it again with the
+// same name appends to the same table
+func (fb *FixtureBuilder) Table(name string) *FixtureTableBuilder {
+	for _, t := range fb.tables {
+		if t.Name == name {
+			return &FixtureTableBuilder{table: t}
+		}
+	}
+	t := &FixtureTable{Name: name}
+	fb.tables = append(fb.tables, t)
+	return &FixtureTableBuilder{table: t}
+}
+
+// Build returns the fixture. The rows are copied, so the builder can keep
+// being used.
+func (fb *FixtureBuilder) Build() *Fixture {
+	f := &Fixture{Tables: make([]*FixtureTable, len(fb.tables))}
+	for i, t := range fb.tables {
+		rows := make([]map[string]any, len(t.Rows))
+		for j, row := range t.Rows {
+			rows[j] = make(map[string]any, len(row))
+			for k, v := range row {
+				rows[j][k] = v
+			}
+		}
+		f.Tables[i] = &FixtureTable{Name: t.Name, Rows: rows, OnConflict: t.OnConflict}
+	}
+	return f
+}
+
+// Row adds a row of column values to the table
+func (tb *FixtureTableBuilder) Row(values map[string]any) *FixtureTableBuilder {
+	tb.table.Rows = append(tb.table.Rows, values)
+	return tb
+}
+
+// OnConflict sets what happens to rows whose key already exists, one of
+// OnConflictError, OnConflictIgnore or OnConflictUpdate
+func (tb *FixtureTableBuilder) OnConflict(mode string) *FixtureTableBuilder {
+	tb.table.OnConflict = mode
+	return tb
+}
+
+// Rows adds several rows to the table
+func (tb *FixtureTableBuilder) Rows(rows ...map[string]any) *FixtureTableBuilder {
+	for _, row := range rows {
+		tb.Row(row)
+	}
+	return tb
+}
+
+// ApplyFixtureData inserts a programmatically built fixture, table by table
+// in the order they were added. Pass a transaction to roll the data back
+// after the test.
+func ApplyFixtureData(ctx context.Context, q Querier, f *Fixture) error {
+	if f == nil {
+		return nil
+	}
+	for _, t := range f.Tables {
+		var primaryKey []string
+		switch t.OnConflict {
+		case "", OnConflictError, OnConflictIgnore:
+		case OnConflictUpdate:
+			schema, name := parseTableName(t.Name)
+			pk, err := getPrimaryKeys(q, schema, name)
+			if err != nil {
+				return fmt.Errorf("fixture table %s: %w", t.Name, err)
+			}
+			if len(pk) == 0 {
+				return fmt.Errorf("fixture table %s: on_conflict update needs a primary key", t.Name)
+			}
+			primaryKey = pk
+		default:
+			return fmt.Errorf("fixture table %s: unknown on_conflict %q (want error, ignore or update)", t.Name, t.OnConflict)
+		}
+
+		for i, row := range t.Rows {
+			query, args := fixtureInsert(t.Name, row)
+			query += onConflictClause(t.OnConflict, primaryKey, row)
+			if _, err := q.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("fixture table %s row %d: %w", t.Name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fixtureInsert builds a parameterized INSERT for one row, with columns in
+// name order. An empty row inserts all column defaults.
+func fixtureInsert(table string, row map[string]any) (string, []any) {
+	schema, name := parseTableName(table)
+	target := QuoteIdentifier(schema) + "." + QuoteIdentifier(name)
+	if len(row) == 0 {
+		return "INSERT INTO " + target + " DEFAULT VALUES", nil
+	}
+
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		columns = append(columns, col)
+	}
+	slices.Sort(columns)
+
+	quoted := make([]string, len(columns))
+	params := make([]string, len(columns))
+	args := make([]any, len(columns))
+	for i, col := range columns {
+		quoted[i] = QuoteIdentifier(col)
+		params[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = row[col]
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		target, strings.Join(quoted, ", "), strings.Join(params, ", "))
+	return query, args
+}
+
+// onConflictClause returns the ON CONFLICT clause of a row insert. Updates
+// set the row's columns outside the primary key; a row with key columns
+// only has nothing to update and is kept as is.
+func onConflictClause(mode string, primaryKey []string, row map[string]any) string {
+	if mode == OnConflictIgnore {
+		return " ON CONFLICT DO NOTHING"
+	}
+	if mode != OnConflictUpdate {
+		return ""
+	}
+
+	target := make([]string, len(primaryKey))
+	for i, col := range primaryKey {
+		target[i] = QuoteIdentifier(col)
+	}
+	var set []string
+	for col := range row {
+		if !slices.Contains(primaryKey, col) {
+			set = append(set, QuoteIdentifier(col)+" = EXCLUDED."+QuoteIdentifier(col))
+		}
+	}
+	if len(set) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(target, ", "))
+	}
+	slices.Sort(set)
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(target, ", "), strings.Join(set, ", "))
+}
@@ -0,0 +1,170 @@
+package regresql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFixtureBuilder(t *testing.T) {
+	fb := NewFixtureBuilder()
+	fb.Table("users").
+		Row(map[string]any{"id": 1, "role": "admin"}).
+		Row(map[string]any{"id": 2, "role": "viewer"})
+	fb.Table("billing.orders").Row(map[string]any{"user_id": 1, "total": 9.5})
+	fb.Table("users").Row(map[string]any{"id": 3, "role": "editor"})
+
+	f := fb.Build()
+	if len(f.Tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(f.Tables))
+	}
+	if f.Tables[0].Name != "users" || len(f.Tables[0].Rows) != 3 {
+		t.Errorf("users table = %+v, want 3 rows", f.Tables[0])
+	}
+	if f.Tables[1].Name != "billing.orders" || len(f.Tables[1].Rows) != 1 {
+		t.Errorf("orders table = %+v, want 1 row", f.Tables[1])
+	}
+
+	// later builder changes do not leak into a built fixture
+	fb.Table("users").Row(map[string]any{"id": 4})
+	if len(f.Tables[0].Rows) != 3 {
+		t.Errorf("built fixture changed after Build: %d rows", len(f.Tables[0].Rows))
+	}
+}
+
+func TestFixtureInsert(t *testing.T) {
+	query, args := fixtureInsert("billing.orders", map[string]any{"user_id": 1, "total": 9.5, "note": nil})
+	wantQuery := `INSERT INTO "billing"."orders" ("note", "total", "user_id") VALUES ($1, $2, $3)`
+	if query != wantQuery {
+		t.Errorf("query = %s, want %s", query, wantQuery)
+	}
+	if !reflect.DeepEqual(args, []any{nil, 9.5, 1}) {
+		t.Errorf("args = %v", args)
+	}
+
+	query, args = fixtureInsert("users", nil)
+	if query != `INSERT INTO "public"."users" DEFAULT VALUES` || args != nil {
+		t.Errorf("empty row = %s %v", query, args)
+	}
+}
+
+func TestOnConflictClause(t *testing.T) {
+	row := map[string]any{"id": 1, "tenant": "acme", "role": "admin", "name": "Ann"}
+	pk := []string{"tenant", "id"}
+
+	if got := onConflictClause("", pk, row); got != "" {
+		t.Errorf("default = %q, want no clause", got)
+	}
+	if got := onConflictClause(OnConflictError, pk, row); got != "" {
+		t.Errorf("error = %q, want no clause", got)
+	}
+	if got := onConflictClause(OnConflictIgnore, nil, row); got != " ON CONFLICT DO NOTHING" {
+		t.Errorf("ignore = %q", got)
+	}
+	want := ` ON CONFLICT ("tenant", "id") DO UPDATE SET "name" = EXCLUDED."name", "role" = EXCLUDED."role"`
+	if got := onConflictClause(OnConflictUpdate, pk, row); got != want {
+		t.Errorf("update = %s, want %s", got, want)
+	}
+	if got := onConflictClause(OnConflictUpdate, pk, map[string]any{"id": 1, "tenant": "acme"}); got != ` ON CONFLICT ("tenant", "id") DO NOTHING` {
+		t.Errorf("update of key columns only = %q", got)
+	}
+}
+
+func TestApplyFixtureDataOnConflict(t *testing.T) {
+	fb := NewFixtureBuilder()
+	fb.Table("users").OnConflict(OnConflictIgnore).Row(map[string]any{"id": 1})
+	f := fb.Build()
+	if f.Tables[0].OnConflict != OnConflictIgnore {
+		t.Fatalf("OnConflict = %q, want kept by Build", f.Tables[0].OnConflict)
+	}
+
+	rec := &execRecorder{}
+	if err := ApplyFixtureData(context.Background(), rec, f); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`INSERT INTO "public"."users" ("id") VALUES ($1) ON CONFLICT DO NOTHING`}
+	if !equalStrings(rec.statements, want) {
+		t.Errorf("executed %q, want %q", rec.statements, want)
+	}
+
+	f.Tables[0].OnConflict = "replace"
+	if err := ApplyFixtureData(context.Background(), &execRecorder{}, f); err == nil || !strings.Contains(err.Error(), "unknown on_conflict") {
+		t.Errorf("err = %v, want unknown mode rejected", err)
+	}
+}
+
+func TestFixtureExport(t *testing.T) {
+	fb := NewFixtureBuilder()
+	fb.Table("users").Row(map[string]any{"id": 1, "name": "O'Brien, Pat"}).Row(map[string]any{"id": 2})
+	fb.Table("billing.invoices").Row(map[string]any{"user_id": 1, "meta": map[string]any{"paid": true}, "due": nil})
+	f := fb.Build()
+
+	files, err := f.Export(ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(files["users.csv"]), "id,name\n1,\"O'Brien, Pat\"\n2,\\N\n"; got != want {
+		t.Errorf("users.csv = %q, want %q", got, want)
+	}
+	if got, want := string(files["billing.invoices.csv"]), "due,meta,user_id\n\\N,\"{\"\"paid\"\":true}\",1\n"; got != want {
+		t.Errorf("billing.invoices.csv = %q, want %q", got, want)
+	}
+
+	files, err = f.Export(ExportSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "public"."users" ("id", "name") VALUES ($q0$1$q0$, $q0$O'Brien, Pat$q0$);
+INSERT INTO "public"."users" ("id") VALUES ($q0$2$q0$);
+INSERT INTO "billing"."invoices" ("due", "meta", "user_id") VALUES (NULL, $q0${"paid":true}$q0$, $q0$1$q0$);
+`
+	if got := string(files["fixture.sql"]); got != want {
+		t.Errorf("fixture.sql =\n%s\nwant\n%s", got, want)
+	}
+
+	files, err = f.Export(ExportJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(files["fixture.json"]), `"table": "billing.invoices"`) {
+		t.Errorf("fixture.json = %s", files["fixture.json"])
+	}
+
+	if _, err := f.Export("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestFixtureExportCSVRoundTrip(t *testing.T) {
+	fb := NewFixtureBuilder()
+	fb.Table("files").
+		Row(map[string]any{"id": 1, "name": "", "data": []byte{0x00, 0xff}}).
+		Row(map[string]any{"id": 2, "name": nil, "data": nil})
+	files, err := fb.Build().Export(ExportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "files.csv")
+	if err := os.WriteFile(path, files["files.csv"], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, log := openRecordingDB(t)
+	if _, err := applyCSVFixture(db, path, "files", ExportCSVNull); err != nil {
+		t.Fatalf("applyCSVFixture() error = %v", err)
+	}
+	want := `INSERT INTO "public"."files" ("data", "id", "name") VALUES ` +
+		`($q0$\x00ff$q0$, $q0$1$q0$, $q0$$q0$), (NULL, $q0$2$q0$, NULL)`
+	if stmts := log.Statements(); len(stmts) != 1 || stmts[0] != want {
+		t.Errorf("statements = %q, want %q", stmts, want)
+	}
+
+	fb = NewFixtureBuilder()
+	fb.Table("files").Row(map[string]any{"name": ExportCSVNull})
+	if _, err := fb.Build().Export(ExportCSV); err == nil {
+		t.Error("expected error for a value equal to the NULL marker")
+	}
+}
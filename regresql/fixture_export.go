@@ -0,0 +1,176 @@
+package regresql
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ExportFormat is a file format Fixture.Export writes
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportJSON ExportFormat = "json"
+	ExportSQL  ExportFormat = "sql"
+)
+
+// ExportCSVNull marks NULL in exported CSV files, so it reads apart from
+// the empty string; load them with snapshot.csv_null_value set to it
+const ExportCSVNull = `\N`
+
+// exportedTable is the JSON form of a fixture table
+type exportedTable struct {
+	Table string           `json:"table"`
+	Rows  []map[string]any `json:"rows"`
+}
+
+// Export serializes the fixture without a database, returning file contents
+// by file name:
+//
+//   - csv: <table>.csv per table, loadable as a snapshot.fixtures CSV
+//     fixture with csv_null_value: '\N'. The header lists every column used
+//     by a row; missing and nil values are written as ExportCSVNull.
+//   - json: fixture.json, the tables in insert order with their rows.
+//   - sql: fixture.sql, one INSERT per row in insert order, loadable as a
+//     snapshot.fixtures SQL fixture. Columns missing from a row keep their
+//     default, as with ApplyFixtureData.
+func (f *Fixture) Export(format ExportFormat) (map[string][]byte, error) {
+	switch format {
+	case ExportCSV:
+		files := make(map[string][]byte, len(f.Tables))
+		for _, t := range f.Tables {
+			data, err := exportCSVTable(t)
+			if err != nil {
+				return nil, fmt.Errorf("fixture table %s: %w", t.Name, err)
+			}
+			files[t.Name+".csv"] = data
+		}
+		return files, nil
+
+	case ExportJSON:
+		tables := make([]exportedTable, len(f.Tables))
+		for i, t := range f.Tables {
+			tables[i] = exportedTable{Table: t.Name, Rows: t.Rows}
+		}
+		data, err := json.MarshalIndent(tables, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fixture: %w", err)
+		}
+		return map[string][]byte{"fixture.json": append(data, '\n')}, nil
+
+	case ExportSQL:
+		var b strings.Builder
+		for _, t := range f.Tables {
+			for i, row := range t.Rows {
+				stmt, err := exportInsert(t.Name, row)
+				if err != nil {
+					return nil, fmt.Errorf("fixture table %s row %d: %w", t.Name, i+1, err)
+				}
+				b.WriteString(stmt + ";\n")
+			}
+		}
+		return map[string][]byte{"fixture.sql": []byte(b.String())}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (expected csv, json or sql)", format)
+}
+
+func exportCSVTable(t *FixtureTable) ([]byte, error) {
+	var columns []string
+	for _, row := range t.Rows {
+		for col := range row {
+			if !slices.Contains(columns, col) {
+				columns = append(columns, col)
+			}
+		}
+	}
+	slices.Sort(columns)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for i, row := range t.Rows {
+		record := make([]string, len(columns))
+		for j, col := range columns {
+			if row[col] == nil {
+				record[j] = ExportCSVNull
+				continue
+			}
+			text, err := exportText(row[col])
+			if err != nil {
+				return nil, fmt.Errorf("row %d column %s: %w", i+1, col, err)
+			}
+			if text == ExportCSVNull {
+				return nil, fmt.Errorf("row %d column %s: value %s would load as NULL", i+1, col, ExportCSVNull)
+			}
+			record[j] = text
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportInsert is fixtureInsert with the values inlined as literals
+func exportInsert(table string, row map[string]any) (string, error) {
+	stmt, args := fixtureInsert(table, row)
+	if len(args) == 0 {
+		return stmt, nil
+	}
+	prefix := stmt[:strings.LastIndex(stmt, " VALUES (")]
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		literal, err := exportSQLLiteral(arg)
+		if err != nil {
+			return "", err
+		}
+		literals[i] = literal
+	}
+	return prefix + " VALUES (" + strings.Join(literals, ", ") + ")", nil
+}
+
+// exportText renders a value as PostgreSQL input text, bytea in hex format;
+// nil renders empty
+func exportText(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return `\x` + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	default:
+		// maps, slices and structs as json/jsonb values
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// exportSQLLiteral renders a value as an untyped SQL literal, so PostgreSQL
+// casts it to the column type like the CSV fixture loader does
+func exportSQLLiteral(v any) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	text, err := exportText(v)
+	if err != nil {
+		return "", err
+	}
+	return QuoteLiteral(text), nil
+}
@@ -0,0 +1,158 @@
+package regresql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureCacheFile records the inputs of the last cached snapshot build,
+// next to the snapshot metadata
+const FixtureCacheFile = ".regresql-fixture-cache.yaml"
+
+// fixtureCache holds content hashes of everything that goes into a snapshot
+// build. When none of them changed, snapshot build --cache reuses the
+// existing snapshot instead of rebuilding it.
+type fixtureCache struct {
+	SchemaHash     string            `yaml:"schema_hash,omitempty"`
+	MigrationsHash string            `yaml:"migrations_hash,omitempty"`
+	OptionsHash    string            `yaml:"options_hash"`
+	Fixtures       map[string]string `yaml:"fixtures,omitempty"` // fixture path -> content hash
+	SnapshotHash   string            `yaml:"snapshot_hash,omitempty"`
+}
+
+// buildInputs hashes the schema, migrations, fixture files and the options
+// that change the built snapshot
+func buildInputs(root string, opts SnapshotBuildOptions) (*fixtureCache, error) {
+	c := &fixtureCache{Fixtures: make(map[string]string)}
+
+	if opts.SchemaPath != "" {
+		hash, err := computeSchemaHash(opts.SchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute schema hash: %w", err)
+		}
+		c.SchemaHash = hash
+	}
+
+	if opts.MigrationsDir != "" {
+		files, err := discoverMigrations(opts.MigrationsDir, opts.MigrationsNaming, opts.IncludeUndo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover migrations: %w", err)
+		}
+		hash, err := computeMigrationsHash(files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute migrations hash: %w", err)
+		}
+		c.MigrationsHash = hash
+	}
+
+	for _, f := range append(append([]string{}, opts.Fixtures...), opts.Fixturize...) {
+		path := f
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		hash, err := computeSingleFileHash(path)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %q: %w", f, err)
+		}
+		c.Fixtures[f] = hash
+	}
+
+	// fixture order, masks and load settings change the result as well
+	h := sha256.New()
+	fmt.Fprintf(h, "format=%s\ncsv_null=%q\nreset_sequences=%t\ndisable_triggers=%t\n",
+		opts.Format, opts.CSVNullValue, opts.ResetSequences, opts.DisableTriggers)
+	fmt.Fprintf(h, "fixtures=%q\nfixturize=%q\ntenant_schema=%q\n", opts.Fixtures, opts.Fixturize, opts.TenantSchema)
+	cols := make([]string, 0, len(opts.Masks))
+	for col := range opts.Masks {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		fmt.Fprintf(h, "mask %s=%s\n", col, opts.Masks[col])
+	}
+	c.OptionsHash = "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	return c, nil
+}
+
+// changes lists what differs between a cached build and the current inputs;
+// an empty result means the cached snapshot is still valid
+func (c *fixtureCache) changes(current *fixtureCache) []string {
+	var changed []string
+	if c.SchemaHash != current.SchemaHash {
+		changed = append(changed, "schema")
+	}
+	if c.MigrationsHash != current.MigrationsHash {
+		changed = append(changed, "migrations")
+	}
+	if c.OptionsHash != current.OptionsHash {
+		changed = append(changed, "build options")
+	}
+
+	names := make([]string, 0, len(current.Fixtures))
+	for name := range current.Fixtures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.Fixtures[name] != current.Fixtures[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+func readFixtureCache(snapshotsDir string) (*fixtureCache, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotsDir, FixtureCacheFile))
+	if err != nil {
+		return nil, err
+	}
+	var c fixtureCache
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FixtureCacheFile, err)
+	}
+	return &c, nil
+}
+
+func writeFixtureCache(snapshotsDir string, c *fixtureCache) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(snapshotsDir, FixtureCacheFile), data, 0o644)
+}
+
+// cachedSnapshot returns the current snapshot when it was built from exactly
+// these inputs and the file on disk is unchanged. Otherwise it returns nil
+// and the reasons the snapshot needs a rebuild.
+func cachedSnapshot(outputPath string, inputs *fixtureCache) (*SnapshotInfo, []string) {
+	snapshotsDir := filepath.Dir(outputPath)
+
+	cache, err := readFixtureCache(snapshotsDir)
+	if err != nil {
+		return nil, []string{"no build cache"}
+	}
+	if changed := cache.changes(inputs); len(changed) > 0 {
+		return nil, changed
+	}
+
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil || metadata.Current == nil || metadata.Current.Hash != cache.SnapshotHash {
+		return nil, []string{"snapshot metadata"}
+	}
+	if filepath.Base(metadata.Current.Path) != filepath.Base(outputPath) {
+		return nil, []string{"snapshot path"}
+	}
+
+	hash, err := computeFileHash(outputPath, DetectSnapshotFormat(outputPath))
+	if err != nil || hash != cache.SnapshotHash {
+		return nil, []string{"snapshot file"}
+	}
+	return metadata.Current, nil
+}
@@ -0,0 +1,107 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildInputsChanges(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("schema.sql", "create table users (id int);")
+	write("users.csv", "id\n1\n")
+	write("orders.sql", "insert into orders values (1);")
+
+	opts := SnapshotBuildOptions{
+		SchemaPath: filepath.Join(root, "schema.sql"),
+		Fixtures:   []string{"users.csv", "orders.sql"},
+		Masks:      map[string]string{"users.email": "'x'"},
+	}
+
+	before, err := buildInputs(root, opts)
+	if err != nil {
+		t.Fatalf("buildInputs: %v", err)
+	}
+	same, err := buildInputs(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := before.changes(same); len(changed) != 0 {
+		t.Errorf("unchanged inputs reported %v", changed)
+	}
+
+	write("users.csv", "id\n1\n2\n")
+	write("schema.sql", "create table users (id bigint);")
+	after, err := buildInputs(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := before.changes(after); !equalStrings(got, []string{"schema", "users.csv"}) {
+		t.Errorf("changes = %v, want [schema users.csv]", got)
+	}
+
+	opts.Masks = nil
+	noMasks, err := buildInputs(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := after.changes(noMasks); !equalStrings(got, []string{"build options"}) {
+		t.Errorf("changes after dropping masks = %v, want [build options]", got)
+	}
+}
+
+func TestCachedSnapshot(t *testing.T) {
+	snapshotsDir := t.TempDir()
+	outputPath := filepath.Join(snapshotsDir, "default.dump")
+	if err := os.WriteFile(outputPath, []byte("dump"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := computeSingleFileHash(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := &fixtureCache{
+		OptionsHash: "sha256:opts",
+		Fixtures:    map[string]string{"users.csv": "sha256:users"},
+	}
+
+	if info, changed := cachedSnapshot(outputPath, inputs); info != nil || len(changed) == 0 {
+		t.Errorf("without cache file: info = %v, changed = %v", info, changed)
+	}
+
+	if err := WriteSnapshotMetadata(snapshotsDir, &SnapshotInfo{Path: "snapshots/default.dump", Hash: hash}); err != nil {
+		t.Fatal(err)
+	}
+	cached := *inputs
+	cached.SnapshotHash = hash
+	if err := writeFixtureCache(snapshotsDir, &cached); err != nil {
+		t.Fatal(err)
+	}
+
+	info, changed := cachedSnapshot(outputPath, inputs)
+	if info == nil || info.Hash != hash {
+		t.Fatalf("expected cache hit, changed = %v", changed)
+	}
+
+	modified := &fixtureCache{
+		OptionsHash: "sha256:opts",
+		Fixtures:    map[string]string{"users.csv": "sha256:users2"},
+	}
+	if info, changed := cachedSnapshot(outputPath, modified); info != nil || !equalStrings(changed, []string{"users.csv"}) {
+		t.Errorf("changed fixture: info = %v, changed = %v", info, changed)
+	}
+
+	// a snapshot file replaced behind the cache's back is rebuilt
+	if err := os.WriteFile(outputPath, []byte("other dump"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if info, changed := cachedSnapshot(outputPath, inputs); info != nil || !equalStrings(changed, []string{"snapshot file"}) {
+		t.Errorf("modified snapshot: info = %v, changed = %v", info, changed)
+	}
+}
@@ -0,0 +1,188 @@
+package regresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// csvInsertBatch is the number of CSV rows sent per INSERT statement when
+// the driver can't COPY
+const csvInsertBatch = 500
+
+func isCSVFixture(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".csv")
+}
+
+// csvFixtureTable derives the target table from the file name:
+// seeds/users.csv loads into users, seeds/billing.invoices.csv into
+// billing.invoices
+func csvFixtureTable(path string) string {
+	base := filepath.Base(path)
+	return base[:len(base)-len(filepath.Ext(base))]
+}
+
+// readCSVHeader returns the column names from the first line of a CSV file
+func readCSVHeader(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := csv.NewReader(f).Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range header {
+		if strings.TrimSpace(col) == "" {
+			return nil, fmt.Errorf("header column %d is empty", i+1)
+		}
+	}
+	return header, nil
+}
+
+// applyCSVFixture loads a CSV file into table (usually the one named after
+// the file, see csvFixtureTable). The header row maps to column names; fields
+// equal to nullValue are loaded as NULL. PostgreSQL casts the text values to
+// the column types, which also reports header/schema mismatches.
+//
+// On pgx connections the file is streamed with COPY FROM STDIN, many times
+// faster than INSERT for large tables; other drivers get batched INSERTs.
+func applyCSVFixture(db *sql.DB, path, table, nullValue string) (int, error) {
+	header, err := readCSVHeader(path)
+	if err != nil {
+		return 0, err
+	}
+
+	schema, name := parseTableName(table)
+	target := QuoteIdentifier(schema) + "." + QuoteIdentifier(name)
+	cols := make([]string, len(header))
+	for i, c := range header {
+		cols[i] = QuoteIdentifier(strings.TrimSpace(c))
+	}
+
+	n, err := copyCSVFixture(db, path, target, cols, nullValue)
+	if !errors.Is(err, errNoCopy) {
+		if err != nil {
+			return 0, fmt.Errorf("copy into %s: %w", table, err)
+		}
+		return n, nil
+	}
+	return insertCSVFixture(db, path, table, target, cols, nullValue)
+}
+
+// errNoCopy reports a database/sql driver other than pgx, which can't COPY
+var errNoCopy = errors.New("driver does not support COPY")
+
+// copyCSVFixture streams a CSV file into target with COPY. FORCE_NULL makes
+// quoted fields equal to nullValue NULL too, as the INSERT path does.
+func copyCSVFixture(db *sql.DB, path, target string, cols []string, nullValue string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	copySQL := csvCopySQL(target, cols, nullValue)
+
+	var rows int64
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errNoCopy
+		}
+		tag, err := c.Conn().PgConn().CopyFrom(ctx, f, copySQL)
+		rows = tag.RowsAffected()
+		return err
+	})
+	return int(rows), err
+}
+
+// csvCopySQL is the COPY statement loading a CSV file with a header row into
+// the quoted target and columns
+func csvCopySQL(target string, cols []string, nullValue string) string {
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN WITH (FORMAT csv, HEADER true, NULL %s, FORCE_NULL (%s))",
+		target, strings.Join(cols, ", "), QuoteLiteral(nullValue), strings.Join(cols, ", "))
+}
+
+// insertCSVFixture loads a CSV file with multi-row INSERTs of
+// csvInsertBatch rows, sending the values as untyped literals
+func insertCSVFixture(db *sql.DB, path, table, target string, cols []string, nullValue string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if _, err := r.Read(); err != nil {
+		return 0, err
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", target, strings.Join(cols, ", "))
+
+	var (
+		total int
+		batch []string
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := db.Exec(prefix + strings.Join(batch, ", ")); err != nil {
+			return fmt.Errorf("insert into %s: %w", table, err)
+		}
+		total += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+
+		values := make([]string, len(record))
+		for i, field := range record {
+			if field == nullValue {
+				values[i] = "NULL"
+			} else {
+				values[i] = QuoteLiteral(field)
+			}
+		}
+		batch = append(batch, "("+strings.Join(values, ", ")+")")
+
+		if len(batch) >= csvInsertBatch {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return total, err
+	}
+	return total, nil
+}
@@ -0,0 +1,152 @@
+package regresql
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+func TestCSVFixtureTable(t *testing.T) {
+	cases := map[string]string{
+		"seeds/users.csv":            "users",
+		"seeds/billing.invoices.csv": "billing.invoices",
+		"Users.CSV":                  "Users",
+	}
+	for path, want := range cases {
+		if got := csvFixtureTable(path); got != want {
+			t.Errorf("csvFixtureTable(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFixturesExistCSV(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("users.csv", "id,email\n1,a@example.com\n")
+	write("empty.csv", "")
+	write("blank_header.csv", "id,,email\n")
+	write("seed.sql", "select 1;\n")
+
+	if err := FixturesExist(root, []string{"seed.sql", "users.csv"}); err != nil {
+		t.Errorf("valid fixtures: unexpected error %v", err)
+	}
+
+	cases := map[string]string{
+		"missing.csv":      "CSV fixture",
+		"empty.csv":        "missing header row",
+		"blank_header.csv": "header column 2 is empty",
+		"data.json":        "only SQL (.sql) and CSV (.csv)",
+	}
+	for name, want := range cases {
+		err := FixturesExist(root, []string{name})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("FixturesExist(%s) = %v, want error containing %q", name, err, want)
+		}
+	}
+}
+
+func TestCSVCopySQL(t *testing.T) {
+	got := csvCopySQL(`"public"."Users"`, []string{`"id"`, `"Full Name"`}, `\N`)
+	want := `COPY "public"."Users" ("id", "Full Name") FROM STDIN WITH (FORMAT csv, HEADER true, NULL $q0$\N$q0$, FORCE_NULL ("id", "Full Name"))`
+	if got != want {
+		t.Errorf("csvCopySQL() =\n%s\nwant\n%s", got, want)
+	}
+
+	// a null marker holding the quote tag still yields a valid literal
+	if got := csvCopySQL("t", []string{"c"}, "$q0$"); !strings.Contains(got, "NULL $q1$$q0$$q1$") {
+		t.Errorf("csvCopySQL() = %s, want the null marker quoted with $q1$", got)
+	}
+}
+
+// copyTestDB starts a postgres container for the COPY path, which needs a
+// pgx connection to a real server
+func copyTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts a postgres container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctr, err := postgres.Run(t.Context(), "postgres:16",
+		postgres.WithDatabase("regresql"),
+		postgres.WithUsername("regresql"),
+		postgres.WithPassword("regresql"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %s", err)
+	}
+	pguri, err := ctr.ConnectionString(t.Context(), "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenDB(pguri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCopyCSVFixture(t *testing.T) {
+	db := copyTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE "Users" (id int PRIMARY KEY, "Full Name" text, note text)`); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("Users.csv", "id,Full Name,note\n"+
+		"1,\"Smith, Jane\",\"said \"\"hi\"\"\nthen left\"\n"+
+		"2,\\N,plain\n"+
+		"3,\"\\N\",\"\"\n")
+	n, err := applyCSVFixture(db, path, "Users", `\N`)
+	if err != nil {
+		t.Fatalf("applyCSVFixture() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("applyCSVFixture() = %d rows, want 3", n)
+	}
+
+	want := map[int][2]sql.NullString{
+		1: {{String: "Smith, Jane", Valid: true}, {String: "said \"hi\"\nthen left", Valid: true}},
+		2: {{}, {String: "plain", Valid: true}},
+		3: {{}, {String: "", Valid: true}},
+	}
+	rows, err := db.Query(`SELECT id, "Full Name", note FROM "Users" ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var name, note sql.NullString
+		if err := rows.Scan(&id, &name, &note); err != nil {
+			t.Fatal(err)
+		}
+		if got := [2]sql.NullString{name, note}; got != want[id] {
+			t.Errorf("row %d = %+v, want %+v", id, got, want[id])
+		}
+	}
+
+	mismatch := write("mismatch.csv", "id,nickname\n4,bob\n")
+	if _, err := applyCSVFixture(db, mismatch, "Users", `\N`); err == nil || !strings.Contains(err.Error(), `"nickname"`) {
+		t.Errorf("applyCSVFixture() with an unknown header column = %v, want an error naming nickname", err)
+	}
+}
@@ -0,0 +1,196 @@
+package regresql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maskStatements turns snapshot.masks entries (table.column or
+// schema.table.column -> SQL expression) into one UPDATE per table. The
+// expression is evaluated against the loaded row, so it can reference the
+// column itself, e.g. md5(email) or 'REDACTED'.
+func maskStatements(masks map[string]string) ([]string, error) {
+	type assignment struct{ column, expr string }
+	byTable := make(map[string][]assignment)
+
+	for key, expr := range masks {
+		table, column, err := splitMask(key, expr)
+		if err != nil {
+			return nil, err
+		}
+		byTable[table] = append(byTable[table], assignment{column, expr})
+	}
+
+	tables := make([]string, 0, len(byTable))
+	for t := range byTable {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	stmts := make([]string, 0, len(tables))
+	for _, table := range tables {
+		cols := byTable[table]
+		sort.Slice(cols, func(i, j int) bool { return cols[i].column < cols[j].column })
+
+		sets := make([]string, len(cols))
+		for i, c := range cols {
+			sets[i] = fmt.Sprintf("%s = %s", QuoteIdentifier(c.column), c.expr)
+		}
+		schema, name := parseTableName(table)
+		stmts = append(stmts, fmt.Sprintf("UPDATE %s.%s SET %s",
+			QuoteIdentifier(schema), QuoteIdentifier(name), strings.Join(sets, ", ")))
+	}
+	return stmts, nil
+}
+
+// splitMask splits a mask key into its table and column
+func splitMask(key, expr string) (table, column string, err error) {
+	dot := strings.LastIndex(key, ".")
+	if dot <= 0 || dot == len(key)-1 {
+		return "", "", fmt.Errorf("invalid mask %q: expected table.column", key)
+	}
+	if strings.TrimSpace(expr) == "" {
+		return "", "", fmt.Errorf("invalid mask %q: empty expression", key)
+	}
+	return key[:dot], key[dot+1:], nil
+}
+
+// resolveMasks qualifies the table of every mask the way PostgreSQL resolves
+// a name: an unqualified table belongs to the first schema of the
+// search_path that has it, with the tenant schema in front. Masks whose
+// table does not exist are dropped with a warning, so a mistyped table or
+// one outside the search_path is not silently left unmasked.
+func resolveMasks(db *sql.DB, masks map[string]string, tenantSchema string) (map[string]string, error) {
+	searchPath, err := maskSearchPath(db, tenantSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(masks))
+	for key := range masks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	schemasOf := make(map[string][]string)
+	resolved := make(map[string]string, len(masks))
+	for _, key := range keys {
+		table, column, err := splitMask(key, masks[key])
+		if err != nil {
+			return nil, err
+		}
+		_, name := parseTableName(table)
+		schemas, ok := schemasOf[name]
+		if !ok {
+			if schemas, err = tableSchemas(db, name); err != nil {
+				return nil, err
+			}
+			schemasOf[name] = schemas
+		}
+
+		qualified := resolveMaskTable(table, searchPath, schemas)
+		if qualified == "" {
+			fmt.Fprintf(os.Stderr, "Warning: mask %s matches no table (search_path: %s), skipped\n",
+				key, strings.Join(searchPath, ", "))
+			continue
+		}
+		resolved[qualified+"."+column] = masks[key]
+	}
+	return resolved, nil
+}
+
+// resolveMaskTable returns schema.table for a mask table, given the schemas
+// that have a table of that name, or "" when none matches
+func resolveMaskTable(table string, searchPath, schemas []string) string {
+	if strings.Contains(table, ".") {
+		schema, name := parseTableName(table)
+		if slices.Contains(schemas, schema) {
+			return schema + "." + name
+		}
+		return ""
+	}
+	for _, schema := range searchPath {
+		if slices.Contains(schemas, schema) {
+			return schema + "." + table
+		}
+	}
+	return ""
+}
+
+// maskSearchPath returns the schemas of the connection's search_path,
+// preceded by the tenant schema when there is one
+func maskSearchPath(db *sql.DB, tenantSchema string) ([]string, error) {
+	rows, err := db.Query(`SELECT s FROM unnest(current_schemas(false)) WITH ORDINALITY AS t(s, i) ORDER BY i`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search_path: %w", err)
+	}
+	defer rows.Close()
+
+	var path []string
+	if tenantSchema != "" {
+		path = append(path, tenantSchema)
+	}
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(path, schema) {
+			path = append(path, schema)
+		}
+	}
+	return path, rows.Err()
+}
+
+// tableSchemas lists the schemas that have a table named name
+func tableSchemas(db *sql.DB, name string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT n.nspname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1 AND c.relkind IN ('r', 'p')`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// applyMasks rewrites masked columns after fixtures are loaded, so the
+// snapshot never contains the original values. The masks must have been
+// qualified by resolveMasks.
+func applyMasks(db *sql.DB, masks map[string]string, verbose bool) ([]string, error) {
+	stmts, err := maskStatements(masks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range stmts {
+		if verbose {
+			fmt.Printf("  %s\n", stmt)
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to apply mask: %w\n  %s", err, stmt)
+		}
+	}
+
+	applied := make([]string, 0, len(masks))
+	for key := range masks {
+		applied = append(applied, key)
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
@@ -0,0 +1,79 @@
+package regresql
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestMaskStatements(t *testing.T) {
+	stmts, err := maskStatements(map[string]string{
+		"users.phone":          "'REDACTED'",
+		"users.email":          "md5(email)",
+		"billing.cards.number": "md5(number)",
+	})
+	if err != nil {
+		t.Fatalf("maskStatements() error = %v", err)
+	}
+
+	want := []string{
+		`UPDATE "billing"."cards" SET "number" = md5(number)`,
+		`UPDATE "public"."users" SET "email" = md5(email), "phone" = 'REDACTED'`,
+	}
+	if !equalStrings(stmts, want) {
+		t.Errorf("maskStatements() =\n%v\nwant\n%v", stmts, want)
+	}
+
+	for _, bad := range []map[string]string{
+		{"email": "md5(email)"},
+		{"users.": "md5(email)"},
+		{"users.email": " "},
+	} {
+		if _, err := maskStatements(bad); err == nil {
+			t.Errorf("maskStatements(%v) expected error", bad)
+		}
+	}
+}
+
+func TestResolveMaskTable(t *testing.T) {
+	searchPath := []string{"acme", "public"}
+	tests := []struct {
+		table   string
+		schemas []string
+		want    string
+	}{
+		{"users", []string{"public"}, "public.users"},
+		{"users", []string{"public", "acme"}, "acme.users"},
+		{"users", []string{"archive"}, ""},
+		{"users", nil, ""},
+		{"archive.users", []string{"archive", "public"}, "archive.users"},
+		{"billing.cards", []string{"public"}, ""},
+	}
+	for _, tt := range tests {
+		if got := resolveMaskTable(tt.table, searchPath, tt.schemas); got != tt.want {
+			t.Errorf("resolveMaskTable(%q, %v) = %q, want %q", tt.table, tt.schemas, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMasks(t *testing.T) {
+	db, log := openRecordingDB(t)
+	// every lookup sees a single "public" row: the search_path is the tenant
+	// schema then public, and each table exists only in public
+	log.Columns = []string{"s"}
+	log.Rows = [][]driver.Value{{"public"}}
+
+	got, err := resolveMasks(db, map[string]string{
+		"users.email":          "md5(email)",
+		"billing.cards.number": "md5(number)",
+	}, "acme")
+	if err != nil {
+		t.Fatalf("resolveMasks() error = %v", err)
+	}
+	if len(got) != 1 || got["public.users.email"] != "md5(email)" {
+		t.Errorf("resolveMasks() = %v, want only public.users.email", got)
+	}
+
+	if _, err := resolveMasks(db, map[string]string{"email": "md5(email)"}, ""); err == nil {
+		t.Error("resolveMasks() of an invalid mask expected error")
+	}
+}
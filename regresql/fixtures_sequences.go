@@ -0,0 +1,102 @@
+package regresql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ownedSequence is a sequence owned by a table column: serial, bigserial
+// (OWNED BY) or identity columns
+type ownedSequence struct {
+	Schema, Name       string
+	TableSchema, Table string
+	Column             string
+	StartValue         int64
+}
+
+const ownedSequencesQuery = `
+SELECT sn.nspname, sc.relname, tn.nspname, tc.relname, a.attname, s.start_value
+FROM pg_sequences s
+JOIN pg_namespace sn ON sn.nspname = s.schemaname
+JOIN pg_class sc ON sc.relnamespace = sn.oid AND sc.relname = s.sequencename
+JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = sc.oid
+	AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+JOIN pg_class tc ON tc.oid = d.refobjid
+JOIN pg_namespace tn ON tn.oid = tc.relnamespace
+JOIN pg_attribute a ON a.attrelid = tc.oid AND a.attnum = d.refobjsubid
+WHERE sn.nspname NOT IN ('pg_catalog', 'information_schema')
+ORDER BY 1, 2`
+
+func (s ownedSequence) qualifiedName() string {
+	return s.Schema + "." + s.Name
+}
+
+// statement moves the sequence past the largest value in its column, or back
+// to its start when the table is empty, so the next nextval() does not clash
+// with loaded rows
+func (s ownedSequence) statement() string {
+	col := QuoteIdentifier(s.Column)
+	return fmt.Sprintf("SELECT setval(%s, GREATEST(MAX(%s), %d), COALESCE(MAX(%s) >= %d, false)) FROM %s.%s",
+		QuoteLiteral(QuoteIdentifier(s.Schema)+"."+QuoteIdentifier(s.Name)),
+		col, s.StartValue, col, s.StartValue,
+		QuoteIdentifier(s.TableSchema), QuoteIdentifier(s.Table))
+}
+
+// filterSequencesByTable keeps sequences owned by one of tables (table or
+// schema.table, defaulting to public); no tables keeps all of them
+func filterSequencesByTable(seqs []ownedSequence, tables []string) []ownedSequence {
+	if len(tables) == 0 {
+		return seqs
+	}
+	wanted := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		schema, name := parseTableName(strings.TrimSpace(t))
+		wanted[schema+"."+name] = true
+	}
+
+	var out []ownedSequence
+	for _, s := range seqs {
+		if wanted[s.TableSchema+"."+s.Table] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ResetSequences sets every sequence owned by a column of tables to the
+// column's current maximum. With no tables, all owned sequences in the
+// database are reset. Returns the reset sequences as schema.name.
+func ResetSequences(db *sql.DB, tables []string) ([]string, error) {
+	rows, err := db.Query(ownedSequencesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sequences: %w", err)
+	}
+	defer rows.Close()
+
+	var seqs []ownedSequence
+	for rows.Next() {
+		var s ownedSequence
+		var start sql.NullInt64
+		if err := rows.Scan(&s.Schema, &s.Name, &s.TableSchema, &s.Table, &s.Column, &start); err != nil {
+			return nil, err
+		}
+		s.StartValue = 1
+		if start.Valid {
+			s.StartValue = start.Int64
+		}
+		seqs = append(seqs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var reset []string
+	for _, s := range filterSequencesByTable(seqs, tables) {
+		if _, err := db.Exec(s.statement()); err != nil {
+			return reset, fmt.Errorf("failed to reset sequence %s: %w", s.qualifiedName(), err)
+		}
+		reset = append(reset, s.qualifiedName())
+	}
+	return reset, nil
+}
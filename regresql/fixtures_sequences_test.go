@@ -0,0 +1,33 @@
+package regresql
+
+import "testing"
+
+func TestOwnedSequenceStatement(t *testing.T) {
+	s := ownedSequence{Schema: "public", Name: "users_id_seq", TableSchema: "public", Table: "users", Column: "id", StartValue: 1}
+	want := `SELECT setval($q0$"public"."users_id_seq"$q0$, GREATEST(MAX("id"), 1), COALESCE(MAX("id") >= 1, false)) FROM "public"."users"`
+	if got := s.statement(); got != want {
+		t.Errorf("statement =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFilterSequencesByTable(t *testing.T) {
+	seqs := []ownedSequence{
+		{Schema: "public", Name: "users_id_seq", TableSchema: "public", Table: "users"},
+		{Schema: "billing", Name: "invoices_id_seq", TableSchema: "billing", Table: "invoices"},
+		{Schema: "public", Name: "orders_id_seq", TableSchema: "public", Table: "orders"},
+	}
+
+	if got := filterSequencesByTable(seqs, nil); len(got) != 3 {
+		t.Errorf("no filter kept %d sequences, want 3", len(got))
+	}
+
+	got := filterSequencesByTable(seqs, []string{"users", "billing.invoices"})
+	var names []string
+	for _, s := range got {
+		names = append(names, s.qualifiedName())
+	}
+	want := []string{"public.users_id_seq", "billing.invoices_id_seq"}
+	if !equalStrings(names, want) {
+		t.Errorf("filtered = %v, want %v", names, want)
+	}
+}
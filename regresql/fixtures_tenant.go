@@ -0,0 +1,45 @@
+package regresql
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// checkSchemaExists fails when the tenant schema has not been created by
+// the schema file or migrations
+func checkSchemaExists(db *sql.DB, schema string) error {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)`, schema).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check tenant schema %q: %w", schema, err)
+	}
+	if !exists {
+		return fmt.Errorf("tenant schema %q does not exist; create it in the schema file or migrations", schema)
+	}
+	return nil
+}
+
+// withSearchPath returns pguri with search_path set to the tenant schema
+// followed by public, passed as a startup option so every pooled connection
+// (and external tools such as fixturize) resolve unqualified names in the
+// tenant schema
+func withSearchPath(pguri, schema string) (string, error) {
+	u, err := url.Parse(pguri)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("options", "-c search_path="+QuoteIdentifier(schema)+",public")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// tenantTable qualifies an unqualified table name with the tenant schema
+func tenantTable(table, schema string) string {
+	if schema == "" || strings.Contains(table, ".") {
+		return table
+	}
+	return schema + "." + table
+}
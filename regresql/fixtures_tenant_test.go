@@ -0,0 +1,36 @@
+package regresql
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTenantTable(t *testing.T) {
+	tests := []struct{ table, schema, want string }{
+		{"users", "", "users"},
+		{"users", "acme", "acme.users"},
+		{"billing.invoices", "acme", "billing.invoices"},
+	}
+	for _, tc := range tests {
+		if got := tenantTable(tc.table, tc.schema); got != tc.want {
+			t.Errorf("tenantTable(%q, %q) = %q, want %q", tc.table, tc.schema, got, tc.want)
+		}
+	}
+}
+
+func TestWithSearchPath(t *testing.T) {
+	got, err := withSearchPath("postgres://user@localhost:5432/app?sslmode=disable", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt := u.Query().Get("options"); opt != `-c search_path="acme",public` {
+		t.Errorf("options = %q", opt)
+	}
+	if u.Query().Get("sslmode") != "disable" {
+		t.Errorf("existing parameters lost: %s", got)
+	}
+}
@@ -0,0 +1,220 @@
+package regresql
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// fixtureTypeSampleLimit caps the invalid values reported per column
+const fixtureTypeSampleLimit = 3
+
+// ValidateFixturesAgainstDatabase checks the CSV fixtures from
+// snapshot.fixtures against the schema of the configured database: the
+// target table and header columns exist, required columns are present and
+// values parse as the column types. All issues are returned together. SQL
+// fixtures are not inspected, PostgreSQL reports their errors on build.
+func ValidateFixturesAgainstDatabase(root string) ([]ValidationIssue, error) {
+	cfg, err := ReadConfig(root)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := GetSnapshotFixtures(cfg.Snapshot)
+	if len(fixtures) == 0 {
+		return nil, nil
+	}
+
+	db, err := OpenDB(cfg.PgUri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer db.Close()
+
+	// fixtures for a schema-per-tenant layout need the tenant schema first
+	tenant := GetSnapshotTenantSchema(cfg.Snapshot)
+	if tenant != "" {
+		if err := checkSchemaExists(db, tenant); err != nil {
+			return []ValidationIssue{{File: "regress.yaml", Field: "snapshot.tenant_schema", Message: err.Error()}}, nil
+		}
+	}
+
+	dbSchema, err := IntrospectSchema(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect schema: %w", err)
+	}
+
+	var issues []ValidationIssue
+	for _, f := range fixtures {
+		if !isCSVFixture(f) {
+			continue
+		}
+		fixtureIssues, err := validateCSVFixture(db, dbSchema, root, f, tenantTable(csvFixtureTable(f), tenant), GetSnapshotCSVNullValue(cfg.Snapshot))
+		if err != nil {
+			return nil, fmt.Errorf("fixture %q: %w", f, err)
+		}
+		issues = append(issues, fixtureIssues...)
+	}
+	return issues, nil
+}
+
+func validateCSVFixture(db *sql.DB, dbSchema *DatabaseSchema, root, fixture, tableName, nullValue string) ([]ValidationIssue, error) {
+	path := filepath.Join(root, fixture)
+	header, err := readCSVHeader(path)
+	if err != nil {
+		return []ValidationIssue{fixtureIssue(fixture, err.Error())}, nil
+	}
+
+	table, err := dbSchema.GetTable(tableName)
+	if err != nil {
+		return []ValidationIssue{fixtureIssue(fixture, err.Error())}, nil
+	}
+
+	issues := checkFixtureColumns(fixture, table, header)
+
+	values, err := readCSVColumnValues(path, header, nullValue)
+	if err != nil {
+		return append(issues, fixtureIssue(fixture, err.Error())), nil
+	}
+	typeIssues, err := checkFixtureValueTypes(db, fixture, table, header, values)
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, typeIssues...), nil
+}
+
+// checkFixtureColumns reports header columns missing from the table and
+// NOT NULL columns without a default that the fixture does not provide
+func checkFixtureColumns(fixture string, table *TableInfo, header []string) []ValidationIssue {
+	var issues []ValidationIssue
+	qualified := table.Schema + "." + table.Name
+
+	provided := make(map[string]bool, len(header))
+	for _, col := range header {
+		col = strings.TrimSpace(col)
+		provided[col] = true
+		if _, ok := table.Columns[col]; !ok {
+			issues = append(issues, fixtureIssue(fixture, fmt.Sprintf("column %q does not exist in %s", col, qualified)))
+		}
+	}
+
+	var missing []string
+	for name, col := range table.Columns {
+		if provided[name] || col.IsNullable || col.Default != nil || col.IsIdentity || col.IsGenerated {
+			continue
+		}
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		issues = append(issues, fixtureIssue(fixture, fmt.Sprintf("required column %q of %s (NOT NULL, no default) is missing", name, qualified)))
+	}
+	return issues
+}
+
+// readCSVColumnValues returns the distinct non-NULL values of each column
+func readCSVColumnValues(path string, header []string, nullValue string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if _, err := r.Read(); err != nil {
+		return nil, err
+	}
+
+	seen := make([]map[string]bool, len(header))
+	values := make([][]string, len(header))
+	for i := range seen {
+		seen[i] = make(map[string]bool)
+	}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, field := range record {
+			if field == nullValue || seen[i][field] {
+				continue
+			}
+			seen[i][field] = true
+			values[i] = append(values[i], field)
+		}
+	}
+	return values, nil
+}
+
+// checkFixtureValueTypes asks PostgreSQL whether the values parse as the
+// column type (pg_input_is_valid, PostgreSQL 16+; skipped on older servers).
+// Enum labels are checked locally.
+func checkFixtureValueTypes(db *sql.DB, fixture string, table *TableInfo, header []string, values [][]string) ([]ValidationIssue, error) {
+	var versionNum int
+	if err := db.QueryRow("SELECT current_setting('server_version_num')::int").Scan(&versionNum); err != nil {
+		return nil, err
+	}
+
+	var issues []ValidationIssue
+	for i, name := range header {
+		col, ok := table.Columns[strings.TrimSpace(name)]
+		if !ok || len(values[i]) == 0 {
+			continue
+		}
+
+		var invalid []string
+		switch {
+		case len(col.EnumValues) > 0:
+			for _, v := range values[i] {
+				if !slices.Contains(col.EnumValues, v) && len(invalid) < fixtureTypeSampleLimit {
+					invalid = append(invalid, v)
+				}
+			}
+		case versionNum >= 160000 && col.TypeName != "":
+			rows, err := db.Query(`SELECT v FROM unnest($1::text[]) AS v WHERE NOT pg_input_is_valid(v, $2) LIMIT $3`,
+				values[i], col.TypeName, fixtureTypeSampleLimit)
+			if err != nil {
+				return nil, fmt.Errorf("type check for column %q: %w", col.Name, err)
+			}
+			for rows.Next() {
+				var v string
+				if err := rows.Scan(&v); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				invalid = append(invalid, v)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(invalid) > 0 {
+			issues = append(issues, fixtureIssue(fixture, fmt.Sprintf("column %q (%s): invalid values %s",
+				col.Name, col.Type, quoteValues(invalid))))
+		}
+	}
+	return issues, nil
+}
+
+func quoteValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func fixtureIssue(fixture, message string) ValidationIssue {
+	return ValidationIssue{File: fixture, Field: "snapshot.fixtures", Message: message}
+}
@@ -0,0 +1,53 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFixtureColumns(t *testing.T) {
+	def := "nextval('users_id_seq')"
+	table := &TableInfo{
+		Schema: "public",
+		Name:   "users",
+		Columns: map[string]*ColumnInfo{
+			"id":         {Name: "id", Default: &def},
+			"uid":        {Name: "uid", IsIdentity: true},
+			"email":      {Name: "email"},
+			"name":       {Name: "name"},
+			"nickname":   {Name: "nickname", IsNullable: true},
+			"email_norm": {Name: "email_norm", IsGenerated: true},
+		},
+	}
+
+	issues := checkFixtureColumns("seeds/users.csv", table, []string{"email", "age"})
+
+	var messages []string
+	for _, i := range issues {
+		messages = append(messages, i.Message)
+	}
+	want := []string{
+		`column "age" does not exist in public.users`,
+		`required column "name" of public.users (NOT NULL, no default) is missing`,
+	}
+	if !equalStrings(messages, want) {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadCSVColumnValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte("id,status\n1,active\n2,\\N\n3,active\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := readCSVColumnValues(path, []string{"id", "status"}, `\N`)
+	if err != nil {
+		t.Fatalf("readCSVColumnValues() error = %v", err)
+	}
+	if !equalStrings(values[0], []string{"1", "2", "3"}) || !equalStrings(values[1], []string{"active"}) {
+		t.Errorf("values = %v", values)
+	}
+}
@@ -15,6 +15,9 @@ type ConsoleOptions struct {
 	FullDiff bool
 	NoDiff   bool
 	Verbose  bool
+
+	// SchemaHash of the tested schema, shown in the header when known
+	SchemaHash string
 }
 
 type ConsoleFormatter struct {
@@ -29,25 +32,28 @@ func (f *ConsoleFormatter) SetOptions(opts ConsoleOptions) {
 }
 
 func (f *ConsoleFormatter) shouldUseColor() bool {
-	// Explicit flags take precedence
-	if f.options.NoColor {
+	return ShouldUseColor(f.options.Color, f.options.NoColor)
+}
+
+// ShouldUseColor decides whether to emit ANSI colors on stdout. The --color
+// and --no-color flags win; then NO_COLOR (https://no-color.org/) and
+// CLICOLOR=0 disable colors, CLICOLOR_FORCE=1 forces them, and otherwise
+// they are used on a terminal that isn't dumb.
+func ShouldUseColor(forceColor, noColor bool) bool {
+	switch {
+	case noColor:
 		return false
-	}
-	if f.options.Color {
+	case forceColor:
 		return true
-	}
-
-	// Respect NO_COLOR environment variable (https://no-color.org/)
-	if _, exists := os.LookupEnv("NO_COLOR"); exists {
+	case os.Getenv("NO_COLOR") != "":
 		return false
-	}
-
-	// Check TERM for dumb terminals
-	if os.Getenv("TERM") == "dumb" {
+	case os.Getenv("CLICOLOR") == "0":
+		return false
+	case os.Getenv("CLICOLOR_FORCE") == "1":
+		return true
+	case os.Getenv("TERM") == "dumb":
 		return false
 	}
-
-	// Auto-detect: use color if stdout is a terminal
 	return term.IsTerminal(int(os.Stdout.Fd()))
 }
 
@@ -82,6 +88,9 @@ func (f *ConsoleFormatter) severityColor(severity string) string {
 
 func (f *ConsoleFormatter) Start(w io.Writer) error {
 	fmt.Fprintln(w, "\nRunning regression tests...")
+	if f.options.SchemaHash != "" {
+		fmt.Fprintf(w, "Schema: %s\n", TruncateHash(f.options.SchemaHash))
+	}
 	if f.options.Verbose {
 		fmt.Fprintln(w)
 	}
@@ -94,6 +103,7 @@ func (f *ConsoleFormatter) AddResult(r TestResult, w io.Writer) error {
 
 	if f.options.Verbose {
 		f.printVerboseResult(r, w)
+		f.printAnalyzeNotice(r, w)
 		return nil
 	}
 
@@ -155,6 +165,13 @@ func (f *ConsoleFormatter) printVerboseResult(r TestResult, w io.Writer) {
 	)
 }
 
+// printAnalyzeNotice points at the EXPLAIN ANALYZE saved by capture_analyze
+func (f *ConsoleFormatter) printAnalyzeNotice(r TestResult, w io.Writer) {
+	if r.AnalyzeFile != "" {
+		fmt.Fprintln(w, f.colorize(fmt.Sprintf("  [analyze saved to: %s]", r.AnalyzeFile), colorDim))
+	}
+}
+
 func (f *ConsoleFormatter) printCostFailure(r TestResult, w io.Writer) {
 	if r.AnalyzeMode {
 		fmt.Fprintf(w, "  Expected buffers: %d\n", r.BaselineBuffers)
@@ -263,6 +280,9 @@ func (f *ConsoleFormatter) printStructuredDiff(diff *StructuredDiff, w io.Writer
 	fmt.Fprintln(w, "  COMPARISON SUMMARY:")
 	fmt.Fprintf(w, "  ├─ Expected: %d rows\n", diff.ExpectedRows)
 	fmt.Fprintf(w, "  ├─ Actual:   %d rows\n", diff.ActualRows)
+	if sampling := diff.SamplingSummary(); sampling != "" {
+		fmt.Fprintf(w, "  ├─ Sampling: %s\n", sampling)
+	}
 
 	switch diff.Type {
 	case DiffTypeOrdering:
@@ -280,6 +300,8 @@ func (f *ConsoleFormatter) printStructuredDiff(diff *StructuredDiff, w io.Writer
 			fmt.Fprintf(w, "  └─ Result:   %s\n", f.colorize(fmt.Sprintf("%d rows removed", diff.RemovedRows), colorRed))
 		} else if diff.AddedRows > 0 {
 			fmt.Fprintf(w, "  └─ Result:   %s\n", f.colorize(fmt.Sprintf("%d rows added", diff.AddedRows), colorGreen))
+		} else {
+			fmt.Fprintf(w, "  └─ Result:   %s\n", f.colorize("Sample unchanged, but the full result size differs", colorYellow))
 		}
 
 		fmt.Fprintln(w)
@@ -301,6 +323,20 @@ func (f *ConsoleFormatter) printStructuredDiff(diff *StructuredDiff, w io.Writer
 		}
 
 	case DiffTypeValues:
+		if len(diff.TypeMismatches) > 0 {
+			fmt.Fprintf(w, "  └─ %s\n", f.colorize(fmt.Sprintf("Column types changed: %d", len(diff.TypeMismatches)), colorYellow))
+			fmt.Fprintln(w)
+			for _, m := range diff.TypeMismatches {
+				fmt.Fprintf(w, "  %s\n", f.colorize(m, colorYellow))
+			}
+			return
+		}
+		if diff.ColumnsReordered {
+			fmt.Fprintf(w, "  └─ %s\n", f.colorize("Columns reordered, expected order: "+strings.Join(diff.Columns, ", "), colorYellow))
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "  Rows match when columns are compared by name; list the columns instead of SELECT *, or add -- regresql: ignore_column_order\n")
+			return
+		}
 		fmt.Fprintf(w, "  ├─ Matching: %d rows\n", diff.MatchingRows)
 		fmt.Fprintf(w, "  └─ %s\n", f.colorize(fmt.Sprintf("Modified: %d rows", diff.ModifiedRows), colorYellow))
 		fmt.Fprintln(w)
@@ -308,6 +344,17 @@ func (f *ConsoleFormatter) printStructuredDiff(diff *StructuredDiff, w io.Writer
 		if len(diff.ModifiedSamples) > 0 {
 			fmt.Fprintf(w, "  %s\n", f.colorize(fmt.Sprintf("MODIFIED ROWS (showing %d of %d):", len(diff.ModifiedSamples), diff.ModifiedRows), colorYellow))
 			for i, sample := range diff.ModifiedSamples {
+				label := fmt.Sprintf("  Row #%d: ", i+1)
+				if len(sample.ColumnDiffs) > 0 {
+					for j, cd := range sample.ColumnDiffs {
+						if j > 0 {
+							label = strings.Repeat(" ", len(label))
+						}
+						fmt.Fprintf(w, "%scolumn %q changed: %s → %s\n", label, cd.Column,
+							f.colorize(cd.Expected, colorRed), f.colorize(cd.Actual, colorGreen))
+					}
+					continue
+				}
 				fmt.Fprintf(w, "  Row #%d:\n", i+1)
 				fmt.Fprintf(w, "    %s %s\n", f.colorize("Expected:", colorRed), f.formatRow(diff.Columns, sample.ExpectedRow))
 				fmt.Fprintf(w, "    %s %s\n", f.colorize("Actual:  ", colorGreen), f.formatRow(diff.Columns, sample.ActualRow))
@@ -399,6 +446,9 @@ func (f *ConsoleFormatter) Finish(s *TestSummary, w io.Writer) error {
 		fmt.Fprintf(w, "  %s %d skipped\n", f.colorize("-", colorDim), s.Skipped)
 	}
 	fmt.Fprintf(w, "  %.2fs total\n", s.Duration)
+	if s.Stopped {
+		fmt.Fprintln(w, f.colorize("  stopped after the first failure (--fail-fast), remaining tests not run", colorDim))
+	}
 
 	// Failing tests details
 	if s.Failed > 0 {
@@ -417,6 +467,7 @@ func (f *ConsoleFormatter) Finish(s *TestSummary, w io.Writer) error {
 				if r.Error != "" {
 					fmt.Fprintf(w, "    %s %s\n", f.colorize("Error:", colorRed), r.Error)
 				}
+				f.printAnalyzeNotice(r, w)
 				f.printPolicyDecisions(r.PolicyApplied, w)
 			}
 		}
@@ -0,0 +1,31 @@
+package regresql
+
+import "testing"
+
+func TestShouldUseColor(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		force, no bool
+		want      bool
+	}{
+		{"NO_COLOR", map[string]string{"NO_COLOR": "1"}, false, false, false},
+		{"empty NO_COLOR ignored", map[string]string{"NO_COLOR": "", "CLICOLOR_FORCE": "1"}, false, false, true},
+		{"CLICOLOR=0", map[string]string{"CLICOLOR": "0", "CLICOLOR_FORCE": "1"}, false, false, false},
+		{"CLICOLOR_FORCE", map[string]string{"CLICOLOR_FORCE": "1"}, false, false, true},
+		{"NO_COLOR beats CLICOLOR_FORCE", map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, false, false, false},
+		{"--color beats NO_COLOR", map[string]string{"NO_COLOR": "1"}, true, false, true},
+		{"--no-color beats CLICOLOR_FORCE", map[string]string{"CLICOLOR_FORCE": "1"}, false, true, false},
+		{"not a terminal", nil, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"NO_COLOR", "CLICOLOR", "CLICOLOR_FORCE", "TERM"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := ShouldUseColor(tt.force, tt.no); got != tt.want {
+				t.Errorf("ShouldUseColor(%v, %v) = %v, want %v", tt.force, tt.no, got, tt.want)
+			}
+		})
+	}
+}
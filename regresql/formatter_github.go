@@ -3,25 +3,73 @@ package regresql
 import (
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
-type GitHubActionsFormatter struct{}
+type GitHubActionsFormatter struct {
+	results []TestResult
+}
+
+// InGitHubActions reports whether regresql runs inside a GitHub Actions job
+func InGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// ghEscape escapes data for a workflow command message
+func ghEscape(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// ghCommand writes a workflow command, annotating the query in its file when
+// known
+func ghCommand(w io.Writer, level string, r TestResult, msg string) {
+	if r.QueryFile != "" {
+		file := r.QueryFile
+		// annotations need paths relative to the repository checkout
+		if ws := os.Getenv("GITHUB_WORKSPACE"); ws != "" && filepath.IsAbs(file) {
+			if rel, err := filepath.Rel(ws, file); err == nil {
+				file = rel
+			}
+		}
+		file = filepath.ToSlash(file)
+		file = strings.NewReplacer("%", "%25", ",", "%2C", ":", "%3A").Replace(file)
+		if r.QueryLine > 0 {
+			fmt.Fprintf(w, "::%s file=%s,line=%d::%s\n", level, file, r.QueryLine, ghEscape(msg))
+			return
+		}
+		fmt.Fprintf(w, "::%s file=%s::%s\n", level, file, ghEscape(msg))
+		return
+	}
+	fmt.Fprintf(w, "::%s::%s\n", level, ghEscape(msg))
+}
 
 func (f *GitHubActionsFormatter) Start(w io.Writer) error {
+	f.results = make([]TestResult, 0)
 	fmt.Fprintln(w, "::group::Running regression tests")
 	return nil
 }
 
 func (f *GitHubActionsFormatter) AddResult(r TestResult, w io.Writer) error {
+	f.results = append(f.results, r)
+
+	// non-critical plan regressions are informational
+	for _, reg := range r.PlanRegressions {
+		if reg.Severity != "critical" {
+			ghCommand(w, "notice", r, fmt.Sprintf("Plan change in %s: %s", r.Name, reg.Message))
+		}
+	}
+
 	switch r.Status {
 	case "passed":
 		// Show plan warnings even for passed tests
 		if len(r.PlanWarnings) > 0 {
 			for _, warning := range r.PlanWarnings {
 				if warning.Severity == "warning" {
-					msg := strings.ReplaceAll(warning.Message, "%", "%%")
-					fmt.Fprintf(w, "::warning::%s - %s\n", r.Name, msg)
+					ghCommand(w, "warning", r, fmt.Sprintf("%s - %s", r.Name, warning.Message))
 				}
 			}
 		}
@@ -45,13 +93,12 @@ func (f *GitHubActionsFormatter) AddResult(r TestResult, w io.Writer) error {
 				}
 			}
 
+			msg := fmt.Sprintf("Cost regression in %s: Expected %.2f, got %.2f (+%.1f%%)",
+				r.Name, r.ExpectedCost, r.ActualCost, r.PercentIncrease)
 			if hasCriticalRegression {
-				fmt.Fprintf(w, "::error::Cost regression in %s: Expected %.2f, got %.2f (+%.1f%%)%s\n",
-					r.Name, r.ExpectedCost, r.ActualCost, r.PercentIncrease, criticalMsg)
-			} else {
-				fmt.Fprintf(w, "::error::Cost regression in %s: Expected %.2f, got %.2f (+%.1f%%)\n",
-					r.Name, r.ExpectedCost, r.ActualCost, r.PercentIncrease)
+				msg += criticalMsg
 			}
+			ghCommand(w, "error", r, msg)
 		} else if r.Type == "output" {
 			// Use structured diff for better error message if available
 			if r.StructuredDiff != nil {
@@ -69,36 +116,40 @@ func (f *GitHubActionsFormatter) AddResult(r TestResult, w io.Writer) error {
 							r.Name, sd.AddedRows, sd.ExpectedRows, sd.ActualRows)
 					}
 				case DiffTypeValues:
-					msg = fmt.Sprintf("Output mismatch in %s: %d rows differ (out of %d)",
-						r.Name, sd.ModifiedRows, sd.ExpectedRows)
+					if len(sd.TypeMismatches) > 0 {
+						msg = fmt.Sprintf("Output mismatch in %s: %s", r.Name, strings.Join(sd.TypeMismatches, "; "))
+					} else if sd.ColumnsReordered {
+						msg = fmt.Sprintf("Output mismatch in %s: columns reordered, rows match when compared by column name (ignore_column_order)", r.Name)
+					} else {
+						msg = fmt.Sprintf("Output mismatch in %s: %d rows differ (out of %d)",
+							r.Name, sd.ModifiedRows, sd.ExpectedRows)
+					}
 				case DiffTypeMultiple:
 					msg = fmt.Sprintf("Output mismatch in %s: %d added, %d removed, %d matching",
 						r.Name, sd.AddedRows, sd.RemovedRows, sd.MatchingRows)
 				default:
 					msg = fmt.Sprintf("Output mismatch in %s", r.Name)
 				}
-				msg = strings.ReplaceAll(msg, "%", "%%")
-				fmt.Fprintf(w, "::error::%s\n", msg)
+				ghCommand(w, "error", r, msg)
 			} else {
 				// Fall back to generic message
-				fmt.Fprintf(w, "::error::Output mismatch in %s\n", r.Name)
+				ghCommand(w, "error", r, fmt.Sprintf("Output mismatch in %s", r.Name))
 			}
 		}
 		if r.Error != "" {
-			fmt.Fprintf(w, "::error::%s: %s\n", r.Name, r.Error)
+			ghCommand(w, "error", r, fmt.Sprintf("%s: %s", r.Name, r.Error))
 		}
 	case "warning":
 		// Show plan quality warnings
 		if len(r.PlanWarnings) > 0 {
 			for _, warning := range r.PlanWarnings {
-				msg := strings.ReplaceAll(warning.Message, "%", "%%")
-				fmt.Fprintf(w, "::warning::%s - %s\n", r.Name, msg)
+				ghCommand(w, "warning", r, fmt.Sprintf("%s - %s", r.Name, warning.Message))
 			}
 		}
 	case "skipped":
-		fmt.Fprintf(w, "::warning::%s skipped: %s\n", r.Name, r.Error)
+		ghCommand(w, "warning", r, fmt.Sprintf("%s skipped: %s", r.Name, r.Error))
 	case "pending":
-		fmt.Fprintf(w, "::notice::%s pending (no baseline): %s\n", r.Name, r.Error)
+		ghCommand(w, "notice", r, fmt.Sprintf("%s pending (no baseline): %s", r.Name, r.Error))
 	}
 	return nil
 }
@@ -126,9 +177,50 @@ func (f *GitHubActionsFormatter) Finish(s *TestSummary, w io.Writer) error {
 		fmt.Fprintf(w, " (%.2fs)\n", s.Duration)
 	}
 
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+		if err := f.writeStepSummary(path, s); err != nil {
+			fmt.Fprintf(w, "::warning::failed to write step summary: %s\n", ghEscape(err.Error()))
+		}
+	}
+
 	return nil
 }
 
+// writeStepSummary appends a Markdown results table to the job summary
+func (f *GitHubActionsFormatter) writeStepSummary(path string, s *TestSummary) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return renderStepSummary(file, f.results, s)
+}
+
+func renderStepSummary(w io.Writer, results []TestResult, s *TestSummary) error {
+	icons := map[string]string{
+		"passed":  "✅",
+		"failed":  "❌",
+		"skipped": "⏭️",
+		"pending": "⏳",
+		"warning": "⚠️",
+	}
+
+	var b strings.Builder
+	b.WriteString("## RegreSQL results\n\n")
+	fmt.Fprintf(&b, "%d passed, %d failed, %d skipped, %d pending (%.2fs)\n\n", s.Passed, s.Failed, s.Skipped, s.Pending, s.Duration)
+	b.WriteString("| | Test | File | Duration |\n|---|---|---|---|\n")
+	for _, r := range results {
+		name := strings.ReplaceAll(r.Name, "|", "\\|")
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %.3fs |\n", icons[r.Status], name, filepath.ToSlash(r.QueryFile), r.Duration)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
 func init() {
 	RegisterFormatter("github-actions", &GitHubActionsFormatter{})
+	RegisterFormatter("github", &GitHubActionsFormatter{})
 }
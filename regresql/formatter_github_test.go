@@ -0,0 +1,68 @@
+package regresql
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitHubActionsFormatterAnnotations(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+	t.Setenv("GITHUB_WORKSPACE", "")
+
+	var buf bytes.Buffer
+	f := &GitHubActionsFormatter{}
+	f.Start(&buf)
+	f.AddResult(TestResult{
+		Name:            "orders_by_id.cost",
+		Type:            "cost",
+		Status:          "failed",
+		QueryFile:       "sql/orders.sql",
+		QueryLine:       12,
+		ExpectedCost:    10,
+		ActualCost:      15,
+		PercentIncrease: 50,
+		PlanRegressions: []PlanRegression{{Severity: "info", Message: "join order changed"}},
+	}, &buf)
+	f.AddResult(TestResult{Name: "q.json", Type: "output", Status: "failed", Error: "line one\nline two"}, &buf)
+
+	out := buf.String()
+	for _, want := range []string{
+		"::error file=sql/orders.sql,line=12::Cost regression in orders_by_id.cost: Expected 10.00, got 15.00 (+50.0%25)\n",
+		"::notice file=sql/orders.sql,line=12::Plan change in orders_by_id.cost: join order changed\n",
+		"::error::q.json: line one%0Aline two\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestGitHubActionsStepSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+
+	var buf bytes.Buffer
+	f := &GitHubActionsFormatter{}
+	f.Start(&buf)
+	f.AddResult(TestResult{Name: "a.json", Type: "output", Status: "passed", QueryFile: "a.sql", Duration: 0.012}, &buf)
+	f.AddResult(TestResult{Name: "b|c.json", Type: "output", Status: "failed", QueryFile: "b.sql"}, &buf)
+	f.Finish(&TestSummary{Total: 2, Passed: 1, Failed: 1}, &buf)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := string(data)
+	for _, want := range []string{
+		"1 passed, 1 failed",
+		"| ✅ | `a.json` | a.sql | 0.012s |",
+		"| ❌ | `b\\|c.json` | b.sql |",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("step summary missing %q\n%s", want, summary)
+		}
+	}
+}
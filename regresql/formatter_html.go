@@ -0,0 +1,226 @@
+package regresql
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+type (
+	HTMLFormatter struct {
+		results []TestResult
+	}
+
+	htmlReport struct {
+		Generated string
+		Summary   *TestSummary
+		Files     []htmlFile
+		Data      []map[string]any
+	}
+
+	htmlFile struct {
+		Name   string
+		Total  int
+		Failed int
+		Tests  []htmlTest
+	}
+
+	htmlTest struct {
+		Name     string
+		Type     string
+		Status   string
+		Duration float64
+		Cost     string
+		Error    string
+		Diff     *StructuredDiff
+	}
+)
+
+func (f *HTMLFormatter) Start(w io.Writer) error {
+	f.results = make([]TestResult, 0)
+	return nil
+}
+
+func (f *HTMLFormatter) AddResult(r TestResult, w io.Writer) error {
+	f.results = append(f.results, r)
+	return nil
+}
+
+func (f *HTMLFormatter) Finish(s *TestSummary, w io.Writer) error {
+	report := htmlReport{
+		Generated: s.StartTime.Format(time.RFC3339),
+		Summary:   s,
+		Data:      formatTests(f.results),
+	}
+
+	// one collapsible section per SQL file, in the order files were first seen
+	index := make(map[string]int)
+	for i, r := range f.results {
+		name := junitSuiteName(r)
+		pos, ok := index[name]
+		if !ok {
+			pos = len(report.Files)
+			index[name] = pos
+			report.Files = append(report.Files, htmlFile{Name: name})
+		}
+
+		file := &report.Files[pos]
+		file.Total++
+		if r.Status == "failed" {
+			file.Failed++
+		}
+
+		test := htmlTest{
+			Name:     r.Name,
+			Type:     r.Type,
+			Status:   r.Status,
+			Duration: r.Duration,
+			Error:    r.Error,
+			Diff:     r.StructuredDiff,
+		}
+		if r.Type == "cost" && r.ExpectedCost > 0 {
+			test.Cost = fmt.Sprintf("%.2f → %.2f (%+.1f%%)", r.ExpectedCost, r.ActualCost, r.PercentIncrease)
+		}
+		file.Tests = append(file.Tests, test)
+		report.Data[i]["file"] = name
+	}
+
+	return htmlReportTemplate.Execute(w, report)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cell": func(v any) string {
+		if v == nil {
+			return "null"
+		}
+		return valueToString(v)
+	},
+	"hasSamples": func(d *StructuredDiff) bool {
+		return d != nil && (len(d.AddedSamples) > 0 || len(d.RemovedSamples) > 0 || len(d.ModifiedSamples) > 0 || len(d.TypeMismatches) > 0)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>RegreSQL test report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+.banner { padding: 1em 1.5em; border-radius: 6px; margin-bottom: 1.5em; }
+.banner.ok { background: #e6f4ea; border: 1px solid #34a853; }
+.banner.fail { background: #fce8e6; border: 1px solid #d93025; }
+.banner span { margin-right: 1.5em; }
+details { border: 1px solid #ddd; border-radius: 6px; margin-bottom: 0.75em; }
+summary { cursor: pointer; padding: 0.5em 1em; font-family: monospace; }
+summary .counts { float: right; color: #666; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { text-align: left; padding: 0.3em 0.75em; border-top: 1px solid #eee; vertical-align: top; }
+th { background: #f6f6f6; cursor: pointer; user-select: none; }
+.status-passed { color: #188038; }
+.status-failed { color: #d93025; font-weight: bold; }
+.status-pending, .status-skipped { color: #80868b; }
+.status-warning { color: #e37400; }
+.diff { margin: 0.5em 0 1em; }
+.diff table { width: auto; font-family: monospace; }
+tr.added td { background: #e6f4ea; }
+tr.removed td { background: #fce8e6; }
+tr.modified td { background: #fef3e0; }
+.error { white-space: pre-wrap; font-family: monospace; color: #d93025; }
+</style>
+</head>
+<body>
+<h1>RegreSQL test report</h1>
+<div class="banner {{if .Summary.Failed}}fail{{else}}ok{{end}}">
+<span><b>{{.Summary.Total}}</b> tests</span>
+<span class="status-passed"><b>{{.Summary.Passed}}</b> passed</span>
+<span class="status-failed"><b>{{.Summary.Failed}}</b> failed</span>
+<span><b>{{.Summary.Skipped}}</b> skipped</span>
+<span><b>{{.Summary.Pending}}</b> pending</span>
+<span>{{printf "%.2f" .Summary.Duration}}s</span>
+<span>{{.Generated}}</span>
+</div>
+
+{{range .Files}}
+<details{{if .Failed}} open{{end}}>
+<summary>{{.Name}} <span class="counts">{{.Total}} tests{{if .Failed}}, <span class="status-failed">{{.Failed}} failed</span>{{end}}</span></summary>
+<table>
+<tr><th>Test</th><th>Type</th><th>Status</th><th>Duration</th><th>Cost</th></tr>
+{{range .Tests}}
+<tr>
+<td>{{.Name}}</td><td>{{.Type}}</td><td class="status-{{.Status}}">{{.Status}}</td>
+<td>{{printf "%.3f" .Duration}}s</td><td>{{.Cost}}</td>
+</tr>
+{{if or .Error (hasSamples .Diff)}}
+<tr><td colspan="5">
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{with .Diff}}{{if hasSamples .}}
+<div class="diff">
+{{range .TypeMismatches}}<div class="status-warning">{{.}}</div>{{end}}
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .RemovedSamples}}<tr class="removed">{{range .}}<td>{{cell .}}</td>{{end}}</tr>{{end}}
+{{range .AddedSamples}}<tr class="added">{{range .}}<td>{{cell .}}</td>{{end}}</tr>{{end}}
+{{range .ModifiedSamples}}
+<tr class="removed">{{range .ExpectedRow}}<td>{{cell .}}</td>{{end}}</tr>
+<tr class="modified">{{range .ActualRow}}<td>{{cell .}}</td>{{end}}</tr>
+{{end}}
+</table>
+</div>
+{{end}}{{end}}
+</td></tr>
+{{end}}
+{{end}}
+</table>
+</details>
+{{end}}
+
+<h2>All tests</h2>
+<table id="all-tests">
+<thead><tr><th data-key="file">File</th><th data-key="name">Test</th><th data-key="status">Status</th><th data-key="duration">Duration</th></tr></thead>
+<tbody></tbody>
+</table>
+
+<script id="regresql-data" type="application/json">{{.Data}}</script>
+<script>
+(function () {
+  var tests = JSON.parse(document.getElementById("regresql-data").textContent) || [];
+  var body = document.querySelector("#all-tests tbody");
+  var sortKey = "file", asc = true;
+
+  function render() {
+    tests.sort(function (a, b) {
+      var x = a[sortKey], y = b[sortKey];
+      if (x === y) return 0;
+      return (x < y ? -1 : 1) * (asc ? 1 : -1);
+    });
+    body.innerHTML = "";
+    tests.forEach(function (t) {
+      var tr = document.createElement("tr");
+      [t.file, t.name, t.status, t.duration.toFixed(3) + "s"].forEach(function (v, i) {
+        var td = document.createElement("td");
+        td.textContent = v;
+        if (i === 2) td.className = "status-" + t.status;
+        tr.appendChild(td);
+      });
+      body.appendChild(tr);
+    });
+  }
+
+  document.querySelectorAll("#all-tests th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var key = th.getAttribute("data-key");
+      asc = key === sortKey ? !asc : true;
+      sortKey = key;
+      render();
+    });
+  });
+  render();
+})();
+</script>
+</body>
+</html>
+`))
+
+func init() {
+	RegisterFormatter("html", &HTMLFormatter{})
+}
@@ -0,0 +1,62 @@
+package regresql
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHTMLFormatter(t *testing.T) {
+	f := &HTMLFormatter{}
+	var buf bytes.Buffer
+
+	results := []TestResult{
+		{Name: "a.q1", Type: "output", Status: "passed", Duration: 0.1, QueryFile: "sql/a.sql"},
+		{Name: "b.q1", Type: "output", Status: "failed", Duration: 0.2, QueryFile: "sql/b.sql",
+			StructuredDiff: &StructuredDiff{
+				Type:           DiffTypeRowCount,
+				Columns:        []string{"id", "name"},
+				AddedSamples:   [][]any{{3, "<script>alert(1)</script>"}},
+				RemovedSamples: [][]any{{2, nil}},
+			}},
+	}
+
+	summary := NewTestSummary()
+	f.Start(&buf)
+	for _, r := range results {
+		summary.AddResult(r)
+		f.AddResult(r, &buf)
+	}
+	if err := f.Finish(summary, &buf); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<summary>sql/b.sql`,
+		`<tr class="added"><td>3</td><td>&lt;script&gt;alert(1)&lt;/script&gt;</td></tr>`,
+		`<tr class="removed"><td>2</td><td>null</td></tr>`,
+		`class="banner fail"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+
+	// the embedded JSON must round-trip so the page can sort without a server
+	start := strings.Index(out, `<script id="regresql-data" type="application/json">`)
+	if start < 0 {
+		t.Fatal("report has no embedded data")
+	}
+	data := out[start+len(`<script id="regresql-data" type="application/json">`):]
+	data = data[:strings.Index(data, "</script>")]
+
+	var tests []map[string]any
+	if err := json.Unmarshal([]byte(data), &tests); err != nil {
+		t.Fatalf("embedded data is not JSON: %v\n%s", err, data)
+	}
+	if len(tests) != 2 || tests[1]["file"] != "sql/b.sql" || tests[1]["status"] != "failed" {
+		t.Errorf("embedded data = %v", tests)
+	}
+}
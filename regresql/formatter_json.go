@@ -139,17 +139,7 @@ func formatTests(results []TestResult) []map[string]any {
 
 			// Add structured diff statistics if available
 			if r.StructuredDiff != nil {
-				sd := r.StructuredDiff
-				test["structured_diff"] = map[string]any{
-					"type":          string(sd.Type),
-					"identical":     sd.Identical,
-					"expected_rows": sd.ExpectedRows,
-					"actual_rows":   sd.ActualRows,
-					"matching_rows": sd.MatchingRows,
-					"added_rows":    sd.AddedRows,
-					"removed_rows":  sd.RemovedRows,
-					"modified_rows": sd.ModifiedRows,
-				}
+				test["structured_diff"] = structuredDiffMap(r.StructuredDiff)
 			}
 		}
 
@@ -159,6 +149,30 @@ func formatTests(results []TestResult) []map[string]any {
 	return tests
 }
 
+// structuredDiffMap holds the diff statistics shared by the JSON outputs
+func structuredDiffMap(sd *StructuredDiff) map[string]any {
+	structured := map[string]any{
+		"type":          string(sd.Type),
+		"identical":     sd.Identical,
+		"expected_rows": sd.ExpectedRows,
+		"actual_rows":   sd.ActualRows,
+		"matching_rows": sd.MatchingRows,
+		"added_rows":    sd.AddedRows,
+		"removed_rows":  sd.RemovedRows,
+		"modified_rows": sd.ModifiedRows,
+	}
+	if len(sd.TypeMismatches) > 0 {
+		structured["type_mismatches"] = sd.TypeMismatches
+	}
+	if sd.ExpectedSampling != nil {
+		structured["expected_sampling"] = sd.ExpectedSampling
+	}
+	if sd.ActualSampling != nil {
+		structured["actual_sampling"] = sd.ActualSampling
+	}
+	return structured
+}
+
 func init() {
 	RegisterFormatter("json", &JSONFormatter{})
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 )
 
 type (
@@ -55,9 +57,19 @@ func (f *JUnitFormatter) AddResult(r TestResult, w io.Writer) error {
 }
 
 func (f *JUnitFormatter) Finish(s *TestSummary, w io.Writer) error {
-	cases := make([]JUnitTestCase, 0, len(f.results))
+	// one <testsuite> per SQL file, in the order files were first seen
+	var order []string
+	bySuite := make(map[string]*JUnitTestSuite)
 
 	for _, r := range f.results {
+		suiteName := junitSuiteName(r)
+		suite, ok := bySuite[suiteName]
+		if !ok {
+			suite = &JUnitTestSuite{Name: suiteName}
+			bySuite[suiteName] = suite
+			order = append(order, suiteName)
+		}
+
 		tc := JUnitTestCase{
 			Name:      r.Name,
 			Classname: "regresql." + r.Type,
@@ -84,7 +96,13 @@ func (f *JUnitFormatter) Finish(s *TestSummary, w io.Writer) error {
 							msg = fmt.Sprintf("%d rows added (expected %d, got %d)", sd.AddedRows, sd.ExpectedRows, sd.ActualRows)
 						}
 					case DiffTypeValues:
-						msg = fmt.Sprintf("%d rows differ (out of %d)", sd.ModifiedRows, sd.ExpectedRows)
+						if len(sd.TypeMismatches) > 0 {
+							msg = strings.Join(sd.TypeMismatches, "; ")
+						} else if sd.ColumnsReordered {
+							msg = "Columns reordered, rows match when compared by column name (ignore_column_order)"
+						} else {
+							msg = fmt.Sprintf("%d rows differ (out of %d)", sd.ModifiedRows, sd.ExpectedRows)
+						}
 					case DiffTypeMultiple:
 						msg = fmt.Sprintf("%d added, %d removed, %d matching", sd.AddedRows, sd.RemovedRows, sd.MatchingRows)
 					default:
@@ -103,31 +121,30 @@ func (f *JUnitFormatter) Finish(s *TestSummary, w io.Writer) error {
 				Type:    r.Type,
 				Content: content,
 			}
+			suite.Failures++
 		} else if r.Status == "skipped" {
 			tc.Skipped = &JUnitSkipped{
 				Message: r.Error,
 			}
+			suite.Skipped++
 		} else if r.Status == "pending" {
 			// pending tests are included in skipped
 			tc.Skipped = &JUnitSkipped{
 				Message: "pending: " + r.Error,
 			}
+			suite.Skipped++
 		}
 
-		cases = append(cases, tc)
-	}
-
-	suite := JUnitTestSuite{
-		Name:     "regresql",
-		Tests:    s.Total,
-		Failures: s.Failed,
-		Skipped:  s.Skipped + s.Pending, // Include pending in skipped count
-		Time:     s.Duration,
-		Cases:    cases,
+		suite.Tests++
+		suite.Time += r.Duration
+		suite.Cases = append(suite.Cases, tc)
 	}
 
 	suites := JUnitTestSuites{
-		Suites: []JUnitTestSuite{suite},
+		Suites: make([]JUnitTestSuite, 0, len(order)),
+	}
+	for _, name := range order {
+		suites.Suites = append(suites.Suites, *bySuite[name])
 	}
 
 	output, err := xml.MarshalIndent(suites, "", "  ")
@@ -139,6 +156,15 @@ func (f *JUnitFormatter) Finish(s *TestSummary, w io.Writer) error {
 	return nil
 }
 
+// junitSuiteName groups results by the SQL file they came from; results
+// without a file (e.g. synthetic errors) fall back to a shared suite
+func junitSuiteName(r TestResult) string {
+	if r.QueryFile == "" {
+		return "regresql"
+	}
+	return filepath.ToSlash(r.QueryFile)
+}
+
 func init() {
 	RegisterFormatter("junit", &JUnitFormatter{})
 }
@@ -0,0 +1,63 @@
+package regresql
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJUnitFormatterGroupsByQueryFile(t *testing.T) {
+	f := &JUnitFormatter{}
+	var buf bytes.Buffer
+
+	results := []TestResult{
+		{Name: "a.q1", Type: "output", Status: "passed", Duration: 0.5, QueryFile: "sql/a.sql"},
+		{Name: "b.q1", Type: "output", Status: "failed", Duration: 0.25, QueryFile: "sql/b.sql", Diff: "-1\n+2"},
+		{Name: "a.q1.cost", Type: "cost", Status: "failed", Duration: 0.25, QueryFile: "sql/a.sql",
+			ExpectedCost: 10, ActualCost: 20, PercentIncrease: 100},
+		{Name: "b.q2", Type: "output", Status: "pending", QueryFile: "sql/b.sql"},
+	}
+
+	if err := f.Start(&buf); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	summary := NewTestSummary()
+	for _, r := range results {
+		summary.AddResult(r)
+		if err := f.AddResult(r, &buf); err != nil {
+			t.Fatalf("AddResult: %v", err)
+		}
+	}
+	if err := f.Finish(summary, &buf); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	var out JUnitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if len(out.Suites) != 2 {
+		t.Fatalf("got %d suites, want 2", len(out.Suites))
+	}
+
+	a, b := out.Suites[0], out.Suites[1]
+	if a.Name != "sql/a.sql" || b.Name != "sql/b.sql" {
+		t.Errorf("suite names = %q, %q; want sql/a.sql, sql/b.sql", a.Name, b.Name)
+	}
+	if a.Tests != 2 || a.Failures != 1 || a.Skipped != 0 {
+		t.Errorf("suite a counts = %d/%d/%d, want 2/1/0", a.Tests, a.Failures, a.Skipped)
+	}
+	if a.Time != 0.75 {
+		t.Errorf("suite a time = %v, want 0.75", a.Time)
+	}
+	if b.Tests != 2 || b.Failures != 1 || b.Skipped != 1 {
+		t.Errorf("suite b counts = %d/%d/%d, want 2/1/1", b.Tests, b.Failures, b.Skipped)
+	}
+
+	cost := a.Cases[1].Failure
+	if cost == nil || !strings.Contains(cost.Message, "expected: 10.00, actual: 20.00") {
+		t.Errorf("cost failure message = %+v", cost)
+	}
+}
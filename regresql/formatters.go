@@ -57,9 +57,13 @@ type (
 
 		// Diagnostics
 		QueryFile    string
+		QueryLine    int // line of the query in QueryFile, 0 when unknown
 		BindingsFile string
 		BindingName  string
 		Parameters   map[string]any
+		ActualFile   string // output tests: result written to out/
+		ExpectedFile string // output tests: expected file compared against
+		AnalyzeFile  string // output tests: EXPLAIN ANALYZE saved by capture_analyze
 
 		// Policy evaluator audit trail — each entry explains one
 		// severity re-mapping made by ApplyPolicies.
@@ -76,6 +80,7 @@ type (
 		Duration  float64
 		Results   []TestResult
 		StartTime time.Time
+		Stopped   bool // --fail-fast stopped the run after the first failure
 	}
 
 	OutputFormatter interface {
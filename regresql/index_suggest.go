@@ -0,0 +1,200 @@
+package regresql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type (
+	// IndexSuggestion is a CREATE INDEX statement that would let the planner
+	// replace a sequential scan with an index scan
+	IndexSuggestion struct {
+		Table     string
+		Columns   []string
+		IndexType string // IndexTypeBTree, IndexTypeTrigram or IndexTypeGIN
+		DDL       string
+		Rationale string
+	}
+
+	// scanPredicate is a single column comparison of a scan condition
+	scanPredicate struct {
+		column   string
+		operator string
+	}
+)
+
+const (
+	IndexTypeBTree   = "btree"
+	IndexTypeTrigram = "gin_trgm"
+	IndexTypeGIN     = "gin"
+)
+
+var (
+	// scanPredicatePattern matches "(col = ...", "((col)::text = ..." and
+	// "(t.col ~~ ..." in EXPLAIN conditions. Expressions such as
+	// "(data ->> 'key')" don't match, they'd need an expression index.
+	scanPredicatePattern = regexp.MustCompile(`\((?:"?[a-zA-Z_][a-zA-Z0-9_]*"?\.)?"?([a-zA-Z_][a-zA-Z0-9_]*)"?\)?(?:::[a-z ]+(?:\[\])?)?\s+(=|>=|<=|>|<|~~\*|~~|@>|<@|\?\||\?&|\?)\s`)
+
+	indexDefinitionPattern = regexp.MustCompile(`USING (\w+) \((.*?)\)(?: INCLUDE| WHERE|$)`)
+)
+
+// SuggestIndexes suggests indexes for the sequential scans of a plan from
+// the predicates of their filters: btree for equality and range
+// comparisons, trigram for LIKE/ILIKE and GIN for JSONB containment and key
+// operators. With a schema, columns the table doesn't have and indexes that
+// already exist are left out; schema may be nil.
+func SuggestIndexes(sig *PlanSignature, schema *DatabaseSchema) []IndexSuggestion {
+	if sig == nil {
+		return nil
+	}
+	tables := make([]string, 0, len(sig.Relations))
+	for table, scan := range sig.Relations {
+		if scan.ScanType == "Seq Scan" {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	var suggestions []IndexSuggestion
+	for _, table := range tables {
+		suggestions = append(suggestions, suggestTableIndexes(table, sig.Relations[table], schema)...)
+	}
+	return suggestions
+}
+
+// suggestTableIndexes suggests the indexes of a single scan: one btree on
+// the equality columns followed by the first range column, and one
+// trigram or GIN index per column matched with LIKE or JSONB operators
+func suggestTableIndexes(table string, scan ScanInfo, schema *DatabaseSchema) []IndexSuggestion {
+	var info *TableInfo
+	if schema != nil {
+		info, _ = schema.GetTable(table)
+	}
+
+	var equality, ranges, trigram, gin []string
+	for _, p := range parseScanPredicates(scan.IndexCond + " " + scan.Filter) {
+		if info != nil && info.Columns[p.column] == nil {
+			continue
+		}
+		switch p.operator {
+		case "=":
+			equality = appendUnique(equality, p.column)
+		case "<", ">", "<=", ">=":
+			ranges = appendUnique(ranges, p.column)
+		case "~~", "~~*":
+			trigram = appendUnique(trigram, p.column)
+		default:
+			gin = appendUnique(gin, p.column)
+		}
+	}
+
+	var suggestions []IndexSuggestion
+	add := func(s IndexSuggestion) {
+		if info != nil && hasCoveringIndex(info, s) {
+			return
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	btree := slices.Clone(equality)
+	var rangeColumn string
+	for _, col := range ranges {
+		if !slices.Contains(equality, col) {
+			rangeColumn = col
+			btree = append(btree, col)
+			break
+		}
+	}
+	if len(btree) > 0 {
+		var reason string
+		switch {
+		case rangeColumn == "":
+			reason = fmt.Sprintf("equality on %s", strings.Join(equality, ", "))
+		case len(equality) == 0:
+			reason = fmt.Sprintf("range on %s", rangeColumn)
+		default:
+			reason = fmt.Sprintf("equality on %s and range on %s", strings.Join(equality, ", "), rangeColumn)
+		}
+		add(IndexSuggestion{
+			Table:     table,
+			Columns:   btree,
+			IndexType: IndexTypeBTree,
+			DDL:       fmt.Sprintf("CREATE INDEX %s_%s_idx ON %s (%s);", table, strings.Join(btree, "_"), table, strings.Join(btree, ", ")),
+			Rationale: fmt.Sprintf("Seq Scan on %s filters by %s", table, reason),
+		})
+	}
+	for _, col := range trigram {
+		add(IndexSuggestion{
+			Table:     table,
+			Columns:   []string{col},
+			IndexType: IndexTypeTrigram,
+			DDL:       fmt.Sprintf("CREATE INDEX %s_%s_trgm_idx ON %s USING gin (%s gin_trgm_ops);", table, col, table, col),
+			Rationale: fmt.Sprintf("Seq Scan on %s matches %s with LIKE; trigram indexes need CREATE EXTENSION pg_trgm", table, col),
+		})
+	}
+	for _, col := range gin {
+		add(IndexSuggestion{
+			Table:     table,
+			Columns:   []string{col},
+			IndexType: IndexTypeGIN,
+			DDL:       fmt.Sprintf("CREATE INDEX %s_%s_idx ON %s USING gin (%s);", table, col, table, col),
+			Rationale: fmt.Sprintf("Seq Scan on %s filters %s with JSONB operators", table, col),
+		})
+	}
+	return suggestions
+}
+
+func parseScanPredicates(cond string) []scanPredicate {
+	var predicates []scanPredicate
+	for _, m := range scanPredicatePattern.FindAllStringSubmatch(cond, -1) {
+		predicates = append(predicates, scanPredicate{column: m[1], operator: m[2]})
+	}
+	return predicates
+}
+
+// hasCoveringIndex reports whether the table already has an index the
+// suggestion would duplicate: a btree leading with the same columns, or a
+// GIN index on the column with the same operator class
+func hasCoveringIndex(info *TableInfo, s IndexSuggestion) bool {
+	for _, idx := range info.Indexes {
+		m := indexDefinitionPattern.FindStringSubmatch(idx.Definition)
+		if m == nil {
+			continue
+		}
+		var columns, opclasses []string
+		for _, part := range strings.Split(m[2], ",") {
+			fields := strings.Fields(part)
+			if len(fields) == 0 {
+				continue
+			}
+			columns = append(columns, strings.Trim(fields[0], `"`))
+			opclasses = append(opclasses, strings.Join(fields[1:], " "))
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		switch s.IndexType {
+		case IndexTypeBTree:
+			if m[1] == "btree" && len(columns) >= len(s.Columns) && slices.Equal(columns[:len(s.Columns)], s.Columns) {
+				return true
+			}
+		default:
+			trgm := opclasses[0] == "gin_trgm_ops"
+			if m[1] == "gin" && columns[0] == s.Columns[0] && trgm == (s.IndexType == IndexTypeTrigram) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,76 @@
+package regresql
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSuggestIndexes(t *testing.T) {
+	sig := &PlanSignature{Relations: map[string]ScanInfo{
+		"orders":   {ScanType: "Seq Scan", Filter: "((customer_id = $1) AND ((status)::text = 'open'::text) AND (created_at >= $2))"},
+		"users":    {ScanType: "Seq Scan", Filter: "((email)::text ~~* '%@example.com'::text)"},
+		"events":   {ScanType: "Seq Scan", Filter: "((payload @> '{\"kind\": \"click\"}'::jsonb) AND ((payload ->> 'id'::text) = '1'::text))"},
+		"accounts": {ScanType: "Index Scan", IndexName: "accounts_pkey", IndexCond: "(id = $1)"},
+	}}
+
+	got := SuggestIndexes(sig, nil)
+	want := []IndexSuggestion{
+		{Table: "events", Columns: []string{"payload"}, IndexType: IndexTypeGIN, DDL: "CREATE INDEX events_payload_idx ON events USING gin (payload);"},
+		{Table: "orders", Columns: []string{"customer_id", "status", "created_at"}, IndexType: IndexTypeBTree,
+			DDL: "CREATE INDEX orders_customer_id_status_created_at_idx ON orders (customer_id, status, created_at);"},
+		{Table: "users", Columns: []string{"email"}, IndexType: IndexTypeTrigram, DDL: "CREATE INDEX users_email_trgm_idx ON users USING gin (email gin_trgm_ops);"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("suggestions = %+v", got)
+	}
+	for i := range want {
+		if got[i].Table != want[i].Table || !slices.Equal(got[i].Columns, want[i].Columns) ||
+			got[i].IndexType != want[i].IndexType || got[i].DDL != want[i].DDL || got[i].Rationale == "" {
+			t.Errorf("suggestion %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSuggestIndexesWithSchema(t *testing.T) {
+	schema := &DatabaseSchema{tables: map[string]*TableInfo{
+		"public.orders": {
+			Schema:  "public",
+			Name:    "orders",
+			Columns: map[string]*ColumnInfo{"customer_id": {}, "created_at": {}, "note": {}},
+			Indexes: []*IndexInfo{
+				{Name: "orders_customer_id_created_at_idx", Definition: "CREATE INDEX orders_customer_id_created_at_idx ON public.orders USING btree (customer_id, created_at)"},
+				{Name: "orders_note_idx", Definition: "CREATE INDEX orders_note_idx ON public.orders USING gin (note)"},
+			},
+		},
+	}}
+	scan := func(filter string) *PlanSignature {
+		return &PlanSignature{Relations: map[string]ScanInfo{"orders": {ScanType: "Seq Scan", Filter: filter}}}
+	}
+
+	if got := SuggestIndexes(scan("(customer_id = $1)"), schema); len(got) != 0 {
+		t.Errorf("suggested %+v, already covered by a btree index", got)
+	}
+	// a plain GIN index doesn't serve LIKE
+	if got := SuggestIndexes(scan("(note ~~ '%x%'::text)"), schema); len(got) != 1 || got[0].IndexType != IndexTypeTrigram {
+		t.Errorf("suggestions = %+v, want a trigram index", got)
+	}
+	// columns the table doesn't have are left out
+	if got := SuggestIndexes(scan("((created_at >= $1) AND (missing = 1))"), schema); len(got) != 1 || !slices.Equal(got[0].Columns, []string{"created_at"}) {
+		t.Errorf("suggestions = %+v, want created_at only", got)
+	}
+}
+
+func TestIndexToSeqScanSuggestsIndex(t *testing.T) {
+	baseline := &PlanSignature{Relations: map[string]ScanInfo{"orders": {ScanType: "Index Scan", IndexName: "orders_customer_idx", IndexCond: "(customer_id = $1)"}}}
+	current := &PlanSignature{HasSeqScan: true, Relations: map[string]ScanInfo{"orders": {ScanType: "Seq Scan", Filter: "(customer_id = $1)"}}}
+
+	regs := DetectPlanRegressions(baseline, current)
+	if len(regs) != 1 || regs[0].Type != IndexToSeqScan {
+		t.Fatalf("regressions = %+v", regs)
+	}
+	recs := strings.Join(regs[0].Recommendations, "\n")
+	if !strings.Contains(recs, "CREATE INDEX orders_customer_id_idx ON orders (customer_id);") {
+		t.Errorf("recommendations miss the suggested index:\n%s", recs)
+	}
+}
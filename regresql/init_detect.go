@@ -0,0 +1,271 @@
+package regresql
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migration tools recognized by `regresql init`
+const (
+	MigrationToolGolangMigrate = "golang-migrate"
+	MigrationToolGoose         = "goose"
+	MigrationToolDbmate        = "dbmate"
+	MigrationToolAtlas         = "atlas"
+	MigrationToolPlainSQL      = "sql"
+)
+
+var (
+	migrationDirCandidates = []string{"migrations", "db/migrations", "sql/migrations"}
+	schemaFileCandidates   = []string{"schema.sql", "db/schema.sql", "sql/schema.sql"}
+	composeFileCandidates  = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+)
+
+type (
+	// ProjectSetup is what `regresql init` found out about the project: how
+	// its schema is built and where its development database runs
+	ProjectSetup struct {
+		MigrationTool    string // one of the MigrationTool constants, empty when none was found
+		MigrationsDir    string // relative to the project root
+		Schema           string // schema file, relative to the project root
+		ComposeFile      string // compose file the pguri was read from
+		PgUri            string // pguri of the compose postgres service
+		PostgresVersion  int    // major version pinned in .tool-versions
+		Migrations       string // suggested snapshot.migrations
+		MigrationCommand string // suggested snapshot.migration_command
+	}
+
+	composeFile struct {
+		Services map[string]composeService `yaml:"services"`
+	}
+
+	composeService struct {
+		Image       string    `yaml:"image"`
+		Ports       []any     `yaml:"ports"`
+		Environment yaml.Node `yaml:"environment"`
+	}
+)
+
+// DetectProjectSetup looks for migrations, a schema file, a compose postgres
+// service and a .tool-versions postgres pin under root
+func DetectProjectSetup(root string) *ProjectSetup {
+	setup := &ProjectSetup{
+		PostgresVersion: parseToolVersions(filepath.Join(root, ".tool-versions")),
+	}
+	setup.detectMigrations(root)
+	for _, f := range schemaFileCandidates {
+		if fileExists(filepath.Join(root, f)) {
+			setup.Schema = f
+			break
+		}
+	}
+	for _, f := range composeFileCandidates {
+		if pguri := composePgUri(filepath.Join(root, f)); pguri != "" {
+			setup.ComposeFile = f
+			setup.PgUri = pguri
+			break
+		}
+	}
+	return setup
+}
+
+// detectMigrations finds the first migrations directory holding .sql files
+// and recognizes the tool from the file names and contents. goose and dbmate
+// keep up and down migrations in one file and atlas tracks a checksum file,
+// so those are applied with their own tool; golang-migrate's .up.sql files
+// and plain SQL files are applied in order by snapshot build.
+func (s *ProjectSetup) detectMigrations(root string) {
+	for _, dir := range migrationDirCandidates {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			continue
+		}
+		var sqlFiles []string
+		atlas := false
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			switch name := e.Name(); {
+			case name == "atlas.sum":
+				atlas = true
+			case strings.HasSuffix(strings.ToLower(name), ".sql"):
+				sqlFiles = append(sqlFiles, name)
+			}
+		}
+		if len(sqlFiles) == 0 {
+			continue
+		}
+
+		s.MigrationsDir = filepath.ToSlash(dir)
+		s.MigrationTool = migrationTool(filepath.Join(root, dir), sqlFiles, atlas || fileExists(filepath.Join(root, "atlas.hcl")))
+		switch s.MigrationTool {
+		case MigrationToolGoose:
+			s.MigrationCommand = fmt.Sprintf(`goose -dir %s postgres "$PGURI" up`, s.MigrationsDir)
+		case MigrationToolDbmate:
+			s.MigrationCommand = fmt.Sprintf("dbmate --migrations-dir %s --no-dump-schema up", s.MigrationsDir)
+		case MigrationToolAtlas:
+			s.MigrationCommand = fmt.Sprintf(`atlas migrate apply --dir file://%s --url "$PGURI"`, s.MigrationsDir)
+		default:
+			s.Migrations = s.MigrationsDir
+		}
+		return
+	}
+}
+
+func migrationTool(dir string, sqlFiles []string, atlas bool) string {
+	if atlas {
+		return MigrationToolAtlas
+	}
+	upFiles := 0
+	for _, name := range sqlFiles {
+		if strings.HasSuffix(strings.ToLower(name), ".up.sql") {
+			upFiles++
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		switch content := string(data); {
+		case strings.Contains(content, "-- +goose Up"):
+			return MigrationToolGoose
+		case strings.Contains(content, "-- migrate:up"):
+			return MigrationToolDbmate
+		}
+	}
+	if upFiles > 0 {
+		return MigrationToolGolangMigrate
+	}
+	return MigrationToolPlainSQL
+}
+
+// composePgUri returns the pguri of the first postgres service, by name, of
+// a compose file, built from its POSTGRES_* environment and published port
+func composePgUri(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var compose composeFile
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return ""
+	}
+	for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+		svc := compose.Services[name]
+		image := svc.Image
+		if i := strings.LastIndex(image, "/"); i >= 0 {
+			image = image[i+1:]
+		}
+		image, _, _ = strings.Cut(image, ":")
+		if image != "postgres" && image != "postgis" {
+			continue
+		}
+
+		env := composeEnvironment(&svc.Environment)
+		user := env["POSTGRES_USER"]
+		if user == "" {
+			user = "postgres"
+		}
+		dbname := env["POSTGRES_DB"]
+		if dbname == "" {
+			dbname = user
+		}
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.User(user),
+			Host:   "localhost:" + composeHostPort(svc.Ports, "5432"),
+			Path:   "/" + dbname,
+		}
+		if password := env["POSTGRES_PASSWORD"]; password != "" {
+			u.User = url.UserPassword(user, password)
+		}
+		return u.String()
+	}
+	return ""
+}
+
+// composeEnvironment reads a service environment given either as a map or
+// as a list of KEY=VALUE entries
+func composeEnvironment(node *yaml.Node) map[string]string {
+	env := make(map[string]string)
+	switch node.Kind {
+	case yaml.MappingNode:
+		node.Decode(&env)
+	case yaml.SequenceNode:
+		var list []string
+		node.Decode(&list)
+		for _, entry := range list {
+			if k, v, ok := strings.Cut(entry, "="); ok {
+				env[k] = v
+			}
+		}
+	}
+	return env
+}
+
+// composeHostPort returns the host port published for the container port,
+// in "5433:5432" or "127.0.0.1:5433:5432" form; the container port itself
+// when it isn't published under another port
+func composeHostPort(ports []any, containerPort string) string {
+	for _, p := range ports {
+		parts := strings.Split(fmt.Sprint(p), ":")
+		if len(parts) < 2 {
+			continue
+		}
+		target, _, _ := strings.Cut(parts[len(parts)-1], "/")
+		if target == containerPort {
+			return parts[len(parts)-2]
+		}
+	}
+	return containerPort
+}
+
+// Snapshot returns the snapshot configuration matching the setup, nil when
+// nothing builds the schema. Migrations take precedence over a schema file,
+// which tools like dbmate dump from the migrated database.
+func (s *ProjectSetup) Snapshot() *SnapshotConfig {
+	switch {
+	case s.Migrations != "":
+		return &SnapshotConfig{Migrations: s.Migrations}
+	case s.MigrationCommand != "":
+		return &SnapshotConfig{MigrationCommand: s.MigrationCommand}
+	case s.Schema != "":
+		return &SnapshotConfig{Schema: s.Schema}
+	}
+	return nil
+}
+
+// Suggestions lists what was found and the setup steps that follow from it
+func (s *ProjectSetup) Suggestions() []string {
+	var out []string
+	switch s.MigrationTool {
+	case "":
+	case MigrationToolPlainSQL:
+		out = append(out, fmt.Sprintf("Found SQL migrations in %s/: 'regresql snapshot build' applies them in version order", s.MigrationsDir))
+	case MigrationToolGolangMigrate:
+		out = append(out, fmt.Sprintf("Found golang-migrate migrations in %s/: 'regresql snapshot build' applies the .up.sql files in order", s.MigrationsDir))
+	default:
+		out = append(out, fmt.Sprintf("Found %s migrations in %s/: 'regresql snapshot build' runs '%s' (needs %s on PATH)", s.MigrationTool, s.MigrationsDir, s.MigrationCommand, s.MigrationTool))
+	}
+	if s.Schema != "" {
+		if s.MigrationTool != "" {
+			out = append(out, fmt.Sprintf("Found %s: not used for snapshots since the migrations build the schema", s.Schema))
+		} else {
+			out = append(out, fmt.Sprintf("Found %s: 'regresql snapshot build' loads it as the schema", s.Schema))
+		}
+	}
+	if s.ComposeFile != "" {
+		out = append(out, fmt.Sprintf("Found a postgres service in %s: start it with 'docker compose up -d' before running tests", s.ComposeFile))
+	}
+	if s.PostgresVersion > 0 {
+		out = append(out, fmt.Sprintf(".tool-versions pins PostgreSQL %d: snapshot commands require pg_dump/pg_restore %d or newer", s.PostgresVersion, s.PostgresVersion))
+	}
+	return out
+}
@@ -42,3 +42,37 @@ func (p *InteractivePrompter) PromptAccept(queryName string, diff string) string
 		return "skip"
 	}
 }
+
+// PromptApprove shows a failing diff and asks user to approve/skip/quit.
+// Anything but an explicit approval skips, so a stray Enter never
+// overwrites an expected file.
+// Returns: "approve", "skip", "quit"
+func (p *InteractivePrompter) PromptApprove(testName string, diff string) string {
+	fmt.Printf("\nTest: %s\n", testName)
+	if diff != "" {
+		fmt.Printf("%s\n", diff)
+	}
+	fmt.Print("[a]pprove / [s]kip / [q]uit: ")
+
+	input, _ := p.reader.ReadString('\n')
+	input = strings.TrimSpace(strings.ToLower(input))
+
+	switch input {
+	case "a", "approve":
+		return "approve"
+	case "q", "quit":
+		return "quit"
+	default:
+		return "skip"
+	}
+}
+
+// PromptConfirm asks a yes/no question; Enter answers yes
+func (p *InteractivePrompter) PromptConfirm(question string) bool {
+	fmt.Printf("%s [Y/n]: ", question)
+
+	input, _ := p.reader.ReadString('\n')
+	input = strings.TrimSpace(strings.ToLower(input))
+
+	return input == "" || input == "y" || input == "yes"
+}
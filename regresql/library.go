@@ -150,7 +150,6 @@ func (p *Plan) CompareCostsData(ctx context.Context, db *sql.DB, baselines []Bas
 		}
 
 		// Detect quality issues (works even without baseline)
-		currentSig := ExtractPlanSignatureFromNode(&explainPlan.Plan)
 		opts := p.Query.GetRegressQLOptions()
 		ignoredTables := GetIgnoredSeqScanTables()
 		criticalTables := GetCriticalTables()
@@ -158,7 +157,7 @@ func (p *Plan) CompareCostsData(ctx context.Context, db *sql.DB, baselines []Bas
 			TotalCost:    explainPlan.Plan.TotalCost,
 			TotalBuffers: explainPlan.Plan.SharedHitBlocks + explainPlan.Plan.SharedReadBlocks,
 		}
-		result.PlanWarnings = DetectPlanQualityIssues(currentSig, opts, ignoredTables, criticalTables, costInfo)
+		result.PlanWarnings = DetectPlanQualityIssues(&explainPlan.Plan, opts, ignoredTables, criticalTables, costInfo)
 
 		results[i] = result
 	}
@@ -166,12 +165,29 @@ func (p *Plan) CompareCostsData(ctx context.Context, db *sql.DB, baselines []Bas
 	return results
 }
 
+// CreateBaselines EXPLAINs every binding of the plan. Like a test run it
+// works in a transaction that is rolled back, as the query's role and
+// between before_each and after_each, so the baselines are planned in the
+// state the tests see.
 func (p *Plan) CreateBaselines(ctx context.Context, db *sql.DB, useAnalyze bool) ([]Baseline, []*ExplainOutput, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := p.setUp(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	if err := p.chainCursors(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+
 	baselines := make([]Baseline, len(p.Names))
 	fullPlans := make([]*ExplainOutput, len(p.Names))
 
 	for i := range p.Names {
-		baseline, fullPlan, err := p.createSingleBaseline(ctx, db, i, useAnalyze)
+		baseline, fullPlan, err := p.createSingleBaseline(ctx, tx, i, useAnalyze)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -179,10 +195,13 @@ func (p *Plan) CreateBaselines(ctx context.Context, db *sql.DB, useAnalyze bool)
 		fullPlans[i] = fullPlan
 	}
 
+	if err := p.runHooks(ctx, tx, "after_each", p.AfterEach); err != nil {
+		return nil, nil, err
+	}
 	return baselines, fullPlans, nil
 }
 
-func (p *Plan) createSingleBaseline(ctx context.Context, db *sql.DB, index int, useAnalyze bool) (Baseline, *ExplainOutput, error) {
+func (p *Plan) createSingleBaseline(ctx context.Context, db Querier, index int, useAnalyze bool) (Baseline, *ExplainOutput, error) {
 	var explainPlan *ExplainOutput
 	var err error
 
@@ -12,6 +12,7 @@ type (
 		Root     string
 		Script   string
 		Command  string
+		Env      []string // extra KEY=VALUE env vars for Command
 		KeepTemp bool
 		Verbose  bool
 		Color    bool
@@ -51,7 +52,12 @@ func Migrate(opts MigrateOptions) int {
 
 	// 2. Restore snapshot (required for migration testing)
 	snapshotPath := GetSnapshotPath(cfg.Snapshot, opts.Root)
-	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) {
+	storage, remoteKey, err := RemoteSnapshot(cfg.Snapshot, snapshotPath)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return 1
+	}
+	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) && remoteKey == "" {
 		fmt.Printf("Error: snapshot not found: %s\n", snapshotPath)
 		fmt.Println("Migration testing requires a snapshot to restore the pre-migration state.")
 		fmt.Println("Run 'regresql snapshot build' to create a snapshot first.")
@@ -64,6 +70,8 @@ func Migrate(opts MigrateOptions) int {
 		InputPath:      snapshotPath,
 		Clean:          true,
 		TargetDatabase: cfg.Snapshot.RestoreDatabase,
+		Storage:        storage,
+		RemoteKey:      remoteKey,
 	}
 	if err := RestoreSnapshot(cfg.PgUri, restoreOpts); err != nil {
 		fmt.Printf("Error: failed to restore snapshot: %s\n", err)
@@ -155,7 +163,8 @@ func applyMigration(pguri string, opts MigrateOptions) error {
 
 	if opts.Command != "" {
 		fmt.Printf("  Command: %s\n", opts.Command)
-		return runMigrationCommand(opts.Command, pguri, opts.Verbose)
+		_, err := runMigrationCommand(opts.Command, pguri, opts.Env, opts.Verbose)
+		return err
 	}
 
 	return fmt.Errorf("no migration script or command specified")
@@ -167,12 +176,12 @@ func compareBeforeAfter(beforeDir, afterDir string) *MigrateResult {
 		Diffs: []MigrateDiff{},
 	}
 
-	// Walk through before directory and find all JSON files
+	// Walk through before directory and find all result files
 	err := filepath.Walk(beforeDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() || filepath.Ext(path) != ".json" {
+		if info.IsDir() || (filepath.Ext(path) != ".json" && !isPsqlResultFile(path)) {
 			return nil
 		}
 
@@ -229,7 +238,7 @@ func compareBeforeAfter(beforeDir, afterDir string) *MigrateResult {
 
 // reportMigrateResults prints the migration test results
 func reportMigrateResults(result *MigrateResult, opts MigrateOptions) {
-	useColor := shouldUseColor(opts.Color, opts.NoColor)
+	useColor := ShouldUseColor(opts.Color, opts.NoColor)
 
 	// Progress indicator
 	fmt.Print("MIGRATION IMPACT:\n  ")
@@ -369,16 +378,3 @@ func printMigrateDiff(diff *StructuredDiff, fullDiff, useColor bool) {
 		}
 	}
 }
-
-// shouldUseColor determines if colored output should be used
-func shouldUseColor(forceColor, noColor bool) bool {
-	if noColor {
-		return false
-	}
-	if forceColor {
-		return true
-	}
-	// Check if stdout is a terminal
-	stat, _ := os.Stdout.Stat()
-	return (stat.Mode() & os.ModeCharDevice) != 0
-}
@@ -0,0 +1,346 @@
+package regresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Migration file naming conventions, set with snapshot.migrations_naming.
+// Without one, versions are read from the leading digits (or a Flyway V
+// prefix) and files without a version are applied last, by name.
+const (
+	MigrationNamingGolangMigrate = "golang-migrate" // 1_init.up.sql, 1_init.down.sql
+	MigrationNamingFlyway        = "flyway"         // V1__init.sql, V1.1__users.sql, R__views.sql
+	MigrationNamingTimestamp     = "timestamp"      // 20240101120000_add_users.sql
+	MigrationNamingDbmate        = "dbmate"         // 20240101120000_add_users.sql with -- migrate:up/down
+	MigrationNamingGoose         = "goose"          // 00001_add_users.sql with -- +goose Up/Down
+)
+
+var (
+	flywayMigrationPattern  = regexp.MustCompile(`^([VRU])(\d+(?:[._]\d+)*)?__.+\.sql$`)
+	leadingVersionPattern   = regexp.MustCompile(`^(\d+)`)
+	migrationVersionPattern = regexp.MustCompile(`\d+`)
+)
+
+// migrationFile is a migration with the version it sorts by; files without
+// a version (Flyway repeatable migrations, unnumbered files) sort after the
+// versioned ones, and undo migrations after those
+type migrationFile struct {
+	path    string
+	version []uint64
+	undo    bool
+}
+
+// MigrationChecksum records one applied migration file, so a rebuild can be
+// asked for naming the files that changed
+type MigrationChecksum struct {
+	File    string `yaml:"file"`
+	Version string `yaml:"version,omitempty"` // empty for repeatable and unnumbered migrations
+	Hash    string `yaml:"hash"`
+}
+
+// ValidateMigrationsNaming checks a snapshot.migrations_naming value
+func ValidateMigrationsNaming(naming string) error {
+	switch naming {
+	case "", MigrationNamingGolangMigrate, MigrationNamingFlyway, MigrationNamingTimestamp,
+		MigrationNamingDbmate, MigrationNamingGoose:
+		return nil
+	}
+	return fmt.Errorf("invalid migrations_naming %q (want %s, %s, %s, %s or %s)", naming,
+		MigrationNamingGolangMigrate, MigrationNamingFlyway, MigrationNamingTimestamp,
+		MigrationNamingDbmate, MigrationNamingGoose)
+}
+
+// discoverMigrations finds the *.sql migrations of dir and sorts them by
+// version, numerically, so 2_users.sql runs before 10_orders.sql. Reverse
+// migrations (*.down.sql, Flyway U files) are skipped; with includeUndo,
+// Flyway undo migrations run last, newest version first.
+func discoverMigrations(dir, naming string, includeUndo bool) ([]string, error) {
+	if err := ValidateMigrationsNaming(naming); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migrationFile
+	seen := make(map[string]string)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".sql") {
+			continue
+		}
+		version, ok, err := migrationVersion(name, naming)
+		if err != nil {
+			return nil, err
+		}
+		undo := isFlywayUndo(name)
+		if undo && includeUndo {
+			migrations = append(migrations, migrationFile{path: filepath.Join(dir, name), version: version, undo: true})
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if naming != "" && version != nil {
+			key := fmt.Sprint(version)
+			if other, dup := seen[key]; dup {
+				return nil, fmt.Errorf("migrations %s and %s have the same version", other, name)
+			}
+			seen[key] = name
+		}
+		migrations = append(migrations, migrationFile{path: filepath.Join(dir, name), version: version})
+	}
+
+	slices.SortFunc(migrations, func(a, b migrationFile) int {
+		switch {
+		case a.undo != b.undo:
+			if a.undo {
+				return 1
+			}
+			return -1
+		case a.undo:
+			return slices.Compare(b.version, a.version)
+		}
+		switch {
+		case a.version == nil && b.version != nil:
+			return 1
+		case a.version != nil && b.version == nil:
+			return -1
+		}
+		if c := slices.Compare(a.version, b.version); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
+	})
+
+	files := make([]string, len(migrations))
+	for i, m := range migrations {
+		files[i] = m.path
+	}
+	return files, nil
+}
+
+// migrationVersion returns the version of a migration file name under a
+// naming convention; ok is false for files that aren't applied, and names
+// that don't follow an explicit convention are an error
+func migrationVersion(name, naming string) (version []uint64, ok bool, err error) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".down.sql") {
+		return nil, false, nil
+	}
+
+	switch naming {
+	case MigrationNamingFlyway:
+		m := flywayMigrationPattern.FindStringSubmatch(name)
+		if m == nil || (m[1] == "V" && m[2] == "") {
+			return nil, false, fmt.Errorf("migration %s doesn't follow the flyway naming (V<version>__<description>.sql or R__<description>.sql)", name)
+		}
+		version, err = parseMigrationVersion(m[2])
+		return version, err == nil && m[1] != "U", err
+
+	case MigrationNamingGolangMigrate:
+		m := leadingVersionPattern.FindStringSubmatch(name)
+		if m == nil || !strings.HasSuffix(lower, ".up.sql") {
+			return nil, false, fmt.Errorf("migration %s doesn't follow the golang-migrate naming (<version>_<title>.up.sql)", name)
+		}
+		version, err = parseMigrationVersion(m[1])
+		return version, err == nil, err
+
+	case MigrationNamingTimestamp, MigrationNamingDbmate:
+		m := leadingVersionPattern.FindStringSubmatch(name)
+		if m == nil {
+			return nil, false, fmt.Errorf("migration %s doesn't start with a timestamp (20240101120000_<title>.sql)", name)
+		}
+		version, err = parseMigrationVersion(m[1])
+		return version, err == nil, err
+
+	case MigrationNamingGoose:
+		m := leadingVersionPattern.FindStringSubmatch(name)
+		if m == nil {
+			return nil, false, fmt.Errorf("migration %s doesn't follow the goose naming (<version>_<title>.sql)", name)
+		}
+		version, err = parseMigrationVersion(m[1])
+		return version, err == nil, err
+	}
+
+	if m := flywayMigrationPattern.FindStringSubmatch(name); m != nil {
+		version, err = parseMigrationVersion(m[2])
+		return version, err == nil && m[1] != "U", err
+	}
+	if m := leadingVersionPattern.FindStringSubmatch(name); m != nil {
+		version, err = parseMigrationVersion(m[1])
+		return version, err == nil, err
+	}
+	return nil, true, nil
+}
+
+// isFlywayUndo reports whether name is a Flyway undo migration (U1__x.sql)
+func isFlywayUndo(name string) bool {
+	m := flywayMigrationPattern.FindStringSubmatch(name)
+	return m != nil && m[1] == "U"
+}
+
+// migrationUpSQL returns the part of a migration to apply. dbmate and goose
+// keep both directions in one file, the down section is left out; other
+// files are applied whole. ok is false when a dbmate or goose file has no
+// up section.
+func migrationUpSQL(content, naming string) (sql string, ok bool) {
+	var up, down string
+	switch naming {
+	case MigrationNamingDbmate:
+		up, down = "-- migrate:up", "-- migrate:down"
+	case MigrationNamingGoose:
+		up, down = "-- +goose up", "-- +goose down"
+	default:
+		return content, true
+	}
+
+	var b strings.Builder
+	inUp := false
+	for line := range strings.SplitAfterSeq(content, "\n") {
+		marker := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(marker, up):
+			inUp, ok = true, true
+			continue
+		case strings.HasPrefix(marker, down):
+			inUp = false
+			continue
+		}
+		if inUp {
+			b.WriteString(line)
+		}
+	}
+	return b.String(), ok
+}
+
+// migrationChecksums hashes each migration file with the version it was
+// applied as
+func migrationChecksums(files []string, naming string) ([]MigrationChecksum, error) {
+	checksums := make([]MigrationChecksum, 0, len(files))
+	for _, f := range files {
+		hash, err := computeSingleFileHash(f)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(f)
+		version, _, _ := migrationVersion(name, naming)
+		checksums = append(checksums, MigrationChecksum{
+			File:    name,
+			Version: formatMigrationVersion(version),
+			Hash:    hash,
+		})
+	}
+	return checksums, nil
+}
+
+func formatMigrationVersion(version []uint64) string {
+	parts := make([]string, len(version))
+	for i, n := range version {
+		parts[i] = strconv.FormatUint(n, 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// parseMigrationVersion splits a version such as 1.2 or 1_2 into its
+// numbers; an empty version (Flyway repeatable migrations) is nil
+func parseMigrationVersion(s string) ([]uint64, error) {
+	var version []uint64
+	for _, part := range migrationVersionPattern.FindAllString(s, -1) {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration version %s: %w", s, err)
+		}
+		version = append(version, n)
+	}
+	return version, nil
+}
+
+// failingStatement finds the statement of a migration script that failed:
+// from the error position when the server reports one, otherwise by
+// replaying the statements in a transaction that is rolled back. Only
+// transactional migrations are replayed: the earlier statements of any
+// other migration are already applied, so a replay would point at the wrong
+// statement and "" is returned instead.
+func failingStatement(db *sql.DB, script string, transactional bool, execErr error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(execErr, &pgErr) && pgErr.Position > 0 {
+		return statementAt(script, int(pgErr.Position))
+	}
+	if !transactional {
+		return ""
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return ""
+	}
+	defer tx.Rollback()
+	for _, stmt := range splitSQLStatements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return stmt
+		}
+	}
+	return ""
+}
+
+// nonTransactionalPattern matches statements PostgreSQL refuses to run in a
+// transaction block
+var nonTransactionalPattern = regexp.MustCompile(`(?is)^\s*(` +
+	`(create|drop)\s+(unique\s+)?index\s+concurrently|reindex\b.*\bconcurrently|` +
+	`vacuum|(create|drop)\s+(database|tablespace)|alter\s+system|` +
+	`(create|drop|alter)\s+subscription)\b`)
+
+// migrationTransactional reports whether a migration runs as a single
+// implicit transaction, so a failure leaves nothing applied. content is
+// the whole file, for the goose and dbmate markers that turn transactions
+// off; script is the SQL that was executed.
+func migrationTransactional(content, script string) bool {
+	for line := range strings.SplitSeq(content, "\n") {
+		marker := strings.ToLower(strings.TrimSpace(line))
+		if marker == "-- +goose no transaction" ||
+			(strings.HasPrefix(marker, "-- migrate:up") && strings.Contains(marker, "transaction:false")) {
+			return false
+		}
+	}
+	for _, stmt := range splitSQLStatements(script) {
+		if checkNoTransactionControl(stmt) != nil || nonTransactionalPattern.MatchString(stmt) {
+			return false
+		}
+	}
+	return true
+}
+
+// statementAt returns the statement of script holding the 1-based
+// character position of a server error
+func statementAt(script string, position int) string {
+	runes := []rune(script)
+	if position > len(runes) {
+		return ""
+	}
+	offset := len(string(runes[:position-1]))
+
+	cursor := 0
+	for _, stmt := range splitSQLStatements(script) {
+		i := strings.Index(script[cursor:], stmt)
+		if i < 0 {
+			break
+		}
+		cursor += i + len(stmt)
+		if offset < cursor {
+			return stmt
+		}
+	}
+	return ""
+}
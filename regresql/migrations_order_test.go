@@ -0,0 +1,264 @@
+package regresql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMigrations(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func migrationNames(files []string) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = filepath.Base(f)
+	}
+	return names
+}
+
+func TestDiscoverMigrationsOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		naming string
+		files  []string
+		want   []string
+	}{
+		{
+			name:  "numeric versions",
+			files: []string{"10_orders.sql", "2_users.sql", "1_init.sql", "seed.sql", "1_init.down.sql", "notes.txt"},
+			want:  []string{"1_init.sql", "2_users.sql", "10_orders.sql", "seed.sql"},
+		},
+		{
+			name:   "golang-migrate",
+			naming: MigrationNamingGolangMigrate,
+			files:  []string{"000010_orders.up.sql", "000002_users.up.sql", "000002_users.down.sql", "000001_init.up.sql"},
+			want:   []string{"000001_init.up.sql", "000002_users.up.sql", "000010_orders.up.sql"},
+		},
+		{
+			name:   "flyway",
+			naming: MigrationNamingFlyway,
+			files:  []string{"R__views.sql", "V1_10__late.sql", "V1_2__users.sql", "V1__init.sql", "U1_2__users.sql", "V2__orders.sql"},
+			want:   []string{"V1__init.sql", "V1_2__users.sql", "V1_10__late.sql", "V2__orders.sql", "R__views.sql"},
+		},
+		{
+			name:   "timestamp",
+			naming: MigrationNamingTimestamp,
+			files:  []string{"20240301090000_orders.sql", "20231215120000_init.sql", "20240101000000_users.sql"},
+			want:   []string{"20231215120000_init.sql", "20240101000000_users.sql", "20240301090000_orders.sql"},
+		},
+		{
+			name:   "dbmate",
+			naming: MigrationNamingDbmate,
+			files:  []string{"20240301090000_orders.sql", "20231215120000_init.sql"},
+			want:   []string{"20231215120000_init.sql", "20240301090000_orders.sql"},
+		},
+		{
+			name:   "goose",
+			naming: MigrationNamingGoose,
+			files:  []string{"00010_orders.sql", "00002_users.sql", "00001_init.sql"},
+			want:   []string{"00001_init.sql", "00002_users.sql", "00010_orders.sql"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := discoverMigrations(writeMigrations(t, tt.files...), tt.naming, false)
+			if err != nil {
+				t.Fatalf("discoverMigrations() error = %v", err)
+			}
+			if got := migrationNames(files); !equalStrings(got, tt.want) {
+				t.Errorf("discoverMigrations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		naming string
+		files  []string
+		errMsg string
+	}{
+		{"unknown naming", "liquibase", []string{"1_init.sql"}, "invalid migrations_naming"},
+		{"not golang-migrate", MigrationNamingGolangMigrate, []string{"1_init.sql"}, "golang-migrate naming"},
+		{"not flyway", MigrationNamingFlyway, []string{"init.sql"}, "flyway naming"},
+		{"no timestamp", MigrationNamingTimestamp, []string{"init.sql"}, "timestamp"},
+		{"not goose", MigrationNamingGoose, []string{"init.sql"}, "goose naming"},
+		{"duplicate version", MigrationNamingFlyway, []string{"V1__init.sql", "V1_0__init.sql", "V01__again.sql"}, "same version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := discoverMigrations(writeMigrations(t, tt.files...), tt.naming, false)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("discoverMigrations() error = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDiscoverMigrationsIncludeUndo(t *testing.T) {
+	dir := writeMigrations(t, "V1__init.sql", "V2__users.sql", "R__views.sql", "U1__init.sql", "U2__users.sql")
+
+	for _, naming := range []string{MigrationNamingFlyway, ""} {
+		files, err := discoverMigrations(dir, naming, true)
+		if err != nil {
+			t.Fatalf("discoverMigrations(%q) error = %v", naming, err)
+		}
+		want := []string{"V1__init.sql", "V2__users.sql", "R__views.sql", "U2__users.sql", "U1__init.sql"}
+		if got := migrationNames(files); !equalStrings(got, want) {
+			t.Errorf("discoverMigrations(%q) = %v, want %v", naming, got, want)
+		}
+	}
+}
+
+func TestMigrationUpSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		naming  string
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{
+			name:    "dbmate",
+			naming:  MigrationNamingDbmate,
+			content: "-- migrate:up\nCREATE TABLE users (id int);\n\n-- migrate:down\nDROP TABLE users;\n",
+			want:    "CREATE TABLE users (id int);\n\n",
+			wantOK:  true,
+		},
+		{
+			name:    "goose",
+			naming:  MigrationNamingGoose,
+			content: "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE users (id int);\n-- +goose StatementEnd\n-- +goose Down\nDROP TABLE users;\n",
+			want:    "-- +goose StatementBegin\nCREATE TABLE users (id int);\n-- +goose StatementEnd\n",
+			wantOK:  true,
+		},
+		{
+			name:    "dbmate without up section",
+			naming:  MigrationNamingDbmate,
+			content: "CREATE TABLE users (id int);\n",
+			wantOK:  false,
+		},
+		{
+			name:    "flyway is applied whole",
+			naming:  MigrationNamingFlyway,
+			content: "-- migrate:down\nCREATE TABLE users (id int);\n",
+			want:    "-- migrate:down\nCREATE TABLE users (id int);\n",
+			wantOK:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := migrationUpSQL(tt.content, tt.naming)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("migrationUpSQL() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMigrationChecksums(t *testing.T) {
+	dir := writeMigrations(t, "V1_2__users.sql", "R__views.sql")
+	files := []string{filepath.Join(dir, "V1_2__users.sql"), filepath.Join(dir, "R__views.sql")}
+
+	checksums, err := migrationChecksums(files, MigrationNamingFlyway)
+	if err != nil {
+		t.Fatalf("migrationChecksums() error = %v", err)
+	}
+	if len(checksums) != 2 {
+		t.Fatalf("migrationChecksums() = %v, want 2 entries", checksums)
+	}
+	if checksums[0].File != "V1_2__users.sql" || checksums[0].Version != "1.2" || !strings.HasPrefix(checksums[0].Hash, "sha256:") {
+		t.Errorf("checksums[0] = %+v", checksums[0])
+	}
+	if checksums[1].Version != "" {
+		t.Errorf("repeatable migration version = %q, want empty", checksums[1].Version)
+	}
+}
+
+func TestMigrationChangeErrorNamesModifiedFiles(t *testing.T) {
+	info := &SnapshotInfo{
+		MigrationsDir:     "db/migrations",
+		MigrationsHash:    "sha256:0000000000000000000000000000",
+		MigrationsApplied: []string{"V1__init.sql", "V2__users.sql"},
+		MigrationChecksums: []MigrationChecksum{
+			{File: "V1__init.sql", Version: "1", Hash: "sha256:aaa"},
+			{File: "V2__users.sql", Version: "2", Hash: "sha256:bbb"},
+		},
+	}
+	current := []MigrationChecksum{
+		{File: "V1__init.sql", Version: "1", Hash: "sha256:aaa"},
+		{File: "V2__users.sql", Version: "2", Hash: "sha256:ccc"},
+	}
+
+	err := migrationChangeError(info, "sha256:1111111111111111111111111111", info.MigrationsApplied, current)
+	if err == nil || !strings.Contains(err.Error(), "~ V2__users.sql") || strings.Contains(err.Error(), "V1__init.sql") {
+		t.Errorf("migrationChangeError() = %v, want only V2__users.sql modified", err)
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	script := `-- users
+CREATE TABLE users (id int);
+INSERT INTO users VALUES (1);
+/* né */ ALTER TABLE users ADD COLUMN nam text;
+`
+	tests := []struct {
+		position int
+		want     string
+	}{
+		{1, "CREATE TABLE users (id int)"},
+		{strings.Index(script, "INSERT") + 5, "INSERT INTO users VALUES (1)"},
+		{len([]rune(script[:strings.Index(script, "nam text")])) + 1, "ALTER TABLE users ADD COLUMN nam text"},
+		{len(script) + 10, ""},
+	}
+	for _, tt := range tests {
+		if got := statementAt(script, tt.position); got != tt.want {
+			t.Errorf("statementAt(%d) = %q, want %q", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestMigrationTransactional(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"plain", "CREATE TABLE users (id int);\nINSERT INTO users VALUES (1);\n", true},
+		{"explicit transaction", "BEGIN;\nCREATE TABLE users (id int);\nCOMMIT;\n", false},
+		{"concurrent index", "CREATE TABLE users (id int);\nCREATE INDEX CONCURRENTLY users_id ON users (id);\n", false},
+		{"unique concurrent index", "create unique index concurrently users_id on users (id);\n", false},
+		{"vacuum", "VACUUM users;\n", false},
+		{"goose no transaction", "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE TABLE users (id int);\n", false},
+		{"dbmate transaction false", "-- migrate:up transaction:false\nCREATE TABLE users (id int);\n", false},
+		{"index named concurrently", "CREATE INDEX users_concurrently ON users (id);\n", true},
+	}
+	for _, tt := range tests {
+		if got := migrationTransactional(tt.content, tt.content); got != tt.want {
+			t.Errorf("%s: migrationTransactional() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFailingStatementSkipsNonTransactional(t *testing.T) {
+	db, log := openRecordingDB(t)
+	script := "CREATE TABLE users (id int);\nCREATE INDEX CONCURRENTLY users_id ON users (id);\n"
+	if got := failingStatement(db, script, false, errors.New("boom")); got != "" {
+		t.Errorf("failingStatement() = %q, want the whole file reported", got)
+	}
+	if stmts := log.Statements(); len(stmts) != 0 {
+		t.Errorf("non-transactional migration replayed: %v", stmts)
+	}
+}
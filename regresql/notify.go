@@ -0,0 +1,110 @@
+package regresql
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request body,
+// keyed with notifications.webhook.secret, as "sha256=<hex>"
+const WebhookSignatureHeader = "X-Regresql-Signature"
+
+const webhookTimeout = 10 * time.Second
+
+type (
+	// webhookPayload is the body POSTed on critical plan regressions
+	webhookPayload struct {
+		Event       string              `json:"event"`
+		Timestamp   string              `json:"timestamp"`
+		Regressions []webhookRegression `json:"regressions"`
+	}
+
+	webhookRegression struct {
+		QueryFile           string   `json:"query_file"`
+		Binding             string   `json:"binding,omitempty"`
+		Table               string   `json:"table"`
+		OldScan             string   `json:"old_scan"`
+		NewScan             string   `json:"new_scan"`
+		CostIncreasePercent float64  `json:"cost_increase_percent"`
+		Message             string   `json:"message"`
+		Recommendations     []string `json:"recommendations,omitempty"`
+	}
+)
+
+// NotifyWebhook POSTs the critical plan regressions of the results to
+// notifications.webhook.url when on_critical_regression is set, in one JSON
+// payload. Nothing is sent when there are none. With a secret, the body is
+// signed in the WebhookSignatureHeader.
+func NotifyWebhook(cfg *NotificationConfig, results []TestResult) error {
+	if cfg == nil || cfg.Webhook == nil || cfg.Webhook.URL == "" || !cfg.Webhook.OnCriticalRegression {
+		return nil
+	}
+	regressions := criticalWebhookRegressions(results)
+	if len(regressions) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:       "critical_plan_regression",
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Regressions: regressions,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(cfg.Webhook.Secret, body))
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func criticalWebhookRegressions(results []TestResult) []webhookRegression {
+	var out []webhookRegression
+	for _, r := range results {
+		for _, reg := range r.PlanRegressions {
+			if reg.Severity != "critical" {
+				continue
+			}
+			out = append(out, webhookRegression{
+				QueryFile:           filepath.ToSlash(r.QueryFile),
+				Binding:             r.BindingName,
+				Table:               reg.Table,
+				OldScan:             reg.OldScan,
+				NewScan:             reg.NewScan,
+				CostIncreasePercent: r.PercentIncrease,
+				Message:             reg.Message,
+				Recommendations:     reg.Recommendations,
+			})
+		}
+	}
+	return out
+}
+
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
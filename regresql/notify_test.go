@@ -0,0 +1,76 @@
+package regresql
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifyWebhook(t *testing.T) {
+	var (
+		requests  int
+		signature string
+		payload   webhookPayload
+		body      []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		signature = r.Header.Get(WebhookSignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	results := []TestResult{
+		{QueryFile: "sql/users.sql", Status: "passed"},
+		{
+			QueryFile: "sql/orders.sql", BindingName: "1", Status: "failed", PercentIncrease: 340,
+			PlanRegressions: []PlanRegression{
+				{Table: "orders", OldScan: "Index Scan", NewScan: "Seq Scan", Severity: "critical", Recommendations: []string{"ANALYZE orders"}},
+				{Table: "users", OldScan: "Index Scan", NewScan: "Bitmap Heap Scan", Severity: "warning"},
+			},
+		},
+	}
+	cfg := &NotificationConfig{Webhook: &WebhookConfig{URL: server.URL, OnCriticalRegression: true, Secret: "s3cret"}}
+
+	if err := NotifyWebhook(cfg, results); err != nil {
+		t.Fatalf("NotifyWebhook: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+	if want := "sha256=" + webhookSignature("s3cret", body); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+	if len(payload.Regressions) != 1 {
+		t.Fatalf("regressions = %+v, want only the critical one", payload.Regressions)
+	}
+	if r := payload.Regressions[0]; r.QueryFile != "sql/orders.sql" || r.Table != "orders" || r.NewScan != "Seq Scan" ||
+		r.CostIncreasePercent != 340 || len(r.Recommendations) != 1 {
+		t.Errorf("regression = %+v", r)
+	}
+
+	// nothing critical, or not enabled: no request
+	if err := NotifyWebhook(cfg, results[:1]); err != nil || requests != 1 {
+		t.Errorf("sent without critical regressions (err %v)", err)
+	}
+	cfg.Webhook.OnCriticalRegression = false
+	if err := NotifyWebhook(cfg, results); err != nil || requests != 1 {
+		t.Errorf("sent with on_critical_regression off (err %v)", err)
+	}
+}
+
+func TestNotifyWebhookErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := &NotificationConfig{Webhook: &WebhookConfig{URL: server.URL, OnCriticalRegression: true}}
+	results := []TestResult{{PlanRegressions: []PlanRegression{{Table: "t", Severity: "critical"}}}}
+	if err := NotifyWebhook(cfg, results); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
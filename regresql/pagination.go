@@ -0,0 +1,142 @@
+package regresql
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// Parameters set on the bindings generated from a plan's pagination key
+const (
+	PageOffsetParam = "__page_offset"
+	PageSizeParam   = "__page_size"
+	PageCursorParam = "__page_cursor"
+
+	PaginationOffset = "offset"
+	PaginationKeyset = "keyset"
+)
+
+// PaginationConfig generates one binding per page, named page_1 to page_N.
+// Offset pages bind :__page_offset and :__page_size. Keyset pages bind
+// :__page_size and :__page_cursor, the cursor_col value of the last row of
+// the previous page (seed for the first page), so they run in order.
+type PaginationConfig struct {
+	Type      string         `yaml:"type" json:"type"`
+	PageSize  int            `yaml:"page_size" json:"page_size"`
+	Pages     int            `yaml:"pages" json:"pages"`
+	CursorCol string         `yaml:"cursor_col,omitempty" json:"cursor_col,omitempty"`
+	Seed      any            `yaml:"seed,omitempty" json:"seed,omitempty"`
+	Params    map[string]any `yaml:"params,omitempty" json:"params,omitempty"` // other parameters of every page
+}
+
+func (c *PaginationConfig) validate() error {
+	switch c.Type {
+	case PaginationOffset:
+	case PaginationKeyset:
+		if c.CursorCol == "" {
+			return fmt.Errorf("keyset pagination requires cursor_col")
+		}
+	default:
+		return fmt.Errorf("unknown pagination type %q (expected offset or keyset)", c.Type)
+	}
+	if c.PageSize < 1 {
+		return fmt.Errorf("pagination page_size must be at least 1")
+	}
+	if c.Pages < 1 {
+		return fmt.Errorf("pagination pages must be at least 1")
+	}
+	return nil
+}
+
+// pageName is the binding name of page n, counting from 1
+func pageName(n int) string {
+	return "page_" + strconv.Itoa(n)
+}
+
+// pageNames lists the binding names the pagination generates
+func (c *PaginationConfig) pageNames() []string {
+	names := make([]string, c.Pages)
+	for i := range names {
+		names[i] = pageName(i + 1)
+	}
+	return names
+}
+
+// bindings generates the page bindings; keyset cursors after the first page
+// are filled in by Plan.Execute, or Plan.chainCursors when the pages are only
+// planned
+func (c *PaginationConfig) bindings() []map[string]any {
+	out := make([]map[string]any, c.Pages)
+	for i := range out {
+		b := maps.Clone(c.Params)
+		if b == nil {
+			b = make(map[string]any)
+		}
+		b[PageSizeParam] = c.PageSize
+		if c.Type == PaginationOffset {
+			b[PageOffsetParam] = i * c.PageSize
+		} else if i == 0 {
+			b[PageCursorParam] = c.Seed
+		}
+		out[i] = b
+	}
+	return out
+}
+
+// nextCursor returns the cursor_col value of the last row of a page; after an
+// empty page the cursor stays, so the following pages are empty too
+func (c *PaginationConfig) nextCursor(page ResultSet, previous any) (any, error) {
+	col := slices.Index(page.Cols, c.CursorCol)
+	if col < 0 {
+		return nil, fmt.Errorf("pagination cursor_col %q is not a result column (columns: %v)", c.CursorCol, page.Cols)
+	}
+	if len(page.Rows) == 0 {
+		return previous, nil
+	}
+	return page.Rows[len(page.Rows)-1][col], nil
+}
+
+// chainCursor sets the keyset cursor of binding i from the result of the page
+// before it
+func (p *Plan) chainCursor(i int) error {
+	if p.Pagination == nil || p.Pagination.Type != PaginationKeyset || i == 0 {
+		return nil
+	}
+	if slices.Index(p.Pagination.pageNames(), p.Names[i]) < 1 {
+		return nil // not a page, or the first one
+	}
+	cursor, err := p.Pagination.nextCursor(p.ResultSets[i-1], p.Bindings[i-1][PageCursorParam])
+	if err != nil {
+		return err
+	}
+	p.Bindings[i][PageCursorParam] = cursor
+	return nil
+}
+
+// chainCursors runs every keyset page but the last in order, filling in the
+// cursor of the page after it, so pages that are only EXPLAINed, such as
+// when creating baselines, are planned with the cursor Execute would bind
+func (p *Plan) chainCursors(ctx context.Context, q Querier) error {
+	if p.Pagination == nil || p.Pagination.Type != PaginationKeyset {
+		return nil
+	}
+	pages := p.Pagination.pageNames()
+	p.ResultSets = make([]ResultSet, len(p.Bindings))
+	for i := range p.Bindings {
+		if err := p.chainCursor(i); err != nil {
+			return err
+		}
+		if i+1 >= len(p.Names) || slices.Index(pages, p.Names[i+1]) < 1 {
+			continue // no page follows
+		}
+		sql, args := p.Query.Prepare(p.Bindings[i])
+		res, err := p.runQuery(ctx, q, p.Names[i], sql, args...)
+		if err != nil {
+			return fmt.Errorf("error executing %s for the next page's cursor: %w", p.Names[i], err)
+		}
+		p.ResultSets[i] = *res
+	}
+	return nil
+}
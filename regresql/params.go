@@ -0,0 +1,229 @@
+package regresql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/boringsql/queries"
+)
+
+// namedParams is the result of scanning a query for :name placeholders
+type namedParams struct {
+	ordinal    string         // query text with placeholders replaced by $N
+	args       []string       // every occurrence, in order, duplicates kept
+	mapping    map[string]int // name -> ordinal
+	positional bool           // query uses $N parameters
+	atSign     bool           // query uses @name parameters
+}
+
+// scanNamedParams rewrites :name (and psql style :'name', :"name")
+// placeholders to $N. Unlike a plain regexp it understands SQL lexing, so
+// string literals, quoted identifiers, dollar-quoted bodies, comments and
+// ::type casts are left untouched. The same name always maps to the same $N.
+func scanNamedParams(text string) namedParams {
+	res := namedParams{mapping: make(map[string]int)}
+
+	var out strings.Builder
+	out.Grow(len(text))
+
+	n := len(text)
+	for i := 0; i < n; {
+		c := text[i]
+
+		switch {
+		case c == '-' && i+1 < n && text[i+1] == '-':
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			out.WriteString(text[i : i+end])
+			i += end
+
+		case c == '/' && i+1 < n && text[i+1] == '*':
+			end := skipBlockComment(text, i)
+			out.WriteString(text[i:end])
+			i = end
+
+		case c == '\'':
+			escapes := i > 0 && (text[i-1] == 'E' || text[i-1] == 'e') && (i < 2 || !isIdentChar(text[i-2]))
+			end := skipQuoted(text, i, '\'', escapes)
+			out.WriteString(text[i:end])
+			i = end
+
+		case c == '"':
+			end := skipQuoted(text, i, '"', false)
+			out.WriteString(text[i:end])
+			i = end
+
+		case c == '$' && (i == 0 || !isIdentChar(text[i-1])):
+			if j := i + 1; j < n && isDigit(text[j]) {
+				for j < n && isDigit(text[j]) {
+					j++
+				}
+				res.positional = true
+				out.WriteString(text[i:j])
+				i = j
+				continue
+			}
+			if tag, ok := dollarTag(text, i); ok {
+				end := strings.Index(text[i+len(tag):], tag)
+				if end < 0 {
+					end = n
+				} else {
+					end = i + len(tag) + end + len(tag)
+				}
+				out.WriteString(text[i:end])
+				i = end
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == ':' && i+1 < n && text[i+1] == ':':
+			out.WriteString("::")
+			i += 2
+
+		case c == ':' && (i == 0 || text[i-1] != ':'):
+			name, end := paramName(text, i+1)
+			if name == "" {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			res.args = append(res.args, name)
+			ord, ok := res.mapping[name]
+			if !ok {
+				ord = len(res.mapping) + 1
+				res.mapping[name] = ord
+			}
+			fmt.Fprintf(&out, "$%d", ord)
+			i = end
+
+		case c == '@' && (i == 0 || text[i-1] != '@'):
+			if name, _ := paramName(text, i+1); name != "" {
+				res.atSign = true
+			}
+			out.WriteByte(c)
+			i++
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	res.ordinal = out.String()
+	return res
+}
+
+// bindNamedParams replaces the library's regexp based handling of :name
+// parameters with scanNamedParams. Queries using $N or @name parameters are
+// left as parsed.
+func bindNamedParams(q *queries.Query) {
+	p := scanNamedParams(q.Raw)
+	if p.positional || p.atSign {
+		return
+	}
+
+	q.Args = p.args
+	q.Mapping = p.mapping
+	q.NamedArgs = make([]sql.NamedArg, len(p.mapping))
+	for name, ord := range p.mapping {
+		q.NamedArgs[ord-1] = sql.Named(name, nil)
+	}
+	q.OrdinalQuery = fmt.Sprintf("-- name: %s\n%s", q.Name, p.ordinal)
+}
+
+// paramName reads an identifier starting at i, optionally wrapped in single
+// or double quotes, and returns it with the offset just past it
+func paramName(text string, i int) (string, int) {
+	var quote byte
+	if i < len(text) && (text[i] == '\'' || text[i] == '"') {
+		quote = text[i]
+		i++
+	}
+	if i >= len(text) || !isIdentStart(text[i]) {
+		return "", i
+	}
+
+	start := i
+	for i < len(text) && isIdentChar(text[i]) {
+		i++
+	}
+	name := text[start:i]
+
+	if quote != 0 {
+		if i >= len(text) || text[i] != quote {
+			return "", i
+		}
+		i++
+	}
+	return name, i
+}
+
+// skipQuoted returns the offset past a quoted string or identifier starting
+// at i. Doubled quotes are escapes; backslash escapes apply to E'...' strings.
+func skipQuoted(text string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(text) && text[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(text)
+}
+
+// skipBlockComment returns the offset past a /* */ comment starting at i;
+// PostgreSQL block comments nest
+func skipBlockComment(text string, i int) int {
+	depth := 0
+	for j := i; j < len(text)-1; j++ {
+		switch {
+		case text[j] == '/' && text[j+1] == '*':
+			depth++
+			j++
+		case text[j] == '*' && text[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(text)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i
+func dollarTag(text string, i int) (string, bool) {
+	j := i + 1
+	if j < len(text) && isIdentStart(text[j]) {
+		for j < len(text) && isIdentChar(text[j]) {
+			j++
+		}
+	}
+	if j < len(text) && text[j] == '$' {
+		return text[i : j+1], true
+	}
+	return "", false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
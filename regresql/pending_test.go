@@ -171,3 +171,50 @@ func TestCompareResultSetsToResultsWithExpectedFile(t *testing.T) {
 		t.Errorf("Expected status='passed', got '%s' (error: %s)", result.Status, result.Error)
 	}
 }
+
+func TestCompareResultSetsToResultsColumns(t *testing.T) {
+	regressDir := t.TempDir()
+	outDir := filepath.Join(regressDir, "out")
+	expectedDir := filepath.Join(regressDir, "expected")
+	for _, dir := range []string{outDir, expectedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new column and a changed timestamp, neither of them asserted
+	expected := `{"columns":["id","status","created_at"],"rows":[[1,"paid","2026-01-01"]]}`
+	actualFile := filepath.Join(outDir, "orders.json")
+	if err := os.WriteFile(filepath.Join(expectedDir, "orders.json"), []byte(expected), 0644); err != nil {
+		t.Fatal(err)
+	}
+	actual := ResultSet{
+		Filename: actualFile,
+		Cols:     []string{"id", "status", "created_at", "note"},
+		Rows:     [][]any{{float64(1), "paid", "2026-02-01", "new"}},
+	}
+
+	compare := func(annotation string) TestResult {
+		t.Helper()
+		q := queryWithMetadata(t, "-- name: orders\n-- regresql: "+annotation+"\nselect 1;\n")
+		plan := &Plan{Query: q, ResultSets: []ResultSet{actual}, Names: []string{"1"}, Bindings: []map[string]any{{}}}
+		results := plan.CompareResultSetsToResults(regressDir, expectedDir)
+		if len(results) != 1 {
+			t.Fatalf("got %d results", len(results))
+		}
+		return results[0]
+	}
+
+	if r := compare("assert_columns=id,status"); r.Status != "passed" {
+		t.Errorf("assert_columns: status %s (%s)", r.Status, r.Error)
+	}
+	if r := compare("assert_columns=id,note"); r.Status != "failed" || r.Error == "" {
+		t.Errorf("assert_columns on a column missing from expected: status %s, error %q", r.Status, r.Error)
+	}
+	if r := compare("ignore_columns=created_at"); r.Status != "passed" {
+		t.Errorf("ignore_columns: status %s (%s)", r.Status, r.Error)
+	}
+	if r := compare("ignore_columns=status"); r.Status != "failed" {
+		t.Errorf("ignore_columns=status: status %s, want failed on created_at", r.Status)
+	}
+}
@@ -0,0 +1,321 @@
+package regresql
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PgTAPOptions struct {
+	Root       string
+	Dir        string // directory with pgTAP test files (default: pgtap_dir or sql/tests)
+	Pattern    string // file name glob (default: *.sql)
+	RunFilter  string
+	FormatName string
+	OutputPath string
+	Color      bool
+	NoColor    bool
+	Verbose    bool
+}
+
+const (
+	DefaultPgTAPDir     = "sql/tests"
+	DefaultPgTAPPattern = "*.sql"
+)
+
+var (
+	// pgTAP test files declare their test count with plan() or no_plan()
+	pgtapPlanCall = regexp.MustCompile(`(?i)\bselect\s+(\*\s+from\s+)?(no_)?plan\s*\(`)
+
+	tapPlanLine = regexp.MustCompile(`^1\.\.(\d+)`)
+	tapTestLine = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(.*))?$`)
+)
+
+// DiscoverPgTAPFiles returns the files below dir whose name matches pattern
+// and that contain pgTAP plan boilerplate, in lexical order
+func DiscoverPgTAPFiles(dir, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = DefaultPgTAPPattern
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pgTAP pattern %q: %w", pattern, err)
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, d.Name()); !ok {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if pgtapPlanCall.Match(data) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover pgTAP files in %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// runPgTAPFile runs a pgTAP file through psql and converts its TAP output
+// into test results. A psql error is reported as a failed result after the
+// tests that ran before it.
+func runPgTAPFile(pguri, path string) []TestResult {
+	start := time.Now()
+	cmd := exec.Command("psql", pguri, "-X", "-q", "-A", "-t", "-v", "ON_ERROR_STOP=1", "-f", path)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	results := ParsePgTAPOutput(path, stdout.String())
+	if runErr != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = runErr.Error()
+		}
+		results = append(results, TestResult{
+			Name:      pgtapTestName(path, 0, "psql"),
+			Type:      "pgtap",
+			Status:    "failed",
+			Error:     fmt.Sprintf("psql failed: %s", msg),
+			QueryFile: path,
+		})
+	}
+
+	// TAP carries no per-test timing; spread the file's run time evenly
+	if len(results) > 0 {
+		each := time.Since(start).Seconds() / float64(len(results))
+		for i := range results {
+			results[i].Duration = each
+		}
+	}
+	return results
+}
+
+// ParsePgTAPOutput converts the TAP stream produced by a pgTAP file into
+// test results. "# SKIP" and failing "# TODO" tests are reported as skipped,
+// diagnostics after a failed test become its error, and a test count that
+// does not match the plan adds a failed result.
+func ParsePgTAPOutput(path, output string) []TestResult {
+	var results []TestResult
+	planned, ran := -1, 0
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// indented lines belong to subtests, summarized by their parent
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		if m := tapPlanLine.FindStringSubmatch(line); m != nil {
+			planned, _ = strconv.Atoi(m[1])
+			continue
+		}
+
+		if strings.HasPrefix(line, "Bail out!") {
+			results = append(results, TestResult{
+				Name:      pgtapTestName(path, 0, "bail out"),
+				Type:      "pgtap",
+				Status:    "failed",
+				Error:     strings.TrimSpace(strings.TrimPrefix(line, "Bail out!")),
+				QueryFile: path,
+			})
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if n := len(results); n > 0 && results[n-1].Status == "failed" {
+				diag := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+				if results[n-1].Error != "" {
+					results[n-1].Error += "\n"
+				}
+				results[n-1].Error += diag
+			}
+			continue
+		}
+
+		m := tapTestLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ran++
+		number := ran
+		if m[2] != "" {
+			number, _ = strconv.Atoi(m[2])
+		}
+		result := TestResult{
+			Name:      pgtapTestName(path, number, m[3]),
+			Type:      "pgtap",
+			Status:    "passed",
+			QueryFile: path,
+		}
+		if m[1] != "" {
+			result.Status = "failed"
+		}
+
+		directive := strings.ToUpper(m[4])
+		switch {
+		case strings.HasPrefix(directive, "SKIP"):
+			result.Status = "skipped"
+			result.Error = strings.TrimSpace(m[4][len("SKIP"):])
+		case strings.HasPrefix(directive, "TODO") && result.Status == "failed":
+			result.Status = "skipped"
+			result.Error = "TODO " + strings.TrimSpace(m[4][len("TODO"):])
+		}
+		results = append(results, result)
+	}
+
+	if planned >= 0 && ran != planned {
+		results = append(results, TestResult{
+			Name:      pgtapTestName(path, 0, "plan"),
+			Type:      "pgtap",
+			Status:    "failed",
+			Error:     fmt.Sprintf("planned %d tests but ran %d", planned, ran),
+			QueryFile: path,
+		})
+	}
+	return results
+}
+
+// pgtapTestName names a pgTAP test after its file, e.g. "users_test #3:
+// users.email is unique"; number 0 is used for file level results
+func pgtapTestName(path string, number int, description string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if number > 0 {
+		name += fmt.Sprintf(" #%d", number)
+	}
+	if description != "" {
+		name += ": " + description
+	}
+	return name
+}
+
+// pgtapResults runs the pgTAP files configured for the suite that match the
+// run filter
+func (s *Suite) pgtapResults(pguri string) ([]TestResult, error) {
+	files, err := DiscoverPgTAPFiles(s.pgtapDir, s.pgtapPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TestResult
+	for _, path := range files {
+		fileName := filepath.Base(path)
+		if !s.matchesRunFilter(fileName, strings.TrimSuffix(fileName, filepath.Ext(fileName))) {
+			continue
+		}
+		results = append(results, runPgTAPFile(pguri, path)...)
+	}
+	return results, nil
+}
+
+// SetPgTAP makes testQueries also run the pgTAP files in dir matching
+// pattern; dir is relative to the suite root
+func (s *Suite) SetPgTAP(dir, pattern string) {
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.Root, dir)
+	}
+	s.pgtapDir = dir
+	s.pgtapPattern = pattern
+}
+
+// PgTAP runs the pgTAP test files of a project and reports them through the
+// regular output formatters
+func PgTAP(opts PgTAPOptions) {
+	config, err := ReadConfig(opts.Root)
+	if err != nil {
+		fmt.Print(err.Error())
+		os.Exit(3)
+	}
+	SetGlobalConfig(config)
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = config.PgTAPDir
+	}
+	if dir == "" {
+		dir = DefaultPgTAPDir
+	}
+
+	if err := TestConnectionString(config.PgUri); err != nil {
+		fmt.Print(err.Error())
+		os.Exit(2)
+	}
+
+	formatName := opts.FormatName
+	if formatName == "" {
+		formatName = "console"
+	}
+	formatter, err := GetFormatter(formatName)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(14)
+	}
+	if cf, ok := formatter.(*ConsoleFormatter); ok {
+		cf.SetOptions(ConsoleOptions{
+			Color:   opts.Color,
+			NoColor: opts.NoColor,
+			Verbose: opts.Verbose,
+		})
+	}
+
+	suite := newSuite(opts.Root)
+	suite.SetRunFilter(opts.RunFilter)
+	suite.SetPgTAP(dir, opts.Pattern)
+
+	summary, err := suite.testPgTAP(config.PgUri, formatter, opts.OutputPath)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		os.Exit(13)
+	}
+	if summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func (s *Suite) testPgTAP(pguri string, formatter OutputFormatter, outputPath string) (*TestSummary, error) {
+	w, close, err := getWriter(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer close()
+
+	summary := NewTestSummary()
+	if err := formatter.Start(w); err != nil {
+		return nil, err
+	}
+
+	results, err := s.pgtapResults(pguri)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		summary.AddResult(r)
+		if err := formatter.AddResult(r, w); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := formatter.Finish(summary, w); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,94 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePgTAPOutput(t *testing.T) {
+	output := `1..5
+ok 1 - users table exists
+not ok 2 - users.email is unique
+# Failed test 2: "users.email is unique"
+#         have: 2
+#         want: 1
+ok 3 # SKIP no replica
+not ok 4 - refunds # TODO not implemented
+    ok 1 - nested subtest
+ok 5 - orders has primary key
+`
+	results := ParsePgTAPOutput("sql/tests/users_test.sql", output)
+
+	want := []struct{ name, status string }{
+		{"users_test #1: users table exists", "passed"},
+		{"users_test #2: users.email is unique", "failed"},
+		{"users_test #3", "skipped"},
+		{"users_test #4: refunds", "skipped"},
+		{"users_test #5: orders has primary key", "passed"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		if results[i].Name != w.name || results[i].Status != w.status {
+			t.Errorf("result %d = %q %s, want %q %s", i, results[i].Name, results[i].Status, w.name, w.status)
+		}
+		if results[i].Type != "pgtap" || results[i].QueryFile != "sql/tests/users_test.sql" {
+			t.Errorf("result %d: Type = %q, QueryFile = %q", i, results[i].Type, results[i].QueryFile)
+		}
+	}
+
+	if got := results[1].Error; got != "Failed test 2: \"users.email is unique\"\nhave: 2\nwant: 1" {
+		t.Errorf("diagnostics = %q", got)
+	}
+	if results[2].Error != "no replica" {
+		t.Errorf("skip reason = %q", results[2].Error)
+	}
+}
+
+func TestParsePgTAPOutputPlanMismatch(t *testing.T) {
+	results := ParsePgTAPOutput("orders_test.sql", "1..3\nok 1 - one\nok 2 - two\n")
+
+	last := results[len(results)-1]
+	if len(results) != 3 || last.Status != "failed" || last.Error != "planned 3 tests but ran 2" {
+		t.Errorf("results = %+v, want a failed plan result", results)
+	}
+}
+
+func TestDiscoverPgTAPFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users_test.sql":       "BEGIN;\nSELECT plan(2);\nSELECT has_table('users');\n",
+		"nested/orders.sql":    "select * from no_plan();\n",
+		"helpers.sql":          "create function helper() returns int language sql as 'select 1';\n",
+		"users_test.sql.orig":  "SELECT plan(1);\n",
+		"nested/not_tests.txt": "SELECT plan(1);\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := DiscoverPgTAPFiles(dir, "")
+	if err != nil {
+		t.Fatalf("DiscoverPgTAPFiles: %v", err)
+	}
+	want := []string{filepath.Join(dir, "nested", "orders.sql"), filepath.Join(dir, "users_test.sql")}
+	if !equalStrings(got, want) {
+		t.Errorf("DiscoverPgTAPFiles = %v, want %v", got, want)
+	}
+
+	got, err = DiscoverPgTAPFiles(dir, "*_test.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(got, want[1:]) {
+		t.Errorf("with pattern = %v, want %v", got, want[1:])
+	}
+}
@@ -0,0 +1,145 @@
+package regresql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PlanNodeAnalyzer inspects a single EXPLAIN plan node. DetectPlanQualityIssues
+// calls every registered analyzer once per node of the plan tree.
+type PlanNodeAnalyzer interface {
+	Analyze(node PlanNode) []PlanWarning
+}
+
+type (
+	// SeqScanAnalyzer reports every sequential scan with its table; table
+	// policies (ignored, critical) are applied by DetectPlanQualityIssues
+	SeqScanAnalyzer struct{}
+
+	// NestedLoopAnalyzer reports nested loops with a sequential scan below
+	// them, which rescan the inner table for every outer row
+	NestedLoopAnalyzer struct{}
+
+	// SortAnalyzer reports every sort, and sorts that spilled to disk
+	SortAnalyzer struct{}
+
+	// HashBatchAnalyzer reports hash nodes that needed more than one batch
+	// and wrote temp files, i.e. the hash table did not fit in work_mem
+	HashBatchAnalyzer struct{}
+)
+
+const (
+	SortDetected    WarningType = "sort_detected"
+	SortSpilledDisk WarningType = "sort_spilled_to_disk"
+	HashSpilledDisk WarningType = "hash_spilled_to_disk"
+)
+
+// PlanNodeAnalyzerRegistry holds the analyzers run by DetectPlanQualityIssues,
+// keyed by name. They run in name order so warnings are stable.
+var PlanNodeAnalyzerRegistry = make(map[string]PlanNodeAnalyzer)
+
+// RegisterPlanNodeAnalyzer adds an analyzer; registering an existing name
+// replaces it, which also allows overriding the built-in analyzers
+func RegisterPlanNodeAnalyzer(name string, analyzer PlanNodeAnalyzer) {
+	PlanNodeAnalyzerRegistry[name] = analyzer
+}
+
+// analyzePlanNodes runs the registered analyzers on node and its children,
+// depth first
+func analyzePlanNodes(node *PlanNode) []PlanWarning {
+	names := make([]string, 0, len(PlanNodeAnalyzerRegistry))
+	for name := range PlanNodeAnalyzerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []PlanWarning
+	var walk func(n *PlanNode)
+	walk = func(n *PlanNode) {
+		for _, name := range names {
+			warnings = append(warnings, PlanNodeAnalyzerRegistry[name].Analyze(*n)...)
+		}
+		for i := range n.Plans {
+			walk(&n.Plans[i])
+		}
+	}
+	walk(node)
+	return warnings
+}
+
+func (SeqScanAnalyzer) Analyze(node PlanNode) []PlanWarning {
+	if node.NodeType != "Seq Scan" || node.RelationName == "" {
+		return nil
+	}
+	return []PlanWarning{{
+		Type:       SeqScanDetected,
+		Severity:   "warning",
+		Table:      node.RelationName,
+		Message:    fmt.Sprintf("Sequential scan detected on table '%s'", node.RelationName),
+		Suggestion: "Consider adding an index if this table is large or this query is frequently executed",
+		Details:    fmt.Sprintf("Table '%s' is being scanned sequentially, which may be slow on large tables", node.RelationName),
+	}}
+}
+
+func (NestedLoopAnalyzer) Analyze(node PlanNode) []PlanWarning {
+	if node.NodeType != "Nested Loop" || !hasSeqScanBelow(&node) {
+		return nil
+	}
+	return []PlanWarning{{
+		Type:       NestedLoopWithSeqScan,
+		Severity:   "warning",
+		Message:    "Nested loop join with sequential scan detected",
+		Suggestion: "Add index on join column to avoid repeated sequential scans",
+		Details:    "Nested loops with seq scans can be very slow; the inner table is scanned repeatedly",
+	}}
+}
+
+func hasSeqScanBelow(node *PlanNode) bool {
+	for i := range node.Plans {
+		if node.Plans[i].NodeType == "Seq Scan" || hasSeqScanBelow(&node.Plans[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (SortAnalyzer) Analyze(node PlanNode) []PlanWarning {
+	if node.NodeType != "Sort" && node.NodeType != "Incremental Sort" {
+		return nil
+	}
+	warnings := []PlanWarning{{
+		Type:     SortDetected,
+		Severity: "info",
+		Message:  "Sort operation detected",
+	}}
+	if node.SortSpaceType == "Disk" {
+		warnings = append(warnings, PlanWarning{
+			Type:       SortSpilledDisk,
+			Severity:   "warning",
+			Message:    fmt.Sprintf("Sort spilled to disk (%s, %dkB)", node.SortMethod, node.SortSpaceUsed),
+			Suggestion: "Increase work_mem or add an index matching the sort keys",
+			Details:    fmt.Sprintf("Sort on %v did not fit in work_mem", node.SortKey),
+		})
+	}
+	return warnings
+}
+
+func (HashBatchAnalyzer) Analyze(node PlanNode) []PlanWarning {
+	if node.NodeType != "Hash" || node.HashBatches <= 1 || node.TempWrittenBlocks == 0 {
+		return nil
+	}
+	return []PlanWarning{{
+		Type:       HashSpilledDisk,
+		Severity:   "warning",
+		Message:    fmt.Sprintf("Hash spilled to disk in %d batches (%d temp blocks written)", node.HashBatches, node.TempWrittenBlocks),
+		Suggestion: "Increase work_mem or reduce the rows feeding the hash join",
+		Details:    fmt.Sprintf("Hash planned %d batches, used %d; peak memory %dkB", node.OriginalHashBatches, node.HashBatches, node.PeakMemoryUsage),
+	}}
+}
+
+func init() {
+	RegisterPlanNodeAnalyzer("seq_scan", SeqScanAnalyzer{})
+	RegisterPlanNodeAnalyzer("nested_loop", NestedLoopAnalyzer{})
+	RegisterPlanNodeAnalyzer("sort", SortAnalyzer{})
+	RegisterPlanNodeAnalyzer("hash_batch", HashBatchAnalyzer{})
+}
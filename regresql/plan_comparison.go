@@ -85,7 +85,7 @@ func compareScanMethods(tableName string, baseline, current ScanInfo) *PlanRegre
 			IndexCond:       baseline.IndexCond,
 			Severity:        "critical",
 			Message:         fmt.Sprintf("Table '%s' changed from %s to Seq Scan", tableName, baseline.ScanType),
-			Recommendations: buildIndexRegressionRecommendations(tableName, baseline),
+			Recommendations: append(buildIndexRegressionRecommendations(tableName, baseline), indexSuggestionRecommendations(tableName, current)...),
 		}
 	}
 
@@ -230,6 +230,16 @@ func buildIndexRegressionRecommendations(tableName string, baseline ScanInfo) []
 	return recs
 }
 
+// indexSuggestionRecommendations suggests indexes for the filter of the
+// sequential scan that replaced an index scan
+func indexSuggestionRecommendations(tableName string, current ScanInfo) []string {
+	var recs []string
+	for _, s := range suggestTableIndexes(tableName, current, nil) {
+		recs = append(recs, "", "-- Suggested index: "+s.Rationale, s.DDL)
+	}
+	return recs
+}
+
 func GetSeveritySymbol(severity string) string {
 	switch severity {
 	case "critical":
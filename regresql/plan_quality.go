@@ -2,6 +2,7 @@ package regresql
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -38,8 +39,18 @@ type PlanCostInfo struct {
 	TotalBuffers int64 // shared_hit + shared_read; -1 if unavailable
 }
 
-func DetectPlanQualityIssues(sig *PlanSignature, opts RegressQLOptions, ignoredTables, criticalTables []string, cost PlanCostInfo) []PlanWarning {
-	var warnings []PlanWarning
+// DetectPlanQualityIssues runs the registered plan node analyzers over plan
+// and applies the table policies: seq scans on critical tables are errors,
+// ignored tables are dropped and scan/join warnings are skipped for trivially
+// cheap queries. Warnings from custom analyzers are passed through as is.
+func DetectPlanQualityIssues(plan *PlanNode, opts RegressQLOptions, ignoredTables, criticalTables []string, cost PlanCostInfo) []PlanWarning {
+	var (
+		warnings   []PlanWarning
+		other      []PlanWarning
+		seqTables  []string
+		sortCount  int
+		nestedLoop *PlanWarning
+	)
 
 	// Skip scan/join warnings for trivially cheap queries — seq scan on
 	// small tables is optimal and warning about it is noise.
@@ -47,12 +58,27 @@ func DetectPlanQualityIssues(sig *PlanSignature, opts RegressQLOptions, ignoredT
 	lowBuffers := cost.TotalBuffers >= 0 && cost.TotalBuffers < lowBufferThreshold
 	trivial := lowCost || lowBuffers
 
+	for _, w := range analyzePlanNodes(plan) {
+		switch w.Type {
+		case SeqScanDetected:
+			if !slices.Contains(seqTables, w.Table) {
+				seqTables = append(seqTables, w.Table)
+			}
+		case SortDetected:
+			sortCount++
+		case NestedLoopWithSeqScan:
+			if nestedLoop == nil {
+				nestedLoop = &w
+			}
+		default:
+			other = append(other, w)
+		}
+	}
+
 	// Critical-table seq scans bypass both the trivial-cost filter and the
 	// ignore list
-	if sig.HasSeqScan && !opts.NoSeqScanWarn && len(criticalTables) > 0 {
-		seqTables := findSeqScanTables(sig.Relations)
-		critical := intersectTables(seqTables, criticalTables)
-		for _, t := range critical {
+	if !opts.NoSeqScanWarn {
+		for _, t := range intersectTables(seqTables, criticalTables) {
 			warnings = append(warnings, PlanWarning{
 				Type:       SeqScanOnCriticalTable,
 				Severity:   "error",
@@ -64,11 +90,8 @@ func DetectPlanQualityIssues(sig *PlanSignature, opts RegressQLOptions, ignoredT
 		}
 	}
 
-	if sig.HasSeqScan && !opts.NoSeqScanWarn && !trivial {
-		seqScanTables := filterIgnoredTables(
-			filterCriticalTables(findSeqScanTables(sig.Relations), criticalTables),
-			ignoredTables,
-		)
+	if !opts.NoSeqScanWarn && !trivial {
+		seqScanTables := filterIgnoredTables(filterCriticalTables(seqTables, criticalTables), ignoredTables)
 
 		switch len(seqScanTables) {
 		case 1:
@@ -93,7 +116,7 @@ func DetectPlanQualityIssues(sig *PlanSignature, opts RegressQLOptions, ignoredT
 		}
 	}
 
-	if sortCount := countNodes(sig.NodeTypes, "Sort"); sortCount > 1 {
+	if sortCount > 1 {
 		warnings = append(warnings, PlanWarning{
 			Type:       MultipleSorts,
 			Severity:   "warning",
@@ -103,49 +126,11 @@ func DetectPlanQualityIssues(sig *PlanSignature, opts RegressQLOptions, ignoredT
 		})
 	}
 
-	if hasNestedLoopWithSeqScan(sig) && !trivial {
-		warnings = append(warnings, PlanWarning{
-			Type:       NestedLoopWithSeqScan,
-			Severity:   "warning",
-			Message:    "Nested loop join with sequential scan detected",
-			Suggestion: "Add index on join column to avoid repeated sequential scans",
-			Details:    "Nested loops with seq scans can be very slow; the inner table is scanned repeatedly",
-		})
-	}
-
-	return warnings
-}
-
-func findSeqScanTables(relations map[string]ScanInfo) []string {
-	var tables []string
-	for tableName, scanInfo := range relations {
-		if scanInfo.ScanType == "Seq Scan" {
-			tables = append(tables, tableName)
-		}
-	}
-	return tables
-}
-
-func countNodes(nodeTypes []string, targetType string) int {
-	count := 0
-	for _, nt := range nodeTypes {
-		if nt == targetType {
-			count++
-		}
+	if nestedLoop != nil && !trivial {
+		warnings = append(warnings, *nestedLoop)
 	}
-	return count
-}
 
-func hasNestedLoopWithSeqScan(sig *PlanSignature) bool {
-	if !sig.HasSeqScan {
-		return false
-	}
-	for _, jt := range sig.JoinTypes {
-		if jt == "Nested Loop" {
-			return true
-		}
-	}
-	return false
+	return append(warnings, other...)
 }
 
 func intersectTables(tables, wanted []string) []string {
@@ -2,15 +2,12 @@ package regresql
 
 import "testing"
 
-func buildSigWithSeqScans(tables ...string) *PlanSignature {
-	sig := &PlanSignature{
-		Relations:  make(map[string]ScanInfo),
-		HasSeqScan: len(tables) > 0,
-	}
+func buildPlanWithSeqScans(tables ...string) *PlanNode {
+	plan := &PlanNode{NodeType: "Append"}
 	for _, t := range tables {
-		sig.Relations[t] = ScanInfo{ScanType: "Seq Scan"}
+		plan.Plans = append(plan.Plans, PlanNode{NodeType: "Seq Scan", RelationName: t})
 	}
-	return sig
+	return plan
 }
 
 func findWarning(warnings []PlanWarning, wType WarningType, table string) *PlanWarning {
@@ -26,9 +23,9 @@ func findWarning(warnings []PlanWarning, wType WarningType, table string) *PlanW
 var nonTrivialCost = PlanCostInfo{TotalCost: 1000.0, TotalBuffers: 1000}
 
 func TestDetectPlanQualityIssues_CriticalTableEmitsError(t *testing.T) {
-	sig := buildSigWithSeqScans("orders")
+	plan := buildPlanWithSeqScans("orders")
 	warnings := DetectPlanQualityIssues(
-		sig, RegressQLOptions{},
+		plan, RegressQLOptions{},
 		nil, []string{"orders"},
 		nonTrivialCost,
 	)
@@ -46,9 +43,9 @@ func TestDetectPlanQualityIssues_CriticalTableEmitsError(t *testing.T) {
 }
 
 func TestDetectPlanQualityIssues_CriticalBeatsIgnored(t *testing.T) {
-	sig := buildSigWithSeqScans("orders")
+	plan := buildPlanWithSeqScans("orders")
 	warnings := DetectPlanQualityIssues(
-		sig, RegressQLOptions{},
+		plan, RegressQLOptions{},
 		[]string{"orders"},     // same table also in ignore list
 		[]string{"orders"},
 		nonTrivialCost,
@@ -64,10 +61,10 @@ func TestDetectPlanQualityIssues_CriticalBeatsIgnored(t *testing.T) {
 }
 
 func TestDetectPlanQualityIssues_CriticalBypassesTrivialCost(t *testing.T) {
-	sig := buildSigWithSeqScans("orders")
+	plan := buildPlanWithSeqScans("orders")
 	trivial := PlanCostInfo{TotalCost: 1.0, TotalBuffers: 1}
 	warnings := DetectPlanQualityIssues(
-		sig, RegressQLOptions{},
+		plan, RegressQLOptions{},
 		nil, []string{"orders"},
 		trivial,
 	)
@@ -77,9 +74,9 @@ func TestDetectPlanQualityIssues_CriticalBypassesTrivialCost(t *testing.T) {
 }
 
 func TestDetectPlanQualityIssues_NonCriticalUnchanged(t *testing.T) {
-	sig := buildSigWithSeqScans("widgets")
+	plan := buildPlanWithSeqScans("widgets")
 	warnings := DetectPlanQualityIssues(
-		sig, RegressQLOptions{},
+		plan, RegressQLOptions{},
 		nil, []string{"orders"},
 		nonTrivialCost,
 	)
@@ -94,9 +91,9 @@ func TestDetectPlanQualityIssues_NonCriticalUnchanged(t *testing.T) {
 }
 
 func TestDetectPlanQualityIssues_NoCriticalConfigNoRegression(t *testing.T) {
-	sig := buildSigWithSeqScans("widgets")
+	plan := buildPlanWithSeqScans("widgets")
 	warnings := DetectPlanQualityIssues(
-		sig, RegressQLOptions{},
+		plan, RegressQLOptions{},
 		nil, nil,
 		nonTrivialCost,
 	)
@@ -139,3 +136,65 @@ func TestHasSeverityViolation(t *testing.T) {
 		t.Errorf("info severity should never trigger violation")
 	}
 }
+
+func TestDetectPlanQualityIssues_Analyzers(t *testing.T) {
+	plan := &PlanNode{
+		NodeType: "Sort",
+		Plans: []PlanNode{{
+			NodeType: "Nested Loop",
+			Plans: []PlanNode{
+				{NodeType: "Index Scan", RelationName: "orders"},
+				{NodeType: "Sort", SortSpaceType: "Disk", SortMethod: "external merge", SortSpaceUsed: 2048, Plans: []PlanNode{
+					{NodeType: "Seq Scan", RelationName: "items"},
+				}},
+			},
+		}, {
+			NodeType:    "Hash",
+			HashBatches: 4, OriginalHashBatches: 1, TempWrittenBlocks: 120,
+		}},
+	}
+
+	warnings := DetectPlanQualityIssues(plan, RegressQLOptions{}, nil, nil, nonTrivialCost)
+
+	for _, wt := range []WarningType{SeqScanDetected, MultipleSorts, NestedLoopWithSeqScan, SortSpilledDisk, HashSpilledDisk} {
+		found := false
+		for _, w := range warnings {
+			found = found || w.Type == wt
+		}
+		if !found {
+			t.Errorf("expected %s warning, got %+v", wt, warnings)
+		}
+	}
+	for _, w := range warnings {
+		if w.Type == SortDetected {
+			t.Errorf("single sort findings should be folded into MultipleSorts, got %+v", w)
+		}
+	}
+
+	trivial := DetectPlanQualityIssues(plan, RegressQLOptions{}, nil, nil, PlanCostInfo{TotalCost: 1, TotalBuffers: 1})
+	for _, w := range trivial {
+		if w.Type == SeqScanDetected || w.Type == NestedLoopWithSeqScan {
+			t.Errorf("scan/join warnings should be skipped for trivial queries, got %+v", w)
+		}
+	}
+}
+
+type limitAnalyzer struct{}
+
+func (limitAnalyzer) Analyze(node PlanNode) []PlanWarning {
+	if node.NodeType != "Limit" {
+		return nil
+	}
+	return []PlanWarning{{Type: "limit_detected", Severity: "info", Message: "limit"}}
+}
+
+func TestRegisterPlanNodeAnalyzer(t *testing.T) {
+	RegisterPlanNodeAnalyzer("test_limit", limitAnalyzer{})
+	t.Cleanup(func() { delete(PlanNodeAnalyzerRegistry, "test_limit") })
+
+	plan := &PlanNode{NodeType: "Limit", Plans: []PlanNode{{NodeType: "Index Scan", RelationName: "orders"}}}
+	warnings := DetectPlanQualityIssues(plan, RegressQLOptions{}, nil, nil, nonTrivialCost)
+	if len(warnings) != 1 || warnings[0].Type != "limit_detected" {
+		t.Errorf("custom analyzer warnings = %+v", warnings)
+	}
+}
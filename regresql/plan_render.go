@@ -0,0 +1,123 @@
+package regresql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlanRenderOptions controls what RenderPlanTree shows for each node
+type PlanRenderOptions struct {
+	ShowActual        bool // actual time, rows and loops (plans captured with ANALYZE)
+	ShowBuffers       bool // shared/temp buffer counts (plans captured with BUFFERS)
+	HighlightSeqScans bool // print Seq Scan nodes in red
+}
+
+// RenderPlanTree renders an EXPLAIN plan as an indented tree, one line per
+// node plus optional detail lines, similar to graphical explain viewers:
+//
+//	Hash Join  (cost=1.09..2.21 rows=5)
+//	├─ Seq Scan on orders  (cost=0.00..1.05 rows=5)
+//	└─ Hash  (cost=1.04..1.04 rows=4)
+//	   └─ Seq Scan on users  (cost=0.00..1.04 rows=4)
+func RenderPlanTree(plan *ExplainOutput, opts PlanRenderOptions) string {
+	var b strings.Builder
+	renderPlanNode(&b, &plan.Plan, "", "", opts)
+
+	if opts.ShowActual && (plan.PlanningTime > 0 || plan.ExecutionTime > 0) {
+		fmt.Fprintf(&b, "Planning Time: %.3f ms\n", plan.PlanningTime)
+		fmt.Fprintf(&b, "Execution Time: %.3f ms\n", plan.ExecutionTime)
+	}
+	return b.String()
+}
+
+// renderPlanNode writes node with prefix before its own line and indent
+// before its details and children
+func renderPlanNode(b *strings.Builder, node *PlanNode, prefix, indent string, opts PlanRenderOptions) {
+	label := planNodeLabel(node)
+	if opts.HighlightSeqScans && node.NodeType == "Seq Scan" {
+		label = colorRed + label + colorReset
+	}
+
+	fmt.Fprintf(b, "%s%s  (cost=%.2f..%.2f rows=%.0f)", prefix, label, node.StartupCost, node.TotalCost, node.PlanRows)
+	if opts.ShowActual && node.ActualLoops > 0 {
+		fmt.Fprintf(b, " (actual time=%.3f..%.3f rows=%.0f loops=%.0f)",
+			node.ActualStartupTime, node.ActualTotalTime, node.ActualRows, node.ActualLoops)
+	}
+	b.WriteString("\n")
+
+	detail := indent + "│  "
+	if len(node.Plans) == 0 {
+		detail = indent + "   "
+	}
+	for _, line := range planNodeDetails(node, opts) {
+		fmt.Fprintf(b, "%s%s\n", detail, line)
+	}
+
+	for i := range node.Plans {
+		if i == len(node.Plans)-1 {
+			renderPlanNode(b, &node.Plans[i], indent+"└─ ", indent+"   ", opts)
+		} else {
+			renderPlanNode(b, &node.Plans[i], indent+"├─ ", indent+"│  ", opts)
+		}
+	}
+}
+
+func planNodeLabel(node *PlanNode) string {
+	label := node.NodeType
+	if node.JoinType != "" && node.JoinType != "Inner" {
+		label = node.NodeType + " " + node.JoinType
+	}
+	if node.IndexName != "" {
+		label += " using " + node.IndexName
+	}
+	if node.RelationName != "" {
+		label += " on " + node.RelationName
+		if node.Alias != "" && node.Alias != node.RelationName {
+			label += " " + node.Alias
+		}
+	}
+	return label
+}
+
+func planNodeDetails(node *PlanNode, opts PlanRenderOptions) []string {
+	var lines []string
+	add := func(name, value string) {
+		if value != "" {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	add("Index Cond", node.IndexCond)
+	add("Hash Cond", node.HashCond)
+	add("Merge Cond", node.MergeCond)
+	add("Join Filter", node.JoinFilter)
+	add("Filter", node.Filter)
+	if len(node.SortKey) > 0 {
+		add("Sort Key", strings.Join(node.SortKey, ", "))
+	}
+
+	if opts.ShowActual {
+		if node.SortMethod != "" {
+			lines = append(lines, fmt.Sprintf("Sort Method: %s  %s: %dkB", node.SortMethod, node.SortSpaceType, node.SortSpaceUsed))
+		}
+		if node.HashBatches > 0 {
+			lines = append(lines, fmt.Sprintf("Buckets: %d  Batches: %d  Memory Usage: %dkB", node.HashBuckets, node.HashBatches, node.PeakMemoryUsage))
+		}
+		if node.RowsRemovedByFilter > 0 {
+			lines = append(lines, fmt.Sprintf("Rows Removed by Filter: %.0f", node.RowsRemovedByFilter))
+		}
+	}
+
+	if opts.ShowBuffers {
+		var parts []string
+		if node.SharedHitBlocks > 0 || node.SharedReadBlocks > 0 {
+			parts = append(parts, fmt.Sprintf("shared hit=%d read=%d", node.SharedHitBlocks, node.SharedReadBlocks))
+		}
+		if node.TempReadBlocks > 0 || node.TempWrittenBlocks > 0 {
+			parts = append(parts, fmt.Sprintf("temp read=%d written=%d", node.TempReadBlocks, node.TempWrittenBlocks))
+		}
+		if len(parts) > 0 {
+			lines = append(lines, "Buffers: "+strings.Join(parts, ", "))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,82 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPlanTree(t *testing.T) {
+	plan := &ExplainOutput{
+		Plan: PlanNode{
+			NodeType: "Hash Join", JoinType: "Left", StartupCost: 1.09, TotalCost: 2.21, PlanRows: 5,
+			HashCond: "(o.user_id = u.id)",
+			Plans: []PlanNode{
+				{NodeType: "Seq Scan", RelationName: "orders", Alias: "o", TotalCost: 1.05, PlanRows: 5},
+				{NodeType: "Hash", StartupCost: 1.04, TotalCost: 1.04, PlanRows: 4, Plans: []PlanNode{
+					{NodeType: "Index Scan", IndexName: "users_pkey", RelationName: "users", Alias: "u", TotalCost: 1.04, PlanRows: 4,
+						ActualTotalTime: 0.02, ActualRows: 4, ActualLoops: 1, SharedHitBlocks: 2},
+				}},
+			},
+		},
+		ExecutionTime: 0.5,
+	}
+
+	want := strings.Join([]string{
+		"Hash Join Left  (cost=1.09..2.21 rows=5)",
+		"│  Hash Cond: (o.user_id = u.id)",
+		"├─ Seq Scan on orders o  (cost=0.00..1.05 rows=5)",
+		"└─ Hash  (cost=1.04..1.04 rows=4)",
+		"   └─ Index Scan using users_pkey on users u  (cost=0.00..1.04 rows=4)",
+		"",
+	}, "\n")
+	if got := RenderPlanTree(plan, PlanRenderOptions{}); got != want {
+		t.Errorf("RenderPlanTree =\n%s\nwant\n%s", got, want)
+	}
+
+	got := RenderPlanTree(plan, PlanRenderOptions{ShowActual: true, ShowBuffers: true, HighlightSeqScans: true})
+	for _, s := range []string{
+		colorRed + "Seq Scan on orders o" + colorReset,
+		"(actual time=0.000..0.020 rows=4 loops=1)",
+		"      Buffers: shared hit=2 read=0",
+		"Execution Time: 0.500 ms",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("rendered plan missing %q:\n%s", s, got)
+		}
+	}
+}
+
+func TestResolveBaselinePath(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("regresql/regress.yaml", "pguri: postgres://localhost/test\n")
+	write("orders/get_order.sql", "-- name: get_order\nselect 1;\n")
+	write("orders/orders.sql", "-- name: by_id\nselect * from orders where id = :id;\n\n-- name: recent\nselect 2;\n")
+	single := write("regresql/baselines/orders/get_order.json", "{}")
+	first := write("regresql/baselines/orders/orders_by_id.1.json", "{}")
+	write("regresql/baselines/orders/orders_by_id.2.json", "{}")
+
+	if got, err := ResolveBaselinePath(root, "orders/get_order.sql", ""); err != nil || got != single {
+		t.Errorf("single query = %q, %v; want %q", got, err, single)
+	}
+	if got, err := ResolveBaselinePath(root, "orders/orders/by_id", "1"); err != nil || got != first {
+		t.Errorf("binding 1 = %q, %v; want %q", got, err, first)
+	}
+	if _, err := ResolveBaselinePath(root, "orders/orders/by_id", ""); err == nil || !strings.Contains(err.Error(), "1, 2") {
+		t.Errorf("ambiguous bindings error = %v", err)
+	}
+	if _, err := ResolveBaselinePath(root, "orders/orders/missing", ""); err == nil {
+		t.Error("expected error for unknown query")
+	}
+}
@@ -2,13 +2,18 @@ package regresql
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/boringsql/regresql/v2/regresql/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,6 +25,29 @@ type (
 		Bindings    []map[string]any
 		ResultSets  []ResultSet
 		PlanQuality *PlanQualityConfig `yaml:"plan_quality,omitempty" json:"plan_quality,omitempty"`
+
+		// BeforeEach and AfterEach run in the query's transaction before the
+		// first and after the last binding, e.g. SET enable_seqscan = off
+		BeforeEach []SQLSpec `yaml:"before_each,omitempty" json:"before_each,omitempty"`
+		AfterEach  []SQLSpec `yaml:"after_each,omitempty" json:"after_each,omitempty"`
+
+		// Pagination appends generated page_N bindings after the others
+		Pagination *PaginationConfig `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+
+		// CheckTypes keeps column types on executed result sets and compares
+		// them against the expected files
+		CheckTypes bool `yaml:"-" json:"-"`
+
+		// Analyses hold the EXPLAIN ANALYZE of each binding when the query
+		// has the capture_analyze option, indexed like ResultSets
+		Analyses []CapturedAnalyze `yaml:"-" json:"-"`
+	}
+
+	// CapturedAnalyze is the EXPLAIN (ANALYZE, BUFFERS) output of one binding;
+	// Explain is nil when capturing it failed
+	CapturedAnalyze struct {
+		Explain  *ExplainOutput
+		Filename string
 	}
 
 	PlanQualityConfig struct {
@@ -30,8 +58,77 @@ type (
 		Name   string
 		Params map[string]any
 	}
+
+	// SQLSpec is an SQL statement given inline or as a file path. In YAML a
+	// plain string ending in .sql is a file, relative to the query's SQL
+	// file; the mapping forms {sql: ...} and {file: ...} are explicit.
+	// With multi_statement the script is split into statements that run
+	// one by one.
+	SQLSpec struct {
+		Inline         string `yaml:"sql,omitempty" json:"sql,omitempty"`
+		File           string `yaml:"file,omitempty" json:"file,omitempty"`
+		MultiStatement bool   `yaml:"multi_statement,omitempty" json:"multi_statement,omitempty"`
+	}
 )
 
+func (s *SQLSpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if isSQLFilePath(node.Value) {
+			s.File = node.Value
+		} else {
+			s.Inline = node.Value
+		}
+		return nil
+	}
+	type plain SQLSpec
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if (s.Inline == "") == (s.File == "") {
+		return fmt.Errorf("line %d: set exactly one of sql or file", node.Line)
+	}
+	return nil
+}
+
+func (s SQLSpec) MarshalYAML() (any, error) {
+	if s.MultiStatement {
+		type plain SQLSpec
+		return plain(s), nil
+	}
+	if s.File != "" {
+		if isSQLFilePath(s.File) {
+			return s.File, nil
+		}
+		return map[string]string{"file": s.File}, nil
+	}
+	if isSQLFilePath(s.Inline) {
+		return map[string]string{"sql": s.Inline}, nil
+	}
+	return s.Inline, nil
+}
+
+// isSQLFilePath tells a file reference from an inline statement: a single
+// word ending in .sql
+func isSQLFilePath(s string) bool {
+	return strings.HasSuffix(strings.ToLower(s), ".sql") && !strings.ContainsAny(s, " \t\n;")
+}
+
+// resolve returns the statement text, reading File relative to dir
+func (s SQLSpec) resolve(dir string) (string, error) {
+	if s.File == "" {
+		return s.Inline, nil
+	}
+	path := s.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	return string(data), nil
+}
+
 func NewPlan(query *Query, testCases []TestCase) *Plan {
 	names := make([]string, len(testCases))
 	bindings := make([]map[string]any, len(testCases))
@@ -48,6 +145,16 @@ func NewPlan(query *Query, testCases []TestCase) *Plan {
 	}
 }
 
+// withoutBindings returns the plan of a query without parameters: a single
+// unnamed binding, keeping the before_each and after_each hooks
+func (p *Plan) withoutBindings() *Plan {
+	plan := NewPlan(p.Query, []TestCase{{Name: ""}})
+	plan.Path = p.Path
+	plan.BeforeEach = p.BeforeEach
+	plan.AfterEach = p.AfterEach
+	return plan
+}
+
 // CreateEmptyPlan creates a plan YAML file for the query
 func (q *Query) CreateEmptyPlan(dir string) (*Plan, error) {
 	var names []string
@@ -122,6 +229,10 @@ func parseYAMLPlan(data []byte, pfile string, q *Query) (*Plan, error) {
 
 	// Extract known top-level fields
 	var planQuality *PlanQualityConfig
+	var hooks struct {
+		BeforeEach []SQLSpec `yaml:"before_each"`
+		AfterEach  []SQLSpec `yaml:"after_each"`
+	}
 
 	// Reject deprecated fixtures and cleanup fields with clear error messages
 	if _, hasFixtures := raw["fixtures"]; hasFixtures {
@@ -148,6 +259,30 @@ func parseYAMLPlan(data []byte, pfile string, q *Query) (*Plan, error) {
 		delete(raw, "plan_quality")
 	}
 
+	if err := yaml.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to parse before_each/after_each in '%s': %w", pfile, err)
+	}
+	delete(raw, "before_each")
+	delete(raw, "after_each")
+
+	var paging struct {
+		Pagination *PaginationConfig `yaml:"pagination"`
+	}
+	if err := yaml.Unmarshal(data, &paging); err != nil {
+		return nil, fmt.Errorf("failed to parse pagination in '%s': %w", pfile, err)
+	}
+	if paging.Pagination != nil {
+		if err := paging.Pagination.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", pfile, err)
+		}
+		for _, name := range paging.Pagination.pageNames() {
+			if _, ok := raw[name]; ok {
+				return nil, fmt.Errorf("%s: binding %q conflicts with the pages generated by pagination", pfile, name)
+			}
+		}
+	}
+	delete(raw, "pagination")
+
 	// Remaining keys are bindings - extract and sort them for consistent ordering
 	var names []string
 	for name := range raw {
@@ -164,6 +299,11 @@ func parseYAMLPlan(data []byte, pfile string, q *Query) (*Plan, error) {
 		}
 	}
 
+	if paging.Pagination != nil {
+		names = append(names, paging.Pagination.pageNames()...)
+		bindings = append(bindings, paging.Pagination.bindings()...)
+	}
+
 	return &Plan{
 		Query:       q,
 		Path:        pfile,
@@ -171,36 +311,183 @@ func parseYAMLPlan(data []byte, pfile string, q *Query) (*Plan, error) {
 		Bindings:    bindings,
 		ResultSets:  []ResultSet{},
 		PlanQuality: planQuality,
+		BeforeEach:  hooks.BeforeEach,
+		AfterEach:   hooks.AfterEach,
+		Pagination:  paging.Pagination,
 	}, nil
 }
 
-// Execute runs the plan's query against the given querier (db or transaction)
+// Execute runs the plan's query against the given querier (db or transaction).
+// A role annotation switches to that role with SET LOCAL ROLE, which only
+// takes effect when q is a transaction.
 func (p *Plan) Execute(ctx context.Context, q Querier) error {
+	return p.execute(ctx, q, true)
+}
+
+// execute runs before_each and the query for every binding, and after_each
+// when afterEach is set. Callers that EXPLAIN the query after executing it
+// run after_each themselves, so the plans see the same state as the results.
+func (p *Plan) execute(ctx context.Context, q Querier, afterEach bool) (err error) {
+	ctx, span := tracing.Start(ctx, "regresql.query.execute",
+		attribute.String("regresql.query.name", p.Query.Name),
+		attribute.String("regresql.query.file", p.Query.Path),
+		attribute.Int("regresql.query.bindings", len(p.Bindings)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	if os.Getenv("REGRESQL_DEBUG") == "1" {
 		fmt.Fprintf(os.Stderr, "[DEBUG] Executing query %s with %d bindings: %v\n", p.Query.Name, len(p.Bindings), p.Names)
 	}
 
+	if err := p.setUp(ctx, q); err != nil {
+		return err
+	}
+
+	opts := p.Query.GetRegressQLOptions()
+	p.Analyses = nil
 	if len(p.Query.Args) == 0 {
-		res, err := RunQuery(ctx, q, p.Query.OrdinalQuery)
+		res, err := p.runQuery(ctx, q, "", p.Query.OrdinalQuery)
 		if err != nil {
 			return fmt.Errorf("error executing query: %w\n%s", err, p.Query.OrdinalQuery)
 		}
 		p.ResultSets = []ResultSet{*res}
-		return nil
+		if opts.CaptureAnalyze {
+			p.Analyses = []CapturedAnalyze{p.captureAnalyze(ctx, q, "", p.Query.OrdinalQuery)}
+		}
+		p.dropColumnTypes()
+		if !afterEach {
+			return nil
+		}
+		return p.runHooks(ctx, q, "after_each", p.AfterEach)
 	}
 
 	p.ResultSets = make([]ResultSet, len(p.Bindings))
-	for i, bindings := range p.Bindings {
-		sql, args := p.Query.Prepare(bindings)
-		res, err := RunQuery(ctx, q, sql, args...)
+	if opts.CaptureAnalyze {
+		p.Analyses = make([]CapturedAnalyze, len(p.Bindings))
+	}
+	for i := range p.Bindings {
+		if err := p.chainCursor(i); err != nil {
+			return err
+		}
+		sql, args := p.Query.Prepare(p.Bindings[i])
+		res, err := p.runQuery(ctx, q, p.Names[i], sql, args...)
 		if err != nil {
 			return fmt.Errorf("error executing query with params %v: %w\n%s", args, err, sql)
 		}
 		p.ResultSets[i] = *res
+		if opts.CaptureAnalyze {
+			p.Analyses[i] = p.captureAnalyze(ctx, q, p.Names[i], sql, args...)
+		}
+	}
+	p.dropColumnTypes()
+	if !afterEach {
+		return nil
+	}
+	return p.runHooks(ctx, q, "after_each", p.AfterEach)
+}
+
+// setUp runs before_each and switches to the query's role, the state the
+// query is executed and planned in
+func (p *Plan) setUp(ctx context.Context, q Querier) error {
+	if err := p.runHooks(ctx, q, "before_each", p.BeforeEach); err != nil {
+		return err
+	}
+	if role := p.Query.GetRegressQLOptions().Role; role != "" {
+		if _, err := q.ExecContext(ctx, "SET LOCAL ROLE "+QuoteIdentifier(role)); err != nil {
+			return fmt.Errorf("failed to set role %q: %w", role, err)
+		}
 	}
 	return nil
 }
 
+// captureAnalyze runs EXPLAIN (ANALYZE, BUFFERS) for one binding inside a
+// savepoint, so the second execution leaves no writes behind. The capture is
+// a debugging aid: a failure is reported as a warning, not a test error.
+func (p *Plan) captureAnalyze(ctx context.Context, q Querier, binding string, query string, args ...any) CapturedAnalyze {
+	opts := DefaultExplainOptions()
+	opts.Analyze = true
+	opts.Buffers = true
+
+	ex, err := func() (*ExplainOutput, error) {
+		if _, err := q.ExecContext(ctx, "SAVEPOINT regresql_capture_analyze"); err != nil {
+			return nil, err
+		}
+		ex, err := ExecuteExplainWithOptions(ctx, q, query, opts, args...)
+		if _, rbErr := q.ExecContext(ctx, "ROLLBACK TO SAVEPOINT regresql_capture_analyze"); err == nil {
+			err = rbErr
+		}
+		return ex, err
+	}()
+	if err != nil {
+		name := p.Query.Name
+		if binding != "" {
+			name += "." + binding
+		}
+		fmt.Fprintf(os.Stderr, "Warning: capture_analyze failed for %s: %v\n", name, err)
+		return CapturedAnalyze{}
+	}
+	return CapturedAnalyze{Explain: ex}
+}
+
+// runHooks executes before_each or after_each statements in order
+func (p *Plan) runHooks(ctx context.Context, q Querier, name string, specs []SQLSpec) error {
+	dir := filepath.Dir(p.Query.Path)
+	for i, spec := range specs {
+		stmt, err := spec.resolve(dir)
+		if err != nil {
+			return fmt.Errorf("%s[%d]: %w", name, i, err)
+		}
+		if !spec.MultiStatement {
+			if _, err := q.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("%s[%d] failed: %w\n%s", name, i, err, stmt)
+			}
+			continue
+		}
+		for j, part := range splitSQLStatements(stmt) {
+			if err := checkNoTransactionControl(part); err != nil {
+				return fmt.Errorf("%s[%d] statement %d: %w", name, i, j+1, err)
+			}
+			if _, err := q.ExecContext(ctx, part); err != nil {
+				return fmt.Errorf("%s[%d] statement %d failed: %w\n%s", name, i, j+1, err, part)
+			}
+		}
+	}
+	return nil
+}
+
+// runQuery runs the query for one binding, sampling the result when a row
+// limit applies
+func (p *Plan) runQuery(ctx context.Context, q Querier, binding string, query string, args ...any) (res *ResultSet, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "regresql.query.binding",
+		attribute.String("regresql.query.name", p.Query.Name),
+		attribute.String("regresql.query.binding", binding),
+	)
+	defer func() {
+		if res != nil {
+			span.SetAttributes(attribute.Int("regresql.query.rows", len(res.Rows)))
+		}
+		span.SetAttributes(attribute.Float64("regresql.query.duration_ms", float64(time.Since(start).Microseconds())/1000))
+		tracing.End(span, err)
+	}()
+
+	if limit := p.sampleLimit(); limit > 0 {
+		return runSampled(ctx, q, query, limit, p.samplingSeed(), args...)
+	}
+	return RunQuery(ctx, q, query, args...)
+}
+
+// dropColumnTypes strips column types unless type checking was requested, so
+// expected files keep their previous shape by default
+func (p *Plan) dropColumnTypes() {
+	if p.CheckTypes {
+		return
+	}
+	for i := range p.ResultSets {
+		p.ResultSets[i].ColumnTypes = nil
+	}
+}
+
 // WriteResultSets serialize the result of running a query, as a Pretty
 // Printed output (comparable to a simplified `psql` output)
 func (p *Plan) WriteResultSets(dir string) error {
@@ -214,21 +501,97 @@ func (p *Plan) WriteResultSets(dir string) error {
 	return nil
 }
 
+// WriteAnalyses writes the captured EXPLAIN ANALYZE outputs to dir, named
+// like the query's baselines
+func (p *Plan) WriteAnalyses(dir string) error {
+	for i, a := range p.Analyses {
+		if a.Explain == nil {
+			continue
+		}
+		if err := ensureDir(dir); err != nil {
+			return err
+		}
+		var binding string
+		if len(p.Query.Args) > 0 {
+			binding = p.Names[i]
+		}
+		path := getBaselinePath(p.Query, dir, binding)
+		data, err := json.MarshalIndent(a.Explain, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal analyze output: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write analyze output '%s': %w", path, err)
+		}
+		p.Analyses[i].Filename = path
+	}
+	return nil
+}
+
+// analyzeFile returns the captured analyze file of a binding, if any
+func (p *Plan) analyzeFile(binding string) string {
+	for i, a := range p.Analyses {
+		if len(p.Query.Args) == 0 || p.Names[i] == binding {
+			return a.Filename
+		}
+	}
+	return ""
+}
+
+// ResolveAnalyzePath finds the EXPLAIN ANALYZE captured for a query with the
+// capture_analyze option; ref and binding are as for ResolveBaselinePath
+func ResolveAnalyzePath(root, ref, binding string) (string, error) {
+	s, q, folderDir, err := resolveQueryRef(root, ref)
+	if err != nil {
+		return "", err
+	}
+	return findQueryFile(q, filepath.Join(s.OutDir, "analyze", folderDir), binding,
+		"captured analyze", "add '-- regresql: capture_analyze' to the query and run 'regresql test'")
+}
+
+// LoadAnalyze reads an EXPLAIN ANALYZE file written by WriteAnalyses
+func LoadAnalyze(path string) (*ExplainOutput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analyze file '%s': %w", path, err)
+	}
+	var ex ExplainOutput
+	if err := json.Unmarshal(data, &ex); err != nil {
+		return nil, fmt.Errorf("failed to parse analyze file '%s': %w", path, err)
+	}
+	return &ex, nil
+}
+
 func (p *Plan) Write() {
 	fmt.Printf("Creating Plan '%s'\n", p.Path)
 
 	// Build the YAML structure
 	planData := make(map[string]any)
 
-	// Add bindings
+	// Add bindings, except the pages generated by pagination
+	var pages []string
+	if p.Pagination != nil {
+		pages = p.Pagination.pageNames()
+	}
 	for i, bindings := range p.Bindings {
-		planData[p.Names[i]] = bindings
+		if !slices.Contains(pages, p.Names[i]) {
+			planData[p.Names[i]] = bindings
+		}
 	}
 
 	// Add optional fields
 	if p.PlanQuality != nil {
 		planData["plan_quality"] = p.PlanQuality
 	}
+	if len(p.BeforeEach) > 0 {
+		planData["before_each"] = p.BeforeEach
+	}
+	if len(p.AfterEach) > 0 {
+		planData["after_each"] = p.AfterEach
+	}
+	if p.Pagination != nil {
+		planData["pagination"] = p.Pagination
+	}
 
 	// Marshal to YAML (empty map becomes {})
 	var data []byte
@@ -265,10 +628,15 @@ func getResultSetPath(p *Plan, targetdir string, index int) string {
 	var rsFileName string
 	basename := strings.TrimSuffix(filepath.Base(p.Path), path.Ext(p.Path))
 
+	// queries run as a role keep their own expected files, e.g. orders.app_user.1.json
+	if role := p.Query.GetRegressQLOptions().Role; role != "" {
+		basename += "." + role
+	}
+
 	if len(p.Query.Args) == 0 {
-		rsFileName = fmt.Sprintf("%s.json", basename)
+		rsFileName = basename + resultFileExt(p.Query)
 	} else {
-		rsFileName = fmt.Sprintf("%s.%s.json", basename, p.Names[index])
+		rsFileName = fmt.Sprintf("%s.%s%s", basename, p.Names[index], resultFileExt(p.Query))
 	}
 	return filepath.Join(targetdir, rsFileName)
 }
@@ -293,6 +661,9 @@ func (p *Plan) ComputeDiffForInteractive(expectedDir string) string {
 			diffs = append(diffs, fmt.Sprintf("  [ERROR] %s: %v", filepath.Base(expectedPath), err))
 			continue
 		}
+		if isPsqlResultFile(expectedPath) {
+			rs = *rs.psqlText()
+		}
 
 		// Compare row counts
 		if len(expected.Rows) != len(rs.Rows) {
@@ -0,0 +1,245 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boringsql/queries"
+)
+
+func testQuery(t *testing.T, name, path string) *Query {
+	t.Helper()
+	q, err := queries.NewQuery(name, path, "SELECT 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Query{Query: q}
+}
+
+func TestParseYAMLPlanHooks(t *testing.T) {
+	data := []byte(`before_each:
+  - SET enable_seqscan = off
+  - setup/temp_tables.sql
+  - file: setup/odd name.sql
+after_each:
+  - sql: DROP TABLE IF EXISTS tmp_ids
+"1":
+  id: 1
+`)
+	plan, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "sql/orders.sql"))
+	if err != nil {
+		t.Fatalf("parseYAMLPlan: %v", err)
+	}
+
+	want := []SQLSpec{
+		{Inline: "SET enable_seqscan = off"},
+		{File: "setup/temp_tables.sql"},
+		{File: "setup/odd name.sql"},
+	}
+	if len(plan.BeforeEach) != len(want) {
+		t.Fatalf("BeforeEach = %+v", plan.BeforeEach)
+	}
+	for i, w := range want {
+		if plan.BeforeEach[i] != w {
+			t.Errorf("BeforeEach[%d] = %+v, want %+v", i, plan.BeforeEach[i], w)
+		}
+	}
+	if len(plan.AfterEach) != 1 || plan.AfterEach[0].Inline != "DROP TABLE IF EXISTS tmp_ids" {
+		t.Errorf("AfterEach = %+v", plan.AfterEach)
+	}
+	if !equalStrings(plan.Names, []string{"1"}) {
+		t.Errorf("Names = %v, want only the binding", plan.Names)
+	}
+}
+
+func TestParseYAMLPlanHooksInvalid(t *testing.T) {
+	data := []byte("before_each:\n  - sql: SELECT 1\n    file: setup.sql\n")
+	_, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "orders.sql"))
+	if err == nil || !strings.Contains(err.Error(), "exactly one of sql or file") {
+		t.Errorf("err = %v, want exactly one of sql or file", err)
+	}
+}
+
+func TestPlanWriteHooks(t *testing.T) {
+	dir := t.TempDir()
+	plan := &Plan{
+		Query:      testQuery(t, "orders", "orders.sql"),
+		Path:       filepath.Join(dir, "orders.yaml"),
+		Names:      []string{"1"},
+		Bindings:   []map[string]any{{"id": 1}},
+		BeforeEach: []SQLSpec{{Inline: "SET enable_seqscan = off"}, {File: "setup.sql"}, {Inline: "misc.sql"}, {File: "seed.sql", MultiStatement: true}},
+		AfterEach:  []SQLSpec{{File: "teardown/drop tmp.sql"}},
+	}
+	plan.Write()
+
+	data, err := os.ReadFile(plan.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseYAMLPlan(data, plan.Path, plan.Query)
+	if err != nil {
+		t.Fatalf("parseYAMLPlan: %v\n%s", err, data)
+	}
+	for i, spec := range plan.BeforeEach {
+		if got.BeforeEach[i] != spec {
+			t.Errorf("BeforeEach[%d] = %+v after round trip, want %+v", i, got.BeforeEach[i], spec)
+		}
+	}
+	if len(got.AfterEach) != 1 || got.AfterEach[0] != plan.AfterEach[0] {
+		t.Errorf("AfterEach = %+v after round trip", got.AfterEach)
+	}
+}
+
+func TestSQLSpecResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "setup.sql"), []byte("SET work_mem = '64MB'"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := SQLSpec{File: "setup.sql"}.resolve(dir)
+	if err != nil || stmt != "SET work_mem = '64MB'" {
+		t.Errorf("resolve file = %q, %v", stmt, err)
+	}
+	if stmt, _ := (SQLSpec{Inline: "SELECT 1"}).resolve(dir); stmt != "SELECT 1" {
+		t.Errorf("resolve inline = %q", stmt)
+	}
+	if _, err := (SQLSpec{File: "missing.sql"}).resolve(dir); err == nil {
+		t.Error("resolve missing file: expected error")
+	}
+}
+
+func TestPlanWriteAnalyses(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "analyze", "orders")
+	q := testQuery(t, "orders", "orders.sql")
+	q.Args = []string{"id"}
+	plan := &Plan{
+		Query: q,
+		Names: []string{"1", "2"},
+		Analyses: []CapturedAnalyze{
+			{Explain: &ExplainOutput{Plan: PlanNode{NodeType: "Index Scan", ActualTotalTime: 0.25}, ExecutionTime: 0.3}},
+			{}, // capture failed
+		},
+	}
+	if err := plan.WriteAnalyses(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join(dir, "orders.1.json")
+	if got := plan.analyzeFile("1"); got != want {
+		t.Errorf("analyzeFile(1) = %q, want %q", got, want)
+	}
+	if got := plan.analyzeFile("2"); got != "" {
+		t.Errorf("analyzeFile(2) = %q, want none for a failed capture", got)
+	}
+
+	ex, err := LoadAnalyze(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.Plan.NodeType != "Index Scan" || ex.Plan.ActualTotalTime != 0.25 || ex.ExecutionTime != 0.3 {
+		t.Errorf("LoadAnalyze = %+v", ex)
+	}
+}
+
+func TestParseYAMLPlanOffsetPagination(t *testing.T) {
+	data := []byte(`pagination:
+  type: offset
+  page_size: 100
+  pages: 3
+  params:
+    status: active
+"all":
+  status: any
+`)
+	plan, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "orders.sql"))
+	if err != nil {
+		t.Fatalf("parseYAMLPlan: %v", err)
+	}
+	if !equalStrings(plan.Names, []string{"all", "page_1", "page_2", "page_3"}) {
+		t.Fatalf("Names = %v", plan.Names)
+	}
+	for i, offset := range []int{0, 100, 200} {
+		b := plan.Bindings[i+1]
+		if b[PageOffsetParam] != offset || b[PageSizeParam] != 100 || b["status"] != "active" {
+			t.Errorf("page_%d bindings = %v", i+1, b)
+		}
+	}
+}
+
+func TestParseYAMLPlanPaginationInvalid(t *testing.T) {
+	cases := map[string]string{
+		"type: cursor\npage_size: 10\npages: 2": "unknown pagination type",
+		"type: keyset\npage_size: 10\npages: 2": "requires cursor_col",
+		"type: offset\npage_size: 0\npages: 2":  "page_size must be at least 1",
+	}
+	for cfg, want := range cases {
+		data := []byte("pagination:\n  " + strings.ReplaceAll(cfg, "\n", "\n  ") + "\n")
+		_, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "orders.sql"))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", cfg, err, want)
+		}
+	}
+
+	data := []byte("pagination: {type: offset, page_size: 10, pages: 2}\npage_2:\n  id: 1\n")
+	if _, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "orders.sql")); err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Errorf("page_2 binding: err = %v", err)
+	}
+}
+
+func TestPlanChainCursor(t *testing.T) {
+	data := []byte("pagination: {type: keyset, cursor_col: id, seed: 0, page_size: 2, pages: 3}\n")
+	plan, err := parseYAMLPlan(data, "plans/orders.yaml", testQuery(t, "orders", "orders.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Bindings[0][PageCursorParam] != 0 {
+		t.Errorf("page_1 cursor = %v, want seed", plan.Bindings[0][PageCursorParam])
+	}
+
+	plan.ResultSets = []ResultSet{
+		{Cols: []string{"id", "name"}, Rows: [][]any{{int64(1), "a"}, {int64(2), "b"}}},
+		{Cols: []string{"id", "name"}},
+	}
+	if err := plan.chainCursor(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Bindings[1][PageCursorParam]; got != int64(2) {
+		t.Errorf("page_2 cursor = %v, want last id of page_1", got)
+	}
+	// empty page keeps the cursor
+	if err := plan.chainCursor(2); err != nil || plan.Bindings[2][PageCursorParam] != int64(2) {
+		t.Errorf("page_3 cursor = %v, %v", plan.Bindings[2][PageCursorParam], err)
+	}
+
+	plan.ResultSets[0].Cols = []string{"uid", "name"}
+	if err := plan.chainCursor(1); err == nil {
+		t.Error("missing cursor_col: expected error")
+	}
+}
+
+func TestPlanWritePagination(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.yaml")
+	data := []byte("pagination: {type: offset, page_size: 10, pages: 2}\n\"1\":\n  id: 1\n")
+	plan, err := parseYAMLPlan(data, path, testQuery(t, "orders", "orders.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Write()
+
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(written), "page_1") {
+		t.Errorf("generated pages written to the plan:\n%s", written)
+	}
+	got, err := parseYAMLPlan(written, path, plan.Query)
+	if err != nil {
+		t.Fatalf("parseYAMLPlan: %v\n%s", err, written)
+	}
+	if !equalStrings(got.Names, []string{"1", "page_1", "page_2"}) || got.Pagination.PageSize != 10 {
+		t.Errorf("after round trip: names %v, pagination %+v", got.Names, got.Pagination)
+	}
+}
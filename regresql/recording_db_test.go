@@ -0,0 +1,149 @@
+package regresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a database/sql driver that records every statement it
+// receives, for tests that check what regresql sends to PostgreSQL and in
+// which order. EXPLAIN returns recordingPlan; other queries return the rows
+// set on the log, none by default.
+type recordingDriver struct{}
+
+const recordingPlan = `[{"Plan":{"Node Type":"Seq Scan","Relation Name":"t","Plan Rows":10,"Total Cost":5}}]`
+
+var (
+	recordingOnce sync.Once
+	recordingMu   sync.Mutex
+	recordings    = map[string]*statementLog{}
+)
+
+type statementLog struct {
+	mu    sync.Mutex
+	stmts []string
+	args  [][]driver.Value
+
+	// Columns and Rows are returned by queries other than EXPLAIN
+	Columns []string
+	Rows    [][]driver.Value
+}
+
+func (l *statementLog) add(stmt string, args []driver.Value) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stmts = append(l.stmts, stmt)
+	l.args = append(l.args, args)
+}
+
+// Statements returns the statements received so far
+func (l *statementLog) Statements() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.stmts...)
+}
+
+// Args returns the arguments of the i-th statement
+func (l *statementLog) Args(i int) []driver.Value {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.args[i]
+}
+
+// openRecordingDB opens a database that records its statements in the
+// returned log
+func openRecordingDB(t *testing.T) (*sql.DB, *statementLog) {
+	t.Helper()
+	recordingOnce.Do(func() { sql.Register("regresql-recording", recordingDriver{}) })
+
+	log := &statementLog{}
+	recordingMu.Lock()
+	recordings[t.Name()] = log
+	recordingMu.Unlock()
+
+	db, err := sql.Open("regresql-recording", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, log
+}
+
+func (recordingDriver) Open(name string) (driver.Conn, error) {
+	recordingMu.Lock()
+	defer recordingMu.Unlock()
+	return &recordingConn{log: recordings[name]}, nil
+}
+
+type recordingConn struct{ log *statementLog }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{log: c.log, query: query}, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.log.add("BEGIN", nil)
+	return c, nil
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+func (c *recordingConn) Commit() error {
+	c.log.add("COMMIT", nil)
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.log.add("ROLLBACK", nil)
+	return nil
+}
+
+type recordingStmt struct {
+	log   *statementLog
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.log.add(s.query, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.log.add(s.query, args)
+	if strings.HasPrefix(s.query, "EXPLAIN") {
+		return &recordingRows{columns: []string{"QUERY PLAN"}, rows: [][]driver.Value{{recordingPlan}}}, nil
+	}
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+	return &recordingRows{columns: s.log.Columns, rows: s.log.Rows}, nil
+}
+
+type recordingRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string { return r.columns }
+func (r *recordingRows) Close() error      { return nil }
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
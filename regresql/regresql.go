@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"golang.org/x/term"
 )
 
 type (
@@ -24,6 +27,12 @@ type (
 		Stats         string // Stats profile name, YAML path, or SQL path
 		Verbose       bool
 		Strict        bool
+		CheckTypes    bool
+		Parallel      int
+		FailFast      bool // stop after the first failed test
+		Interactive   bool // review failing output diffs and approve them into expected/
+		Percentile    int  // compare costs against this recorded percentile (overrides analyze.cost_percentile)
+		StrictSchema  bool // fail instead of warning when the schema state is unknown
 	}
 
 	UpdateOptions struct {
@@ -36,6 +45,17 @@ type (
 		Interactive bool
 		DryRun      bool
 		Snapshot    string
+		CheckTypes  bool
+		Role        string
+		Query       string // only the query with this name
+		Binding     string // only this binding of queries with parameters
+		Failed      bool   // only the output tests that failed in the last test run
+	}
+
+	InitOptions struct {
+		Root  string
+		PgUri string // detected from a compose file when empty
+		Yes   bool   // apply the detected snapshot setup without asking
 	}
 )
 
@@ -43,23 +63,80 @@ type (
 Init initializes a code repository for RegreSQL processing.
 
 That means creating the ./regresql/ directory and the regress.yaml config.
+Init also looks at how the project builds its schema (golang-migrate, goose,
+dbmate or atlas migrations, or a schema.sql) and offers to configure
+snapshots accordingly; a postgres service in a compose file provides the
+pguri when none is given.
 Use 'regresql add' to add individual queries to the test suite.
 */
-func Init(root string, pguri string) {
+func Init(opts InitOptions) {
+	setup := DetectProjectSetup(opts.Root)
+
+	pguri := opts.PgUri
+	if pguri == "" {
+		if setup.PgUri == "" {
+			fmt.Println("No connection string given and no postgres service found in a compose file.")
+			fmt.Println("Usage: regresql init postgres://user@host/dbname")
+			os.Exit(1)
+		}
+		pguri = setup.PgUri
+		fmt.Printf("Using the postgres service of %s: %s\n", setup.ComposeFile, pguri)
+	}
+
 	if err := TestConnectionString(pguri); err != nil {
 		fmt.Print(err.Error())
+		if opts.PgUri == "" {
+			fmt.Printf("\nIs the database running? Start it with 'docker compose -f %s up -d'\n", setup.ComposeFile)
+		}
 		os.Exit(2)
 	}
 
-	suite := Walk(root, []string{})
+	snapshot := setup.Snapshot()
+	if snapshot != nil && !opts.Yes {
+		if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+			fmt.Println("")
+			if !NewInteractivePrompter().PromptConfirm(fmt.Sprintf("Configure snapshots with %s in regress.yaml?", describeSnapshotSetup(snapshot))) {
+				snapshot = nil
+			}
+		} else {
+			fmt.Printf("\nDetected %s; re-run with --yes to configure snapshots with it\n", describeSnapshotSetup(snapshot))
+			snapshot = nil
+		}
+	}
+
+	suite := Walk(opts.Root, []string{})
 
 	suite.createRegressDir()
-	suite.setupConfig(pguri)
+	suite.setupConfig(pguri, snapshot)
 
 	fmt.Println("")
 	fmt.Println("Initialized RegreSQL in ./regresql/")
+	if suggestions := setup.Suggestions(); len(suggestions) > 0 {
+		fmt.Println("")
+		for _, s := range suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
 	fmt.Println("")
-	fmt.Println(`Use 'regresql add <path/to/query.sql>' to add queries to the test suite.`)
+	fmt.Println("Next steps:")
+	fmt.Println(`  regresql discover                     list the SQL files of the project`)
+	fmt.Println(`  regresql add <path/to/query.sql>      add queries to the test suite`)
+	if snapshot != nil {
+		fmt.Println(`  regresql snapshot build               build a reproducible test database`)
+	}
+	fmt.Println(`  regresql update                       capture the expected results`)
+}
+
+// describeSnapshotSetup names the snapshot setting Init would write
+func describeSnapshotSetup(snapshot *SnapshotConfig) string {
+	switch {
+	case snapshot.Migrations != "":
+		return fmt.Sprintf("snapshot.migrations: %s", snapshot.Migrations)
+	case snapshot.MigrationCommand != "":
+		return fmt.Sprintf("snapshot.migration_command: %s", snapshot.MigrationCommand)
+	default:
+		return fmt.Sprintf("snapshot.schema: %s", snapshot.Schema)
+	}
 }
 
 // PlanQueries create query plans for queries found in the root repository
@@ -118,6 +195,7 @@ func Update(opts UpdateOptions) {
 	suite := Walk(opts.Root, ignorePatterns)
 	suite.SetRunFilter(opts.RunFilter)
 	suite.SetPathFilters(opts.Paths)
+	suite.SetCheckTypes(opts.CheckTypes)
 	config, err = suite.readConfig()
 	if err != nil {
 		fmt.Print(err.Error())
@@ -155,7 +233,7 @@ func Update(opts UpdateOptions) {
 	maybeRestore(config, opts.Root, opts.NoRestore, snapshotOverride, "")
 
 	// Validate schema hasn't changed since last snapshot build
-	if err := ValidateSchemaHash(opts.Root); err != nil {
+	if _, err := ValidateSchemaHash(opts.Root, false); err != nil {
 		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
@@ -183,12 +261,32 @@ func Update(opts UpdateOptions) {
 		os.Exit(1)
 	}
 
+	// Validate schema matches the snapshot's schema state (snapshot.schema_drift)
+	if err := validateSchemaDrift(config, opts.Root, currentSnapshot); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
 	updateOpts := createExpectedOptions{
 		Commit:      opts.Commit,
 		Pending:     opts.Pending,
 		Interactive: opts.Interactive,
 		DryRun:      opts.DryRun,
 		Snapshot:    currentSnapshot,
+		Role:        opts.Role,
+		Selection:   updateSelection{Query: opts.Query, Binding: opts.Binding},
+	}
+	if opts.Failed {
+		failed, err := readLastTestFailures(suite.RegressDir)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		if len(failed) == 0 {
+			fmt.Println("No output test failed in the last 'regresql test' run")
+			return
+		}
+		updateOpts.Selection.Expected = failed
 	}
 	if err := suite.createExpectedResults(config.PgUri, updateOpts); err != nil {
 		fmt.Print(err.Error())
@@ -232,7 +330,12 @@ func maybeRestore(cfg config, root string, noRestore bool, snapshotOverride stri
 		snapshotPath = GetSnapshotPath(cfg.Snapshot, root)
 	}
 
-	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) {
+	storage, remoteKey, err := RemoteSnapshot(cfg.Snapshot, snapshotPath)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) && remoteKey == "" {
 		fmt.Printf("Error: snapshot file not found: %s\n\nRun 'regresql snapshot build' to create a snapshot, or use '--no-restore' to skip\n", snapshotPath)
 		os.Exit(1)
 	}
@@ -249,6 +352,8 @@ func maybeRestore(cfg config, root string, noRestore bool, snapshotOverride stri
 		InputPath:      snapshotPath,
 		Clean:          true,
 		TargetDatabase: targetDB,
+		Storage:        storage,
+		RemoteKey:      remoteKey,
 	}
 	if err := RestoreSnapshot(cfg.PgUri, opts); err != nil {
 		fmt.Printf("Error: failed to restore snapshot: %s\n", err)
@@ -335,6 +440,52 @@ func validateServerSettings(cfg config, root string) error {
 	return nil
 }
 
+// validateSchemaDrift compares the live schema with the schema state captured
+// at snapshot build, as configured by snapshot.schema_drift. snapshot is the
+// snapshot selected with --snapshot; nil means the current one.
+func validateSchemaDrift(cfg config, root string, snapshot *SnapshotInfo) error {
+	mode := GetSchemaDrift(cfg.Snapshot)
+	if mode == ValidateSettingsIgnore {
+		return nil
+	}
+
+	expected, err := snapshotSchema(root, snapshot)
+	if err != nil {
+		if mode == ValidateSettingsStrict {
+			return err
+		}
+		fmt.Printf("Warning: schema drift not checked: %s\n\n", err)
+		return nil
+	}
+	live, err := LoadSchema(root, cfg.PgUri, SchemaSourceLive)
+	if err != nil {
+		return fmt.Errorf("failed to introspect schema: %w", err)
+	}
+
+	diff := DiffSchemas(expected, live)
+	if !diff.HasChanges() {
+		return nil
+	}
+	diff.From, diff.To = SchemaSourceSnapshot, SchemaSourceLive
+
+	var sb strings.Builder
+	PrintSchemaDiff(&sb, diff)
+	if mode == ValidateSettingsStrict {
+		return fmt.Errorf("database schema drifted from the snapshot\n\n%s\nRebuild the snapshot or use schema_drift: warn to continue", sb.String())
+	}
+	fmt.Printf("Warning: database schema drifted from the snapshot\n\n%s\n", sb.String())
+	return nil
+}
+
+// snapshotSchema loads the schema state of snapshot, or of the current
+// snapshot when it is nil
+func snapshotSchema(root string, snapshot *SnapshotInfo) (*DatabaseSchema, error) {
+	if snapshot == nil {
+		return LoadSchema(root, "", SchemaSourceSnapshot)
+	}
+	return LoadSnapshotSchema(snapshot)
+}
+
 // Test runs regression tests for all queries.
 // Each query runs in its own transaction that rolls back (unless commit is true).
 //
@@ -357,17 +508,36 @@ func Test(opts TestOptions) {
 
 	suite := Walk(opts.Root, ignorePatterns)
 	suite.SetRunFilter(opts.RunFilter)
+	suite.SetCheckTypes(opts.CheckTypes)
+	suite.SetParallel(opts.Parallel)
+	suite.SetFailFast(opts.FailFast)
 	config, err = suite.readConfig()
 	if err != nil {
 		fmt.Print(err.Error())
 		os.Exit(3)
 	}
+	if config.PgTAPDir != "" {
+		suite.SetPgTAP(config.PgTAPDir, "")
+	}
+	if opts.Percentile != 0 {
+		if config.Analyze == nil {
+			config.Analyze = &AnalyzeConfig{}
+		}
+		config.Analyze.CostPercentile = opts.Percentile
+	}
+	if config.Analyze != nil {
+		if err := ValidateCostPercentile(config.Analyze.CostPercentile); err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+			os.Exit(3)
+		}
+	}
 
 	// Cache config for plan quality analysis
 	SetGlobalConfig(config)
 
 	// If specific snapshot requested, resolve and use it
 	var snapshotOverride string
+	var overrideSnapshot *SnapshotInfo
 	if opts.Snapshot != "" {
 		snapshotsDir := GetSnapshotsDir(opts.Root)
 		metadata, err := ReadSnapshotMetadata(snapshotsDir)
@@ -385,13 +555,15 @@ func Test(opts TestOptions) {
 			os.Exit(1)
 		}
 		snapshotOverride = info.Path
+		overrideSnapshot = info
 		fmt.Printf("Using snapshot: %s (%s)\n", FormatSnapshotRef(info), info.Path)
 	}
 
 	maybeRestore(config, opts.Root, opts.NoRestore, snapshotOverride, opts.Stats)
 
 	// Validate schema hasn't changed since last snapshot build
-	if err := ValidateSchemaHash(opts.Root); err != nil {
+	schemaHash, err := ValidateSchemaHash(opts.Root, opts.StrictSchema)
+	if err != nil {
 		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
@@ -419,6 +591,12 @@ func Test(opts TestOptions) {
 		os.Exit(1)
 	}
 
+	// Validate schema matches the snapshot's schema state (snapshot.schema_drift)
+	if err := validateSchemaDrift(config, opts.Root, overrideSnapshot); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
 	formatName := opts.FormatName
 	if formatName == "" {
 		formatName = "console"
@@ -432,11 +610,12 @@ func Test(opts TestOptions) {
 	// Configure console formatter options
 	if cf, ok := formatter.(*ConsoleFormatter); ok {
 		cf.SetOptions(ConsoleOptions{
-			Color:    opts.Color,
-			NoColor:  opts.NoColor,
-			FullDiff: opts.FullDiff,
-			NoDiff:   opts.NoDiff,
-			Verbose:  opts.Verbose,
+			Color:      opts.Color,
+			NoColor:    opts.NoColor,
+			FullDiff:   opts.FullDiff,
+			NoDiff:     opts.NoDiff,
+			Verbose:    opts.Verbose,
+			SchemaHash: schemaHash,
 		})
 	}
 
@@ -445,7 +624,25 @@ func Test(opts TestOptions) {
 		fmt.Print(err.Error())
 		os.Exit(13)
 	}
-	if summary.Failed > 0 {
+
+	if err := writeLastTestFailures(suite.RegressDir, summary.Results); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record test failures: %s\n", err)
+	}
+
+	if store := GetResultStoreConfig(); store != nil {
+		if err := StoreResults(store.PgUri, summary.Results, NewTestRun(opts.Root, summary.StartTime)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to store results: %s\n", err)
+		}
+	}
+	if err := NotifyWebhook(GetNotificationConfig(), summary.Results); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send webhook notification: %s\n", err)
+	}
+
+	failed := summary.Failed
+	if opts.Interactive {
+		failed -= reviewFailures(suite, summary, formatter, opts.OutputPath)
+	}
+	if failed > 0 {
 		os.Exit(1)
 	}
 	if opts.FailOnSkipped && summary.Skipped > 0 {
@@ -456,6 +653,34 @@ func Test(opts TestOptions) {
 	}
 }
 
+// reviewFailures prompts for approval of failing output diffs when the
+// console is interactive, or writes them to a JSON manifest otherwise. It
+// returns the number of approved results.
+func reviewFailures(suite *Suite, summary *TestSummary, formatter OutputFormatter, outputPath string) int {
+	approvals := PendingApprovals(summary.Results)
+
+	if outputPath != "" || !isInteractiveFormatter(formatter) {
+		path, err := writeApprovalManifest(suite.RegressDir, approvals)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		} else if path != "" {
+			fmt.Fprintf(os.Stderr, "Not a terminal: %d pending approval(s) written to %s\n", len(approvals), path)
+		}
+		return 0
+	}
+
+	if len(approvals) == 0 {
+		return 0
+	}
+	fmt.Printf("\n%d failing output test(s) to review\n", len(approvals))
+	approved, err := approveInteractively(approvals, NewInteractivePrompter())
+	if err != nil && err != ErrUserQuit {
+		fmt.Printf("Error: %s\n", err)
+	}
+	fmt.Printf("\nApproved %d of %d\n", approved, len(approvals))
+	return approved
+}
+
 func hasSeverityViolation(results []TestResult, strict bool) bool {
 	match := func(sev string) bool {
 		if sev == "error" {
@@ -489,3 +714,14 @@ func List(dir string) {
 	suite := Walk(dir, ignorePatterns)
 	suite.Println()
 }
+
+// ListDetail prints the named queries of each SQL file with their plan,
+// expected and baseline status
+func ListDetail(dir string) error {
+	results, err := Discover(DiscoverOptions{Root: dir})
+	if err != nil {
+		return err
+	}
+	PrintQueryDetail(os.Stdout, results)
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Package regresqltest runs a RegreSQL suite from standard Go tests, so that
+queries show up as subtests and work with go test -run, -v and -timeout:
+
+	func TestQueries(t *testing.T) {
+		regresqltest.NewSuiteFromT(t, "..", os.Getenv("PGURI")).Run()
+	}
+
+Every planned query becomes a subtest named after its SQL file and query,
+with one nested subtest per test result (output comparison per binding,
+cost baseline, timeout). Expected files and baselines are the ones written
+by `regresql update` and `regresql baseline`.
+
+To run the suite against a throwaway PostgreSQL container, use
+regresql/testing/containers. It is a separate package so that this one
+does not depend on testcontainers-go and the Docker client.
+*/
+package regresqltest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// TestSuite runs the planned queries of a RegreSQL project as Go subtests
+type TestSuite struct {
+	t         *testing.T
+	root      string
+	pguri     string
+	runFilter string
+	paths     []string
+}
+
+// NewSuiteFromT prepares a suite rooted at root. When pguri is empty the
+// connection string from regresql/regress.yaml (or DATABASE_URL) is used.
+func NewSuiteFromT(t *testing.T, root, pguri string) *TestSuite {
+	t.Helper()
+	return &TestSuite{t: t, root: root, pguri: pguri}
+}
+
+// SetRunFilter limits the suite to queries whose file or query name matches
+// the regexp, like `regresql test --run`
+func (s *TestSuite) SetRunFilter(pattern string) *TestSuite {
+	s.runFilter = pattern
+	return s
+}
+
+// SetPathFilters limits the suite to the given files or directories,
+// relative to the suite root
+func (s *TestSuite) SetPathFilters(paths ...string) *TestSuite {
+	s.paths = paths
+	return s
+}
+
+// Run executes every planned query as a subtest. Queries run in rolled back
+// transactions; the test context and the go test deadline bound each query.
+func (s *TestSuite) Run() {
+	t := s.t
+	t.Helper()
+
+	cfg, err := regresql.ReadConfig(s.root)
+	if err != nil {
+		t.Fatalf("regresql: %s", err)
+	}
+	regresql.SetGlobalConfig(cfg)
+
+	pguri := s.pguri
+	if pguri == "" {
+		pguri = cfg.PgUri
+	}
+
+	db, err := sql.Open("pgx", pguri)
+	if err != nil {
+		t.Fatalf("regresql: failed to connect to '%s': %s", pguri, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	suite := regresql.Walk(s.root, cfg.Ignore)
+	suite.SetRunFilter(s.runFilter)
+	suite.SetPathFilters(s.paths)
+
+	plannedQueries, err := suite.PlannedQueries()
+	if err != nil {
+		t.Fatalf("regresql: %s", err)
+	}
+	if len(plannedQueries) == 0 {
+		t.Skip("regresql: no planned queries found")
+	}
+
+	for _, pq := range plannedQueries {
+		t.Run(subtestName(pq), func(t *testing.T) {
+			ctx := t.Context()
+			if deadline, ok := t.Deadline(); ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, deadline)
+				defer cancel()
+			}
+
+			results, err := suite.RunPlannedQuery(ctx, db, pq)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			for _, r := range results {
+				t.Run(r.Name, func(t *testing.T) {
+					report(t, r)
+				})
+			}
+		})
+	}
+}
+
+// subtestName is the SQL file path without extension plus the query name,
+// e.g. orders/get_order
+func subtestName(pq *regresql.PlannedQuery) string {
+	base := strings.TrimSuffix(filepath.ToSlash(pq.RelPath), filepath.Ext(pq.RelPath))
+	if filepath.Base(base) == pq.Query.Name {
+		return base
+	}
+	return base + "/" + pq.Query.Name
+}
+
+// report turns a single test result into test failures or skips
+func report(t *testing.T, r regresql.TestResult) {
+	t.Helper()
+
+	switch r.Status {
+	case "failed":
+		t.Errorf("%s", failureMessage(r))
+	case "skipped":
+		t.Skip(r.Error)
+	case "pending":
+		t.Skipf("pending: %s", r.Error)
+	}
+}
+
+func failureMessage(r regresql.TestResult) string {
+	var b strings.Builder
+
+	switch r.Type {
+	case "cost":
+		if r.AnalyzeMode && r.BaselineBuffers > 0 {
+			fmt.Fprintf(&b, "buffers increased by %.1f%% (baseline: %d, actual: %d)",
+				r.BufferIncrease, r.BaselineBuffers, r.ActualBuffers)
+		} else {
+			fmt.Fprintf(&b, "cost increased by %.1f%% (baseline: %.2f, actual: %.2f, threshold: %.0f%%)",
+				r.PercentIncrease, r.ExpectedCost, r.ActualCost, r.Threshold)
+		}
+		for _, reg := range r.PlanRegressions {
+			fmt.Fprintf(&b, "\n%s: %s", reg.Severity, reg.Message)
+		}
+	case "output":
+		if sd := r.StructuredDiff; sd != nil {
+			fmt.Fprintf(&b, "output differs (%s): expected %d rows, got %d, %d matching",
+				sd.Type, sd.ExpectedRows, sd.ActualRows, sd.MatchingRows)
+			for _, m := range sd.TypeMismatches {
+				fmt.Fprintf(&b, "\n%s", m)
+			}
+		} else {
+			b.WriteString("output differs from expected")
+		}
+		if r.Diff != "" {
+			fmt.Fprintf(&b, "\n%s", r.Diff)
+		}
+	}
+
+	if r.Error != "" {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(r.Error)
+	}
+	return b.String()
+}
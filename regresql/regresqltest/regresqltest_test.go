@@ -0,0 +1,53 @@
+package regresqltest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/boringsql/regresql/v2/regresql"
+)
+
+func TestSubtestName(t *testing.T) {
+	single, _ := regresql.NewQueryFromString("get_order", "select 1")
+	multi, _ := regresql.NewQueryFromString("by_id", "select 1")
+
+	cases := []struct {
+		pq   *regresql.PlannedQuery
+		want string
+	}{
+		{&regresql.PlannedQuery{Query: single, RelPath: "orders/get_order.sql"}, "orders/get_order"},
+		{&regresql.PlannedQuery{Query: multi, RelPath: "orders/orders.sql"}, "orders/orders/by_id"},
+	}
+	for _, tc := range cases {
+		if got := subtestName(tc.pq); got != tc.want {
+			t.Errorf("subtestName(%s) = %q, want %q", tc.pq.RelPath, got, tc.want)
+		}
+	}
+}
+
+func TestFailureMessage(t *testing.T) {
+	cost := failureMessage(regresql.TestResult{
+		Type:            "cost",
+		ExpectedCost:    10,
+		ActualCost:      25,
+		PercentIncrease: 150,
+		Threshold:       10,
+	})
+	if !strings.Contains(cost, "baseline: 10.00, actual: 25.00") {
+		t.Errorf("cost message = %q", cost)
+	}
+
+	output := failureMessage(regresql.TestResult{
+		Type: "output",
+		StructuredDiff: &regresql.StructuredDiff{
+			Type:         regresql.DiffTypeRowCount,
+			ExpectedRows: 3,
+			ActualRows:   2,
+			MatchingRows: 2,
+		},
+		Diff: "-row",
+	})
+	if !strings.Contains(output, "expected 3 rows, got 2") || !strings.HasSuffix(output, "-row") {
+		t.Errorf("output message = %q", output)
+	}
+}
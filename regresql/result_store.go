@@ -0,0 +1,259 @@
+package regresql
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultResultStoreTable is the table StoreResults writes to when
+// result_store.table isn't set
+const DefaultResultStoreTable = "regresql_results"
+
+type (
+	// TestRun identifies one `regresql test` run in the result store
+	TestRun struct {
+		ID        string
+		Timestamp time.Time
+		Root      string // query files are stored relative to it when set
+	}
+
+	ResultsQueryOptions struct {
+		Root  string
+		Since string // e.g. "30d", "2w" or "12h"
+		Query string // query file relative to Root, empty for all
+	}
+
+	// DailyResults are the stored results of one day
+	DailyResults struct {
+		Day         time.Time
+		Runs        int
+		Tests       int
+		Failed      int
+		AvgDuration float64 // seconds
+	}
+
+	// QueryResults are the stored results of one query binding
+	QueryResults struct {
+		QueryFile   string
+		Binding     string
+		Tests       int
+		Failed      int
+		AvgDuration float64 // seconds
+		AvgCost     float64 // of cost tests, 0 without any
+		LastStatus  string
+		LastRun     time.Time
+	}
+)
+
+// NewTestRun returns a run started at start, with an ID unique across runs
+func NewTestRun(root string, start time.Time) TestRun {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return TestRun{
+		ID:        start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		Timestamp: start,
+		Root:      root,
+	}
+}
+
+// StoreResults appends the results of a run to the result_store table,
+// creating the table when it doesn't exist. Cost and percent_increase are
+// only set for cost tests; duration is in seconds.
+func StoreResults(pguri string, results []TestResult, run TestRun) error {
+	db, err := OpenDB(pguri)
+	if err != nil {
+		return fmt.Errorf("failed to connect to result store: %w", err)
+	}
+	defer db.Close()
+
+	table := resultStoreTable()
+	if _, err := db.Exec(createResultsTableSQL(table)); err != nil {
+		return fmt.Errorf("failed to create result store table: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO " + table + ` (run_id, timestamp, query_file, binding_name, test_name, test_type, status, duration, cost, percent_increase)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range results {
+		if _, err := stmt.Exec(resultRow(r, run)...); err != nil {
+			return fmt.Errorf("failed to store result %s: %w", r.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// resultStoreTable returns the quoted result_store table name
+func resultStoreTable() string {
+	table := DefaultResultStoreTable
+	if cfg := GetResultStoreConfig(); cfg != nil && cfg.Table != "" {
+		table = cfg.Table
+	}
+	schema, name := parseTableName(table)
+	return QuoteIdentifier(schema) + "." + QuoteIdentifier(name)
+}
+
+func createResultsTableSQL(table string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + table + ` (
+	run_id           text NOT NULL,
+	timestamp        timestamptz NOT NULL,
+	query_file       text NOT NULL,
+	binding_name     text NOT NULL,
+	test_name        text NOT NULL,
+	test_type        text NOT NULL,
+	status           text NOT NULL,
+	duration         double precision NOT NULL,
+	cost             double precision,
+	percent_increase double precision
+)`
+}
+
+// resultRow returns the insert arguments of a result
+func resultRow(r TestResult, run TestRun) []any {
+	queryFile := r.QueryFile
+	if run.Root != "" && filepath.IsAbs(queryFile) == filepath.IsAbs(run.Root) {
+		if rel, err := filepath.Rel(run.Root, queryFile); err == nil && !strings.HasPrefix(rel, "..") {
+			queryFile = rel
+		}
+	}
+	var cost, increase any
+	if r.Type == "cost" {
+		cost, increase = r.ActualCost, r.PercentIncrease
+	}
+	return []any{
+		run.ID, run.Timestamp, filepath.ToSlash(queryFile), r.BindingName, r.Name, r.Type, r.Status,
+		r.Duration, cost, increase,
+	}
+}
+
+// QueryResultTrends reads the results stored since a time, per day and per
+// query binding; queryFile limits them to one query file
+func QueryResultTrends(pguri string, since time.Time, queryFile string) ([]DailyResults, []QueryResults, error) {
+	db, err := OpenDB(pguri)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to result store: %w", err)
+	}
+	defer db.Close()
+
+	table := resultStoreTable()
+	where := " WHERE timestamp >= $1 AND ($2 = '' OR query_file = $2)"
+	args := []any{since, filepath.ToSlash(queryFile)}
+
+	rows, err := db.Query(`SELECT date_trunc('day', timestamp), count(DISTINCT run_id), count(*),
+		count(*) FILTER (WHERE status = 'failed'), avg(duration)
+		FROM `+table+where+` GROUP BY 1 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query result store: %w", err)
+	}
+	var days []DailyResults
+	for rows.Next() {
+		var d DailyResults
+		if err := rows.Scan(&d.Day, &d.Runs, &d.Tests, &d.Failed, &d.AvgDuration); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		days = append(days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = db.Query(`SELECT query_file, binding_name, count(*),
+		count(*) FILTER (WHERE status = 'failed'), avg(duration), coalesce(avg(cost), 0),
+		(array_agg(status ORDER BY timestamp DESC))[1], max(timestamp)
+		FROM `+table+where+`
+		GROUP BY 1, 2 ORDER BY 4 DESC, 1, 2`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query result store: %w", err)
+	}
+	defer rows.Close()
+	var queries []QueryResults
+	for rows.Next() {
+		var q QueryResults
+		if err := rows.Scan(&q.QueryFile, &q.Binding, &q.Tests, &q.Failed, &q.AvgDuration, &q.AvgCost, &q.LastStatus, &q.LastRun); err != nil {
+			return nil, nil, err
+		}
+		queries = append(queries, q)
+	}
+	return days, queries, rows.Err()
+}
+
+// QueryStoredResults prints the pass rate trend of the stored results.
+// Returns exit code: 0 = ok, 2 = error
+func QueryStoredResults(opts ResultsQueryOptions) int {
+	config, err := ReadConfig(opts.Root)
+	if err != nil {
+		fmt.Printf("Error reading config: %s\n", err.Error())
+		return 2
+	}
+	SetGlobalConfig(config)
+	store := GetResultStoreConfig()
+	if store == nil {
+		fmt.Println("Error: no result_store.pguri configured in regresql/regress.yaml")
+		return 2
+	}
+	age, err := ParseRetention(opts.Since)
+	if err != nil {
+		fmt.Printf("Error: --since: %s\n", err.Error())
+		return 2
+	}
+
+	since := time.Now().Add(-age)
+	days, queries, err := QueryResultTrends(store.PgUri, since, opts.Query)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return 2
+	}
+	if len(days) == 0 {
+		fmt.Printf("No results stored since %s\n", since.Format(time.DateOnly))
+		return 0
+	}
+	PrintResultTrends(os.Stdout, days, queries)
+	return 0
+}
+
+// PrintResultTrends writes the per-day and per-query results as tables
+func PrintResultTrends(w io.Writer, days []DailyResults, queries []QueryResults) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "day\truns\ttests\tfailed\tpass rate\tavg duration\t")
+	for _, d := range days {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%.3fs\t\n", d.Day.Format(time.DateOnly), d.Runs, d.Tests, d.Failed, passRate(d.Tests, d.Failed), d.AvgDuration)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "query\ttests\tfailed\tpass rate\tavg duration\tavg cost\tlast\t")
+	for _, q := range queries {
+		cost := "-"
+		if q.AvgCost > 0 {
+			cost = fmt.Sprintf("%.2f", q.AvgCost)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%.3fs\t%s\t%s\t\n", benchmarkLabel(q.QueryFile, q.Binding), q.Tests, q.Failed, passRate(q.Tests, q.Failed), q.AvgDuration, cost, q.LastStatus)
+	}
+	tw.Flush()
+}
+
+func passRate(tests, failed int) string {
+	if tests == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(tests-failed)/float64(tests)*100)
+}
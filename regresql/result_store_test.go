@@ -0,0 +1,62 @@
+package regresql
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultRow(t *testing.T) {
+	run := TestRun{ID: "run-1", Timestamp: time.Unix(0, 0), Root: filepath.FromSlash("/project")}
+
+	cost := TestResult{
+		Name: "get_order.1.cost", Type: "cost", Status: "failed", Duration: 0.5,
+		ActualCost: 120, PercentIncrease: 20, BindingName: "1",
+		QueryFile: filepath.FromSlash("/project/sql/orders/get_order.sql"),
+	}
+	row := resultRow(cost, run)
+	if row[2] != "sql/orders/get_order.sql" || row[3] != "1" || row[6] != "failed" || row[8] != 120.0 || row[9] != 20.0 {
+		t.Errorf("cost row = %v", row)
+	}
+
+	output := TestResult{Name: "get_order.1.json", Type: "output", Status: "passed", QueryFile: "elsewhere/q.sql"}
+	row = resultRow(output, run)
+	if row[2] != "elsewhere/q.sql" || row[8] != nil || row[9] != nil {
+		t.Errorf("output row = %v, want query file kept and no cost", row)
+	}
+}
+
+func TestResultStoreTable(t *testing.T) {
+	defer SetGlobalConfig(config{})
+
+	SetGlobalConfig(config{})
+	if got := resultStoreTable(); got != `"public"."regresql_results"` {
+		t.Errorf("default table = %s", got)
+	}
+
+	t.Setenv("METRICS_DB", "postgres://metrics/db")
+	SetGlobalConfig(config{ResultStore: &ResultStoreConfig{PgUri: "$METRICS_DB", Table: "ci.results"}})
+	if got := resultStoreTable(); got != `"ci"."results"` {
+		t.Errorf("configured table = %s", got)
+	}
+	if got := GetResultStoreConfig().PgUri; got != "postgres://metrics/db" {
+		t.Errorf("pguri = %s, want $METRICS_DB expanded", got)
+	}
+}
+
+func TestPrintResultTrends(t *testing.T) {
+	days := []DailyResults{{Day: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Runs: 2, Tests: 8, Failed: 2, AvgDuration: 0.25}}
+	queries := []QueryResults{
+		{QueryFile: "sql/orders.sql", Binding: "1", Tests: 4, Failed: 2, AvgDuration: 0.1, AvgCost: 42, LastStatus: "failed"},
+		{QueryFile: "sql/users.sql", Tests: 4, AvgDuration: 0.4, LastStatus: "passed"},
+	}
+	var b strings.Builder
+	PrintResultTrends(&b, days, queries)
+	out := b.String()
+	for _, want := range []string{"2026-01-02", "75.0%", "sql/orders.sql.1", "50.0%", "42.00", "100.0%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
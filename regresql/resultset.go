@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,9 +20,13 @@ A ResultSet stores the result of a Query in Filename, with Cols and Rows
 separated.
 */
 type ResultSet struct {
-	Cols     []string `json:"columns"`
-	Rows     [][]any  `json:"rows"`
-	Filename string   `json:"-"`
+	Cols        []string `json:"columns"`
+	ColumnTypes []string `json:"column_types,omitempty"`
+	Rows        [][]any  `json:"rows"`
+	Filename    string   `json:"-"`
+
+	// Sampling is set when Rows is a sample of a larger result
+	Sampling *SamplingMetadata `json:"sampling,omitempty"`
 }
 
 // TestConnectionString connects to PostgreSQL with pguri and issue a single
@@ -63,6 +68,15 @@ func RunQuery(ctx context.Context, q Querier, query string, args ...any) (*Resul
 		return nil, err
 	}
 
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	types := make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		types[i] = normalizeColumnType(ct.DatabaseTypeName())
+	}
+
 	res := make([][]any, 0)
 	for rows.Next() {
 		container := make([]any, len(cols))
@@ -78,7 +92,9 @@ func RunQuery(ctx context.Context, q Querier, query string, args ...any) (*Resul
 		}
 		res = append(res, r)
 	}
-	return &ResultSet{cols, res, ""}, nil
+	rows.Close()
+	resolveTypeOIDs(ctx, q, types)
+	return &ResultSet{Cols: cols, ColumnTypes: types, Rows: res}, nil
 }
 
 // Println outputs to standard output a Pretty Printed result set.
@@ -146,17 +162,22 @@ func (r *ResultSet) PrettyPrint() string {
 }
 
 // Writes the Result Set r to filename, overwriting it if already exists
-// when overwrite is true
+// when overwrite is true. A .txt filename (format=psql queries) gets the
+// psql table format, anything else JSON.
 func (r *ResultSet) Write(filename string, overwrite bool) error {
+	if _, err := os.Stat(filename); err == nil && !overwrite {
+		return fmt.Errorf("target file '%s' already exists", filename)
+	}
+
+	if isPsqlResultFile(filename) {
+		return r.WritePsqlFormat(filename)
+	}
+
 	jsonBytes, err := json.MarshalIndent(r, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal result set to JSON: %w", err)
 	}
 
-	if _, err = os.Stat(filename); err == nil && !overwrite {
-		return fmt.Errorf("target file '%s' already exists", filename)
-	}
-
 	if err := os.WriteFile(filename, jsonBytes, 0644); err != nil {
 		return fmt.Errorf("failed to write JSON to file '%s': %w", filename, err)
 	}
@@ -164,13 +185,23 @@ func (r *ResultSet) Write(filename string, overwrite bool) error {
 	return nil
 }
 
-// LoadResultSet loads a ResultSet from a JSON file
+// LoadResultSet loads a ResultSet from a JSON file, or from a .txt file in
+// the psql table format
 func LoadResultSet(filename string) (*ResultSet, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file '%s': %w", filename, err)
 	}
 
+	if isPsqlResultFile(filename) {
+		rs, err := parsePsqlFormat(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %w", filename, err)
+		}
+		rs.Filename = filename
+		return rs, nil
+	}
+
 	var rs ResultSet
 	if err := json.Unmarshal(data, &rs); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON from '%s': %w", filename, err)
@@ -180,6 +211,19 @@ func LoadResultSet(filename string) (*ResultSet, error) {
 	return &rs, nil
 }
 
+// Project returns the result set with only the named columns, in the given
+// order; it fails when a column is missing
+func (r *ResultSet) Project(columns []string) (*ResultSet, error) {
+	for _, name := range columns {
+		if !slices.Contains(r.Cols, name) {
+			return nil, fmt.Errorf("column %q not in result (columns: %s)", name, strings.Join(r.Cols, ", "))
+		}
+	}
+	projected := projectByNames(r, columns)
+	projected.Sampling = r.Sampling
+	return projected, nil
+}
+
 // ToJSON returns the JSON representation of the ResultSet as a string
 func (r *ResultSet) ToJSON() string {
 	jsonBytes, err := json.Marshal(r)
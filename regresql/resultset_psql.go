@@ -0,0 +1,187 @@
+package regresql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Result set file formats, picked with the format=psql query annotation
+const (
+	ResultFormatJSON = "json"
+	ResultFormatPsql = "psql"
+
+	resultFileExtJSON = ".json"
+	resultFileExtPsql = ".txt"
+)
+
+var psqlFooterPattern = regexp.MustCompile(`^\((\d+) rows?\)$`)
+
+// resultFileExt returns the extension of the expected and actual files of
+// a query: .txt for format=psql, .json otherwise
+func resultFileExt(q *Query) string {
+	if q != nil && q.Query != nil && q.GetRegressQLOptions().Format == ResultFormatPsql {
+		return resultFileExtPsql
+	}
+	return resultFileExtJSON
+}
+
+// isPsqlResultFile reports whether filename holds a result set in the psql
+// table format rather than JSON
+func isPsqlResultFile(filename string) bool {
+	return filepath.Ext(filename) == resultFileExtPsql
+}
+
+// alternateResultFile returns filename with the other result format's
+// extension, so expected files in either format are found
+func alternateResultFile(filename string) string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if isPsqlResultFile(filename) {
+		return base + resultFileExtJSON
+	}
+	return base + resultFileExtPsql
+}
+
+// FormatPsql renders the result set like psql's aligned output: a centered
+// header, numbers aligned right, NULL as an empty cell and a row count
+// footer. Newlines inside values are written as \n to keep one line per row.
+func (r *ResultSet) FormatPsql() string {
+	cells := make([][]string, len(r.Rows))
+	widths := make([]int, len(r.Cols))
+	for i, col := range r.Cols {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for i, row := range r.Rows {
+		cells[i] = make([]string, len(r.Cols))
+		for j := range r.Cols {
+			if j >= len(row) || row[j] == nil {
+				continue
+			}
+			s := strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(valueToString(row[j]))
+			cells[i][j] = s
+			widths[j] = max(widths[j], utf8.RuneCountInString(s))
+		}
+	}
+
+	var b strings.Builder
+	line := func(values []string, align func(col int, s string) string) {
+		parts := make([]string, len(values))
+		for j, s := range values {
+			parts[j] = " " + align(j, s) + " "
+		}
+		b.WriteString(strings.TrimRight(strings.Join(parts, "|"), " ") + "\n")
+	}
+	pad := func(s string, width int, right bool) string {
+		fill := strings.Repeat(" ", width-utf8.RuneCountInString(s))
+		if right {
+			return fill + s
+		}
+		return s + fill
+	}
+
+	line(r.Cols, func(j int, s string) string {
+		left := (widths[j] - utf8.RuneCountInString(s)) / 2
+		return pad(strings.Repeat(" ", left)+s, widths[j], false)
+	})
+	dashes := make([]string, len(widths))
+	for j, w := range widths {
+		dashes[j] = strings.Repeat("-", w+2)
+	}
+	b.WriteString(strings.Join(dashes, "+") + "\n")
+	for i, row := range cells {
+		line(row, func(j int, s string) string {
+			return pad(s, widths[j], j < len(r.Rows[i]) && isNumericValue(r.Rows[i][j]))
+		})
+	}
+
+	if len(r.Rows) == 1 {
+		b.WriteString("(1 row)\n")
+	} else {
+		fmt.Fprintf(&b, "(%d rows)\n", len(r.Rows))
+	}
+	return b.String()
+}
+
+// WritePsqlFormat writes the result set to filename in the psql table
+// format, overwriting it if it exists
+func (r *ResultSet) WritePsqlFormat(filename string) error {
+	if err := os.WriteFile(filename, []byte(r.FormatPsql()), 0644); err != nil {
+		return fmt.Errorf("failed to write result set to file '%s': %w", filename, err)
+	}
+	return nil
+}
+
+// parsePsqlFormat reads a result set written by FormatPsql. Cells are split
+// on the column widths of the dashed separator line, so values containing
+// '|' survive; all values come back as strings, and empty cells as NULL.
+func parsePsqlFormat(data string) (*ResultSet, error) {
+	lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("not a psql table: missing header, separator or row count")
+	}
+	footer := psqlFooterPattern.FindStringSubmatch(lines[len(lines)-1])
+	if footer == nil {
+		return nil, fmt.Errorf("not a psql table: missing row count footer")
+	}
+
+	var widths []int
+	if lines[1] != "" {
+		for _, dashes := range strings.Split(lines[1], "+") {
+			if strings.Trim(dashes, "-") != "" || len(dashes) < 2 {
+				return nil, fmt.Errorf("not a psql table: invalid separator line %q", lines[1])
+			}
+			widths = append(widths, len(dashes))
+		}
+	}
+	split := func(line string) []string {
+		runes := []rune(line)
+		cells := make([]string, len(widths))
+		start := 0
+		for j, w := range widths {
+			end := min(start+w, len(runes))
+			if start < end {
+				cells[j] = strings.TrimSpace(string(runes[start:end]))
+			}
+			start += w + 1
+		}
+		return cells
+	}
+
+	rs := &ResultSet{Cols: split(lines[0]), Rows: make([][]any, 0)}
+	for _, line := range lines[2 : len(lines)-1] {
+		row := make([]any, len(widths))
+		for j, cell := range split(line) {
+			if cell != "" {
+				row[j] = cell
+			}
+		}
+		rs.Rows = append(rs.Rows, row)
+	}
+	if footer[1] != fmt.Sprint(len(rs.Rows)) {
+		return nil, fmt.Errorf("psql table has %d rows, footer says %s", len(rs.Rows), footer[1])
+	}
+	return rs, nil
+}
+
+// psqlText returns the result set as it reads back from the psql format,
+// to compare it with an expected file in that format
+func (r *ResultSet) psqlText() *ResultSet {
+	rs, err := parsePsqlFormat(r.FormatPsql())
+	if err != nil {
+		return r
+	}
+	rs.Filename = r.Filename
+	rs.Sampling = r.Sampling
+	return rs
+}
+
+func isNumericValue(v any) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
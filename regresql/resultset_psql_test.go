@@ -0,0 +1,107 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatPsql(t *testing.T) {
+	rs := &ResultSet{
+		Cols: []string{"id", "name", "note"},
+		Rows: [][]any{
+			{int64(1), "alice", nil},
+			{int64(42), "bob | co", "two\nlines"},
+		},
+	}
+	want := ` id |   name   |    note
+----+----------+------------
+  1 | alice    |
+ 42 | bob | co | two\nlines
+(2 rows)
+`
+	if got := rs.FormatPsql(); got != want {
+		t.Errorf("FormatPsql() =\n%s\nwant\n%s", got, want)
+	}
+
+	parsed, err := parsePsqlFormat(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(parsed.Cols, rs.Cols) || len(parsed.Rows) != 2 {
+		t.Fatalf("parsed = %+v", parsed)
+	}
+	if parsed.Rows[0][0] != "1" || parsed.Rows[0][2] != nil || parsed.Rows[1][1] != "bob | co" || parsed.Rows[1][2] != `two\nlines` {
+		t.Errorf("rows = %v", parsed.Rows)
+	}
+
+	if _, err := parsePsqlFormat(" id\n----\n 1\n(2 rows)\n"); err == nil {
+		t.Error("expected an error for a wrong row count")
+	}
+	if _, err := parsePsqlFormat(`{"columns":["id"],"rows":[[1]]}`); err == nil {
+		t.Error("expected an error for JSON")
+	}
+}
+
+func TestResultSetWritePsql(t *testing.T) {
+	dir := t.TempDir()
+	rs := &ResultSet{Cols: []string{"n"}, Rows: [][]any{{int64(7)}}}
+	path := filepath.Join(dir, "count.txt")
+	if err := rs.Write(path, false); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != " n\n---\n 7\n(1 row)\n" {
+		t.Errorf("written %q", data)
+	}
+
+	loaded, err := LoadResultSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Filename != path || loaded.Rows[0][0] != "7" {
+		t.Errorf("loaded = %+v", loaded)
+	}
+}
+
+func TestCompareResultSetsToResultsPsql(t *testing.T) {
+	regressDir := t.TempDir()
+	outDir := filepath.Join(regressDir, "out")
+	expectedDir := filepath.Join(regressDir, "expected")
+	for _, dir := range []string{outDir, expectedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: format=psql\nselect 1;\n")
+
+	compare := func(rows [][]any) TestResult {
+		t.Helper()
+		actual := ResultSet{Filename: filepath.Join(outDir, "orders.txt"), Cols: []string{"id", "total"}, Rows: rows}
+		if err := actual.Write(actual.Filename, true); err != nil {
+			t.Fatal(err)
+		}
+		plan := &Plan{Query: q, ResultSets: []ResultSet{actual}, Names: []string{"1"}, Bindings: []map[string]any{{}}}
+		return plan.CompareResultSetsToResults(regressDir, expectedDir)[0]
+	}
+
+	// an expected file still in JSON is found and compared by value
+	expected := `{"columns":["id","total"],"rows":[[1,9.5]]}`
+	if err := os.WriteFile(filepath.Join(expectedDir, "orders.json"), []byte(expected), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r := compare([][]any{{int64(1), 9.5}}); r.Status != "passed" || filepath.Ext(r.ExpectedFile) != ".json" {
+		t.Errorf("json expected: status %s, expected file %s", r.Status, r.ExpectedFile)
+	}
+
+	table := " id | total\n----+-------\n  1 |   9.5\n(1 row)\n"
+	if err := os.WriteFile(filepath.Join(expectedDir, "orders.txt"), []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r := compare([][]any{{int64(1), 9.5}}); r.Status != "passed" || filepath.Ext(r.ExpectedFile) != ".txt" {
+		t.Errorf("psql expected: status %s (%s), expected file %s", r.Status, r.Error, r.ExpectedFile)
+	}
+	if r := compare([][]any{{int64(1), 10.5}}); r.Status != "failed" || r.Diff == "" {
+		t.Errorf("changed total: status %s, diff %q", r.Status, r.Diff)
+	}
+}
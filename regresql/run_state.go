@@ -0,0 +1,80 @@
+package regresql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunStateFile records the last regresql test run and the schema hash it
+// computed, next to the snapshot metadata
+const RunStateFile = ".regresql-run.yaml"
+
+// schemaHashCacheTTL is how long a recorded schema hash is reused before the
+// schema is hashed again, so repeated runs skip hashing large schemas
+const schemaHashCacheTTL = time.Minute
+
+type runState struct {
+	LastRun        time.Time `yaml:"last_run"`
+	SchemaPath     string    `yaml:"schema_path,omitempty"`
+	SchemaMtime    time.Time `yaml:"schema_mtime,omitempty"`
+	SchemaSize     int64     `yaml:"schema_size,omitempty"`
+	SchemaHash     string    `yaml:"schema_hash,omitempty"`
+	SchemaHashedAt time.Time `yaml:"schema_hashed_at,omitempty"`
+}
+
+func readRunState(snapshotsDir string) (*runState, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotsDir, RunStateFile))
+	if err != nil {
+		return nil, err
+	}
+	var state runState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func writeRunState(snapshotsDir string, state *runState) error {
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(snapshotsDir, RunStateFile), data, 0o644)
+}
+
+// cachedSchemaHash returns the hash of the schema file. The hash recorded
+// by the last run is reused when it's younger than schemaHashCacheTTL and
+// the file's size and modification time haven't changed.
+func cachedSchemaHash(snapshotsDir, schemaPath string, now time.Time) (string, error) {
+	stat, err := os.Stat(schemaPath)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := readRunState(snapshotsDir)
+	if err != nil {
+		state = &runState{}
+	}
+	state.LastRun = now
+	if state.SchemaPath != schemaPath || state.SchemaHash == "" || now.Sub(state.SchemaHashedAt) >= schemaHashCacheTTL ||
+		state.SchemaSize != stat.Size() || !state.SchemaMtime.Equal(stat.ModTime()) {
+		hash, err := computeSchemaHash(schemaPath)
+		if err != nil {
+			return "", err
+		}
+		state.SchemaPath = schemaPath
+		state.SchemaMtime = stat.ModTime()
+		state.SchemaSize = stat.Size()
+		state.SchemaHash = hash
+		state.SchemaHashedAt = now
+	}
+
+	if err := writeRunState(snapshotsDir, state); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record run state: %s\n", err)
+	}
+	return state.SchemaHash, nil
+}
@@ -0,0 +1,105 @@
+package regresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SamplingMetadata describes how a result set was reduced to a sample. It is
+// stored in the result JSON so expected files show what they cover.
+type SamplingMetadata struct {
+	Seed      string  `json:"seed"`
+	Limit     int     `json:"limit"`
+	Fraction  float64 `json:"fraction"`
+	TotalRows int64   `json:"total_rows"`
+}
+
+// sampleLimit returns the row limit for a query: the sample=N annotation
+// wins over the global max_result_rows (0 = no sampling)
+func (p *Plan) sampleLimit() int {
+	if p.Query != nil {
+		if n := p.Query.GetRegressQLOptions().Sample; n > 0 {
+			return n
+		}
+	}
+	return GetMaxResultRows()
+}
+
+// samplingSeed is derived from the query name so update and test pick the
+// same rows
+func (p *Plan) samplingSeed() string {
+	return "regresql:" + p.Query.Name
+}
+
+// runSampled runs query and, when it returns more than limit rows, keeps a
+// deterministic sample: rows are ordered by a hash of their content and the
+// seed, so an unchanged result always yields the same sample and order.
+func runSampled(ctx context.Context, q Querier, query string, limit int, seed string, args ...any) (*ResultSet, error) {
+	countSQL, sampleSQL := samplingQueries(query, limit, seed)
+
+	var total int64
+	if err := q.QueryRowContext(ctx, countSQL, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+	if total <= int64(limit) {
+		return RunQuery(ctx, q, query, args...)
+	}
+
+	res, err := RunQuery(ctx, q, sampleSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	res.Sampling = &SamplingMetadata{
+		Seed:      seed,
+		Limit:     limit,
+		Fraction:  float64(limit) / float64(total),
+		TotalRows: total,
+	}
+	return res, nil
+}
+
+// samplingQueries wraps query as a subquery to count its rows and to draw
+// the sample. The closing parenthesis goes on its own line, so a query
+// ending in a -- comment doesn't comment it out.
+func samplingQueries(query string, limit int, seed string) (countSQL, sampleSQL string) {
+	inner := strings.TrimRight(strings.TrimSpace(query), ";")
+	if stmts := splitSQLStatements(query); len(stmts) == 1 {
+		inner = stmts[0] // drops a terminating ; followed by a comment
+	}
+	countSQL = fmt.Sprintf("SELECT count(*) FROM (%s\n) AS regresql_q", inner)
+	sampleSQL = fmt.Sprintf("SELECT regresql_q.* FROM (%s\n) AS regresql_q ORDER BY md5(regresql_q::text || %s) LIMIT %d",
+		inner, QuoteLiteral(seed), limit)
+	return countSQL, sampleSQL
+}
+
+// samplingChanged reports whether two sampled result sets were drawn from
+// results of different sizes, which the sample itself may not reveal
+func samplingChanged(expected, actual *ResultSet) bool {
+	e, a := expected.Sampling, actual.Sampling
+	if e == nil && a == nil {
+		return false
+	}
+	if e == nil || a == nil {
+		return true
+	}
+	return e.TotalRows != a.TotalRows
+}
+
+// SamplingSummary describes the sampled totals of a comparison, empty when
+// neither side was sampled
+func (d *StructuredDiff) SamplingSummary() string {
+	e, a := d.ExpectedSampling, d.ActualSampling
+	switch {
+	case e == nil && a == nil:
+		return ""
+	case e == nil:
+		return fmt.Sprintf("actual result sampled: %d of %d rows; expected result was not sampled", a.Limit, a.TotalRows)
+	case a == nil:
+		return fmt.Sprintf("expected result sampled: %d of %d rows; actual result was not sampled", e.Limit, e.TotalRows)
+	case e.TotalRows != a.TotalRows:
+		return fmt.Sprintf("sampled %d rows; total rows changed from %d to %d", a.Limit, e.TotalRows, a.TotalRows)
+	default:
+		return fmt.Sprintf("sampled %d of %d rows", a.Limit, a.TotalRows)
+	}
+}
@@ -0,0 +1,84 @@
+package regresql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareResultSetsSampling(t *testing.T) {
+	rows := [][]any{{int64(1)}, {int64(2)}}
+	sampled := func(total int64) *ResultSet {
+		return &ResultSet{
+			Cols:     []string{"id"},
+			Rows:     rows,
+			Sampling: &SamplingMetadata{Seed: "regresql:q", Limit: 2, Fraction: 2 / float64(total), TotalRows: total},
+		}
+	}
+
+	diff := CompareResultSets(sampled(100), sampled(100), nil)
+	if !diff.Identical {
+		t.Errorf("same sample and total should be identical, got %s", diff.Type)
+	}
+	if got := diff.SamplingSummary(); got != "sampled 2 of 100 rows" {
+		t.Errorf("SamplingSummary() = %q", got)
+	}
+
+	diff = CompareResultSets(sampled(100), sampled(150), nil)
+	if diff.Identical || diff.Type != DiffTypeRowCount {
+		t.Errorf("changed total should fail as row_count, got identical=%v type=%s", diff.Identical, diff.Type)
+	}
+	if got := diff.SamplingSummary(); !strings.Contains(got, "from 100 to 150") {
+		t.Errorf("SamplingSummary() = %q", got)
+	}
+
+	plain := &ResultSet{Cols: []string{"id"}, Rows: rows}
+	if diff := CompareResultSets(plain, plain, nil); !diff.Identical || diff.SamplingSummary() != "" {
+		t.Errorf("unsampled results: identical=%v summary=%q", diff.Identical, diff.SamplingSummary())
+	}
+}
+
+func TestSampleLimit(t *testing.T) {
+	prev := cachedConfig
+	t.Cleanup(func() { cachedConfig = prev })
+
+	annotated := &Plan{Query: queryWithMetadata(t, "-- name: big\n-- regresql: sample=1000\nselect 1;\n")}
+	plain := &Plan{Query: queryWithMetadata(t, "-- name: small\nselect 1;\n")}
+
+	SetGlobalConfig(config{})
+	if got := annotated.sampleLimit(); got != 1000 {
+		t.Errorf("annotated sampleLimit() = %d, want 1000", got)
+	}
+	if got := plain.sampleLimit(); got != 0 {
+		t.Errorf("plain sampleLimit() = %d, want 0", got)
+	}
+
+	SetGlobalConfig(config{MaxResultRows: 5000})
+	if got := plain.sampleLimit(); got != 5000 {
+		t.Errorf("sampleLimit() with max_result_rows = %d, want 5000", got)
+	}
+	if got := annotated.sampleLimit(); got != 1000 {
+		t.Errorf("annotation should win over max_result_rows, got %d", got)
+	}
+}
+
+func TestSamplingQueriesTrailingComment(t *testing.T) {
+	tests := []string{
+		"SELECT id FROM users\n-- newest first",
+		"SELECT id FROM users -- all of them\n",
+		"SELECT id FROM users; -- done",
+	}
+	for _, query := range tests {
+		countSQL, sampleSQL := samplingQueries(query, 10, "regresql:q")
+		for _, sql := range []string{countSQL, sampleSQL} {
+			// the subquery must be closed outside the comment
+			for _, line := range strings.Split(sql, "\n") {
+				if i := strings.Index(line, "--"); i >= 0 && strings.Contains(line[i:], "regresql_q") {
+					t.Errorf("query %q: comment swallows the subquery end in %q", query, sql)
+				}
+			}
+			if strings.Contains(sql, ";") {
+				t.Errorf("query %q: terminating semicolon left in %q", query, sql)
+			}
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package regresql
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -14,32 +16,43 @@ type (
 
 	// TableInfo contains metadata about table
 	TableInfo struct {
-		Schema      string
-		Name        string
-		Columns     map[string]*ColumnInfo
-		PrimaryKey  []string
-		ForeignKeys []*ForeignKeyInfo
+		Schema      string                 `json:"schema"`
+		Name        string                 `json:"name"`
+		Columns     map[string]*ColumnInfo `json:"columns"`
+		PrimaryKey  []string               `json:"primary_key,omitempty"`
+		ForeignKeys []*ForeignKeyInfo      `json:"foreign_keys,omitempty"`
+		Indexes     []*IndexInfo           `json:"indexes,omitempty"`
 	}
 
 	// ColumnInfo provides metadata about a database column
 	ColumnInfo struct {
-		Name         string
-		Type         string
-		IsNullable   bool
-		IsPrimaryKey bool
-		IsForeignKey bool
-		IsUnique     bool
-		ForeignKey   *ForeignKeyInfo
-		Default      *string
-		MaxLength    *int
+		Name         string          `json:"name"`
+		Type         string          `json:"type"`
+		IsNullable   bool            `json:"nullable"`
+		IsPrimaryKey bool            `json:"primary_key,omitempty"`
+		IsForeignKey bool            `json:"foreign_key,omitempty"`
+		IsUnique     bool            `json:"unique,omitempty"`
+		ForeignKey   *ForeignKeyInfo `json:"-"` // one of the table's ForeignKeys
+		Default      *string         `json:"default,omitempty"`
+		MaxLength    *int            `json:"max_length,omitempty"`
+		EnumValues   []string        `json:"enum_values,omitempty"` // labels in sort order, for ENUM columns
+		TypeName     string          `json:"type_name,omitempty"`   // schema-qualified type usable in casts, e.g. pg_catalog.int4
+		IsIdentity   bool            `json:"identity,omitempty"`    // GENERATED ... AS IDENTITY
+		IsGenerated  bool            `json:"generated,omitempty"`   // GENERATED ALWAYS AS (expr) STORED
 	}
 
 	// ForeignKeyInfo describes a foreign key relationship
 	ForeignKeyInfo struct {
-		ConstraintName   string
-		ColumnName       string
-		ReferencedTable  string
-		ReferencedColumn string
+		ConstraintName   string `json:"constraint_name"`
+		ColumnName       string `json:"column_name"`
+		ReferencedTable  string `json:"referenced_table"`
+		ReferencedColumn string `json:"referenced_column"`
+	}
+
+	// IndexInfo describes an index, including the one backing the primary key
+	IndexInfo struct {
+		Name       string `json:"name"`
+		Definition string `json:"definition"` // CREATE INDEX statement from pg_indexes
 	}
 )
 
@@ -112,6 +125,12 @@ func IntrospectSchema(db *sql.DB) (*DatabaseSchema, error) {
 			}
 		}
 
+		indexes, err := getIndexes(db, schemaName, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get indexes for table '%s': %w", qualifiedName, err)
+		}
+		tableInfo.Indexes = indexes
+
 		dbSchema.tables[qualifiedName] = tableInfo
 	}
 
@@ -155,19 +174,94 @@ func parseTableName(name string) (schema, table string) {
 	return "public", name
 }
 
+// pgTypeNames maps the type names the driver reports for result columns
+// (pg_type.typname) to the information_schema names of ColumnInfo.Type
+var pgTypeNames = map[string]string{
+	"int2":         "smallint",
+	"int4":         "integer",
+	"int8":         "bigint",
+	"float4":       "real",
+	"float8":       "double precision",
+	"bool":         "boolean",
+	"varchar":      "character varying",
+	"bpchar":       "character",
+	"char":         `"char"`,
+	"varbit":       "bit varying",
+	"time":         "time without time zone",
+	"timetz":       "time with time zone",
+	"timestamp":    "timestamp without time zone",
+	"timestamptz":  "timestamp with time zone",
+	"array":        "ARRAY",
+	"user-defined": "USER-DEFINED",
+}
+
+// normalizeColumnType returns the ColumnInfo.Type name of a result column
+// type, so int4 reads integer and timestamptz timestamp with time zone.
+// Arrays keep their element type, as format_type() writes them: _int4 reads
+// integer[]. Types the driver only knows by OID are returned as the OID, for
+// RunQuery to resolve with format_type().
+func normalizeColumnType(name string) string {
+	lower := strings.ToLower(name)
+	if mapped, ok := pgTypeNames[lower]; ok {
+		return mapped
+	}
+	if elem, ok := strings.CutPrefix(lower, "_"); ok && elem != "" {
+		return normalizeColumnType(elem) + "[]"
+	}
+	return lower
+}
+
+// isTypeOID reports whether a column type is a bare type OID
+func isTypeOID(name string) bool {
+	return name != "" && strings.Trim(name, "0123456789") == ""
+}
+
+// comparableColumnType reports whether a recorded column type names the
+// type precisely. Expected files written before element and user-defined
+// types were resolved hold ARRAY, USER-DEFINED or a bare OID, which can't
+// be compared.
+func comparableColumnType(name string) bool {
+	return name != "ARRAY" && name != "USER-DEFINED" && !isTypeOID(name)
+}
+
+// resolveTypeOIDs replaces the column types the driver reported as OIDs
+// (enums, domains, composite types and their arrays) with their format_type()
+// names. A type that can't be resolved keeps its OID.
+func resolveTypeOIDs(ctx context.Context, q Querier, types []string) {
+	for i, typ := range types {
+		if !isTypeOID(typ) {
+			continue
+		}
+		var name string
+		if err := q.QueryRowContext(ctx, "SELECT format_type($1::oid, NULL)", typ).Scan(&name); err == nil && name != "" {
+			types[i] = name
+		}
+	}
+}
+
 // getColumns retrieves column metadata for a table
 func getColumns(db *sql.DB, schemaName, tableName string) (map[string]*ColumnInfo, error) {
 	query := `
 		SELECT
-			column_name,
-			data_type,
-			is_nullable,
-			column_default,
-			character_maximum_length
-		FROM information_schema.columns
-		WHERE table_schema = $1
-		  AND table_name = $2
-		ORDER BY ordinal_position
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			c.column_default,
+			c.character_maximum_length,
+			c.udt_schema || '.' || c.udt_name AS type_name,
+			c.is_identity = 'YES' AS is_identity,
+			c.is_generated = 'ALWAYS' AS is_generated,
+			(SELECT json_agg(e.enumlabel ORDER BY e.enumsortorder)
+			   FROM pg_enum e
+			   JOIN pg_type t ON t.oid = e.enumtypid
+			   JOIN pg_namespace n ON n.oid = t.typnamespace
+			  WHERE c.data_type = 'USER-DEFINED'
+			    AND n.nspname = c.udt_schema
+			    AND t.typname = c.udt_name) AS enum_labels
+		FROM information_schema.columns c
+		WHERE c.table_schema = $1
+		  AND c.table_name = $2
+		ORDER BY c.ordinal_position
 	`
 
 	rows, err := db.Query(query, schemaName, tableName)
@@ -184,17 +278,25 @@ func getColumns(db *sql.DB, schemaName, tableName string) (map[string]*ColumnInf
 			isNullable    string
 			columnDefault *string
 			maxLength     *int64
+			typeName      string
+			isIdentity    bool
+			isGenerated   bool
+			enumLabels    []byte
 		)
 
-		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &maxLength); err != nil {
+		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault, &maxLength,
+			&typeName, &isIdentity, &isGenerated, &enumLabels); err != nil {
 			return nil, err
 		}
 
 		col := &ColumnInfo{
-			Name:       columnName,
-			Type:       dataType,
-			IsNullable: isNullable == "YES",
-			Default:    columnDefault,
+			Name:        columnName,
+			Type:        dataType,
+			IsNullable:  isNullable == "YES",
+			Default:     columnDefault,
+			TypeName:    typeName,
+			IsIdentity:  isIdentity,
+			IsGenerated: isGenerated,
 		}
 
 		if maxLength != nil {
@@ -202,6 +304,12 @@ func getColumns(db *sql.DB, schemaName, tableName string) (map[string]*ColumnInf
 			col.MaxLength = &length
 		}
 
+		if enumLabels != nil {
+			if err := json.Unmarshal(enumLabels, &col.EnumValues); err != nil {
+				return nil, fmt.Errorf("failed to parse enum labels for %s.%s.%s: %w", schemaName, tableName, columnName, err)
+			}
+		}
+
 		columns[columnName] = col
 	}
 
@@ -209,7 +317,7 @@ func getColumns(db *sql.DB, schemaName, tableName string) (map[string]*ColumnInf
 }
 
 // getPrimaryKeys retrieves primary key column names for a table
-func getPrimaryKeys(db *sql.DB, schemaName, tableName string) ([]string, error) {
+func getPrimaryKeys(db Querier, schemaName, tableName string) ([]string, error) {
 	// Use schema-qualified name for regclass cast
 	qualifiedName := schemaName + "." + tableName
 	query := `
@@ -310,6 +418,62 @@ func getUniqueColumns(db *sql.DB, schemaName, tableName string) (map[string]bool
 	return uniqueCols, rows.Err()
 }
 
+// getIndexes retrieves the indexes of a table, ordered by name
+func getIndexes(db *sql.DB, schemaName, tableName string) ([]*IndexInfo, error) {
+	query := `
+		SELECT indexname, indexdef
+		FROM pg_indexes
+		WHERE schemaname = $1
+		  AND tablename = $2
+		ORDER BY indexname
+	`
+
+	rows, err := db.Query(query, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []*IndexInfo
+	for rows.Next() {
+		var idx IndexInfo
+		if err := rows.Scan(&idx.Name, &idx.Definition); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, &idx)
+	}
+	return indexes, rows.Err()
+}
+
+// MarshalJSON encodes the schema as {"tables": {"public.users": {...}}}
+func (ds *DatabaseSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Tables map[string]*TableInfo `json:"tables"`
+	}{ds.tables})
+}
+
+// UnmarshalJSON decodes a schema written by MarshalJSON
+func (ds *DatabaseSchema) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Tables map[string]*TableInfo `json:"tables"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Tables == nil {
+		v.Tables = make(map[string]*TableInfo)
+	}
+	for _, table := range v.Tables {
+		for _, fk := range table.ForeignKeys {
+			if col, exists := table.Columns[fk.ColumnName]; exists {
+				col.ForeignKey = fk
+			}
+		}
+	}
+	ds.tables = v.Tables
+	return nil
+}
+
 // GetTable retrieves table metadata by name (schema-qualified or unqualified)
 func (ds *DatabaseSchema) GetTable(name string) (*TableInfo, error) {
 	// Try exact match first (for schema-qualified names)
@@ -0,0 +1,300 @@
+package regresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type (
+	// SchemaDiff lists the structural differences between two schema states
+	SchemaDiff struct {
+		From          string      `json:"from"`
+		To            string      `json:"to"`
+		AddedTables   []string    `json:"added_tables,omitempty"`
+		RemovedTables []string    `json:"removed_tables,omitempty"`
+		ChangedTables []TableDiff `json:"changed_tables,omitempty"`
+	}
+
+	// TableDiff lists the differences of a table present in both states
+	TableDiff struct {
+		Table              string            `json:"table"`
+		AddedColumns       []*ColumnInfo     `json:"added_columns,omitempty"`
+		RemovedColumns     []*ColumnInfo     `json:"removed_columns,omitempty"`
+		ChangedColumns     []ColumnChange    `json:"changed_columns,omitempty"`
+		AddedIndexes       []*IndexInfo      `json:"added_indexes,omitempty"`
+		RemovedIndexes     []*IndexInfo      `json:"removed_indexes,omitempty"`
+		AddedForeignKeys   []*ForeignKeyInfo `json:"added_foreign_keys,omitempty"`
+		RemovedForeignKeys []*ForeignKeyInfo `json:"removed_foreign_keys,omitempty"`
+	}
+
+	// ColumnChange is a column whose type or nullability differs
+	ColumnChange struct {
+		Column       string `json:"column"`
+		FromType     string `json:"from_type"`
+		ToType       string `json:"to_type"`
+		FromNullable bool   `json:"from_nullable"`
+		ToNullable   bool   `json:"to_nullable"`
+	}
+)
+
+const (
+	// SchemaSourceLive introspects the database at pguri
+	SchemaSourceLive = "live"
+	// SchemaSourceSnapshot reads the schema state captured with the current
+	// snapshot
+	SchemaSourceSnapshot = "snapshot"
+)
+
+// HasChanges reports whether the two schema states differ
+func (d *SchemaDiff) HasChanges() bool {
+	return len(d.AddedTables) > 0 || len(d.RemovedTables) > 0 || len(d.ChangedTables) > 0
+}
+
+// DiffSchemas compares two schema states. Indexes are matched by definition
+// and foreign keys by constraint, column and referenced column, so a renamed
+// index shows up as removed and added.
+func DiffSchemas(from, to *DatabaseSchema) *SchemaDiff {
+	diff := &SchemaDiff{}
+
+	for _, name := range sortedTableNames(to) {
+		if _, ok := from.tables[name]; !ok {
+			diff.AddedTables = append(diff.AddedTables, name)
+		}
+	}
+	for _, name := range sortedTableNames(from) {
+		toTable, ok := to.tables[name]
+		if !ok {
+			diff.RemovedTables = append(diff.RemovedTables, name)
+			continue
+		}
+		if td := diffTable(name, from.tables[name], toTable); td != nil {
+			diff.ChangedTables = append(diff.ChangedTables, *td)
+		}
+	}
+	return diff
+}
+
+func diffTable(name string, from, to *TableInfo) *TableDiff {
+	td := &TableDiff{Table: name}
+
+	for _, col := range sortedColumns(to) {
+		if _, ok := from.Columns[col.Name]; !ok {
+			td.AddedColumns = append(td.AddedColumns, col)
+		}
+	}
+	for _, col := range sortedColumns(from) {
+		toCol, ok := to.Columns[col.Name]
+		if !ok {
+			td.RemovedColumns = append(td.RemovedColumns, col)
+			continue
+		}
+		fromType, toType := columnTypeString(col), columnTypeString(toCol)
+		if fromType != toType || col.IsNullable != toCol.IsNullable {
+			td.ChangedColumns = append(td.ChangedColumns, ColumnChange{
+				Column:       col.Name,
+				FromType:     fromType,
+				ToType:       toType,
+				FromNullable: col.IsNullable,
+				ToNullable:   toCol.IsNullable,
+			})
+		}
+	}
+
+	td.AddedIndexes, td.RemovedIndexes = diffByKey(from.Indexes, to.Indexes,
+		func(idx *IndexInfo) string { return idx.Definition })
+	td.AddedForeignKeys, td.RemovedForeignKeys = diffByKey(from.ForeignKeys, to.ForeignKeys, foreignKeyString)
+
+	if len(td.AddedColumns)+len(td.RemovedColumns)+len(td.ChangedColumns)+
+		len(td.AddedIndexes)+len(td.RemovedIndexes)+
+		len(td.AddedForeignKeys)+len(td.RemovedForeignKeys) == 0 {
+		return nil
+	}
+	return td
+}
+
+// diffByKey returns the items of to missing from from (added) and the items
+// of from missing from to (removed), keeping their order
+func diffByKey[T any](from, to []T, key func(T) string) (added, removed []T) {
+	fromKeys := make(map[string]bool, len(from))
+	for _, item := range from {
+		fromKeys[key(item)] = true
+	}
+	toKeys := make(map[string]bool, len(to))
+	for _, item := range to {
+		toKeys[key(item)] = true
+		if !fromKeys[key(item)] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range from {
+		if !toKeys[key(item)] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+// columnTypeString renders a column type with its length, using the type
+// name for user-defined and array types, which information_schema only
+// reports as USER-DEFINED and ARRAY
+func columnTypeString(col *ColumnInfo) string {
+	typ := col.Type
+	if (typ == "USER-DEFINED" || typ == "ARRAY") && col.TypeName != "" {
+		typ = col.TypeName
+	}
+	if col.MaxLength != nil {
+		typ += fmt.Sprintf("(%d)", *col.MaxLength)
+	}
+	return typ
+}
+
+func foreignKeyString(fk *ForeignKeyInfo) string {
+	return fmt.Sprintf("%s (%s) -> %s (%s)", fk.ConstraintName, fk.ColumnName, fk.ReferencedTable, fk.ReferencedColumn)
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+func sortedTableNames(ds *DatabaseSchema) []string {
+	names := ds.GetTables()
+	slices.Sort(names)
+	return names
+}
+
+func sortedColumns(t *TableInfo) []*ColumnInfo {
+	cols := make([]*ColumnInfo, 0, len(t.Columns))
+	for _, col := range t.Columns {
+		cols = append(cols, col)
+	}
+	slices.SortFunc(cols, func(a, b *ColumnInfo) int { return strings.Compare(a.Name, b.Name) })
+	return cols
+}
+
+// PrintSchemaDiff writes a human readable schema diff: + added, - removed,
+// ~ changed
+func PrintSchemaDiff(w io.Writer, d *SchemaDiff) {
+	if !d.HasChanges() {
+		fmt.Fprintf(w, "No schema differences (%s -> %s)\n", d.From, d.To)
+		return
+	}
+
+	fmt.Fprintf(w, "Schema diff: %s -> %s\n\n", d.From, d.To)
+	for _, t := range d.AddedTables {
+		fmt.Fprintf(w, "+ table %s\n", t)
+	}
+	for _, t := range d.RemovedTables {
+		fmt.Fprintf(w, "- table %s\n", t)
+	}
+	for _, td := range d.ChangedTables {
+		fmt.Fprintf(w, "~ table %s\n", td.Table)
+		for _, col := range td.AddedColumns {
+			fmt.Fprintf(w, "    + column %s %s %s\n", col.Name, columnTypeString(col), nullability(col.IsNullable))
+		}
+		for _, col := range td.RemovedColumns {
+			fmt.Fprintf(w, "    - column %s\n", col.Name)
+		}
+		for _, c := range td.ChangedColumns {
+			if c.FromType != c.ToType {
+				fmt.Fprintf(w, "    ~ column %s: %s -> %s\n", c.Column, c.FromType, c.ToType)
+			}
+			if c.FromNullable != c.ToNullable {
+				fmt.Fprintf(w, "    ~ column %s: %s -> %s\n", c.Column, nullability(c.FromNullable), nullability(c.ToNullable))
+			}
+		}
+		for _, idx := range td.AddedIndexes {
+			fmt.Fprintf(w, "    + index %s: %s\n", idx.Name, idx.Definition)
+		}
+		for _, idx := range td.RemovedIndexes {
+			fmt.Fprintf(w, "    - index %s\n", idx.Name)
+		}
+		for _, fk := range td.AddedForeignKeys {
+			fmt.Fprintf(w, "    + foreign key %s\n", foreignKeyString(fk))
+		}
+		for _, fk := range td.RemovedForeignKeys {
+			fmt.Fprintf(w, "    - foreign key %s\n", foreignKeyString(fk))
+		}
+	}
+}
+
+// WriteSchemaDiffJSON writes the diff as indented JSON
+func WriteSchemaDiffJSON(w io.Writer, d *SchemaDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// schemaStatePath is the schema state file stored next to a snapshot, e.g.
+// snapshots/default.schema.json for snapshots/default.dump
+func schemaStatePath(snapshotPath string) string {
+	return strings.TrimSuffix(snapshotPath, filepath.Ext(snapshotPath)) + ".schema.json"
+}
+
+// WriteSchemaState saves an introspected schema as JSON
+func WriteSchemaState(path string, ds *DatabaseSchema) error {
+	data, err := json.MarshalIndent(ds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema state '%s': %w", path, err)
+	}
+	return nil
+}
+
+// ReadSchemaState loads a schema saved with WriteSchemaState
+func ReadSchemaState(path string) (*DatabaseSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema state '%s': %w", path, err)
+	}
+	ds := &DatabaseSchema{}
+	if err := json.Unmarshal(data, ds); err != nil {
+		return nil, fmt.Errorf("failed to parse schema state '%s': %w", path, err)
+	}
+	return ds, nil
+}
+
+// LoadSnapshotSchema reads the schema state captured with the snapshot info
+func LoadSnapshotSchema(info *SnapshotInfo) (*DatabaseSchema, error) {
+	if info.SchemaState == "" {
+		return nil, fmt.Errorf("snapshot %s has no schema state; rebuild it with 'regresql snapshot build'", FormatSnapshotRef(info))
+	}
+	return ReadSchemaState(info.SchemaState)
+}
+
+// LoadSchema resolves a schema source: "live" introspects the database at
+// pguri, "snapshot" reads the state captured with the current snapshot and
+// anything else is a schema state file
+func LoadSchema(root, pguri, source string) (*DatabaseSchema, error) {
+	switch source {
+	case SchemaSourceLive:
+		db, err := OpenDB(pguri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to '%s': %w", pguri, err)
+		}
+		defer db.Close()
+		return IntrospectSchema(db)
+
+	case SchemaSourceSnapshot:
+		metadata, err := ReadSnapshotMetadata(GetSnapshotsDir(root))
+		if err != nil {
+			return nil, fmt.Errorf("no snapshot metadata: %w", err)
+		}
+		if metadata.Current == nil {
+			return nil, fmt.Errorf("no current snapshot; build it with 'regresql snapshot build'")
+		}
+		return LoadSnapshotSchema(metadata.Current)
+
+	default:
+		return ReadSchemaState(source)
+	}
+}
@@ -0,0 +1,248 @@
+package regresql
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func schemaFixture() *DatabaseSchema {
+	length := 100
+	return &DatabaseSchema{tables: map[string]*TableInfo{
+		"public.users": {
+			Schema: "public",
+			Name:   "users",
+			Columns: map[string]*ColumnInfo{
+				"id":       {Name: "id", Type: "integer"},
+				"email":    {Name: "email", Type: "character varying", MaxLength: &length},
+				"nickname": {Name: "nickname", Type: "text", IsNullable: true},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []*IndexInfo{
+				{Name: "users_pkey", Definition: "CREATE UNIQUE INDEX users_pkey ON public.users USING btree (id)"},
+			},
+		},
+		"public.orders": {
+			Schema: "public",
+			Name:   "orders",
+			Columns: map[string]*ColumnInfo{
+				"id":      {Name: "id", Type: "integer"},
+				"user_id": {Name: "user_id", Type: "integer"},
+			},
+			ForeignKeys: []*ForeignKeyInfo{
+				{ConstraintName: "orders_user_id_fkey", ColumnName: "user_id", ReferencedTable: "public.users", ReferencedColumn: "id"},
+			},
+		},
+		"public.legacy": {Schema: "public", Name: "legacy", Columns: map[string]*ColumnInfo{}},
+	}}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	from := schemaFixture()
+	to := schemaFixture()
+
+	delete(to.tables, "public.legacy")
+	to.tables["public.audit_log"] = &TableInfo{Schema: "public", Name: "audit_log", Columns: map[string]*ColumnInfo{}}
+
+	users := to.tables["public.users"]
+	users.Columns["email"] = &ColumnInfo{Name: "email", Type: "text"}
+	users.Columns["nickname"] = &ColumnInfo{Name: "nickname", Type: "text"}
+	delete(users.Columns, "id")
+	users.Columns["last_login"] = &ColumnInfo{Name: "last_login", Type: "timestamp with time zone", IsNullable: true}
+	users.Indexes = append(users.Indexes, &IndexInfo{Name: "users_email_idx", Definition: "CREATE INDEX users_email_idx ON public.users USING btree (email)"})
+
+	to.tables["public.orders"].ForeignKeys = nil
+
+	diff := DiffSchemas(from, to)
+	if !diff.HasChanges() {
+		t.Fatal("HasChanges = false, want true")
+	}
+	if !equalStrings(diff.AddedTables, []string{"public.audit_log"}) || !equalStrings(diff.RemovedTables, []string{"public.legacy"}) {
+		t.Errorf("tables added %v, removed %v", diff.AddedTables, diff.RemovedTables)
+	}
+	if len(diff.ChangedTables) != 2 {
+		t.Fatalf("got %d changed tables, want 2: %+v", len(diff.ChangedTables), diff.ChangedTables)
+	}
+
+	orders, usersDiff := diff.ChangedTables[0], diff.ChangedTables[1]
+	if orders.Table != "public.orders" || len(orders.RemovedForeignKeys) != 1 {
+		t.Errorf("orders diff = %+v", orders)
+	}
+
+	if usersDiff.Table != "public.users" {
+		t.Fatalf("second changed table = %s", usersDiff.Table)
+	}
+	if len(usersDiff.AddedColumns) != 1 || usersDiff.AddedColumns[0].Name != "last_login" {
+		t.Errorf("added columns = %+v", usersDiff.AddedColumns)
+	}
+	if len(usersDiff.RemovedColumns) != 1 || usersDiff.RemovedColumns[0].Name != "id" {
+		t.Errorf("removed columns = %+v", usersDiff.RemovedColumns)
+	}
+	want := []ColumnChange{
+		{Column: "email", FromType: "character varying(100)", ToType: "text"},
+		{Column: "nickname", FromType: "text", ToType: "text", FromNullable: true},
+	}
+	if len(usersDiff.ChangedColumns) != len(want) {
+		t.Fatalf("changed columns = %+v", usersDiff.ChangedColumns)
+	}
+	for i, w := range want {
+		if usersDiff.ChangedColumns[i] != w {
+			t.Errorf("changed column %d = %+v, want %+v", i, usersDiff.ChangedColumns[i], w)
+		}
+	}
+	if len(usersDiff.AddedIndexes) != 1 || usersDiff.AddedIndexes[0].Name != "users_email_idx" || len(usersDiff.RemovedIndexes) != 0 {
+		t.Errorf("indexes added %+v, removed %+v", usersDiff.AddedIndexes, usersDiff.RemovedIndexes)
+	}
+
+	var out strings.Builder
+	PrintSchemaDiff(&out, diff)
+	for _, line := range []string{
+		"+ table public.audit_log",
+		"- table public.legacy",
+		"    - foreign key orders_user_id_fkey (user_id) -> public.users (id)",
+		"    + column last_login timestamp with time zone NULL",
+		"    ~ column email: character varying(100) -> text",
+		"    ~ column nickname: NULL -> NOT NULL",
+		"    + index users_email_idx: CREATE INDEX users_email_idx ON public.users USING btree (email)",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("output missing %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDiffSchemasUnchanged(t *testing.T) {
+	diff := DiffSchemas(schemaFixture(), schemaFixture())
+	if diff.HasChanges() {
+		t.Errorf("identical schemas differ: %+v", diff)
+	}
+}
+
+func TestSchemaStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.schema.json")
+	if err := WriteSchemaState(path, schemaFixture()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSchemaState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := DiffSchemas(schemaFixture(), got); diff.HasChanges() {
+		t.Errorf("schema changed after round trip: %+v", diff)
+	}
+	orders, err := got.GetTable("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fk := orders.Columns["user_id"].ForeignKey; fk == nil || fk.ReferencedTable != "public.users" {
+		t.Errorf("user_id foreign key = %+v, want link to public.users", fk)
+	}
+}
+
+func TestSnapshotSchemaOverride(t *testing.T) {
+	root := t.TempDir()
+	snapshotsDir := GetSnapshotsDir(root)
+	if err := os.MkdirAll(snapshotsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	older := schemaFixture()
+	delete(older.tables, "public.orders")
+	currentState := filepath.Join(snapshotsDir, "default.schema.json")
+	olderState := filepath.Join(snapshotsDir, "default-v1.schema.json")
+	if err := WriteSchemaState(currentState, schemaFixture()); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSchemaState(olderState, older); err != nil {
+		t.Fatal(err)
+	}
+	metadata := &SnapshotMetadata{
+		Current: &SnapshotInfo{Path: filepath.Join(snapshotsDir, "default.dump"), Hash: "sha256:bbb", SchemaState: currentState},
+		History: []*SnapshotInfo{{Path: filepath.Join(snapshotsDir, "default-v1.dump"), Hash: "sha256:aaa", Tag: "v1", SchemaState: olderState}},
+	}
+	if err := WriteSnapshotMetadataFull(snapshotsDir, metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := snapshotSchema(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := current.GetTable("orders"); err != nil {
+		t.Errorf("current snapshot schema: %v", err)
+	}
+
+	info, err := ResolveSnapshot(metadata, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := snapshotSchema(root, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := DiffSchemas(older, got); diff.HasChanges() {
+		t.Errorf("override schema is not the v1 schema state: %+v", diff)
+	}
+}
+
+func TestDiffSchemasArrayAndEnumTypes(t *testing.T) {
+	schema := func(tagsType, statusType string) *DatabaseSchema {
+		return &DatabaseSchema{tables: map[string]*TableInfo{
+			"public.posts": {Schema: "public", Name: "posts", Columns: map[string]*ColumnInfo{
+				"tags":   {Name: "tags", Type: "ARRAY", TypeName: tagsType},
+				"status": {Name: "status", Type: "USER-DEFINED", TypeName: statusType},
+			}},
+		}}
+	}
+
+	diff := DiffSchemas(schema("pg_catalog._int4", "public.post_status"), schema("pg_catalog._text", "public.post_state"))
+	if len(diff.ChangedTables) != 1 || len(diff.ChangedTables[0].ChangedColumns) != 2 {
+		t.Fatalf("DiffSchemas() = %+v, want tags and status changed", diff)
+	}
+}
+
+func TestResolveTypeOIDs(t *testing.T) {
+	db, log := openRecordingDB(t)
+	log.Columns = []string{"format_type"}
+	log.Rows = [][]driver.Value{{"mood"}}
+
+	types := []string{"integer", "16394"}
+	resolveTypeOIDs(context.Background(), db, types)
+	if !equalStrings(types, []string{"integer", "mood"}) {
+		t.Errorf("resolveTypeOIDs() = %v, want [integer mood]", types)
+	}
+	if stmts := log.Statements(); len(stmts) != 1 {
+		t.Errorf("statements = %v, want one format_type lookup", stmts)
+	}
+}
+
+func TestSchemaStatePath(t *testing.T) {
+	if got := schemaStatePath("snapshots/default.dump"); got != "snapshots/default.schema.json" {
+		t.Errorf("schemaStatePath = %s", got)
+	}
+}
+
+func TestNormalizeColumnType(t *testing.T) {
+	for in, want := range map[string]string{
+		"INT4":                     "integer",
+		"TIMESTAMPTZ":              "timestamp with time zone",
+		"VARCHAR":                  "character varying",
+		"_INT4":                    "integer[]",
+		"_TEXT":                    "text[]",
+		"16394":                    "16394",
+		"TEXT":                     "text",
+		"integer":                  "integer",
+		"integer[]":                "integer[]",
+		"timestamp with time zone": "timestamp with time zone",
+		"ARRAY":                    "ARRAY",
+		"USER-DEFINED":             "USER-DEFINED",
+	} {
+		if got := normalizeColumnType(in); got != want {
+			t.Errorf("normalizeColumnType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -21,26 +21,48 @@ type (
 	SnapshotMetadata struct {
 		Current *SnapshotInfo   `yaml:"current"`
 		History []*SnapshotInfo `yaml:"history,omitempty"`
+		// FailedMigrationCommand records the last migration_command that
+		// failed during snapshot build; cleared by the next successful build
+		FailedMigrationCommand *MigrationCommandResult `yaml:"failed_migration_command,omitempty"`
 	}
 
 	SnapshotInfo struct {
-		Path                 string         `yaml:"path"`
-		Hash                 string         `yaml:"hash"`
-		Created              time.Time      `yaml:"created"`
-		SizeBytes            int64          `yaml:"size_bytes"`
-		Format               string         `yaml:"format"`
-		Tag                  string         `yaml:"tag,omitempty"`
-		Note                 string         `yaml:"note,omitempty"`
-		SchemaPath           string         `yaml:"schema_path,omitempty"`
-		SchemaHash           string         `yaml:"schema_hash,omitempty"`
-		MigrationsDir        string         `yaml:"migrations_dir,omitempty"`
-		MigrationsHash       string         `yaml:"migrations_hash,omitempty"`
-		MigrationsApplied    []string       `yaml:"migrations_applied,omitempty"`
-		MigrationCommand     string         `yaml:"migration_command,omitempty"`
-		MigrationCommandHash string         `yaml:"migration_command_hash,omitempty"`
-		FixturesUsed         []string       `yaml:"fixtures_used,omitempty"`
-		FixturizeUsed        []string       `yaml:"fixturize_used,omitempty"`
-		Server               *ServerContext `yaml:"server,omitempty"`
+		Path                   string                  `yaml:"path"`
+		Hash                   string                  `yaml:"hash"`
+		Created                time.Time               `yaml:"created"`
+		SizeBytes              int64                   `yaml:"size_bytes"`
+		Format                 string                  `yaml:"format"`
+		Tag                    string                  `yaml:"tag,omitempty"`
+		Note                   string                  `yaml:"note,omitempty"`
+		SchemaPath             string                  `yaml:"schema_path,omitempty"`
+		SchemaHash             string                  `yaml:"schema_hash,omitempty"`
+		MigrationsDir          string                  `yaml:"migrations_dir,omitempty"`
+		MigrationsHash         string                  `yaml:"migrations_hash,omitempty"`
+		MigrationsApplied      []string                `yaml:"migrations_applied,omitempty"`
+		MigrationsNaming       string                  `yaml:"migrations_naming,omitempty"`
+		MigrationsIncludeUndo  bool                    `yaml:"migrations_include_undo,omitempty"`
+		MigrationChecksums     []MigrationChecksum     `yaml:"migration_checksums,omitempty"`
+		MigrationCommand       string                  `yaml:"migration_command,omitempty"`
+		MigrationCommandHash   string                  `yaml:"migration_command_hash,omitempty"`
+		MigrationCommandResult *MigrationCommandResult `yaml:"migration_command_result,omitempty"`
+		FixturesUsed           []string                `yaml:"fixtures_used,omitempty"`
+		FixturizeUsed          []string                `yaml:"fixturize_used,omitempty"`
+		MasksApplied           []string                `yaml:"masks_applied,omitempty"`
+		Server                 *ServerContext          `yaml:"server,omitempty"`
+		SchemaState            string                  `yaml:"schema_state,omitempty"` // introspected schema, see WriteSchemaState
+		TenantSchema           string                  `yaml:"tenant_schema,omitempty"`
+		RemoteURL              string                  `yaml:"remote_url,omitempty"`
+	}
+
+	// MigrationCommandResult is the outcome of an external migration command.
+	// Stdout and Stderr keep the tail of the output (see migrationOutputLimit).
+	MigrationCommandResult struct {
+		Command  string        `yaml:"command"`
+		ExitCode int           `yaml:"exit_code"`
+		Stdout   string        `yaml:"stdout,omitempty"`
+		Stderr   string        `yaml:"stderr,omitempty"`
+		Started  time.Time     `yaml:"started"`
+		Duration time.Duration `yaml:"duration"`
 	}
 
 	ServerContext struct {
@@ -80,7 +102,8 @@ type (
 		Format         SnapshotFormat
 		SchemaOnly     bool
 		Section        string
-		WithStatistics bool // PostgreSQL 18+: include optimizer statistics
+		WithStatistics bool            // PostgreSQL 18+: include optimizer statistics
+		Storage        SnapshotStorage // upload after capture when set
 	}
 
 	SectionsOptions struct {
@@ -105,6 +128,15 @@ type (
 		Clean          bool   // drop existing objects before restore
 		TargetDatabase string // override database name from connection string
 		WithStatistics bool   // PostgreSQL 18+: restore optimizer statistics
+		Progress       bool   // report progress on stderr while restoring
+		// Storage and RemoteKey locate the snapshot remotely; it is downloaded
+		// to InputPath first when missing locally
+		Storage   SnapshotStorage
+		RemoteKey string
+		// Verify runs VerifySnapshot before restoring, against ExpectedHash
+		// when set
+		Verify       bool
+		ExpectedHash string
 	}
 )
 
@@ -118,6 +150,10 @@ const (
 	SnapshotMetadataFile  = ".regresql-snapshot.yaml"
 	RestoreStateFile      = ".regresql-restore-state.yaml"
 
+	// DefaultSnapshotHistoryMaxEntries caps the snapshot history unless
+	// snapshot.history_max_entries is set
+	DefaultSnapshotHistoryMaxEntries = 50
+
 	ValidateSettingsWarn   ValidateSettingsMode = "warn"
 	ValidateSettingsStrict ValidateSettingsMode = "strict"
 	ValidateSettingsIgnore ValidateSettingsMode = "ignore"
@@ -237,6 +273,12 @@ func CaptureSnapshot(pguri string, opts SnapshotOptions) (*SnapshotInfo, error)
 		Format:    string(opts.Format),
 	}
 
+	if opts.Storage != nil {
+		if err := PushSnapshot(opts.Storage, opts.OutputPath, info); err != nil {
+			return nil, err
+		}
+	}
+
 	return info, nil
 }
 
@@ -402,6 +444,10 @@ func computeDirectoryHash(dirPath string) (string, error) {
 	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// WriteSnapshotMetadata records info as the current snapshot. A tagged
+// previous snapshot moves to the history, which is then capped at
+// snapshot.history_max_entries. Entries dropped by the cap keep their files;
+// only `snapshot prune` deletes snapshot files.
 func WriteSnapshotMetadata(snapshotsDir string, info *SnapshotInfo) error {
 	metadataPath := filepath.Join(snapshotsDir, SnapshotMetadataFile)
 
@@ -409,8 +455,21 @@ func WriteSnapshotMetadata(snapshotsDir string, info *SnapshotInfo) error {
 	var metadata SnapshotMetadata
 	if existing, err := ReadSnapshotMetadata(snapshotsDir); err == nil {
 		metadata.History = existing.History
+		if previous := retireSnapshot(existing.Current, info); previous != nil {
+			metadata.History = append([]*SnapshotInfo{previous}, metadata.History...)
+		}
 	}
 	metadata.Current = info
+	if limit := GetSnapshotHistoryMaxEntries(); len(metadata.History) > limit {
+		dropped := metadata.History[limit:]
+		metadata.History = metadata.History[:limit]
+		fmt.Fprintf(os.Stderr, "Note: snapshot history is capped at %d entries, dropped %d older entries from the metadata\n", limit, len(dropped))
+		for _, d := range dropped {
+			if d.Path != "" && fileExists(d.Path) {
+				fmt.Fprintf(os.Stderr, "  %s is no longer tracked, delete it by hand if unused\n", d.Path)
+			}
+		}
+	}
 
 	data, err := yaml.Marshal(&metadata)
 	if err != nil {
@@ -420,10 +479,31 @@ func WriteSnapshotMetadata(snapshotsDir string, info *SnapshotInfo) error {
 	if err := os.WriteFile(metadataPath, data, 0o644); err != nil {
 		return fmt.Errorf("failed to write snapshot metadata: %w", err)
 	}
-
 	return nil
 }
 
+// retireSnapshot returns the history entry of the snapshot next replaces,
+// nil when it isn't tagged. Rebuilding the same snapshot keeps its tag
+// instead. A history entry whose file next overwrote loses its path, so the
+// tag can't restore the new data.
+func retireSnapshot(previous, next *SnapshotInfo) *SnapshotInfo {
+	if previous == nil || previous.Tag == "" {
+		return nil
+	}
+	samePath := filepath.Clean(previous.Path) == filepath.Clean(next.Path)
+	if samePath && previous.Hash == next.Hash {
+		if next.Tag == "" {
+			next.Tag, next.Note = previous.Tag, previous.Note
+		}
+		return nil
+	}
+	retired := *previous
+	if samePath {
+		retired.Path = ""
+	}
+	return &retired
+}
+
 // WriteSnapshotMetadataFull writes the complete metadata including history
 func WriteSnapshotMetadataFull(snapshotsDir string, metadata *SnapshotMetadata) error {
 	metadataPath := filepath.Join(snapshotsDir, SnapshotMetadataFile)
@@ -554,9 +634,18 @@ func replaceDatabase(pguri, newDB string) (string, error) {
 
 // RestoreSnapshot restores a database snapshot using pg_restore or psql
 func RestoreSnapshot(pguri string, opts RestoreOptions) error {
+	if err := fetchSnapshot(opts.Storage, opts.RemoteKey, opts.InputPath); err != nil {
+		return err
+	}
 	if _, err := os.Stat(opts.InputPath); os.IsNotExist(err) {
 		return fmt.Errorf("snapshot file not found: %s", opts.InputPath)
 	}
+	if opts.Verify {
+		if result := VerifySnapshot(opts.InputPath, opts.Format, opts.ExpectedHash); !result.Valid {
+			return fmt.Errorf("snapshot %s failed verification:\n  %s\n(skip the check with --no-verify)",
+				opts.InputPath, strings.Join(result.Errors, "\n  "))
+		}
+	}
 
 	// Override target database if specified
 	targetURI := pguri
@@ -604,7 +693,7 @@ func restoreWithPgRestore(pguri string, opts RestoreOptions, format SnapshotForm
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := runRestoreCommand(cmd, pguri, opts); err != nil {
 		return fmt.Errorf("pg_restore failed: %w", err)
 	}
 	return nil
@@ -621,7 +710,7 @@ func restoreWithPsql(pguri string, opts RestoreOptions) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := runRestoreCommand(cmd, pguri, opts); err != nil {
 		return fmt.Errorf("psql failed: %w", err)
 	}
 	return nil
@@ -650,32 +739,45 @@ func computeSchemaHash(schemaPath string) (string, error) {
 	return computeFileHash(schemaPath, format)
 }
 
-func ValidateSchemaHash(root string) error {
+// ValidateSchemaHash fails when the schema file changed since the current
+// snapshot was built, and returns the schema's hash for the test output.
+// Without snapshot metadata or a recorded schema it only warns, unless
+// strict is set.
+func ValidateSchemaHash(root string, strict bool) (string, error) {
 	snapshotsDir := GetSnapshotsDir(root)
 
 	metadata, err := ReadSnapshotMetadata(snapshotsDir)
 	if err != nil {
+		if strict {
+			return "", fmt.Errorf("no snapshot metadata found, --strict-schema needs a snapshot from 'regresql snapshot build'")
+		}
 		fmt.Fprintf(os.Stderr, "Warning: no snapshot metadata found. Consider using 'regresql snapshot build' for reproducible tests.\n")
-		return nil
+		return "", nil
 	}
 
 	if metadata.Current == nil || metadata.Current.SchemaPath == "" {
-		return nil
+		if strict {
+			return "", fmt.Errorf("snapshot metadata records no schema file, build the snapshot with 'regresql snapshot build --schema=<file>' to use --strict-schema")
+		}
+		return "", nil
 	}
 
 	// Check if schema file still exists
 	if _, err := os.Stat(metadata.Current.SchemaPath); os.IsNotExist(err) {
+		if strict {
+			return "", fmt.Errorf("schema file %s recorded in the snapshot metadata no longer exists", metadata.Current.SchemaPath)
+		}
 		// Schema file referenced in metadata doesn't exist - stale metadata
-		return nil
+		return "", nil
 	}
 
-	currentHash, err := computeSchemaHash(metadata.Current.SchemaPath)
+	currentHash, err := cachedSchemaHash(snapshotsDir, metadata.Current.SchemaPath, time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to hash schema %s: %w", metadata.Current.SchemaPath, err)
+		return "", fmt.Errorf("failed to hash schema %s: %w", metadata.Current.SchemaPath, err)
 	}
 
 	if currentHash != metadata.Current.SchemaHash {
-		return fmt.Errorf(`schema has changed since last snapshot build
+		return "", fmt.Errorf(`schema has changed since last snapshot build
 
   Schema file: %s
   Expected:    %s
@@ -688,7 +790,7 @@ Run 'regresql snapshot build --schema=%s' to rebuild the snapshot`,
 			metadata.Current.SchemaPath)
 	}
 
-	return nil
+	return currentHash, nil
 }
 
 func ValidateMigrationsHash(root string) error {
@@ -708,7 +810,7 @@ func ValidateMigrationsHash(root string) error {
 		return nil // Stale metadata - directory no longer exists
 	}
 
-	currentFiles, err := discoverMigrations(info.MigrationsDir)
+	currentFiles, err := discoverMigrations(info.MigrationsDir, info.MigrationsNaming, info.MigrationsIncludeUndo)
 	if err != nil {
 		return fmt.Errorf("failed to discover migrations in %s: %w", info.MigrationsDir, err)
 	}
@@ -718,7 +820,7 @@ func ValidateMigrationsHash(root string) error {
 	}
 
 	if len(currentFiles) == 0 {
-		return migrationChangeError(info, "", nil, info.MigrationsApplied)
+		return migrationChangeError(info, "", nil, nil)
 	}
 
 	currentHash, err := computeMigrationsHash(currentFiles)
@@ -736,10 +838,17 @@ func ValidateMigrationsHash(root string) error {
 		currentNames[i] = filepath.Base(f)
 	}
 
-	return migrationChangeError(info, currentHash, currentNames, info.MigrationsApplied)
+	checksums, err := migrationChecksums(currentFiles, info.MigrationsNaming)
+	if err != nil {
+		return fmt.Errorf("failed to hash migrations: %w", err)
+	}
+	return migrationChangeError(info, currentHash, currentNames, checksums)
 }
 
-func migrationChangeError(info *SnapshotInfo, currentHash string, current, stored []string) error {
+// migrationChangeError lists the migrations added and removed since the
+// snapshot, and the modified ones when it recorded per-file checksums
+func migrationChangeError(info *SnapshotInfo, currentHash string, current []string, checksums []MigrationChecksum) error {
+	stored := info.MigrationsApplied
 	currentSet := make(map[string]bool)
 	for _, name := range current {
 		currentSet[name] = true
@@ -761,11 +870,26 @@ func migrationChangeError(info *SnapshotInfo, currentHash string, current, store
 		}
 	}
 
+	storedHashes := make(map[string]string, len(info.MigrationChecksums))
+	for _, c := range info.MigrationChecksums {
+		storedHashes[c.File] = c.Hash
+	}
+	var modified []string
+	for _, c := range checksums {
+		if hash, ok := storedHashes[c.File]; ok && hash != c.Hash {
+			modified = append(modified, c.File)
+		}
+	}
+
 	var changes strings.Builder
 	changes.WriteString("\n  Changes detected:")
-	if len(added) == 0 && len(removed) == 0 {
+	if len(added) == 0 && len(removed) == 0 && len(modified) == 0 {
 		changes.WriteString("\n    ~ content modified")
 	}
+	for _, name := range modified {
+		changes.WriteString("\n    ~ ")
+		changes.WriteString(name)
+	}
 	for _, name := range added {
 		changes.WriteString("\n    + ")
 		changes.WriteString(name)
@@ -841,6 +965,21 @@ Run 'regresql snapshot build' to rebuild the snapshot`,
 	return nil
 }
 
+// RecordMigrationCommandFailure stores a failed migration command result in
+// the snapshot metadata, keeping the current snapshot and history intact
+func RecordMigrationCommandFailure(snapshotsDir string, result *MigrationCommandResult) error {
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil {
+		metadata = &SnapshotMetadata{}
+	}
+	metadata.FailedMigrationCommand = result
+
+	if err := os.MkdirAll(snapshotsDir, 0o755); err != nil {
+		return err
+	}
+	return WriteSnapshotMetadataFull(snapshotsDir, metadata)
+}
+
 // PlannerSettings is the list of PostgreSQL settings that affect query plans
 var PlannerSettings = []string{
 	"random_page_cost",
@@ -979,3 +1118,19 @@ func GetValidateSettings(cfg *SnapshotConfig) ValidateSettingsMode {
 		return ValidateSettingsWarn
 	}
 }
+
+// GetSchemaDrift returns how regresql test handles schema drift from the
+// snapshot's schema state (warn, strict, ignore); checking is off by default
+func GetSchemaDrift(cfg *SnapshotConfig) ValidateSettingsMode {
+	if cfg == nil {
+		return ValidateSettingsIgnore
+	}
+	switch cfg.SchemaDrift {
+	case "strict":
+		return ValidateSettingsStrict
+	case "warn":
+		return ValidateSettingsWarn
+	default:
+		return ValidateSettingsIgnore
+	}
+}
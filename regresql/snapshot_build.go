@@ -1,17 +1,21 @@
 package regresql
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/boringsql/regresql/v2/regresql/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type (
@@ -20,22 +24,59 @@ type (
 		Format             SnapshotFormat
 		SchemaPath         string
 		MigrationsDir      string
+		MigrationsNaming   string // golang-migrate, flyway, timestamp, dbmate or goose; see discoverMigrations
+		IncludeUndo        bool   // also apply Flyway undo (U) migrations, last
 		MigrationCommand   string
 		Fixtures           []string
 		Fixturize          []string
+		CSVNullValue       string            // CSV field value loaded as NULL
+		Masks              map[string]string // table.column -> SQL expression applied after fixtures
+		ResetSequences     bool              // move owned sequences past the loaded rows
 		Verbose            bool
 		IgnoreSchemaErrors bool
 		DisableTriggers    bool
+		Storage            SnapshotStorage // upload the captured snapshot when set
+		Cache              bool            // reuse the current snapshot when no input changed
+		Incremental        bool            // reload only tables of changed CSV fixtures (implies Cache)
+		TenantSchema       string          // load fixtures into this schema (schema-per-tenant)
+	}
+
+	// MigrationCommandError is returned by BuildSnapshot when the external
+	// migration command fails; Result holds its exit code and output
+	MigrationCommandError struct {
+		Result *MigrationCommandResult
+		Err    error
 	}
 
 	snapshotBuildResult struct {
 		Info         *SnapshotInfo
 		FixturesUsed []string
 		Duration     time.Duration
+		Cached       bool     // nothing changed, Info is the existing snapshot
+		Changed      []string // with Cache, what triggered the rebuild
+		Reloaded     []string // with Incremental, the tables truncated and reloaded
 	}
 )
 
+// BuildSnapshot builds a snapshot in a temporary database from the schema,
+// migrations and fixtures in opts, traced as a regresql.snapshot.build span
 func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*snapshotBuildResult, error) {
+	ctx, span := tracing.Start(context.Background(), "regresql.snapshot.build",
+		attribute.String("regresql.snapshot.path", opts.OutputPath),
+		attribute.StringSlice("regresql.fixtures", opts.Fixtures),
+	)
+	result, err := buildSnapshot(ctx, basePgUri, root, opts)
+	if result != nil {
+		span.SetAttributes(
+			attribute.Bool("regresql.snapshot.cached", result.Cached),
+			attribute.Float64("regresql.snapshot.duration_ms", float64(result.Duration.Microseconds())/1000),
+		)
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func buildSnapshot(ctx context.Context, basePgUri string, root string, opts SnapshotBuildOptions) (*snapshotBuildResult, error) {
 	startTime := time.Now()
 
 	if err := CheckPgTool("pg_dump", root); err != nil {
@@ -60,6 +101,46 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 		return nil, fmt.Errorf("no schema, migrations, or fixtures specified for snapshot build")
 	}
 
+	// with Cache, skip the build when schema, migrations, fixtures and
+	// options all hash the same as for the current snapshot. The result of
+	// an external migration command cannot be hashed, so it always rebuilds.
+	var inputs *fixtureCache
+	var changed []string
+	if (opts.Cache || opts.Incremental) && opts.MigrationCommand == "" {
+		var err error
+		inputs, err = buildInputs(root, opts)
+		if err != nil {
+			return nil, err
+		}
+		var info *SnapshotInfo
+		if info, changed = cachedSnapshot(opts.OutputPath, inputs); info != nil {
+			return &snapshotBuildResult{
+				Info:         info,
+				FixturesUsed: info.FixturesUsed,
+				Duration:     time.Since(startTime),
+				Cached:       true,
+			}, nil
+		}
+	}
+
+	// with Incremental, reload only the tables whose CSV fixtures changed
+	// into a copy of the current snapshot
+	if opts.Incremental {
+		reason := "migration_command is set"
+		if inputs != nil {
+			result, r, err := buildIncremental(basePgUri, root, opts, inputs)
+			if err != nil {
+				return nil, err
+			}
+			if result != nil {
+				result.Duration = time.Since(startTime)
+				return result, nil
+			}
+			reason = r
+		}
+		fmt.Printf("Incremental build not possible (%s), running a full build\n", reason)
+	}
+
 	if opts.Verbose {
 		fmt.Printf("Creating temporary database...\n")
 	}
@@ -106,10 +187,12 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 	// Apply migrations - either from directory or via external command (mutually exclusive)
 	var migrationsApplied []string
 	var migrationsHash string
+	var checksums []MigrationChecksum
 	var migrationCommandHash string
+	var migrationCommandResult *MigrationCommandResult
 
 	if opts.MigrationsDir != "" {
-		migrationFiles, err := discoverMigrations(opts.MigrationsDir)
+		migrationFiles, err := discoverMigrations(opts.MigrationsDir, opts.MigrationsNaming, opts.IncludeUndo)
 		if err != nil {
 			return nil, fmt.Errorf("failed to discover migrations: %w", err)
 		}
@@ -118,7 +201,7 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 			if opts.Verbose {
 				fmt.Printf("Applying %d migration(s)...\n", len(migrationFiles))
 			}
-			if err := applyMigrations(db, migrationFiles, opts.Verbose); err != nil {
+			if err := applyMigrations(db, migrationFiles, opts.MigrationsNaming, opts.Verbose); err != nil {
 				return nil, err
 			}
 
@@ -130,21 +213,45 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 			if err != nil {
 				return nil, fmt.Errorf("failed to compute migrations hash: %w", err)
 			}
+			checksums, err = migrationChecksums(migrationFiles, opts.MigrationsNaming)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute migration checksums: %w", err)
+			}
 		}
 	} else if opts.MigrationCommand != "" {
-		if err := runMigrationCommand(opts.MigrationCommand, tempDB.PgUri, opts.Verbose); err != nil {
-			return nil, err
+		migrationCommandResult, err = runMigrationCommand(opts.MigrationCommand, tempDB.PgUri, nil, opts.Verbose)
+		if err != nil {
+			return nil, &MigrationCommandError{Result: migrationCommandResult, Err: err}
 		}
 		migrationCommandHash = computeCommandHash(opts.MigrationCommand)
 	}
 
+	// with a tenant schema, fixtures run on connections whose search_path
+	// starts with that schema
+	fixtureDB, fixtureURI := db, tempDB.PgUri
+	if opts.TenantSchema != "" {
+		if err := checkSchemaExists(db, opts.TenantSchema); err != nil {
+			return nil, err
+		}
+		if fixtureURI, err = withSearchPath(tempDB.PgUri, opts.TenantSchema); err != nil {
+			return nil, err
+		}
+		if fixtureDB, err = OpenDB(fixtureURI); err != nil {
+			return nil, fmt.Errorf("failed to connect to temp database: %w", err)
+		}
+		defer fixtureDB.Close()
+		if opts.Verbose {
+			fmt.Printf("Loading fixtures into tenant schema %s\n", opts.TenantSchema)
+		}
+	}
+
 	var fixturesUsed []string
 	if len(opts.Fixtures) > 0 {
 		if opts.DisableTriggers {
 			if opts.Verbose {
 				fmt.Println("Disabling triggers (session_replication_role = replica)...")
 			}
-			if _, err := db.Exec("SET session_replication_role = 'replica'"); err != nil {
+			if _, err := fixtureDB.Exec("SET session_replication_role = 'replica'"); err != nil {
 				return nil, fmt.Errorf("failed to disable triggers: %w", err)
 			}
 		}
@@ -152,13 +259,18 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 		if opts.Verbose {
 			fmt.Printf("Applying %d fixture(s)...\n", len(opts.Fixtures))
 		}
-		fixturesUsed, err = applySQLFixtures(db, root, opts.Fixtures, opts.Verbose)
+		_, span := tracing.Start(ctx, "regresql.fixtures.apply",
+			attribute.StringSlice("regresql.fixtures", opts.Fixtures),
+			attribute.String("regresql.fixtures.schema", opts.TenantSchema),
+		)
+		fixturesUsed, err = applyFixtures(fixtureDB, root, opts.Fixtures, opts.CSVNullValue, opts.TenantSchema, opts.Verbose)
+		tracing.End(span, err)
 		if err != nil {
 			return nil, err
 		}
 
 		if opts.DisableTriggers {
-			if _, err := db.Exec("SET session_replication_role = 'origin'"); err != nil {
+			if _, err := fixtureDB.Exec("SET session_replication_role = 'origin'"); err != nil {
 				return nil, fmt.Errorf("failed to re-enable triggers: %w", err)
 			}
 		}
@@ -170,13 +282,82 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 		if opts.Verbose {
 			fmt.Printf("Applying %d fixturize fixture(s)...\n", len(opts.Fixturize))
 		}
-		fixturizeUsed, err = applyFixturizeFiles(tempDB.PgUri, root, opts.Fixturize, opts.DisableTriggers, opts.Verbose)
+		_, span := tracing.Start(ctx, "regresql.fixturize.apply",
+			attribute.StringSlice("regresql.fixtures", opts.Fixturize),
+		)
+		fixturizeUsed, err = applyFixturizeFiles(fixtureURI, root, opts.Fixturize, opts.DisableTriggers, opts.Verbose)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var masksApplied []string
+	if len(opts.Masks) > 0 {
+		if opts.Verbose {
+			fmt.Printf("Applying %d mask(s)...\n", len(opts.Masks))
+		}
+		masks, err := resolveMasks(db, opts.Masks, opts.TenantSchema)
+		if err != nil {
+			return nil, err
+		}
+		masksApplied, err = applyMasks(db, masks, opts.Verbose)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Capture server context before snapshot
+	if opts.ResetSequences {
+		reset, err := ResetSequences(db, nil)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Verbose {
+			fmt.Printf("Reset %d sequence(s)\n", len(reset))
+		}
+	}
+
+	info, err := captureBuild(db, tempDB.PgUri, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	info.SchemaPath = opts.SchemaPath
+	info.SchemaHash = schemaHash
+	info.MigrationsDir = opts.MigrationsDir
+	info.MigrationsHash = migrationsHash
+	info.MigrationsApplied = migrationsApplied
+	info.MigrationChecksums = checksums
+	if migrationsHash != "" {
+		info.MigrationsNaming = opts.MigrationsNaming
+		info.MigrationsIncludeUndo = opts.IncludeUndo
+	}
+	info.MigrationCommand = opts.MigrationCommand
+	info.MigrationCommandHash = migrationCommandHash
+	info.MigrationCommandResult = migrationCommandResult
+	info.FixturesUsed = fixturesUsed
+	info.FixturizeUsed = fixturizeUsed
+	info.MasksApplied = masksApplied
+
+	if inputs != nil {
+		inputs.SnapshotHash = info.Hash
+		if err := writeFixtureCache(filepath.Dir(opts.OutputPath), inputs); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write %s: %v\n", FixtureCacheFile, err)
+		}
+	}
+
+	return &snapshotBuildResult{
+		Info:         info,
+		FixturesUsed: fixturesUsed,
+		Duration:     time.Since(startTime),
+		Changed:      changed,
+	}, nil
+}
+
+// captureBuild analyzes the built temporary database and captures it as the
+// snapshot at opts.OutputPath, together with the server context and the
+// schema state
+func captureBuild(db *sql.DB, pguri string, opts SnapshotBuildOptions) (*SnapshotInfo, error) {
 	if opts.Verbose {
 		fmt.Printf("Capturing server context...\n")
 	}
@@ -197,47 +378,62 @@ func BuildSnapshot(basePgUri string, root string, opts SnapshotBuildOptions) (*s
 		fmt.Printf("Capturing snapshot with pg_dump...\n")
 	}
 
-	info, err := CaptureSnapshot(tempDB.PgUri, SnapshotOptions{
+	info, err := CaptureSnapshot(pguri, SnapshotOptions{
 		OutputPath:     opts.OutputPath,
 		Format:         opts.Format,
 		WithStatistics: serverCtx.MajorVersion() >= 18,
+		Storage:        opts.Storage,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture snapshot: %w", err)
 	}
-
-	info.SchemaPath = opts.SchemaPath
-	info.SchemaHash = schemaHash
-	info.MigrationsDir = opts.MigrationsDir
-	info.MigrationsHash = migrationsHash
-	info.MigrationsApplied = migrationsApplied
-	info.MigrationCommand = opts.MigrationCommand
-	info.MigrationCommandHash = migrationCommandHash
-	info.FixturesUsed = fixturesUsed
-	info.FixturizeUsed = fixturizeUsed
 	info.Server = serverCtx
+	info.TenantSchema = opts.TenantSchema
+
+	// Keep the introspected schema so 'regresql schema diff' and
+	// schema_drift can compare a live database against it
+	if dbSchema, err := IntrospectSchema(db); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to introspect schema: %v\n", err)
+	} else if err := WriteSchemaState(schemaStatePath(opts.OutputPath), dbSchema); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else {
+		info.SchemaState = schemaStatePath(opts.OutputPath)
+	}
+	return info, nil
+}
 
-	return &snapshotBuildResult{
-		Info:         info,
-		FixturesUsed: fixturesUsed,
-		Duration:     time.Since(startTime),
-	}, nil
+// ApplyFixtures loads SQL and CSV fixtures (paths relative to root) into db,
+// the same way snapshot build does
+func ApplyFixtures(db *sql.DB, root string, fixtures []string, csvNullValue string) ([]string, error) {
+	return applyFixtures(db, root, fixtures, csvNullValue, "", false)
 }
 
-// applySQLFixtures executes SQL fixture files in order.
-func applySQLFixtures(db *sql.DB, root string, fixtures []string, verbose bool) ([]string, error) {
+// applyFixtures executes SQL fixture files and loads CSV fixture files in
+// the order they are listed. CSV files named after an unqualified table load
+// into schema when set.
+func applyFixtures(db *sql.DB, root string, fixtures []string, csvNullValue, schema string, verbose bool) ([]string, error) {
 	var applied []string
 
 	for _, f := range fixtures {
-		if !isSQLFixture(f) {
-			return nil, fmt.Errorf("fixture %q: only SQL fixtures (.sql) are supported; use fixturize for JSON fixtures", f)
-		}
-
-		if verbose {
-			fmt.Printf("  Executing SQL: %s\n", f)
-		}
-		if err := execSQLFile(db, filepath.Join(root, f)); err != nil {
-			return nil, fmt.Errorf("fixture %q: %w", f, err)
+		switch {
+		case isSQLFixture(f):
+			if verbose {
+				fmt.Printf("  Executing SQL: %s\n", f)
+			}
+			if err := execSQLFile(db, filepath.Join(root, f)); err != nil {
+				return nil, fmt.Errorf("fixture %q: %w", f, err)
+			}
+		case isCSVFixture(f):
+			table := tenantTable(csvFixtureTable(f), schema)
+			n, err := applyCSVFixture(db, filepath.Join(root, f), table, csvNullValue)
+			if err != nil {
+				return nil, fmt.Errorf("fixture %q: %w", f, err)
+			}
+			if verbose {
+				fmt.Printf("  Loaded CSV: %s (%d rows into %s)\n", f, n, table)
+			}
+		default:
+			return nil, fmt.Errorf("fixture %q: only SQL (.sql) and CSV (.csv) fixtures are supported; use fixturize for JSON fixtures", f)
 		}
 		applied = append(applied, f)
 	}
@@ -349,6 +545,31 @@ func GetSnapshotFixtures(cfg *SnapshotConfig) []string {
 	return cfg.Fixtures
 }
 
+func GetSnapshotCSVNullValue(cfg *SnapshotConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.CSVNullValue
+}
+
+func GetSnapshotMasks(cfg *SnapshotConfig) map[string]string {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Masks
+}
+
+func GetSnapshotResetSequences(cfg *SnapshotConfig) bool {
+	return cfg != nil && cfg.ResetSequences
+}
+
+func GetSnapshotTenantSchema(cfg *SnapshotConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.TenantSchema
+}
+
 func GetSnapshotSchema(cfg *SnapshotConfig) string {
 	if cfg == nil {
 		return ""
@@ -363,6 +584,13 @@ func GetSnapshotMigrations(cfg *SnapshotConfig) string {
 	return cfg.Migrations
 }
 
+func GetSnapshotMigrationsNaming(cfg *SnapshotConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.MigrationsNaming
+}
+
 func GetSnapshotMigrationCommand(cfg *SnapshotConfig) string {
 	if cfg == nil {
 		return ""
@@ -370,69 +598,140 @@ func GetSnapshotMigrationCommand(cfg *SnapshotConfig) string {
 	return cfg.MigrationCommand
 }
 
-// runMigrationCommand executes an external migration tool with PGURI env var set
-func runMigrationCommand(command, pguri string, verbose bool) error {
+// migrationOutputLimit caps the stdout/stderr kept in snapshot metadata; the
+// tail is kept since that is where migration tools report failures
+const migrationOutputLimit = 64 << 10
+
+func (e *MigrationCommandError) Error() string { return e.Err.Error() }
+func (e *MigrationCommandError) Unwrap() error { return e.Err }
+
+// migrationAttempts and migrationRetryDelay bound the retries of a migration
+// command that failed with a transient error; the delay doubles per attempt
+var (
+	migrationAttempts   = 3
+	migrationRetryDelay = time.Second
+)
+
+// transientMigrationErrors are output fragments of failures worth retrying:
+// the database not accepting connections yet, or a dropped connection
+var transientMigrationErrors = []string{
+	"connection refused",
+	"could not connect",
+	"connection reset",
+	"timeout expired",
+	"i/o timeout",
+	"too many clients",
+	"the database system is starting up",
+	"the database system is shutting down",
+	"server closed the connection unexpectedly",
+}
+
+// runMigrationCommand executes an external migration tool with the PGURI and
+// DATABASE_URL env vars set, plus the KEY=VALUE entries of env. {pguri} in
+// the command is replaced with the shell-quoted connection string. Failures
+// that look transient are retried with exponential backoff.
+func runMigrationCommand(command, pguri string, env []string, verbose bool) (*MigrationCommandResult, error) {
+	delay := migrationRetryDelay
+	for attempt := 1; ; attempt++ {
+		result, err := runMigrationCommandOnce(command, pguri, env, verbose)
+		if err == nil || attempt == migrationAttempts || !isTransientMigrationFailure(result) {
+			return result, err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: migration command failed (attempt %d/%d), retrying in %s: %v\n", attempt, migrationAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func runMigrationCommandOnce(command, pguri string, env []string, verbose bool) (*MigrationCommandResult, error) {
 	if verbose {
 		fmt.Printf("Running migration command: %s\n", command)
 	}
 
-	cmd := exec.Command("sh", "-c", command)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", strings.ReplaceAll(command, "{pguri}", shellQuote(pguri)))
 	cmd.Env = append(os.Environ(), "PGURI="+pguri, "DATABASE_URL="+pguri)
+	cmd.Env = append(cmd.Env, env...)
+	if verbose {
+		cmd.Stdout = io.MultiWriter(&stdout, os.Stdout)
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+	} else {
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+	}
 
-	output, err := cmd.CombinedOutput()
-	if verbose && len(output) > 0 {
-		fmt.Printf("%s", output)
+	result := &MigrationCommandResult{Command: command, Started: time.Now()}
+	err := cmd.Run()
+	result.Duration = time.Since(result.Started)
+	result.Stdout = outputTail(stdout.String(), migrationOutputLimit)
+	result.Stderr = outputTail(stderr.String(), migrationOutputLimit)
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	} else {
+		result.ExitCode = -1
 	}
 
 	if err != nil {
-		// Always show output on error, even if not verbose
-		if !verbose && len(output) > 0 {
-			return fmt.Errorf("migration command failed: %w\n%s", err, output)
+		// Always include the output on error, even if it was streamed; some
+		// tools report their errors on stdout
+		if output := strings.TrimRight(result.Stdout+result.Stderr, "\n"); output != "" {
+			return result, fmt.Errorf("migration command failed: %w\n%s", err, output)
 		}
-		return fmt.Errorf("migration command failed: %w", err)
+		return result, fmt.Errorf("migration command failed: %w", err)
 	}
-	return nil
+	return result, nil
 }
 
-func computeCommandHash(command string) string {
-	h := sha256.Sum256([]byte(command))
-	return "sha256:" + hex.EncodeToString(h[:])
+func isTransientMigrationFailure(result *MigrationCommandResult) bool {
+	if result == nil {
+		return false
+	}
+	output := strings.ToLower(result.Stdout + result.Stderr)
+	for _, fragment := range transientMigrationErrors {
+		if strings.Contains(output, fragment) {
+			return true
+		}
+	}
+	return false
 }
 
-// discoverMigrations finds *.sql files in directory (skips *.down.sql), sorted by name
-func discoverMigrations(dir string) ([]string, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
-	var files []string
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		lower := strings.ToLower(name)
-		if !strings.HasSuffix(lower, ".sql") {
-			continue
-		}
-		// Skip .down.sql files (reverse migrations)
-		if strings.HasSuffix(lower, ".down.sql") {
-			continue
-		}
-		files = append(files, filepath.Join(dir, name))
+// outputTail returns the last limit bytes of s, marking the cut
+func outputTail(s string, limit int) string {
+	if len(s) <= limit {
+		return s
 	}
-	sort.Strings(files) // Lexical sort: 001_init.sql, 002_users.sql, etc.
-	return files, nil
+	return "[... truncated ...]\n" + s[len(s)-limit:]
+}
+
+func computeCommandHash(command string) string {
+	h := sha256.Sum256([]byte(command))
+	return "sha256:" + hex.EncodeToString(h[:])
 }
 
 // applyMigrations executes migration files in order
-func applyMigrations(db *sql.DB, files []string, verbose bool) error {
+func applyMigrations(db *sql.DB, files []string, naming string, verbose bool) error {
 	for _, f := range files {
 		if verbose {
 			fmt.Printf("  Migration: %s\n", filepath.Base(f))
 		}
-		if err := execSQLFile(db, f); err != nil {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("migration %q: read: %w", filepath.Base(f), err)
+		}
+		script, ok := migrationUpSQL(string(content), naming)
+		if !ok {
+			return fmt.Errorf("migration %q has no up section for %s", filepath.Base(f), naming)
+		}
+		if _, err := db.Exec(script); err != nil {
+			err = fmt.Errorf("exec: %w", err)
+			if stmt := failingStatement(db, script, migrationTransactional(string(content), script), err); stmt != "" {
+				return fmt.Errorf("migration %q: %w\n  statement: %s", filepath.Base(f), err, stmt)
+			}
 			return fmt.Errorf("migration %q: %w", filepath.Base(f), err)
 		}
 	}
@@ -458,11 +757,20 @@ func computeMigrationsHash(files []string) (string, error) {
 // FixturesExist validates that all fixture files exist before build.
 func FixturesExist(root string, fixtures []string) error {
 	for _, f := range fixtures {
-		if !isSQLFixture(f) {
-			return fmt.Errorf("fixture %q: only SQL fixtures (.sql) are supported; use fixturize for JSON fixtures", f)
-		}
-		if err := checkFile(filepath.Join(root, f)); err != nil {
-			return fmt.Errorf("SQL fixture %q: %w", f, err)
+		switch {
+		case isSQLFixture(f):
+			if err := checkFile(filepath.Join(root, f)); err != nil {
+				return fmt.Errorf("SQL fixture %q: %w", f, err)
+			}
+		case isCSVFixture(f):
+			if err := checkFile(filepath.Join(root, f)); err != nil {
+				return fmt.Errorf("CSV fixture %q: %w", f, err)
+			}
+			if _, err := readCSVHeader(filepath.Join(root, f)); err != nil {
+				return fmt.Errorf("CSV fixture %q: %w", f, err)
+			}
+		default:
+			return fmt.Errorf("fixture %q: only SQL (.sql) and CSV (.csv) fixtures are supported; use fixturize for JSON fixtures", f)
 		}
 	}
 	return nil
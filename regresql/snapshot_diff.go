@@ -3,7 +3,10 @@ package regresql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -27,6 +30,10 @@ type (
 		FromResult *ResultSet
 		ToResult   *ResultSet
 		Diff       string
+
+		// StructuredDiff compares the two outputs row by row, with the
+		// "from" snapshot as the expected side
+		StructuredDiff *StructuredDiff
 	}
 
 	// QueryError represents an error running a query against a snapshot
@@ -80,13 +87,13 @@ func DiffSnapshots(root string, from, to *SnapshotInfo, queryFilter, runFilter s
 	fromDB := fmt.Sprintf("regresql_diff_from_%d", ts)
 	toDB := fmt.Sprintf("regresql_diff_to_%d", ts+1)
 
-	fmt.Printf("Restoring %s to temp database...\n", result.FromTag)
+	fmt.Fprintf(os.Stderr, "Restoring %s to temp database...\n", result.FromTag)
 	if err := createAndRestore(config.PgUri, fromDB, from.Path); err != nil {
 		return nil, fmt.Errorf("failed to restore 'from' snapshot: %w", err)
 	}
 	defer dropDatabase(config.PgUri, fromDB)
 
-	fmt.Printf("Restoring %s to temp database...\n", result.ToTag)
+	fmt.Fprintf(os.Stderr, "Restoring %s to temp database...\n", result.ToTag)
 	if err := createAndRestore(config.PgUri, toDB, to.Path); err != nil {
 		return nil, fmt.Errorf("failed to restore 'to' snapshot: %w", err)
 	}
@@ -107,7 +114,7 @@ func DiffSnapshots(root string, from, to *SnapshotInfo, queryFilter, runFilter s
 	}
 	defer toConn.Close()
 
-	fmt.Printf("Comparing %d queries...\n\n", len(queries))
+	fmt.Fprintf(os.Stderr, "Comparing %d queries...\n\n", len(queries))
 
 	for _, q := range queries {
 		fromResult, fromErr := executeQueryForDiff(fromConn, q.SQL)
@@ -144,6 +151,8 @@ func DiffSnapshots(root string, from, to *SnapshotInfo, queryFilter, runFilter s
 				FromResult: fromResult,
 				ToResult:   toResult,
 				Diff:       diff,
+
+				StructuredDiff: CompareResultSets(fromResult, toResult, nil),
 			})
 		}
 	}
@@ -151,6 +160,98 @@ func DiffSnapshots(root string, from, to *SnapshotInfo, queryFilter, runFilter s
 	return result, nil
 }
 
+// RowCountChanged reports whether the query returned a different number of
+// rows in the two snapshots
+func (d QueryDiff) RowCountChanged() bool {
+	return d.FromRows != d.ToRows
+}
+
+// RowCountChanges returns the changed queries whose row count differs
+func (r *SnapshotDiffResult) RowCountChanges() []QueryDiff {
+	var changes []QueryDiff
+	for _, d := range r.Changed {
+		if d.RowCountChanged() {
+			changes = append(changes, d)
+		}
+	}
+	return changes
+}
+
+// ValueChanges returns the changed queries that kept their row count but
+// returned different values or columns
+func (r *SnapshotDiffResult) ValueChanges() []QueryDiff {
+	var changes []QueryDiff
+	for _, d := range r.Changed {
+		if !d.RowCountChanged() {
+			changes = append(changes, d)
+		}
+	}
+	return changes
+}
+
+// WriteSnapshotDiffJSON writes the diff result as indented JSON, including
+// the structured diff and its sample rows for every changed query
+func WriteSnapshotDiffJSON(w io.Writer, r *SnapshotDiffResult) error {
+	changed := make([]map[string]any, 0, len(r.Changed))
+	for _, d := range r.Changed {
+		entry := map[string]any{
+			"query":             d.QueryPath,
+			"from_rows":         d.FromRows,
+			"to_rows":           d.ToRows,
+			"row_count_changed": d.RowCountChanged(),
+			"diff":              d.Diff,
+		}
+		if sd := d.StructuredDiff; sd != nil {
+			structured := structuredDiffMap(sd)
+			structured["columns"] = sd.Columns
+			structured["added_samples"] = sd.AddedSamples
+			structured["removed_samples"] = sd.RemovedSamples
+
+			modified := make([]map[string]any, 0, len(sd.ModifiedSamples))
+			for _, m := range sd.ModifiedSamples {
+				modified = append(modified, map[string]any{
+					"from": m.ExpectedRow,
+					"to":   m.ActualRow,
+				})
+			}
+			structured["modified_samples"] = modified
+			entry["structured_diff"] = structured
+		}
+		changed = append(changed, entry)
+	}
+
+	errs := make([]map[string]any, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		errs = append(errs, map[string]any{
+			"query": e.QueryPath,
+			"error": e.Error,
+		})
+	}
+
+	unchanged := r.Unchanged
+	if unchanged == nil {
+		unchanged = []string{}
+	}
+
+	output := map[string]any{
+		"from": r.FromTag,
+		"to":   r.ToTag,
+		"summary": map[string]any{
+			"unchanged":         len(r.Unchanged),
+			"row_count_changes": len(r.RowCountChanges()),
+			"value_changes":     len(r.ValueChanges()),
+			"errors":            len(r.Errors),
+		},
+		"unchanged": unchanged,
+		"changed":   changed,
+		"errors":    errs,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
 func createAndRestore(pguri, dbName, snapshotPath string) error {
 	adminURI, err := replaceDatabase(pguri, "postgres")
 	if err != nil {
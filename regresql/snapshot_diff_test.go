@@ -0,0 +1,77 @@
+package regresql
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSnapshotDiffJSON(t *testing.T) {
+	from := &ResultSet{Cols: []string{"id", "total"}, Rows: [][]any{{1, 10}, {2, 20}}}
+	sameCount := &ResultSet{Cols: []string{"id", "total"}, Rows: [][]any{{1, 10}, {2, 25}}}
+	moreRows := &ResultSet{Cols: []string{"id", "total"}, Rows: [][]any{{1, 10}, {2, 20}, {3, 30}}}
+
+	result := &SnapshotDiffResult{
+		FromTag:   "v1",
+		ToTag:     "v2",
+		Unchanged: []string{"users.sql"},
+		Changed: []QueryDiff{
+			{QueryPath: "totals.sql", FromRows: 2, ToRows: 2, StructuredDiff: CompareResultSets(from, sameCount, nil)},
+			{QueryPath: "orders.sql", FromRows: 2, ToRows: 3, StructuredDiff: CompareResultSets(from, moreRows, nil)},
+		},
+	}
+
+	if got := result.RowCountChanges(); len(got) != 1 || got[0].QueryPath != "orders.sql" {
+		t.Errorf("RowCountChanges = %v", got)
+	}
+	if got := result.ValueChanges(); len(got) != 1 || got[0].QueryPath != "totals.sql" {
+		t.Errorf("ValueChanges = %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshotDiffJSON(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Summary map[string]int
+		Changed []struct {
+			Query           string
+			RowCountChanged bool `json:"row_count_changed"`
+			StructuredDiff  struct {
+				Type            string
+				ModifiedRows    int `json:"modified_rows"`
+				AddedRows       int `json:"added_rows"`
+				ModifiedSamples []struct {
+					From []any
+					To   []any
+				} `json:"modified_samples"`
+			} `json:"structured_diff"`
+		}
+		Errors []any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	want := map[string]int{"unchanged": 1, "row_count_changes": 1, "value_changes": 1, "errors": 0}
+	for k, v := range want {
+		if out.Summary[k] != v {
+			t.Errorf("summary[%s] = %d, want %d", k, out.Summary[k], v)
+		}
+	}
+	if out.Errors == nil {
+		t.Error("errors should be an empty list, not null")
+	}
+	if len(out.Changed) != 2 {
+		t.Fatalf("changed = %d entries, want 2", len(out.Changed))
+	}
+
+	values := out.Changed[0].StructuredDiff
+	if out.Changed[0].RowCountChanged || values.ModifiedRows != 1 || len(values.ModifiedSamples) != 1 {
+		t.Errorf("value change = %+v", out.Changed[0])
+	}
+	if !out.Changed[1].RowCountChanged || out.Changed[1].StructuredDiff.AddedRows != 1 {
+		t.Errorf("row count change = %+v", out.Changed[1])
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // TagPattern validates tag names: alphanumeric, hyphen, underscore
@@ -48,6 +52,9 @@ func TagSnapshot(snapshotsDir, tag, note, archivePath string) error {
 		if err := copyFile(metadata.Current.Path, archivePath); err != nil {
 			return fmt.Errorf("failed to archive snapshot: %w", err)
 		}
+		if err := archiveSchemaState(metadata.Current, archivePath); err != nil {
+			return err
+		}
 		// Update path in current to the archive location
 		metadata.Current.Path = archivePath
 	}
@@ -82,6 +89,9 @@ func AddToHistory(snapshotsDir, archivePath string) error {
 		if err := copyFile(metadata.Current.Path, archivePath); err != nil {
 			return fmt.Errorf("failed to archive snapshot: %w", err)
 		}
+		if err := archiveSchemaState(metadata.Current, archivePath); err != nil {
+			return err
+		}
 		metadata.Current.Path = archivePath
 	}
 
@@ -92,6 +102,142 @@ func AddToHistory(snapshotsDir, archivePath string) error {
 	return WriteSnapshotMetadataFull(snapshotsDir, metadata)
 }
 
+// archiveSchemaState copies the schema state of a snapshot next to its
+// archive path, so a later build does not overwrite it
+func archiveSchemaState(info *SnapshotInfo, archivePath string) error {
+	if info.SchemaState == "" {
+		return nil
+	}
+	statePath := schemaStatePath(archivePath)
+	if err := copyFile(info.SchemaState, statePath); err != nil {
+		return fmt.Errorf("failed to archive schema state: %w", err)
+	}
+	info.SchemaState = statePath
+	return nil
+}
+
+type (
+	// PruneOptions is the retention policy for snapshot history
+	PruneOptions struct {
+		Keep      int           // keep the N most recent tagged snapshots (0 = no limit)
+		OlderThan time.Duration // remove snapshots created before now-OlderThan (0 = no limit)
+		DryRun    bool          // report what would be removed without touching anything
+	}
+
+	// PruneResult lists the snapshots removed (or that would be removed) and kept
+	PruneResult struct {
+		Removed    []*SnapshotInfo
+		Kept       []*SnapshotInfo
+		FreedBytes int64
+	}
+)
+
+// PruneSnapshots removes history entries exceeding the retention policy along
+// with their backing files, and rewrites the metadata. Keep counts tagged
+// entries only, so untagged builds never push a tagged snapshot out; they
+// are removed by OlderThan alone. The current snapshot is never pruned, nor
+// is any file it (or a kept entry) still points to.
+func PruneSnapshots(snapshotsDir string, opts PruneOptions) (*PruneResult, error) {
+	if opts.Keep < 0 {
+		return nil, fmt.Errorf("keep must not be negative")
+	}
+	if opts.Keep == 0 && opts.OlderThan <= 0 {
+		return nil, fmt.Errorf("no retention policy given: use keep and/or older-than")
+	}
+
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*SnapshotInfo, len(metadata.History))
+	copy(history, metadata.History)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Created.After(history[j].Created)
+	})
+
+	cutoff := time.Now().Add(-opts.OlderThan)
+	result := &PruneResult{}
+	tagged := 0
+	for _, info := range history {
+		expired := opts.OlderThan > 0 && info.Created.Before(cutoff)
+		overKeep := false
+		if info.Tag != "" {
+			overKeep = opts.Keep > 0 && tagged >= opts.Keep
+			tagged++
+		}
+		if overKeep || expired {
+			result.Removed = append(result.Removed, info)
+		} else {
+			result.Kept = append(result.Kept, info)
+		}
+	}
+
+	if len(result.Removed) == 0 {
+		return result, nil
+	}
+
+	// files still referenced after pruning must survive
+	inUse := make(map[string]bool)
+	if metadata.Current != nil && metadata.Current.Path != "" {
+		inUse[filepath.Clean(metadata.Current.Path)] = true
+	}
+	for _, info := range result.Kept {
+		if info.Path != "" {
+			inUse[filepath.Clean(info.Path)] = true
+		}
+	}
+
+	for _, info := range result.Removed {
+		path := filepath.Clean(info.Path)
+		if info.Path == "" || inUse[path] {
+			continue
+		}
+		inUse[path] = true // entries sharing a file remove it once
+		result.FreedBytes += info.SizeBytes
+		if opts.DryRun {
+			continue
+		}
+		// directory-format snapshots are removed recursively
+		if err := os.RemoveAll(info.Path); err != nil {
+			return nil, fmt.Errorf("failed to remove snapshot %s: %w", FormatSnapshotRef(info), err)
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	metadata.History = result.Kept
+	if err := WriteSnapshotMetadataFull(snapshotsDir, metadata); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseRetention parses a retention duration. On top of time.ParseDuration
+// units it accepts whole days and weeks, e.g. 30d or 2w.
+func ParseRetention(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		count, err := strconv.Atoi(s[:n-1])
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		day := 24 * time.Hour
+		if s[n-1] == 'w' {
+			day *= 7
+		}
+		return time.Duration(count) * day, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
 // GetSnapshotByTag returns snapshot info for a given tag
 func GetSnapshotByTag(metadata *SnapshotMetadata, tag string) (*SnapshotInfo, error) {
 	if metadata.Current != nil && metadata.Current.Tag == tag {
@@ -107,6 +253,32 @@ func GetSnapshotByTag(metadata *SnapshotMetadata, tag string) (*SnapshotInfo, er
 	return nil, fmt.Errorf("snapshot with tag %q not found", tag)
 }
 
+// GetSnapshotByPath returns the snapshot, current or from the history,
+// stored at path. Recorded paths are relative to the directory the snapshot
+// was built from: the project root, unless the build ran from elsewhere
+// with -C, so they are resolved against root first and then as they are.
+func GetSnapshotByPath(metadata *SnapshotMetadata, root, path string) (*SnapshotInfo, error) {
+	want, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range ListSnapshots(metadata) {
+		if info.Path == "" {
+			continue
+		}
+		candidates := []string{info.Path}
+		if !filepath.IsAbs(info.Path) {
+			candidates = []string{filepath.Join(root, info.Path), info.Path}
+		}
+		for _, c := range candidates {
+			if got, err := filepath.Abs(c); err == nil && got == want {
+				return info, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no snapshot recorded at %s", path)
+}
+
 // GetSnapshotByHash returns snapshot info for a given hash prefix
 func GetSnapshotByHash(metadata *SnapshotMetadata, hashPrefix string) (*SnapshotInfo, []*SnapshotInfo, error) {
 	var matches []*SnapshotInfo
@@ -165,6 +337,29 @@ func ListSnapshots(metadata *SnapshotMetadata) []*SnapshotInfo {
 	return all
 }
 
+// ReadSnapshotHistory returns every recorded snapshot, the current one
+// first and then the history newest first
+func ReadSnapshotHistory(snapshotsDir string) ([]*SnapshotInfo, error) {
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+	return SnapshotHistory(metadata), nil
+}
+
+// SnapshotHistory returns every snapshot of metadata, the current one first
+// and then the history newest first
+func SnapshotHistory(metadata *SnapshotMetadata) []*SnapshotInfo {
+	history := slices.Clone(metadata.History)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Created.After(history[j].Created)
+	})
+	if metadata.Current != nil {
+		history = append([]*SnapshotInfo{metadata.Current}, history...)
+	}
+	return history
+}
+
 // IsCurrent returns true if the given info is the current snapshot
 func IsCurrent(metadata *SnapshotMetadata, info *SnapshotInfo) bool {
 	return metadata.Current != nil && metadata.Current.Hash == info.Hash
@@ -0,0 +1,241 @@
+package regresql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// incrementalPlan is what an incremental snapshot build reloads
+type incrementalPlan struct {
+	Tables   []string // qualified tables truncated before the reload, sorted
+	Fixtures []string // CSV fixtures loading into Tables, in config order
+}
+
+// buildIncremental restores the current snapshot into a temporary database,
+// truncates the tables of changed CSV fixtures (and the tables referencing
+// them) and loads only their fixtures again. When the changes cannot be
+// applied that way it returns a nil result and the reason, and the caller
+// falls back to a full build.
+func buildIncremental(basePgUri, root string, opts SnapshotBuildOptions, inputs *fixtureCache) (*snapshotBuildResult, string, error) {
+	snapshotsDir := filepath.Dir(opts.OutputPath)
+	cache, err := readFixtureCache(snapshotsDir)
+	if err != nil {
+		return nil, "no build cache", nil
+	}
+	changedFixtures, reason := incrementalChanges(cache, inputs, opts)
+	if reason != "" {
+		return nil, reason, nil
+	}
+
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil || metadata.Current == nil || metadata.Current.Hash != cache.SnapshotHash {
+		return nil, "snapshot metadata does not match the build cache", nil
+	}
+	previous := metadata.Current
+	if filepath.Base(previous.Path) != filepath.Base(opts.OutputPath) {
+		return nil, "snapshot path changed", nil
+	}
+	if hash, err := computeFileHash(previous.Path, DetectSnapshotFormat(previous.Path)); err != nil || hash != cache.SnapshotHash {
+		return nil, "snapshot file changed", nil
+	}
+
+	if err := CheckPgTool(DetectSnapshotFormat(previous.Path).RestoreTool(), root); err != nil {
+		return nil, "", err
+	}
+
+	tempDB, err := CreateTempDB(TempDBOptions{BasePgUri: basePgUri})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp database: %w", err)
+	}
+	defer func() {
+		if err := tempDB.Drop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to drop temp database: %v\n", err)
+		}
+	}()
+
+	if opts.Verbose {
+		fmt.Printf("Restoring %s into %s...\n", previous.Path, tempDB.Name)
+	}
+	if err := RestoreSnapshot(tempDB.PgUri, RestoreOptions{InputPath: previous.Path}); err != nil {
+		return nil, "", fmt.Errorf("failed to restore current snapshot: %w", err)
+	}
+
+	db, err := OpenDB(tempDB.PgUri)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to temp database: %w", err)
+	}
+	defer db.Close()
+
+	dbSchema, err := IntrospectSchema(db)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to introspect snapshot schema: %w", err)
+	}
+	plan, reason := planIncremental(changedFixtures, opts, dbSchema)
+	if reason != "" {
+		return nil, reason, nil
+	}
+
+	if opts.Verbose {
+		fmt.Printf("Reloading %d table(s): %s\n", len(plan.Tables), strings.Join(plan.Tables, ", "))
+	}
+	quoted := make([]string, len(plan.Tables))
+	for i, t := range plan.Tables {
+		schema, name := parseTableName(t)
+		quoted[i] = QuoteIdentifier(schema) + "." + QuoteIdentifier(name)
+	}
+	if _, err := db.Exec("TRUNCATE " + strings.Join(quoted, ", ")); err != nil {
+		return nil, "", fmt.Errorf("failed to truncate changed tables: %w", err)
+	}
+
+	if opts.DisableTriggers {
+		if _, err := db.Exec("SET session_replication_role = 'replica'"); err != nil {
+			return nil, "", fmt.Errorf("failed to disable triggers: %w", err)
+		}
+	}
+	if _, err := applyFixtures(db, root, plan.Fixtures, opts.CSVNullValue, opts.TenantSchema, opts.Verbose); err != nil {
+		return nil, "", err
+	}
+	if opts.DisableTriggers {
+		if _, err := db.Exec("SET session_replication_role = 'origin'"); err != nil {
+			return nil, "", fmt.Errorf("failed to re-enable triggers: %w", err)
+		}
+	}
+
+	if len(opts.Masks) > 0 {
+		masks, err := resolveMasks(db, opts.Masks, opts.TenantSchema)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := applyMasks(db, masksForTables(masks, plan.Tables), opts.Verbose); err != nil {
+			return nil, "", err
+		}
+	}
+	if opts.ResetSequences {
+		if _, err := ResetSequences(db, plan.Tables); err != nil {
+			return nil, "", err
+		}
+	}
+
+	info, err := captureBuild(db, tempDB.PgUri, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	info.SchemaPath = previous.SchemaPath
+	info.SchemaHash = previous.SchemaHash
+	info.MigrationsDir = previous.MigrationsDir
+	info.MigrationsHash = previous.MigrationsHash
+	info.MigrationsApplied = previous.MigrationsApplied
+	info.MigrationsNaming = previous.MigrationsNaming
+	info.MigrationsIncludeUndo = previous.MigrationsIncludeUndo
+	info.MigrationChecksums = previous.MigrationChecksums
+	info.FixturesUsed = previous.FixturesUsed
+	info.FixturizeUsed = previous.FixturizeUsed
+	info.MasksApplied = previous.MasksApplied
+
+	inputs.SnapshotHash = info.Hash
+	if err := writeFixtureCache(snapshotsDir, inputs); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write %s: %v\n", FixtureCacheFile, err)
+	}
+
+	return &snapshotBuildResult{
+		Info:         info,
+		FixturesUsed: plan.Fixtures,
+		Changed:      changedFixtures,
+		Reloaded:     plan.Tables,
+	}, "", nil
+}
+
+// incrementalChanges returns the changed fixtures when all of them are CSV
+// files, or the reason an incremental build is not possible. Builds with
+// SQL or fixturize fixtures are never incremental: those may insert rows
+// into the tables the reload truncates, which only a full build restores.
+func incrementalChanges(cache, inputs *fixtureCache, opts SnapshotBuildOptions) ([]string, string) {
+	for _, f := range opts.Fixtures {
+		if !isCSVFixture(f) {
+			return nil, fmt.Sprintf("non-CSV fixture %s may load rows into the reloaded tables", f)
+		}
+	}
+	if len(opts.Fixturize) > 0 {
+		return nil, "fixturize fixtures may load rows into the reloaded tables"
+	}
+
+	changed := cache.changes(inputs)
+	for _, c := range changed {
+		if !slices.Contains(opts.Fixtures, c) {
+			return nil, c + " changed"
+		}
+	}
+	return changed, ""
+}
+
+// planIncremental finds the tables to reload for the changed CSV fixtures.
+// TRUNCATE must also empty every table with a foreign key into them, so
+// those are added too; all of the tables have to be loaded from CSV
+// fixtures alone, otherwise their data cannot be restored.
+func planIncremental(changed []string, opts SnapshotBuildOptions, dbSchema *DatabaseSchema) (*incrementalPlan, string) {
+	csvTables := make(map[string][]string) // qualified table -> CSV fixtures loading it
+	for _, f := range opts.Fixtures {
+		if isCSVFixture(f) {
+			t := qualifyTable(tenantTable(csvFixtureTable(f), opts.TenantSchema))
+			csvTables[t] = append(csvTables[t], f)
+		}
+	}
+
+	reload := make(map[string]bool)
+	for _, f := range changed {
+		reload[qualifyTable(tenantTable(csvFixtureTable(f), opts.TenantSchema))] = true
+	}
+	for grew := true; grew; {
+		grew = false
+		for _, name := range dbSchema.GetTables() {
+			if reload[name] {
+				continue
+			}
+			table, _ := dbSchema.GetTable(name)
+			for _, fk := range table.ForeignKeys {
+				if reload[fk.ReferencedTable] {
+					reload[name] = true
+					grew = true
+					break
+				}
+			}
+		}
+	}
+
+	plan := &incrementalPlan{}
+	for t := range reload {
+		if len(csvTables[t]) == 0 {
+			return nil, fmt.Sprintf("table %s is not loaded from CSV fixtures", t)
+		}
+		plan.Tables = append(plan.Tables, t)
+	}
+	slices.Sort(plan.Tables)
+
+	for _, f := range opts.Fixtures {
+		if isCSVFixture(f) && reload[qualifyTable(tenantTable(csvFixtureTable(f), opts.TenantSchema))] {
+			plan.Fixtures = append(plan.Fixtures, f)
+		}
+	}
+	return plan, ""
+}
+
+// masksForTables keeps the resolved masks of the given qualified tables
+func masksForTables(masks map[string]string, tables []string) map[string]string {
+	out := make(map[string]string)
+	for key, expr := range masks {
+		dot := strings.LastIndex(key, ".")
+		if dot > 0 && slices.Contains(tables, qualifyTable(key[:dot])) {
+			out[key] = expr
+		}
+	}
+	return out
+}
+
+// qualifyTable adds the public schema to an unqualified table name
+func qualifyTable(name string) string {
+	schema, table := parseTableName(name)
+	return schema + "." + table
+}
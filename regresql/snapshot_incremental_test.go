@@ -0,0 +1,85 @@
+package regresql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncrementalChanges(t *testing.T) {
+	opts := SnapshotBuildOptions{Fixtures: []string{"seeds/users.csv", "seeds/orders.csv"}}
+	cache := &fixtureCache{OptionsHash: "o", Fixtures: map[string]string{"seeds/users.csv": "b", "seeds/orders.csv": "c"}}
+
+	current := &fixtureCache{OptionsHash: "o", Fixtures: map[string]string{"seeds/users.csv": "B", "seeds/orders.csv": "c"}}
+	changed, reason := incrementalChanges(cache, current, opts)
+	if reason != "" || !equalStrings(changed, []string{"seeds/users.csv"}) {
+		t.Errorf("CSV change = %v, %q", changed, reason)
+	}
+
+	cases := map[string]*fixtureCache{
+		"schema changed":        {SchemaHash: "s", OptionsHash: "o", Fixtures: cache.Fixtures},
+		"build options changed": {OptionsHash: "O", Fixtures: cache.Fixtures},
+	}
+	for want, current := range cases {
+		if _, reason := incrementalChanges(cache, current, opts); reason != want {
+			t.Errorf("reason = %q, want %q", reason, want)
+		}
+	}
+}
+
+func TestIncrementalChangesWithSQLFixtures(t *testing.T) {
+	// seed.sql may insert into users, which the reload would truncate
+	opts := SnapshotBuildOptions{Fixtures: []string{"seed.sql", "seeds/users.csv"}}
+	cache := &fixtureCache{OptionsHash: "o", Fixtures: map[string]string{"seed.sql": "a", "seeds/users.csv": "b"}}
+	current := &fixtureCache{OptionsHash: "o", Fixtures: map[string]string{"seed.sql": "a", "seeds/users.csv": "B"}}
+
+	if _, reason := incrementalChanges(cache, current, opts); !strings.Contains(reason, "non-CSV fixture seed.sql") {
+		t.Errorf("reason = %q, want a full build because of seed.sql", reason)
+	}
+
+	opts = SnapshotBuildOptions{Fixtures: []string{"seeds/users.csv"}, Fixturize: []string{"fixturize/users.json"}}
+	if _, reason := incrementalChanges(cache, current, opts); !strings.Contains(reason, "fixturize") {
+		t.Errorf("reason = %q, want a full build because of fixturize", reason)
+	}
+}
+
+func TestPlanIncremental(t *testing.T) {
+	// orders references users; legacy is unrelated
+	dbSchema := schemaFixture()
+	opts := SnapshotBuildOptions{Fixtures: []string{"seeds/orders.csv", "seeds/users.csv", "seeds/legacy.csv"}}
+
+	plan, reason := planIncremental([]string{"seeds/users.csv"}, opts, dbSchema)
+	if reason != "" {
+		t.Fatalf("unexpected fallback: %s", reason)
+	}
+	if !equalStrings(plan.Tables, []string{"public.orders", "public.users"}) {
+		t.Errorf("Tables = %v, want users and the orders referencing it", plan.Tables)
+	}
+	if !equalStrings(plan.Fixtures, []string{"seeds/orders.csv", "seeds/users.csv"}) {
+		t.Errorf("Fixtures = %v, want config order", plan.Fixtures)
+	}
+
+	plan, reason = planIncremental([]string{"seeds/orders.csv"}, opts, dbSchema)
+	if reason != "" || !equalStrings(plan.Tables, []string{"public.orders"}) {
+		t.Errorf("orders only = %+v, %q", plan, reason)
+	}
+
+	// orders data comes from somewhere else, so truncating it would lose rows
+	opts.Fixtures = []string{"seeds/users.csv"}
+	_, reason = planIncremental([]string{"seeds/users.csv"}, opts, dbSchema)
+	if !strings.Contains(reason, "public.orders is not loaded from CSV fixtures") {
+		t.Errorf("reason = %q", reason)
+	}
+}
+
+func TestMasksForTables(t *testing.T) {
+	masks := map[string]string{
+		"users.email":           "'x'",
+		"public.orders.note":    "NULL",
+		"billing.cards.number":  "md5(number)",
+		"public.legacy.comment": "''",
+	}
+	got := masksForTables(masks, []string{"public.users", "public.orders"})
+	if len(got) != 2 || got["users.email"] != "'x'" || got["public.orders.note"] != "NULL" {
+		t.Errorf("masksForTables = %v", got)
+	}
+}
@@ -0,0 +1,170 @@
+package regresql
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	restoreProgressInterval = 2 * time.Second
+
+	// restoreProgressBarSize is the snapshot size from which restore
+	// progress is shown as a bar with the estimated time remaining
+	restoreProgressBarSize  = 100 << 20
+	restoreProgressBarWidth = 30
+)
+
+// restoreProgress reports a running restore by polling the target
+// database: the COPY statements in flight and how much the database grew
+// since the restore started, against the size of the snapshot
+type restoreProgress struct {
+	w      io.Writer
+	db     *sql.DB // nil when the target can't be polled
+	total  int64   // snapshot size
+	base   int64   // database size before the restore
+	start  time.Time
+	redraw bool // rewrite a single line instead of printing one per poll
+	drawn  bool
+}
+
+// runRestoreCommand runs a pg_restore or psql command, reporting progress
+// on stderr every restoreProgressInterval while it runs when asked for
+func runRestoreCommand(cmd *exec.Cmd, pguri string, opts RestoreOptions) error {
+	if !opts.Progress {
+		return cmd.Run()
+	}
+
+	p := newRestoreProgress(os.Stderr, pguri, opts.InputPath)
+	if p.db != nil {
+		defer p.db.Close()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		p.poll(done)
+		close(stopped)
+	}()
+	err := cmd.Wait()
+	close(done)
+	<-stopped
+	return err
+}
+
+func newRestoreProgress(w io.Writer, pguri, inputPath string) *restoreProgress {
+	p := &restoreProgress{
+		w:      w,
+		total:  snapshotInputSize(inputPath),
+		start:  time.Now(),
+		redraw: w == os.Stderr && term.IsTerminal(int(os.Stderr.Fd())),
+	}
+	db, err := OpenDB(pguri)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: cannot poll restore progress: %v\n", err)
+		return p
+	}
+	if err := db.QueryRow("SELECT pg_database_size(current_database())").Scan(&p.base); err != nil {
+		fmt.Fprintf(w, "Warning: cannot poll restore progress: %v\n", err)
+		db.Close()
+		return p
+	}
+	p.db = db
+	return p
+}
+
+func (p *restoreProgress) poll(done <-chan struct{}) {
+	ticker := time.NewTicker(restoreProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			if p.drawn && p.redraw {
+				fmt.Fprintln(p.w)
+			}
+			return
+		case <-ticker.C:
+			p.report()
+		}
+	}
+}
+
+func (p *restoreProgress) report() {
+	elapsed := time.Since(p.start)
+	line := fmt.Sprintf("restoring, %s elapsed", elapsed.Round(time.Second))
+	if p.db != nil {
+		var copies int
+		var size int64
+		// the query text of this very poll contains COPY too, leave it out
+		err := p.db.QueryRow(`SELECT count(*) FROM pg_stat_activity
+			WHERE datname = current_database() AND query LIKE '%COPY%' AND pid <> pg_backend_pid()`).Scan(&copies)
+		if err == nil {
+			err = p.db.QueryRow("SELECT pg_database_size(current_database())").Scan(&size)
+		}
+		if err == nil {
+			line = formatRestoreProgress(size-p.base, p.total, copies, elapsed)
+		}
+	}
+
+	if p.redraw {
+		fmt.Fprintf(p.w, "\r%s\x1b[K", line)
+	} else {
+		fmt.Fprintln(p.w, line)
+	}
+	p.drawn = true
+}
+
+// formatRestoreProgress renders one progress line. Snapshots from
+// restoreProgressBarSize get a bar and the time remaining; as compressed
+// archives grow on restore, the estimate stays below 100% until the
+// restore tool exits.
+func formatRestoreProgress(restored, total int64, copies int, elapsed time.Duration) string {
+	restored = max(restored, 0)
+	if total < restoreProgressBarSize {
+		return fmt.Sprintf("%s restored, %d COPY active, %s elapsed", FormatBytes(restored), copies, elapsed.Round(time.Second))
+	}
+
+	fraction := min(float64(restored)/float64(total), 0.99)
+	filled := int(fraction * restoreProgressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", restoreProgressBarWidth-filled)
+	eta := "--"
+	if fraction > 0 {
+		eta = time.Duration(float64(elapsed) * (1 - fraction) / fraction).Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%s] %3.0f%% %s / %s, %d COPY active, ETA %s",
+		bar, fraction*100, FormatBytes(restored), FormatBytes(total), copies, eta)
+}
+
+// snapshotInputSize returns the size of a snapshot file, or the total size
+// of the files of a directory format snapshot
+func snapshotInputSize(path string) int64 {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	if !stat.IsDir() {
+		return stat.Size()
+	}
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
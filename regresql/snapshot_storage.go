@@ -0,0 +1,444 @@
+package regresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type (
+	// SnapshotStorage keeps snapshot files outside the working tree. Keys are
+	// relative to the storage root (bucket and prefix for S3).
+	SnapshotStorage interface {
+		Upload(localPath string, key string) error
+		Download(key string, localPath string) error
+		List() ([]StorageEntry, error)
+		// URL returns a human readable location for key, recorded in the
+		// snapshot metadata as remote_url
+		URL(key string) string
+	}
+
+	StorageEntry struct {
+		Key          string
+		SizeBytes    int64
+		LastModified time.Time
+	}
+
+	// S3Storage stores snapshots in an S3 bucket under an optional prefix.
+	// Credentials and region come from the standard AWS environment
+	// (AWS_PROFILE, AWS_REGION, instance roles, ...). An endpoint points it
+	// at an S3-compatible service such as MinIO or Ceph.
+	S3Storage struct {
+		client *s3.Client
+		bucket string
+		prefix string
+	}
+)
+
+const StorageTypeS3 = "s3"
+
+// S3-compatible services ignore the region but requests must still be signed
+// for one; this is used when the AWS environment sets none
+const defaultS3CompatibleRegion = "us-east-1"
+
+var (
+	// storageRetryAttempts and storageRetryDelay set how transfers are
+	// retried after network failures: the delay doubles after every attempt
+	storageRetryAttempts = 4
+	storageRetryDelay    = time.Second
+)
+
+// NewSnapshotStorage returns the storage backend configured under
+// snapshot.storage, or nil when snapshots only live on local disk
+func NewSnapshotStorage(cfg *SnapshotConfig) (SnapshotStorage, error) {
+	if cfg == nil || cfg.Storage == nil || cfg.Storage.Type == "" {
+		return nil, nil
+	}
+
+	switch strings.ToLower(cfg.Storage.Type) {
+	case StorageTypeS3:
+		if cfg.Storage.Bucket == "" {
+			return nil, fmt.Errorf("snapshot.storage.bucket is required for s3 storage")
+		}
+		return NewS3Storage(cfg.Storage)
+	default:
+		return nil, fmt.Errorf("unknown snapshot storage type %q (supported: s3)", cfg.Storage.Type)
+	}
+}
+
+func NewS3Storage(cfg *SnapshotStorageConfig) (*S3Storage, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if cfg.Endpoint != "" && awsCfg.Region == "" {
+		awsCfg.Region = defaultS3CompatibleRegion
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = &cfg.Endpoint
+		}
+		o.UsePathStyle = cfg.ForcePathStyle
+	})
+	return &S3Storage{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+	}, nil
+}
+
+// Upload stores a snapshot file under key. Directory format snapshots are
+// uploaded file by file below key/.
+func (s *S3Storage) Upload(localPath string, key string) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return s.putFile(localPath, key)
+	}
+
+	return filepath.WalkDir(localPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, p)
+		if err != nil {
+			return err
+		}
+		return s.putFile(p, path.Join(key, filepath.ToSlash(rel)))
+	})
+}
+
+func (s *S3Storage) putFile(localPath, key string) error {
+	return withStorageRetry("upload "+s.URL(key), func() error {
+		return s.putFileOnce(localPath, key)
+	})
+}
+
+func (s *S3Storage) putFileOnce(localPath, key string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = s.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: &s.bucket,
+		Key:    ptr(s.objectKey(key)),
+		Body:   f,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", s.URL(key), err)
+	}
+	return nil
+}
+
+// Download fetches key into localPath. When key was uploaded as a directory
+// snapshot, its files are restored below localPath.
+func (s *S3Storage) Download(key string, localPath string) error {
+	objects, err := s.listObjects(s.objectKey(key) + "/")
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return s.getFile(s.objectKey(key), localPath)
+	}
+
+	dirPrefix := s.objectKey(key) + "/"
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, dirPrefix)
+		if err := s.getFile(obj.Key, filepath.Join(localPath, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) getFile(objectKey, localPath string) error {
+	return withStorageRetry(fmt.Sprintf("download s3://%s/%s", s.bucket, objectKey), func() error {
+		return s.getFileOnce(objectKey, localPath)
+	})
+}
+
+func (s *S3Storage) getFileOnce(objectKey, localPath string) error {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &objectKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download s3://%s/%s: %w", s.bucket, objectKey, err)
+	}
+	defer out.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so an interrupted download never
+	// leaves a truncated snapshot behind
+	tmp := localPath + ".download"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, out.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to download s3://%s/%s: %w", s.bucket, objectKey, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, localPath)
+}
+
+// List returns the objects stored under the configured prefix
+func (s *S3Storage) List() ([]StorageEntry, error) {
+	objects, err := s.listObjects(s.objectKey(""))
+	if err != nil {
+		return nil, err
+	}
+
+	trim := s.objectKey("")
+	entries := make([]StorageEntry, 0, len(objects))
+	for _, obj := range objects {
+		obj.Key = strings.TrimPrefix(obj.Key, trim)
+		entries = append(entries, obj)
+	}
+	return entries, nil
+}
+
+func (s *S3Storage) listObjects(prefix string) ([]StorageEntry, error) {
+	var entries []StorageEntry
+	err := withStorageRetry(fmt.Sprintf("list s3://%s/%s", s.bucket, prefix), func() error {
+		var err error
+		entries, err = s.listObjectsOnce(prefix)
+		return err
+	})
+	return entries, err
+}
+
+func (s *S3Storage) listObjectsOnce(prefix string) ([]StorageEntry, error) {
+	var entries []StorageEntry
+
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", s.bucket, prefix, err)
+		}
+		for _, obj := range page.Contents {
+			entry := StorageEntry{}
+			if obj.Key != nil {
+				entry.Key = *obj.Key
+			}
+			if obj.Size != nil {
+				entry.SizeBytes = *obj.Size
+			}
+			if obj.LastModified != nil {
+				entry.LastModified = *obj.LastModified
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.objectKey(key))
+}
+
+// objectKey prepends the configured prefix; with an empty key it returns the
+// prefix itself (with a trailing slash) for listing
+func (s *S3Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// withStorageRetry runs a transfer, retrying it with exponential backoff
+// while it fails on the network. Other errors, such as a missing object or
+// denied access, are returned right away.
+func withStorageRetry(op string, fn func() error) error {
+	delay := storageRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= storageRetryAttempts || !isNetworkError(err) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s failed (attempt %d of %d), retrying in %s: %v\n", op, attempt, storageRetryAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isNetworkError reports whether err comes from the connection rather than
+// from the storage service answering
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// SnapshotStorageKey is the remote key of a snapshot: the first 12 characters
+// of its content hash plus the file name, so every history entry keeps its
+// own copy (e.g. 3f2a9c1b7d4e/default.dump)
+func SnapshotStorageKey(info *SnapshotInfo) string {
+	hash := strings.TrimPrefix(info.Hash, "sha256:")
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return path.Join(hash, snapshotFileName(info))
+}
+
+// snapshotFileName is the file name of a snapshot. A tagged snapshot whose
+// file was overwritten keeps no path, its name then comes from the remote
+// URL it was pushed to. Returns "" when neither is known.
+func snapshotFileName(info *SnapshotInfo) string {
+	if info.Path != "" {
+		return filepath.Base(info.Path)
+	}
+	if info.RemoteURL != "" {
+		return path.Base(info.RemoteURL)
+	}
+	return ""
+}
+
+// PushSnapshot uploads the snapshot at localPath described by info and
+// records where it went in info.RemoteURL
+func PushSnapshot(storage SnapshotStorage, localPath string, info *SnapshotInfo) error {
+	if info.Hash == "" {
+		return fmt.Errorf("snapshot %s has no hash recorded", info.Path)
+	}
+	key := SnapshotStorageKey(info)
+	if err := storage.Upload(localPath, key); err != nil {
+		return err
+	}
+	info.RemoteURL = storage.URL(key)
+	return nil
+}
+
+// PullSnapshot downloads the snapshot described by info to localPath,
+// replacing what is there
+func PullSnapshot(storage SnapshotStorage, localPath string, info *SnapshotInfo) error {
+	if err := os.RemoveAll(localPath); err != nil {
+		return err
+	}
+	return storage.Download(SnapshotStorageKey(info), localPath)
+}
+
+// RemoteSnapshotKey looks up the remote key for the snapshot at localPath in
+// the metadata stored in snapshotsDir. Entries are matched by file name, so
+// the lookup works regardless of the directory regresql runs from. Returns
+// "" when the snapshot was never pushed.
+func RemoteSnapshotKey(snapshotsDir, localPath string) string {
+	metadata, err := ReadSnapshotMetadata(snapshotsDir)
+	if err != nil {
+		return ""
+	}
+
+	entries := append([]*SnapshotInfo{metadata.Current}, metadata.History...)
+	for _, info := range entries {
+		if info == nil || info.RemoteURL == "" {
+			continue
+		}
+		if filepath.Base(info.Path) == filepath.Base(localPath) {
+			return SnapshotStorageKey(info)
+		}
+	}
+	return ""
+}
+
+// fetchSnapshot downloads a snapshot that is missing locally. It is a no-op
+// when the file exists or no storage is configured.
+func fetchSnapshot(storage SnapshotStorage, key, localPath string) error {
+	if _, err := os.Stat(localPath); !errors.Is(err, fs.ErrNotExist) || storage == nil || key == "" {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Downloading snapshot %s\n", storage.URL(key))
+	if err := storage.Download(key, localPath); err != nil {
+		return fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	return nil
+}
+
+// RemoteSnapshot returns the storage and key to download the snapshot at
+// snapshotPath from, when the file is missing locally but was pushed to the
+// configured storage. Both are empty when there is nothing to download.
+func RemoteSnapshot(cfg *SnapshotConfig, snapshotPath string) (SnapshotStorage, string, error) {
+	if _, err := os.Stat(snapshotPath); !errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	}
+
+	key := RemoteSnapshotKey(filepath.Dir(snapshotPath), snapshotPath)
+	if key == "" {
+		return nil, "", nil
+	}
+	storage, err := NewSnapshotStorage(cfg)
+	if err != nil || storage == nil {
+		return nil, "", err
+	}
+	return storage, key, nil
+}
+
+// LocalSnapshotPath returns where a snapshot of the metadata lives on local
+// disk. An older snapshot that shares its file name with the current one
+// gets the tag (or short hash) in its name, so pulling it doesn't replace
+// the current snapshot: default.dump becomes default-v1.0.dump. Push uploads
+// and pull replaces what is at that path, so it fails when the path is the
+// snapshots directory itself, or a directory while the snapshot isn't in
+// directory format.
+func LocalSnapshotPath(snapshotsDir string, metadata *SnapshotMetadata, info *SnapshotInfo) (string, error) {
+	name := snapshotFileName(info)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "", fmt.Errorf("snapshot %s has no file name recorded", FormatSnapshotRef(info))
+	}
+
+	localPath := filepath.Join(snapshotsDir, name)
+	current := metadata.Current
+	if current != nil && info != current && name == filepath.Base(current.Path) {
+		label := info.Tag
+		if label == "" {
+			label = strings.TrimPrefix(info.Hash, "sha256:")
+			if len(label) > 12 {
+				label = label[:12]
+			}
+		}
+		ext := filepath.Ext(name)
+		localPath = filepath.Join(snapshotsDir, strings.TrimSuffix(name, ext)+"-"+label+ext)
+	}
+
+	if filepath.Clean(localPath) == filepath.Clean(snapshotsDir) {
+		return "", fmt.Errorf("snapshot %s resolves to the snapshots directory %s", FormatSnapshotRef(info), snapshotsDir)
+	}
+	if stat, err := os.Stat(localPath); err == nil && stat.IsDir() && info.Format != string(FormatDirectory) {
+		return "", fmt.Errorf("snapshot %s is not in directory format but %s is a directory", FormatSnapshotRef(info), localPath)
+	}
+	return localPath, nil
+}
@@ -0,0 +1,282 @@
+package regresql
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// dirStorage is a SnapshotStorage backed by a local directory
+type dirStorage struct {
+	root string
+}
+
+func (d *dirStorage) Upload(localPath, key string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(d.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
+
+func (d *dirStorage) Download(key, localPath string) error {
+	data, err := os.ReadFile(filepath.Join(d.root, filepath.FromSlash(key)))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(localPath, data, 0o644)
+}
+
+func (d *dirStorage) List() ([]StorageEntry, error) {
+	return nil, nil
+}
+
+func (d *dirStorage) URL(key string) string {
+	return "dir://" + key
+}
+
+func TestNewSnapshotStorage(t *testing.T) {
+	if s, err := NewSnapshotStorage(nil); s != nil || err != nil {
+		t.Errorf("nil config = %v, %v; want no storage", s, err)
+	}
+	if s, err := NewSnapshotStorage(&SnapshotConfig{}); s != nil || err != nil {
+		t.Errorf("no storage section = %v, %v; want no storage", s, err)
+	}
+	if _, err := NewSnapshotStorage(&SnapshotConfig{Storage: &SnapshotStorageConfig{Type: "ftp"}}); err == nil {
+		t.Error("unknown storage type should fail")
+	}
+	if _, err := NewSnapshotStorage(&SnapshotConfig{Storage: &SnapshotStorageConfig{Type: "s3"}}); err == nil {
+		t.Error("s3 without bucket should fail")
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	s := &S3Storage{bucket: "snaps", prefix: "myapp"}
+	if got := s.URL("3f2a9c1b7d4e/default.dump"); got != "s3://snaps/myapp/3f2a9c1b7d4e/default.dump" {
+		t.Errorf("URL = %q", got)
+	}
+
+	s = &S3Storage{bucket: "snaps"}
+	if got := s.URL("default.dump"); got != "s3://snaps/default.dump" {
+		t.Errorf("URL without prefix = %q", got)
+	}
+}
+
+func TestSnapshotStorageKey(t *testing.T) {
+	info := &SnapshotInfo{
+		Path: "snapshots/default.dump",
+		Hash: "sha256:3f2a9c1b7d4e5f60718293a4b5c6d7e8f9",
+	}
+	if got := SnapshotStorageKey(info); got != "3f2a9c1b7d4e/default.dump" {
+		t.Errorf("SnapshotStorageKey = %q", got)
+	}
+}
+
+func TestPushAndFetchSnapshot(t *testing.T) {
+	storage := &dirStorage{root: t.TempDir()}
+	snapshotsDir := t.TempDir()
+	localPath := filepath.Join(snapshotsDir, "default.dump")
+	if err := os.WriteFile(localPath, []byte("dump"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:abcdef0123456789"}
+	if err := PushSnapshot(storage, localPath, info); err != nil {
+		t.Fatalf("PushSnapshot: %v", err)
+	}
+	if info.RemoteURL != "dir://abcdef012345/default.dump" {
+		t.Errorf("RemoteURL = %q", info.RemoteURL)
+	}
+	if err := WriteSnapshotMetadata(snapshotsDir, info); err != nil {
+		t.Fatal(err)
+	}
+
+	// the local file is gone (fresh CI checkout); the key comes from metadata
+	os.Remove(localPath)
+	key := RemoteSnapshotKey(snapshotsDir, localPath)
+	if key != "abcdef012345/default.dump" {
+		t.Fatalf("RemoteSnapshotKey = %q", key)
+	}
+	if got := RemoteSnapshotKey(snapshotsDir, filepath.Join(snapshotsDir, "other.dump")); got != "" {
+		t.Errorf("RemoteSnapshotKey for unknown file = %q, want none", got)
+	}
+
+	if err := fetchSnapshot(storage, key, localPath); err != nil {
+		t.Fatalf("fetchSnapshot: %v", err)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil || string(data) != "dump" {
+		t.Errorf("downloaded snapshot = %q, %v", data, err)
+	}
+}
+
+func TestPushSnapshotRequiresHash(t *testing.T) {
+	storage := &dirStorage{root: t.TempDir()}
+	if err := PushSnapshot(storage, "default.dump", &SnapshotInfo{Path: "default.dump"}); err == nil {
+		t.Error("PushSnapshot without hash should fail")
+	}
+}
+
+func TestNewS3StorageEndpoint(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "none"))
+
+	s, err := NewS3Storage(&SnapshotStorageConfig{
+		Type:           "s3",
+		Bucket:         "snaps",
+		Endpoint:       "http://localhost:9000",
+		ForcePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	opts := s.client.Options()
+	if opts.BaseEndpoint == nil || *opts.BaseEndpoint != "http://localhost:9000" {
+		t.Errorf("BaseEndpoint = %v, want http://localhost:9000", opts.BaseEndpoint)
+	}
+	if !opts.UsePathStyle {
+		t.Error("UsePathStyle = false, want true")
+	}
+	if opts.Region != defaultS3CompatibleRegion {
+		t.Errorf("Region = %q, want %q", opts.Region, defaultS3CompatibleRegion)
+	}
+
+	s, err = NewS3Storage(&SnapshotStorageConfig{Type: "s3", Bucket: "snaps"})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	if opts := s.client.Options(); opts.BaseEndpoint != nil || opts.UsePathStyle {
+		t.Errorf("AWS storage got endpoint %v, path style %v", opts.BaseEndpoint, opts.UsePathStyle)
+	}
+}
+
+func TestWithStorageRetry(t *testing.T) {
+	defer func(d time.Duration) { storageRetryDelay = d }(storageRetryDelay)
+	storageRetryDelay = time.Millisecond
+
+	calls := 0
+	err := withStorageRetry("upload", func() error {
+		calls++
+		if calls < 3 {
+			return &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("network failures: err = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = withStorageRetry("upload", func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) || calls != storageRetryAttempts {
+		t.Errorf("persistent failure: err = %v after %d calls, want %d attempts", err, calls, storageRetryAttempts)
+	}
+
+	calls = 0
+	err = withStorageRetry("download", func() error {
+		calls++
+		return errors.New("NoSuchKey")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("service error: err = %v after %d calls, want no retry", err, calls)
+	}
+}
+
+func TestLocalSnapshotPath(t *testing.T) {
+	current := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:aaa"}
+	tagged := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:bbb", Tag: "v1.0"}
+	untagged := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:0123456789abcdef"}
+	renamed := &SnapshotInfo{Path: "snapshots/old.dump", Hash: "sha256:ccc", Tag: "v0.9"}
+	metadata := &SnapshotMetadata{Current: current, History: []*SnapshotInfo{tagged, untagged, renamed}}
+
+	tests := []struct {
+		info *SnapshotInfo
+		want string
+	}{
+		{current, "default.dump"},
+		{tagged, "default-v1.0.dump"},
+		{untagged, "default-0123456789ab.dump"},
+		{renamed, "old.dump"},
+	}
+	for _, tt := range tests {
+		if got, err := LocalSnapshotPath("snaps", metadata, tt.info); err != nil || got != filepath.Join("snaps", tt.want) {
+			t.Errorf("LocalSnapshotPath(%s) = %q, %v, want %q", FormatSnapshotRef(tt.info), got, err, tt.want)
+		}
+	}
+
+	if got, err := LocalSnapshotPath("snaps", metadata, &SnapshotInfo{Hash: "sha256:ddd", Tag: "lost"}); err == nil {
+		t.Errorf("LocalSnapshotPath() without path or remote URL = %q, want error", got)
+	}
+}
+
+func TestLocalSnapshotPathRefusesDirectory(t *testing.T) {
+	snapshotsDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(snapshotsDir, "default.dump"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	metadata := &SnapshotMetadata{}
+
+	custom := &SnapshotInfo{Path: "snapshots/default.dump", Format: string(FormatCustom)}
+	if _, err := LocalSnapshotPath(snapshotsDir, metadata, custom); err == nil {
+		t.Error("LocalSnapshotPath() of a custom snapshot on a directory expected error")
+	}
+	dir := &SnapshotInfo{Path: "snapshots/default.dump", Format: string(FormatDirectory)}
+	if _, err := LocalSnapshotPath(snapshotsDir, metadata, dir); err != nil {
+		t.Errorf("LocalSnapshotPath() of a directory snapshot error = %v", err)
+	}
+}
+
+func TestPullRetiredSnapshot(t *testing.T) {
+	storage := &dirStorage{root: t.TempDir()}
+	snapshotsDir := t.TempDir()
+	localPath := filepath.Join(snapshotsDir, "default.dump")
+	if err := os.WriteFile(localPath, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v1 := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:1111111111111111", Tag: "v1", Format: string(FormatCustom)}
+	if err := PushSnapshot(storage, localPath, v1); err != nil {
+		t.Fatal(err)
+	}
+
+	// rebuilding overwrites default.dump, the v1 entry loses its path
+	if err := os.WriteFile(localPath, []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	current := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:2222222222222222", Format: string(FormatCustom)}
+	retired := retireSnapshot(v1, current)
+	if retired == nil || retired.Path != "" {
+		t.Fatalf("retireSnapshot() = %+v, want an entry without path", retired)
+	}
+	metadata := &SnapshotMetadata{Current: current, History: []*SnapshotInfo{retired}}
+
+	pullPath, err := LocalSnapshotPath(snapshotsDir, metadata, retired)
+	if err != nil {
+		t.Fatalf("LocalSnapshotPath() error = %v", err)
+	}
+	if pullPath != filepath.Join(snapshotsDir, "default-v1.dump") {
+		t.Fatalf("LocalSnapshotPath() = %q, want default-v1.dump", pullPath)
+	}
+	if err := PullSnapshot(storage, pullPath, retired); err != nil {
+		t.Fatalf("PullSnapshot() error = %v", err)
+	}
+
+	for path, want := range map[string]string{pullPath: "v1", localPath: "v2"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(path), data, err, want)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package regresql
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateFormat(t *testing.T) {
@@ -239,6 +242,239 @@ func TestSnapshotMetadataRoundtrip(t *testing.T) {
 	}
 }
 
+func TestPruneSnapshots(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	snapshot := func(tag string, age time.Duration) *SnapshotInfo {
+		path := filepath.Join(tmpDir, tag+".dump")
+		if err := os.WriteFile(path, []byte(tag), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return &SnapshotInfo{Path: path, Hash: "sha256:" + tag, Tag: tag, Created: now.Add(-age), SizeBytes: 10}
+	}
+
+	current := snapshot("current", 0)
+	v3 := snapshot("v3", 24*time.Hour)
+	v2 := snapshot("v2", 10*24*time.Hour)
+	v1 := snapshot("v1", 40*24*time.Hour)
+	// an old entry pointing at the current file must not delete it
+	shared := &SnapshotInfo{Path: current.Path, Hash: "sha256:old", Tag: "v0", Created: now.Add(-50 * 24 * time.Hour)}
+
+	write := func() {
+		meta := &SnapshotMetadata{Current: current, History: []*SnapshotInfo{v3, v2, v1, shared}}
+		if err := WriteSnapshotMetadataFull(tmpDir, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tags := func(infos []*SnapshotInfo) []string {
+		var out []string
+		for _, i := range infos {
+			out = append(out, i.Tag)
+		}
+		return out
+	}
+
+	write()
+	if _, err := PruneSnapshots(tmpDir, PruneOptions{}); err == nil {
+		t.Error("expected error without a retention policy")
+	}
+
+	res, err := PruneSnapshots(tmpDir, PruneOptions{OlderThan: 30 * 24 * time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneSnapshots() error = %v", err)
+	}
+	if got := tags(res.Removed); !equalStrings(got, []string{"v1", "v0"}) {
+		t.Errorf("dry run removed = %v, want [v1 v0]", got)
+	}
+	if !SnapshotExists(v1) {
+		t.Error("dry run deleted a file")
+	}
+	if meta, _ := ReadSnapshotMetadata(tmpDir); len(meta.History) != 4 {
+		t.Errorf("dry run rewrote metadata: %d history entries", len(meta.History))
+	}
+
+	res, err = PruneSnapshots(tmpDir, PruneOptions{Keep: 1, OlderThan: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("PruneSnapshots() error = %v", err)
+	}
+	if got := tags(res.Kept); !equalStrings(got, []string{"v3"}) {
+		t.Errorf("kept = %v, want [v3]", got)
+	}
+	if SnapshotExists(v2) || SnapshotExists(v1) {
+		t.Error("pruned snapshot files should be removed")
+	}
+	if !SnapshotExists(current) || !SnapshotExists(v3) {
+		t.Error("current and kept snapshot files must survive")
+	}
+	if res.FreedBytes != 20 {
+		t.Errorf("FreedBytes = %d, want 20", res.FreedBytes)
+	}
+
+	meta, err := ReadSnapshotMetadata(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Current == nil || meta.Current.Tag != "current" {
+		t.Error("current snapshot must never be pruned")
+	}
+	if got := tags(meta.History); !equalStrings(got, []string{"v3"}) {
+		t.Errorf("history = %v, want [v3]", got)
+	}
+}
+
+func TestPruneSnapshotsKeepCountsTagged(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+
+	var history []*SnapshotInfo
+	// newest first: untagged builds between tagged snapshots
+	for i, tag := range []string{"", "v3", "", "", "v2", "", "v1"} {
+		name := tag
+		if name == "" {
+			name = fmt.Sprintf("build%d", i)
+		}
+		path := filepath.Join(tmpDir, name+".dump")
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, &SnapshotInfo{Path: path, Hash: "sha256:" + name, Tag: tag, Created: now.Add(-time.Duration(i+1) * time.Hour)})
+	}
+	current := &SnapshotInfo{Path: filepath.Join(tmpDir, "current.dump"), Hash: "sha256:current", Created: now}
+	if err := WriteSnapshotMetadataFull(tmpDir, &SnapshotMetadata{Current: current, History: history}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := PruneSnapshots(tmpDir, PruneOptions{Keep: 2})
+	if err != nil {
+		t.Fatalf("PruneSnapshots() error = %v", err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0].Tag != "v1" {
+		t.Errorf("removed = %v, want only v1", res.Removed)
+	}
+	if len(res.Kept) != 6 {
+		t.Errorf("kept %d entries, want 6 (v3, v2 and the untagged builds)", len(res.Kept))
+	}
+	if !SnapshotExists(history[4]) {
+		t.Error("tagged v2 must survive untagged builds newer than it")
+	}
+}
+
+func TestWriteSnapshotMetadataHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	defer SetGlobalConfig(config{})
+	SetGlobalConfig(config{})
+	now := time.Now()
+
+	dump := filepath.Join(tmpDir, "default.dump")
+	write := func(info *SnapshotInfo) *SnapshotMetadata {
+		t.Helper()
+		if err := WriteSnapshotMetadata(tmpDir, info); err != nil {
+			t.Fatalf("WriteSnapshotMetadata() error = %v", err)
+		}
+		meta, err := ReadSnapshotMetadata(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta
+	}
+
+	// untagged snapshots are replaced without a trace
+	write(&SnapshotInfo{Path: dump, Hash: "sha256:a", Created: now.Add(-3 * time.Hour)})
+	meta := write(&SnapshotInfo{Path: dump, Hash: "sha256:b", Tag: "v1", Note: "first", Created: now.Add(-2 * time.Hour)})
+	if len(meta.History) != 0 {
+		t.Fatalf("history = %d entries, want none for an untagged snapshot", len(meta.History))
+	}
+
+	// rebuilding identical data keeps the tag
+	meta = write(&SnapshotInfo{Path: dump, Hash: "sha256:b", Created: now.Add(-time.Hour)})
+	if meta.Current.Tag != "v1" || meta.Current.Note != "first" || len(meta.History) != 0 {
+		t.Errorf("current = %+v, history = %d, want tag v1 kept", meta.Current, len(meta.History))
+	}
+
+	// new data over the same file keeps the entry but drops its path
+	meta = write(&SnapshotInfo{Path: dump, Hash: "sha256:c", Tag: "v2", Created: now})
+	if len(meta.History) != 1 || meta.History[0].Tag != "v1" || meta.History[0].Path != "" {
+		t.Fatalf("history = %+v, want v1 without a path", meta.History)
+	}
+
+	// a tagged snapshot in its own file stays restorable
+	meta = write(&SnapshotInfo{Path: filepath.Join(tmpDir, "v3.dump"), Hash: "sha256:d", Tag: "v3", Created: now.Add(time.Hour)})
+	if len(meta.History) != 2 || meta.History[0].Tag != "v2" || meta.History[0].Path != dump {
+		t.Errorf("history = %+v, want v2 first with its path", meta.History)
+	}
+}
+
+func TestWriteSnapshotMetadataHistoryLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	defer SetGlobalConfig(config{})
+	SetGlobalConfig(config{Snapshot: &SnapshotConfig{HistoryMaxEntries: 2}})
+	now := time.Now()
+
+	for i, tag := range []string{"v1", "v2", "v3", "v4", "v5"} {
+		path := filepath.Join(tmpDir, tag+".dump")
+		if err := os.WriteFile(path, []byte(tag), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info := &SnapshotInfo{Path: path, Hash: "sha256:" + tag, Tag: tag, Created: now.Add(time.Duration(i) * time.Hour)}
+		if err := WriteSnapshotMetadata(tmpDir, info); err != nil {
+			t.Fatalf("WriteSnapshotMetadata() error = %v", err)
+		}
+	}
+
+	history, err := ReadSnapshotHistory(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, info := range history {
+		tags = append(tags, info.Tag)
+	}
+	if !equalStrings(tags, []string{"v5", "v4", "v3"}) {
+		t.Errorf("history = %v, want [v5 v4 v3]", tags)
+	}
+	for _, tag := range []string{"v1", "v2", "v3"} {
+		if !fileExists(filepath.Join(tmpDir, tag+".dump")) {
+			t.Errorf("%s.dump was deleted; capping the history must not remove files", tag)
+		}
+	}
+}
+
+func TestSnapshotHistoryWithoutCurrent(t *testing.T) {
+	now := time.Now()
+	older := &SnapshotInfo{Hash: "sha256:aaa", Tag: "v1", Created: now.Add(-time.Hour)}
+	newer := &SnapshotInfo{Hash: "sha256:bbb", Tag: "v2", Created: now}
+	metadata := &SnapshotMetadata{History: []*SnapshotInfo{older, newer}}
+
+	history := SnapshotHistory(metadata)
+	if len(history) != 2 || history[0] != newer || history[1] != older {
+		t.Errorf("SnapshotHistory() = %v, want v2 then v1", history)
+	}
+	if history[0] == metadata.Current {
+		t.Error("newest history entry taken for the current snapshot")
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"xd", 0, true},
+		{"-1h", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRetention(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
 func TestFormatBytes(t *testing.T) {
 	tests := []struct {
 		bytes int64
@@ -467,3 +703,205 @@ func TestCaptureSectionsCreatesDirectory(t *testing.T) {
 		t.Error("CaptureSections should create output directory before calling pg_dump")
 	}
 }
+
+func TestRunMigrationCommandResult(t *testing.T) {
+	res, err := runMigrationCommand("echo applied; echo oops >&2; exit 3", "postgres://localhost/x", nil, false)
+	if err == nil {
+		t.Fatal("expected error for non-zero exit")
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", res.ExitCode)
+	}
+	if res.Stdout != "applied\n" || res.Stderr != "oops\n" {
+		t.Errorf("Stdout = %q, Stderr = %q", res.Stdout, res.Stderr)
+	}
+	if !strings.HasSuffix(err.Error(), "\napplied\noops") {
+		t.Errorf("error should end with stdout and stderr: %q", err.Error())
+	}
+
+	// tools such as some flyway wrappers report errors on stdout only
+	_, err = runMigrationCommand("echo 'ERROR: Validate failed'; exit 1", "postgres://localhost/x", nil, false)
+	if err == nil || !strings.Contains(err.Error(), "ERROR: Validate failed") {
+		t.Errorf("error should include stdout: %v", err)
+	}
+
+	res, err = runMigrationCommand(`test "$PGURI" = postgres://localhost/x`, "postgres://localhost/x", nil, false)
+	if err != nil || res.ExitCode != 0 {
+		t.Errorf("command should see PGURI: exit %d, err %v", res.ExitCode, err)
+	}
+}
+
+func TestRunMigrationCommandPlaceholderAndEnv(t *testing.T) {
+	pguri := "postgres://localhost/x?sslmode=disable&application_name=it's"
+	cmd := `test {pguri} = "$DATABASE_URL" && test "$MIGRATE_LOCK" = off`
+	if _, err := runMigrationCommand(cmd, pguri, []string{"MIGRATE_LOCK=off"}, false); err != nil {
+		t.Errorf("placeholder or env not applied: %v", err)
+	}
+}
+
+func TestRunMigrationCommandRetry(t *testing.T) {
+	saved := migrationRetryDelay
+	migrationRetryDelay = time.Millisecond
+	defer func() { migrationRetryDelay = saved }()
+
+	counter := filepath.Join(t.TempDir(), "attempts")
+	// fails with a connection error until the third attempt
+	cmd := `echo x >> ` + counter + `; [ $(wc -l < ` + counter + `) -ge 3 ] || { echo "could not connect to server: Connection refused" >&2; exit 1; }`
+	if _, err := runMigrationCommand(cmd, "postgres://localhost/x", nil, false); err != nil {
+		t.Fatalf("expected success on third attempt: %v", err)
+	}
+
+	// other failures are not retried
+	if err := os.Remove(counter); err != nil {
+		t.Fatal(err)
+	}
+	cmd = `echo x >> ` + counter + `; echo 'relation "users" already exists' >&2; exit 1`
+	if _, err := runMigrationCommand(cmd, "postgres://localhost/x", nil, false); err == nil {
+		t.Fatal("expected error")
+	}
+	data, _ := os.ReadFile(counter)
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("non-transient failure ran %d times, want 1", n)
+	}
+}
+
+func TestRecordMigrationCommandFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	current := &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:abc"}
+	if err := WriteSnapshotMetadata(tmpDir, current); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := &MigrationCommandResult{Command: "goose up", ExitCode: 1, Stderr: "no such table"}
+	if err := RecordMigrationCommandFailure(tmpDir, failed); err != nil {
+		t.Fatalf("RecordMigrationCommandFailure() error = %v", err)
+	}
+	meta, err := ReadSnapshotMetadata(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Current == nil || meta.Current.Hash != current.Hash {
+		t.Error("current snapshot should be preserved")
+	}
+	if meta.FailedMigrationCommand == nil || meta.FailedMigrationCommand.Stderr != "no such table" {
+		t.Errorf("FailedMigrationCommand = %+v", meta.FailedMigrationCommand)
+	}
+
+	// a successful build clears the failure
+	if err := WriteSnapshotMetadata(tmpDir, current); err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := ReadSnapshotMetadata(tmpDir); meta.FailedMigrationCommand != nil {
+		t.Error("FailedMigrationCommand should be cleared by a new build")
+	}
+}
+
+func TestOutputTail(t *testing.T) {
+	if got := outputTail("short", 10); got != "short" {
+		t.Errorf("outputTail(short) = %q", got)
+	}
+	if got := outputTail("0123456789", 4); got != "[... truncated ...]\n6789" {
+		t.Errorf("outputTail = %q", got)
+	}
+}
+
+func TestFormatRestoreProgress(t *testing.T) {
+	small := formatRestoreProgress(5<<20, 10<<20, 2, 3*time.Second)
+	if small != "5.0 MB restored, 2 COPY active, 3s elapsed" {
+		t.Errorf("small snapshot = %q", small)
+	}
+
+	half := formatRestoreProgress(100<<20, 200<<20, 1, 10*time.Second)
+	if !strings.HasPrefix(half, "[###############---------------]  50% 100.0 MB / 200.0 MB") || !strings.HasSuffix(half, "ETA 10s") {
+		t.Errorf("half restored = %q", half)
+	}
+
+	grown := formatRestoreProgress(500<<20, 200<<20, 0, time.Minute)
+	if !strings.Contains(grown, " 99% ") {
+		t.Errorf("restore past the snapshot size = %q, want capped at 99%%", grown)
+	}
+
+	started := formatRestoreProgress(-1, 200<<20, 0, time.Second)
+	if !strings.Contains(started, "  0% 0 B") || !strings.HasSuffix(started, "ETA --") {
+		t.Errorf("nothing restored yet = %q", started)
+	}
+}
+
+func TestSnapshotInputSize(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "toc.dat"), make([]byte, 100), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "3001.dat.gz"), make([]byte, 50), 0644)
+
+	if got := snapshotInputSize(dir); got != 150 {
+		t.Errorf("directory size = %d, want 150", got)
+	}
+	if got := snapshotInputSize(filepath.Join(dir, "toc.dat")); got != 100 {
+		t.Errorf("file size = %d, want 100", got)
+	}
+	if got := snapshotInputSize(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("missing file size = %d, want 0", got)
+	}
+}
+
+func TestValidateSchemaHashStrict(t *testing.T) {
+	root := t.TempDir()
+	snapshotsDir := GetSnapshotsDir(root)
+	os.MkdirAll(snapshotsDir, 0755)
+
+	if _, err := ValidateSchemaHash(root, false); err != nil {
+		t.Errorf("no metadata = %v, want a warning only", err)
+	}
+	if _, err := ValidateSchemaHash(root, true); err == nil {
+		t.Error("no metadata with strict = nil, want error")
+	}
+
+	WriteSnapshotMetadata(snapshotsDir, &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:abc"})
+	if _, err := ValidateSchemaHash(root, true); err == nil || !strings.Contains(err.Error(), "no schema file") {
+		t.Errorf("metadata without schema with strict = %v, want no schema file error", err)
+	}
+
+	schemaPath := filepath.Join(root, "schema.sql")
+	os.WriteFile(schemaPath, []byte("CREATE TABLE t (id int);\n"), 0644)
+	hash, _ := computeSchemaHash(schemaPath)
+	WriteSnapshotMetadata(snapshotsDir, &SnapshotInfo{Path: "snapshots/default.dump", Hash: "sha256:def", SchemaPath: schemaPath, SchemaHash: hash})
+	got, err := ValidateSchemaHash(root, true)
+	if err != nil || got != hash {
+		t.Errorf("ValidateSchemaHash() = %q, %v, want %q", got, err, hash)
+	}
+}
+
+func TestCachedSchemaHash(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.sql")
+	os.WriteFile(schemaPath, []byte("CREATE TABLE t (id int);\n"), 0644)
+	now := time.Now()
+
+	hash, err := cachedSchemaHash(dir, schemaPath, now)
+	if err != nil {
+		t.Fatalf("cachedSchemaHash() error = %v", err)
+	}
+	state, err := readRunState(dir)
+	if err != nil || state.SchemaHash != hash || !state.LastRun.Equal(now) {
+		t.Fatalf("run state = %+v, %v", state, err)
+	}
+
+	// a recorded hash within the minute is reused without hashing again
+	state.SchemaHash = "sha256:cached"
+	writeRunState(dir, state)
+	if got, _ := cachedSchemaHash(dir, schemaPath, now.Add(30*time.Second)); got != "sha256:cached" {
+		t.Errorf("within a minute = %q, want the cached hash", got)
+	}
+	if got, _ := cachedSchemaHash(dir, schemaPath, now.Add(2*time.Minute)); got != hash {
+		t.Errorf("after a minute = %q, want %q", got, hash)
+	}
+
+	// a changed file is hashed again right away
+	state, _ = readRunState(dir)
+	state.SchemaHash = "sha256:cached"
+	writeRunState(dir, state)
+	os.WriteFile(schemaPath, []byte("CREATE TABLE t (id bigint);\n"), 0644)
+	if got, _ := cachedSchemaHash(dir, schemaPath, state.SchemaHashedAt.Add(time.Second)); got == "sha256:cached" || got == hash {
+		t.Errorf("changed schema = %q, want a new hash", got)
+	}
+}
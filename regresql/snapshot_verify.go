@@ -0,0 +1,166 @@
+package regresql
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// VerifyResult is the outcome of VerifySnapshot
+type VerifyResult struct {
+	Valid  bool
+	Errors []string
+}
+
+func (r *VerifyResult) addError(format string, args ...any) {
+	r.Valid = false
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// VerifySnapshot checks a snapshot is readable before spending time on a
+// restore. Custom and directory dumps must have a table of contents
+// pg_restore can list, directory dumps must also hold the data file of
+// every TABLE DATA entry, and plain dumps must be UTF-8 ending with a
+// complete statement. When expectedHash is set, the snapshot must also hash
+// to it, as recorded in SnapshotInfo at capture.
+func VerifySnapshot(path string, format SnapshotFormat, expectedHash string) *VerifyResult {
+	result := &VerifyResult{Valid: true}
+	if _, err := os.Stat(path); err != nil {
+		result.addError("snapshot not found: %s", path)
+		return result
+	}
+	if format == "" {
+		format = DetectSnapshotFormat(path)
+	}
+
+	switch format {
+	case FormatPlain:
+		verifyPlainSnapshot(path, result)
+	case FormatDirectory:
+		if list, ok := listSnapshotTOC(path, result); ok {
+			verifyDirectoryFiles(path, list, result)
+		}
+	default:
+		listSnapshotTOC(path, result)
+	}
+
+	if expectedHash != "" {
+		hash, err := computeFileHash(path, format)
+		if err != nil {
+			result.addError("failed to hash snapshot: %v", err)
+		} else if hash != expectedHash {
+			result.addError("snapshot hash %s does not match the recorded %s; the file changed since it was captured",
+				TruncateHash(hash), TruncateHash(expectedHash))
+		}
+	}
+	return result
+}
+
+// listSnapshotTOC runs pg_restore --list, which reads the table of contents
+// of a custom or directory dump without restoring anything
+func listSnapshotTOC(path string, result *VerifyResult) (string, bool) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("pg_restore", "--list", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		result.addError("pg_restore cannot read the table of contents: %s", msg)
+		return "", false
+	}
+	return stdout.String(), true
+}
+
+// verifyDirectoryFiles checks every data file the table of contents of a
+// directory dump refers to is present
+func verifyDirectoryFiles(dir, list string, result *VerifyResult) {
+	for _, id := range tocDataIDs(list) {
+		matches, _ := filepath.Glob(filepath.Join(dir, id+".dat*"))
+		if len(matches) == 0 {
+			result.addError("data file %s.dat of TOC entry %s is missing", id, id)
+		}
+	}
+}
+
+// tocDataIDs returns the dump ids of the TABLE DATA entries in pg_restore
+// --list output; a directory dump stores each in <id>.dat, compressed or not
+func tocDataIDs(list string) []string {
+	var ids []string
+	for line := range strings.SplitSeq(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		id, rest, ok := strings.Cut(line, ";")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) >= 4 && fields[2] == "TABLE" && fields[3] == "DATA" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// verifyPlainSnapshot checks a plain SQL dump is valid UTF-8 and its last
+// statement is terminated, which a dump cut short by a full disk or a killed
+// pg_dump usually is not
+func verifyPlainSnapshot(path string, result *VerifyResult) {
+	f, err := os.Open(path)
+	if err != nil {
+		result.addError("failed to open snapshot: %v", err)
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	lineNo, last := 0, ""
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			lineNo++
+			if !utf8.ValidString(line) {
+				result.addError("line %d is not valid UTF-8", lineNo)
+				return
+			}
+			if s := strings.TrimSpace(line); s != "" && !strings.HasPrefix(s, "--") {
+				last = s
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			result.addError("failed to read snapshot: %v", err)
+			return
+		}
+	}
+
+	switch {
+	case last == "":
+		result.addError("snapshot contains no SQL statements")
+	case strings.HasSuffix(last, ";"), strings.HasPrefix(last, `\`):
+		// a terminated statement, or a psql meta-command such as \. or \connect
+	default:
+		result.addError("snapshot ends with an incomplete statement: %q", truncateStatement(last))
+	}
+}
+
+func truncateStatement(s string) string {
+	const limit = 80
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,154 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySnapshotPlain(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "complete dump",
+			content: `CREATE TABLE users (id int);
+COPY public.users (id) FROM stdin;
+1
+\.
+
+--
+-- PostgreSQL database dump complete
+--
+`,
+		},
+		{
+			name:    "truncated statement",
+			content: "CREATE TABLE users (id int);\nCREATE TABLE orders (\n  id int\n",
+			wantErr: "incomplete statement",
+		},
+		{
+			name:    "invalid utf-8",
+			content: "INSERT INTO users VALUES ('\xff');\n",
+			wantErr: "not valid UTF-8",
+		},
+		{
+			name:    "only comments",
+			content: "-- nothing here\n",
+			wantErr: "no SQL statements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.sql")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write snapshot: %v", err)
+			}
+
+			result := VerifySnapshot(path, "", "")
+			if tt.wantErr == "" {
+				if !result.Valid {
+					t.Errorf("VerifySnapshot() errors = %v, want valid", result.Errors)
+				}
+				return
+			}
+			if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantErr) {
+				t.Errorf("VerifySnapshot() = %+v, want error containing %q", result, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySnapshotHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.sql")
+	if err := os.WriteFile(path, []byte("SELECT 1;\n"), 0644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	hash, err := computeSingleFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := VerifySnapshot(path, FormatPlain, hash); !result.Valid {
+		t.Errorf("matching hash: errors = %v", result.Errors)
+	}
+
+	if err := os.WriteFile(path, []byte("SELECT 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := VerifySnapshot(path, FormatPlain, hash)
+	if result.Valid || !strings.Contains(result.Errors[0], "does not match") {
+		t.Errorf("changed file: result = %+v, want hash mismatch", result)
+	}
+}
+
+func TestVerifySnapshotMissing(t *testing.T) {
+	result := VerifySnapshot(filepath.Join(t.TempDir(), "missing.dump"), "", "")
+	if result.Valid {
+		t.Error("VerifySnapshot() of a missing file is valid")
+	}
+}
+
+func TestTocDataIDs(t *testing.T) {
+	list := `;
+; Archive created at 2026-01-01 10:00:00 UTC
+;     dbname: shop
+;
+; Selected TOC Entries:
+;
+215; 1259 16385 TABLE public users postgres
+216; 1259 16390 TABLE public orders postgres
+3345; 0 16385 TABLE DATA public users postgres
+3346; 0 16390 TABLE DATA public orders postgres
+3200; 2606 16395 CONSTRAINT public users users_pkey postgres
+`
+	got := tocDataIDs(list)
+	if !equalStrings(got, []string{"3345", "3346"}) {
+		t.Errorf("tocDataIDs() = %v, want [3345 3346]", got)
+	}
+}
+
+func TestVerifyDirectoryFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "3345.dat.gz"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := "3345; 0 16385 TABLE DATA public users postgres\n3346; 0 16390 TABLE DATA public orders postgres\n"
+
+	result := &VerifyResult{Valid: true}
+	verifyDirectoryFiles(dir, list, result)
+	if result.Valid || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "3346.dat") {
+		t.Errorf("verifyDirectoryFiles() = %+v, want 3346.dat missing", result)
+	}
+}
+
+func TestGetSnapshotByPath(t *testing.T) {
+	dir := t.TempDir()
+	current := &SnapshotInfo{Path: filepath.Join(dir, "default.dump"), Hash: "sha256:aaa"}
+	old := &SnapshotInfo{Path: filepath.Join(dir, "v1.dump"), Hash: "sha256:bbb", Tag: "v1"}
+	metadata := &SnapshotMetadata{Current: current, History: []*SnapshotInfo{old}}
+
+	info, err := GetSnapshotByPath(metadata, dir, filepath.Join(dir, "sub", "..", "v1.dump"))
+	if err != nil || info != old {
+		t.Errorf("GetSnapshotByPath() = %v, %v, want the v1 snapshot", info, err)
+	}
+	if _, err := GetSnapshotByPath(metadata, dir, filepath.Join(dir, "other.dump")); err == nil {
+		t.Error("GetSnapshotByPath() of an unknown path expected error")
+	}
+}
+
+func TestGetSnapshotByPathRelativeToRoot(t *testing.T) {
+	// built from the project root, then verified with -C <root> from elsewhere
+	root := t.TempDir()
+	current := &SnapshotInfo{Path: filepath.Join("snapshots", "default.dump"), Hash: "sha256:aaa"}
+	metadata := &SnapshotMetadata{Current: current}
+
+	info, err := GetSnapshotByPath(metadata, root, filepath.Join(root, "snapshots", "default.dump"))
+	if err != nil || info != current {
+		t.Errorf("GetSnapshotByPath() = %v, %v, want the current snapshot", info, err)
+	}
+}
@@ -2,6 +2,10 @@ package regresql
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +23,25 @@ type (
 		NoSeqScanWarn      bool
 		DiffFloatTolerance float64
 		Timeout            time.Duration // statement_timeout override (0 = unset)
+		CostThreshold      float64       // analyze.cost_threshold override in percent (0 = unset)
+		Sample             int           // keep a deterministic sample of N rows (0 = unset)
+		Role               string        // run as this role via SET LOCAL ROLE (RLS testing)
+		CaptureAnalyze     bool          // save EXPLAIN (ANALYZE, BUFFERS) of each binding to out/analyze/
+		IgnoreColumnOrder  bool          // compare result columns by name, not position
+
+		// ColumnFloatTolerances overrides DiffFloatTolerance per column,
+		// from float_tolerance_col_<column>=<tolerance>
+		ColumnFloatTolerances map[string]float64
+
+		// AssertColumns limits the output comparison to these columns, from
+		// assert_columns=id,status; IgnoreColumns leaves these out of it,
+		// from ignore_columns=created_at,updated_at
+		AssertColumns []string
+		IgnoreColumns []string
+
+		// Format of the expected and actual files, from format=psql
+		// (ResultFormatPsql) or format=json (default)
+		Format string
 	}
 )
 
@@ -29,10 +52,19 @@ func (q *Query) GetRegressQLOptions() RegressQLOptions {
 		return opts
 	}
 
+	// column lists share the comma separator with the options, so the plain
+	// words following assert_columns= or ignore_columns= extend the list
+	var columns *[]string
 	for _, part := range strings.Split(metadata, ",") {
 		part = strings.TrimSpace(part)
 		partLower := strings.ToLower(part)
 
+		if columns != nil && part != "" && !strings.ContainsAny(part, "=:") && !isRegressQLFlag(partLower) {
+			*columns = append(*columns, part)
+			continue
+		}
+		columns = nil
+
 		switch {
 		case partLower == "notest":
 			opts.NoTest = true
@@ -40,33 +72,135 @@ func (q *Query) GetRegressQLOptions() RegressQLOptions {
 			opts.NoBaseline = true
 		case partLower == "noseqscanwarn":
 			opts.NoSeqScanWarn = true
+		case partLower == "capture_analyze":
+			opts.CaptureAnalyze = true
+		case partLower == "ignore_column_order":
+			opts.IgnoreColumnOrder = true
 		case strings.HasPrefix(partLower, "difffloattolerance:"):
 			// Parse DiffFloatTolerance:0.01
 			value := strings.TrimPrefix(part, "DiffFloatTolerance:")
 			value = strings.TrimPrefix(value, "difffloattolerance:")
 			fmt.Sscanf(value, "%f", &opts.DiffFloatTolerance)
+		case strings.HasPrefix(partLower, "float_tolerance_col_"):
+			// float_tolerance_col_amount=0.01 (or float_tolerance_col_amount:0.01)
+			name, value, ok := strings.Cut(part[len("float_tolerance_col_"):], "=")
+			if !ok {
+				name, value, ok = strings.Cut(name, ":")
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); ok && err == nil && v >= 0 {
+				if opts.ColumnFloatTolerances == nil {
+					opts.ColumnFloatTolerances = make(map[string]float64)
+				}
+				opts.ColumnFloatTolerances[strings.TrimSpace(name)] = v
+			}
 		case strings.HasPrefix(partLower, "timeout:"):
 			value := part[len("timeout:"):]
 			if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil {
 				opts.Timeout = d
 			}
+		case strings.HasPrefix(partLower, "cost_threshold:"), strings.HasPrefix(partLower, "cost_threshold="):
+			// Parse cost_threshold=5.0 (or cost_threshold:5.0)
+			value := strings.TrimSpace(part[len("cost_threshold="):])
+			if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 {
+				opts.CostThreshold = v
+			}
+		case strings.HasPrefix(partLower, "sample="), strings.HasPrefix(partLower, "sample:"):
+			if n, err := strconv.Atoi(strings.TrimSpace(part[len("sample="):])); err == nil && n > 0 {
+				opts.Sample = n
+			}
+		case strings.HasPrefix(partLower, "role="), strings.HasPrefix(partLower, "role:"):
+			// role names are case sensitive, keep them as written
+			opts.Role = strings.TrimSpace(part[len("role="):])
+		case strings.HasPrefix(partLower, "format="), strings.HasPrefix(partLower, "format:"):
+			opts.Format = strings.ToLower(strings.TrimSpace(part[len("format="):]))
+		case strings.HasPrefix(partLower, "assert_columns="), strings.HasPrefix(partLower, "assert_columns:"):
+			columns = &opts.AssertColumns
+			if name := strings.TrimSpace(part[len("assert_columns="):]); name != "" {
+				opts.AssertColumns = append(opts.AssertColumns, name)
+			}
+		case strings.HasPrefix(partLower, "ignore_columns="), strings.HasPrefix(partLower, "ignore_columns:"):
+			columns = &opts.IgnoreColumns
+			if name := strings.TrimSpace(part[len("ignore_columns="):]); name != "" {
+				opts.IgnoreColumns = append(opts.IgnoreColumns, name)
+			}
 		}
 	}
 
 	return opts
 }
 
+// isRegressQLFlag reports whether a lowercased option takes no value
+func isRegressQLFlag(option string) bool {
+	switch option {
+	case "notest", "nobaseline", "noseqscanwarn", "capture_analyze", "ignore_column_order":
+		return true
+	}
+	return false
+}
+
+// costThreshold returns the per-query cost threshold when set, otherwise the
+// global one
+func (o RegressQLOptions) costThreshold(global float64) float64 {
+	if o.CostThreshold > 0 {
+		return o.CostThreshold
+	}
+	return global
+}
+
+// nameTagRE matches the "-- name: <query>" line that starts a query
+var nameTagRE = regexp.MustCompile(`^\s*--\s*name:\s*(\S+)`)
+
+// startLine returns the line of the query in its SQL file: its name tag, or
+// the first line of SQL for a file holding a single unnamed query. It is 0
+// when the file cannot be read.
+func (q *Query) startLine() int {
+	data, err := os.ReadFile(q.Path)
+	if err != nil {
+		return 0
+	}
+	implicit := strings.TrimSuffix(filepath.Base(q.Path), filepath.Ext(q.Path))
+	first := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		if m := nameTagRE.FindStringSubmatch(line); m != nil {
+			if m[1] == q.Name || (m[1] == "default" && q.Name == implicit) {
+				return i + 1
+			}
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); first == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			first = i + 1
+		}
+	}
+	return first
+}
+
 func parseQueryFile(queryPath string) (map[string]*Query, error) {
 	store := queries.NewQueryStore()
 	if err := store.LoadFromFile(queryPath); err != nil {
 		return nil, fmt.Errorf("failed to open query file '%s': %w", queryPath, err)
 	}
 
+	// "-- name: default" names the implicit query: like SQL without an
+	// annotation it is named after the file, so its plan, expected and
+	// baseline files are the same either way
+	implicit := strings.TrimSuffix(filepath.Base(queryPath), filepath.Ext(queryPath))
+	all := store.Queries()
 	result := make(map[string]*Query)
-	for name, bqQuery := range store.Queries() {
-		if name == "default" && bqQuery.RawQuery() == "" {
+	for name, bqQuery := range all {
+		if name == "default" {
+			if bqQuery.RawQuery() == "" {
+				continue
+			}
+			if other, ok := all[implicit]; ok && implicit != "default" && strings.TrimSpace(other.RawQuery()) != "" {
+				return nil, fmt.Errorf("query file '%s': '-- name: default' and the SQL before the first annotation are both the query %s", queryPath, implicit)
+			}
+			name = implicit
+			bqQuery.Name = implicit
+		}
+		if _, ok := result[name]; ok && strings.TrimSpace(bqQuery.RawQuery()) == "" {
 			continue
 		}
+		bindNamedParams(bqQuery)
 		result[name] = &Query{Query: bqQuery}
 	}
 
@@ -78,6 +212,7 @@ func NewQueryFromString(name, sqlText string) (*Query, error) {
 	if err != nil {
 		return nil, err
 	}
+	bindNamedParams(q)
 	return &Query{Query: q}, nil
 }
 
@@ -0,0 +1,126 @@
+package regresql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitSQLStatements splits a script into its statements on the semicolons
+// outside string literals ('...', E'...'), quoted identifiers, dollar-quoted
+// bodies ($$...$$, $fn$...$fn$) and comments. Leading comments and empty
+// statements are dropped.
+func splitSQLStatements(script string) []string {
+	var (
+		statements []string
+		start      = -1 // start of the current statement, -1 before its first token
+	)
+	isIdent := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	flush := func(end int) {
+		if start >= 0 {
+			statements = append(statements, strings.TrimSpace(script[start:end]))
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			// block comments nest in PostgreSQL
+			depth := 0
+			for ; i < len(script); i++ {
+				if strings.HasPrefix(script[i:], "/*") {
+					depth++
+					i++
+				} else if strings.HasPrefix(script[i:], "*/") {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			continue
+		case c == ';':
+			flush(i)
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+
+		if start < 0 {
+			start = i
+		}
+		switch {
+		case c == '\'':
+			escapes := i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') && (i < 2 || !isIdent(script[i-2]))
+			for i++; i < len(script); i++ {
+				if escapes && script[i] == '\\' {
+					i++
+				} else if script[i] == '\'' {
+					if i+1 < len(script) && script[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '"':
+			for i++; i < len(script); i++ {
+				if script[i] == '"' {
+					if i+1 < len(script) && script[i+1] == '"' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '$' && (i == 0 || !isIdent(script[i-1])):
+			// $tag$ where the tag is empty or an identifier not starting
+			// with a digit, so $1 parameters are left alone
+			end := i + 1
+			for end < len(script) && isIdent(script[end]) {
+				end++
+			}
+			if end >= len(script) || script[end] != '$' || (end > i+1 && script[i+1] >= '0' && script[i+1] <= '9') {
+				continue
+			}
+			tag := script[i : end+1]
+			if close := strings.Index(script[end+1:], tag); close >= 0 {
+				i = end + close + len(tag)
+			} else {
+				i = len(script)
+			}
+		}
+	}
+	flush(len(script))
+	return statements
+}
+
+// checkNoTransactionControl rejects statements that would end or nest the
+// transaction a script runs in; savepoints are fine
+func checkNoTransactionControl(stmt string) error {
+	fields := strings.Fields(strings.ToLower(stmt))
+	if len(fields) == 0 {
+		return nil
+	}
+	switch fields[0] {
+	case "begin", "start", "commit", "end", "abort":
+	case "rollback":
+		if len(fields) > 1 && fields[1] == "to" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("transaction control (%s) is not allowed here, the statements run in the query's transaction; use SAVEPOINT, RELEASE and ROLLBACK TO", strings.ToUpper(fields[0]))
+}
@@ -0,0 +1,84 @@
+package regresql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := `-- setup
+SAVEPOINT s1;
+INSERT INTO notes VALUES ('a;b', E'it\'s; fine', 'don''t;');
+/* block; /* nested; */ still comment; */
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+CREATE FUNCTION g() RETURNS int AS $body$ BEGIN RETURN 2; END; $body$ LANGUAGE plpgsql;
+SELECT "semi;colon", $1 FROM t;
+RELEASE s1;
+-- trailing comment`
+
+	want := []string{
+		"SAVEPOINT s1",
+		`INSERT INTO notes VALUES ('a;b', E'it\'s; fine', 'don''t;')`,
+		"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql",
+		"CREATE FUNCTION g() RETURNS int AS $body$ BEGIN RETURN 2; END; $body$ LANGUAGE plpgsql",
+		`SELECT "semi;colon", $1 FROM t`,
+		"RELEASE s1",
+	}
+	got := splitSQLStatements(script)
+	if !equalStrings(got, want) {
+		t.Errorf("splitSQLStatements() =\n%q\nwant\n%q", got, want)
+	}
+
+	// backslashes don't escape in standard strings
+	if got := splitSQLStatements(`SELECT name = 'x\'; SELECT 2`); len(got) != 2 {
+		t.Errorf("standard string with backslash: %q", got)
+	}
+}
+
+func TestCheckNoTransactionControl(t *testing.T) {
+	for _, stmt := range []string{"BEGIN", "start transaction", "COMMIT", "END", "ROLLBACK", "abort"} {
+		if checkNoTransactionControl(stmt) == nil {
+			t.Errorf("%q: expected an error", stmt)
+		}
+	}
+	for _, stmt := range []string{"SAVEPOINT s1", "ROLLBACK TO SAVEPOINT s1", "RELEASE s1", "UPDATE t SET x = 1"} {
+		if err := checkNoTransactionControl(stmt); err != nil {
+			t.Errorf("%q: %v", stmt, err)
+		}
+	}
+}
+
+// execRecorder records the statements passed to ExecContext
+type execRecorder struct {
+	Querier
+	statements []string
+}
+
+func (r *execRecorder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.statements = append(r.statements, query)
+	return nil, nil
+}
+
+func TestRunHooksMultiStatement(t *testing.T) {
+	p := &Plan{Query: testQuery(t, "orders", "sql/orders.sql")}
+	rec := &execRecorder{}
+
+	specs := []SQLSpec{
+		{Inline: "SET work_mem = '64MB'; SET enable_seqscan = off"},
+		{Inline: "SAVEPOINT s1; UPDATE t SET x = 1; RELEASE s1;", MultiStatement: true},
+	}
+	if err := p.runHooks(context.Background(), rec, "before_each", specs); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SET work_mem = '64MB'; SET enable_seqscan = off", "SAVEPOINT s1", "UPDATE t SET x = 1", "RELEASE s1"}
+	if !equalStrings(rec.statements, want) {
+		t.Errorf("executed %q, want %q", rec.statements, want)
+	}
+
+	err := p.runHooks(context.Background(), &execRecorder{}, "before_each", []SQLSpec{{Inline: "BEGIN; SELECT 1; COMMIT", MultiStatement: true}})
+	if err == nil || !strings.Contains(err.Error(), "statement 1") {
+		t.Errorf("err = %v, want transaction control rejected", err)
+	}
+}
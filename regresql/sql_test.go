@@ -1,6 +1,9 @@
 package regresql
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -80,3 +83,260 @@ func TestPrepareTwoParams(t *testing.T) {
 		t.Error("Bindings not properly applied, got ", params)
 	}
 }
+
+func TestNamedParamsSkipLiteralsAndComments(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+		args []string
+	}{
+		{
+			name: "string literal",
+			sql:  `select ':notparam', :id`,
+			want: `select ':notparam', $1`,
+			args: []string{"id"},
+		},
+		{
+			name: "escaped quote in literal",
+			sql:  `select 'it''s :x' where a = :a`,
+			want: `select 'it''s :x' where a = $1`,
+			args: []string{"a"},
+		},
+		{
+			name: "E string with backslash escape",
+			sql:  `select E'\' :x' where a = :a`,
+			want: `select E'\' :x' where a = $1`,
+			args: []string{"a"},
+		},
+		{
+			name: "quoted identifier",
+			sql:  `select "col:x" from t where a = :a`,
+			want: `select "col:x" from t where a = $1`,
+			args: []string{"a"},
+		},
+		{
+			name: "dollar quoted body",
+			sql:  "select $fn$ :x $fn$, $$ :y $$ where a = :a",
+			want: "select $fn$ :x $fn$, $$ :y $$ where a = $1",
+			args: []string{"a"},
+		},
+		{
+			name: "comments",
+			sql:  "select 1 -- :x\nwhere a = :a /* :y /* :z */ */",
+			want: "select 1 -- :x\nwhere a = $1 /* :y /* :z */ */",
+			args: []string{"a"},
+		},
+		{
+			name: "casts and prefixes",
+			sql:  `select :a::int, :ab, :a`,
+			want: `select $1::int, $2, $1`,
+			args: []string{"a", "ab", "a"},
+		},
+		{
+			name: "psql quoted variable",
+			sql:  `select * from t where name = :'name'`,
+			want: `select * from t where name = $1`,
+			args: []string{"name"},
+		},
+		{
+			name: "only literals",
+			sql:  `select to_char(now(), 'HH24:MI:SS'), 'a:b'`,
+			want: `select to_char(now(), 'HH24:MI:SS'), 'a:b'`,
+			args: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := NewQueryFromString("q", tc.sql)
+			if err != nil {
+				t.Fatalf("NewQueryFromString failed: %v", err)
+			}
+			if want := "-- name: q\n" + tc.want; q.OrdinalQuery != want {
+				t.Errorf("OrdinalQuery = %q, want %q", q.OrdinalQuery, want)
+			}
+			if !equalStrings(q.Args, tc.args) {
+				t.Errorf("Args = %v, want %v", q.Args, tc.args)
+			}
+			for i, arg := range q.NamedArgs {
+				if q.Mapping[arg.Name] != i+1 {
+					t.Errorf("NamedArgs[%d] = %s maps to $%d", i, arg.Name, q.Mapping[arg.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestNamedParamsLeavePositionalQueries(t *testing.T) {
+	q, err := NewQueryFromString("q", `select * from t where a = $1 and b = $2`)
+	if err != nil {
+		t.Fatalf("NewQueryFromString failed: %v", err)
+	}
+	if !equalStrings(q.Args, []string{"arg1", "arg2"}) {
+		t.Errorf("Args = %v, want [arg1 arg2]", q.Args)
+	}
+	if q.OrdinalQuery != "-- name: q\nselect * from t where a = $1 and b = $2" {
+		t.Errorf("OrdinalQuery = %q", q.OrdinalQuery)
+	}
+}
+
+func TestGetRegressQLOptions_Role(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: nobaseline, role=App_User\nselect 1;\n")
+	if got := q.GetRegressQLOptions().Role; got != "App_User" {
+		t.Errorf("Role = %q, want App_User", got)
+	}
+
+	q.Path = "orders.sql"
+	q.Args = []string{"id"}
+	p := &Plan{Query: q, Path: "plans/orders.yaml", Names: []string{"1"}}
+	if got, want := getResultSetPath(p, "expected", 0), filepath.Join("expected", "orders.App_User.1.json"); got != want {
+		t.Errorf("getResultSetPath = %q, want %q", got, want)
+	}
+}
+
+func TestGetRegressQLOptions_ColumnFloatTolerances(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: totals\n-- regresql: DiffFloatTolerance:0.5, float_tolerance_col_amount=0.01, float_tolerance_col_score:0.0001\nselect 1;\n")
+	opts := q.GetRegressQLOptions()
+
+	if opts.DiffFloatTolerance != 0.5 {
+		t.Errorf("DiffFloatTolerance = %v, want 0.5", opts.DiffFloatTolerance)
+	}
+	want := map[string]float64{"amount": 0.01, "score": 0.0001}
+	if len(opts.ColumnFloatTolerances) != len(want) {
+		t.Fatalf("ColumnFloatTolerances = %v, want %v", opts.ColumnFloatTolerances, want)
+	}
+	for col, tol := range want {
+		if opts.ColumnFloatTolerances[col] != tol {
+			t.Errorf("ColumnFloatTolerances[%s] = %v, want %v", col, opts.ColumnFloatTolerances[col], tol)
+		}
+	}
+}
+
+func TestGetRegressQLOptions_Columns(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: assert_columns=id,status,amount, nobaseline, ignore_columns=created_at,updated_at, sample=5\nselect 1;\n")
+	opts := q.GetRegressQLOptions()
+
+	if !equalStrings(opts.AssertColumns, []string{"id", "status", "amount"}) {
+		t.Errorf("AssertColumns = %v", opts.AssertColumns)
+	}
+	if !equalStrings(opts.IgnoreColumns, []string{"created_at", "updated_at"}) {
+		t.Errorf("IgnoreColumns = %v", opts.IgnoreColumns)
+	}
+	if !opts.NoBaseline || opts.Sample != 5 {
+		t.Errorf("options after the column lists lost: %+v", opts)
+	}
+}
+
+func TestParseQueryFileMultipleQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.sql")
+	content := "-- regresql: notest\nselect 0;\n\n-- name: by_id\n-- regresql: nobaseline\nselect *\n  from orders\n where id = :id;\n\n-- name: recent\nselect 2;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := parseQueryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 3 {
+		t.Fatalf("got %d queries, want orders, by_id and recent", len(queries))
+	}
+	if q := queries["orders"]; q == nil || !q.GetRegressQLOptions().NoTest {
+		t.Errorf("unnamed query = %+v, want named after the file with its own options", q)
+	}
+	byID := queries["by_id"]
+	if byID == nil || !strings.Contains(byID.OrdinalQuery, "where id = $1") || strings.Contains(byID.OrdinalQuery, "select 2") {
+		t.Errorf("by_id = %+v, want it to end at the next name", byID)
+	}
+	if opts := byID.GetRegressQLOptions(); !opts.NoBaseline || opts.NoTest {
+		t.Errorf("by_id options = %+v, want nobaseline only", opts)
+	}
+	if q := queries["recent"]; q == nil || q.GetRegressQLOptions().NoBaseline {
+		t.Errorf("recent = %+v, want it without by_id's options", q)
+	}
+}
+
+func TestParseQueryFileDefaultName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.sql")
+	content := "-- name: default\n-- regresql: notest\nselect 0;\n\n-- name: by_id\nselect 1;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := parseQueryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := queries["orders"]
+	if len(queries) != 2 || q == nil || queries["default"] != nil {
+		t.Fatalf("queries = %v, want orders and by_id", queries)
+	}
+	if q.Name != "orders" || !q.GetRegressQLOptions().NoTest || !strings.Contains(q.OrdinalQuery, "select 0") {
+		t.Errorf("default query = %+v, want it named after the file", q)
+	}
+	if got := q.startLine(); got != 1 {
+		t.Errorf("startLine() = %d, want the name: default line", got)
+	}
+
+	both := filepath.Join(dir, "users.sql")
+	if err := os.WriteFile(both, []byte("select 0;\n\n-- name: default\nselect 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseQueryFile(both); err == nil {
+		t.Error("parseQueryFile() with SQL before '-- name: default' expected error")
+	}
+}
+
+func TestGetRegressQLOptions_CaptureAnalyze(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: capture_analyze, timeout:5s\nselect 1;\n")
+	if !q.GetRegressQLOptions().CaptureAnalyze {
+		t.Error("CaptureAnalyze = false, want true")
+	}
+}
+
+func TestGetRegressQLOptions_IgnoreColumnOrder(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: users\n-- regresql: ignore_columns=created_at, ignore_column_order\nselect * from users;\n")
+	opts := q.GetRegressQLOptions()
+	if !opts.IgnoreColumnOrder {
+		t.Error("IgnoreColumnOrder = false, want true")
+	}
+	if !equalStrings(opts.IgnoreColumns, []string{"created_at"}) {
+		t.Errorf("IgnoreColumns = %v, want [created_at]", opts.IgnoreColumns)
+	}
+}
+
+func TestGetRegressQLOptions_Format(t *testing.T) {
+	q := queryWithMetadata(t, "-- name: orders\n-- regresql: format=psql\nselect 1;\n")
+	if got := q.GetRegressQLOptions().Format; got != ResultFormatPsql {
+		t.Errorf("Format = %q, want psql", got)
+	}
+
+	q.Path = "orders.sql"
+	q.Args = []string{"id"}
+	p := &Plan{Query: q, Path: "plans/orders.yaml", Names: []string{"1"}}
+	if got, want := getResultSetPath(p, "expected", 0), filepath.Join("expected", "orders.1.txt"); got != want {
+		t.Errorf("getResultSetPath = %q, want %q", got, want)
+	}
+}
+
+func TestQueryStartLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.sql")
+	content := "-- orders queries\n\n-- name: by_id\nSELECT * FROM orders WHERE id = :id;\n\n-- name: recent\n-- regresql: notest\nSELECT * FROM orders\nORDER BY created_at DESC;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseQueryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"by_id": 3, "recent": 6} {
+		if got := parsed[name].startLine(); got != want {
+			t.Errorf("startLine(%s) = %d, want %d", name, got, want)
+		}
+	}
+
+	unnamed := queryWithMetadata(t, "-- regresql: notest\n\nSELECT 1;\n")
+	if got := unnamed.startLine(); got != 3 {
+		t.Errorf("startLine() of an unnamed query = %d, want 3", got)
+	}
+}
@@ -3,13 +3,17 @@ package regresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/boringsql/regresql/v2/regresql/tracing"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 /*
@@ -46,6 +50,11 @@ type (
 		runFilter     string
 		pathFilters   []string
 		ignoreMatcher *IgnoreMatcher
+		checkTypes    bool
+		parallel      int
+		failFast      bool
+		pgtapDir      string // run pgTAP files from here as part of testQueries
+		pgtapPattern  string
 	}
 
 	Folder struct {
@@ -66,6 +75,18 @@ type (
 		Interactive bool
 		DryRun      bool
 		Snapshot    *SnapshotInfo
+		Role        string // only update queries annotated with this role
+		Selection   updateSelection
+	}
+
+	// testJob is one planned query scheduled by testQueries
+	testJob struct {
+		pq         *PlannedQuery
+		outDir     string
+		analyzeDir string // EXPLAIN ANALYZE captures of capture_analyze queries
+		expectDir  string
+		baseDir    string
+		noBaseline bool
 	}
 )
 
@@ -178,6 +199,21 @@ func (s *Suite) SetPathFilters(paths []string) {
 	s.pathFilters = paths
 }
 
+// SetCheckTypes enables recording and comparing result column types
+func (s *Suite) SetCheckTypes(check bool) {
+	s.checkTypes = check
+}
+
+// SetParallel sets how many queries testQueries runs concurrently
+func (s *Suite) SetParallel(n int) {
+	s.parallel = n
+}
+
+// SetFailFast makes testQueries stop after the first failed test
+func (s *Suite) SetFailFast(failFast bool) {
+	s.failFast = failFast
+}
+
 // matchesPathFilter checks if a file path matches any of the path filters
 // Returns true if there's no filter set, or if the path matches any filter
 func (s *Suite) matchesPathFilter(filePath string) bool {
@@ -335,6 +371,9 @@ func (s *Suite) createExpectedResults(pguri string, opts createExpectedOptions)
 		if qopts.NoTest {
 			continue
 		}
+		if opts.Role != "" && qopts.Role != opts.Role {
+			continue
+		}
 
 		folderDir := filepath.Dir(pq.RelPath)
 		edir, ok := expectedDirs[folderDir]
@@ -352,6 +391,11 @@ func (s *Suite) createExpectedResults(pguri string, opts createExpectedOptions)
 			}
 		}
 
+		selected := opts.Selection.bindings(pq.Plan, s.RegressDir, edir.path)
+		if len(selected) == 0 {
+			continue
+		}
+
 		if !opts.DryRun {
 			if err := edir.Ensure(); err != nil {
 				return err
@@ -366,6 +410,7 @@ func (s *Suite) createExpectedResults(pguri string, opts createExpectedOptions)
 			if err := applyStatementTimeout(context.Background(), tx, timeout); err != nil {
 				return err
 			}
+			pq.Plan.CheckTypes = s.checkTypes
 			if err := pq.Plan.Execute(context.Background(), tx); err != nil {
 				// timeout: can't produce an expected result, skip
 				if isTimeoutError(err) {
@@ -374,6 +419,7 @@ func (s *Suite) createExpectedResults(pguri string, opts createExpectedOptions)
 				}
 				return err
 			}
+			pq.Plan.keepResultSets(selected)
 
 			// Dry-run: just record what would be updated
 			if opts.DryRun {
@@ -399,9 +445,14 @@ func (s *Suite) createExpectedResults(pguri string, opts createExpectedOptions)
 				return err
 			}
 
-			// Track written files for metadata recording
+			// Track written files for metadata recording; a query switched
+			// between format=psql and JSON drops its file in the old format
 			for _, rs := range pq.Plan.ResultSets {
-				writtenFiles = append(writtenFiles, filepath.Join(edir.path, filepath.Base(rs.Filename)))
+				written := filepath.Join(edir.path, filepath.Base(rs.Filename))
+				writtenFiles = append(writtenFiles, written)
+				if err := os.Remove(alternateResultFile(written)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
 			}
 			return nil
 		}); err != nil {
@@ -473,13 +524,82 @@ func (s *Suite) testQueries(pguri string, formatter OutputFormatter, outputPath
 		return nil, err
 	}
 
+	plannedQueries, err := s.PlannedQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracing.Start(context.Background(), "regresql.test",
+		attribute.Int("regresql.queries", len(plannedQueries)),
+	)
+	defer span.End()
+	// cancelled on a fail-fast stop, interrupting queries still in flight
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// jobs are built up front so workers never race on directory creation
+	jobs := make([]testJob, 0, len(plannedQueries))
+	for _, pq := range plannedQueries {
+		job, err := s.newTestJob(pq)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	emit := func(results []TestResult) error {
+		for _, r := range results {
+			summary.AddResult(r)
+			if err := formatter.AddResult(r, w); err != nil {
+				return err
+			}
+			if s.failFast && summary.Failed > 0 {
+				cancel()
+				return errStopTests
+			}
+		}
+		return nil
+	}
+	run := func(job testJob) ([]TestResult, error) {
+		return s.runTestJob(ctx, db, job, commit)
+	}
+	err = runTestJobs(jobs, s.parallel, run, emit)
+	summary.Stopped = errors.Is(err, errStopTests)
+	if err != nil && !summary.Stopped {
+		return nil, err
+	}
+
+	if s.pgtapDir != "" && !summary.Stopped {
+		results, err := s.pgtapResults(pguri)
+		if err != nil {
+			return nil, err
+		}
+		if err := emit(results); err != nil {
+			return nil, err
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("regresql.tests.passed", summary.Passed),
+		attribute.Int("regresql.tests.failed", summary.Failed),
+		attribute.Int("regresql.tests.skipped", summary.Skipped),
+	)
+
+	if err := formatter.Finish(summary, w); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// PlannedQueries returns the planned queries selected by the suite's run and
+// path filters, leaving out queries marked notest
+func (s *Suite) PlannedQueries() ([]*PlannedQuery, error) {
 	plannedQueries, err := WalkPlans(s.Root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to walk plans: %w", err)
 	}
 
-	outDirs := make(map[string]*lazyDir)
-
+	var selected []*PlannedQuery
 	for _, pq := range plannedQueries {
 		fileName := filepath.Base(pq.SQLPath)
 		if !s.matchesRunFilter(fileName, pq.Query.Name) {
@@ -488,86 +608,180 @@ func (s *Suite) testQueries(pguri string, formatter OutputFormatter, outputPath
 		if !s.matchesPathFilter(pq.RelPath) {
 			continue
 		}
-
-		opts := pq.Query.GetRegressQLOptions()
-		if opts.NoTest {
+		if pq.Query.GetRegressQLOptions().NoTest {
 			continue
 		}
+		selected = append(selected, pq)
+	}
+	return selected, nil
+}
 
-		folderDir := filepath.Dir(pq.RelPath)
-		odir, ok := outDirs[folderDir]
-		if !ok {
-			odir = &lazyDir{path: filepath.Join(s.OutDir, folderDir)}
-			outDirs[folderDir] = odir
-		}
-
-		edir := filepath.Join(s.ExpectedDir, folderDir)
-		bdir := filepath.Join(s.BaselineDir, folderDir)
+// RunPlannedQuery executes pq in a rolled back transaction and compares its
+// output against the expected files and, when present, its cost baselines
+func (s *Suite) RunPlannedQuery(ctx context.Context, db *sql.DB, pq *PlannedQuery) ([]TestResult, error) {
+	job, err := s.newTestJob(pq)
+	if err != nil {
+		return nil, err
+	}
+	return s.runTestJob(ctx, db, job, false)
+}
 
-		if err := odir.Ensure(); err != nil {
-			return nil, err
-		}
+// newTestJob resolves the out, expected and baseline directories for pq,
+// creating the out directory
+func (s *Suite) newTestJob(pq *PlannedQuery) (testJob, error) {
+	folderDir := filepath.Dir(pq.RelPath)
+	outDir := filepath.Join(s.OutDir, folderDir)
+	if err := ensureDir(outDir); err != nil {
+		return testJob{}, err
+	}
+	return testJob{
+		pq:         pq,
+		outDir:     outDir,
+		analyzeDir: filepath.Join(s.OutDir, "analyze", folderDir),
+		expectDir:  filepath.Join(s.ExpectedDir, folderDir),
+		baseDir:    filepath.Join(s.BaselineDir, folderDir),
+		noBaseline: pq.Query.GetRegressQLOptions().NoBaseline,
+	}, nil
+}
 
-		timeout := resolveTimeout(pq.Query)
-		var timedOut bool
-		if err := s.runInTransaction(db, commit, func(tx *sql.Tx) error {
-			if err := applyStatementTimeout(context.Background(), tx, timeout); err != nil {
-				return err
-			}
-			if err := pq.Plan.Execute(context.Background(), tx); err != nil {
-				// timeout = divergence, not a fatal error: record and continue
-				if isTimeoutError(err) {
-					timedOut = true
-					return nil
-				}
+// errStopTests is returned by a testQueries emit callback to stop after the
+// first failure (--fail-fast); testQueries still finishes the report
+var errStopTests = errors.New("stopped after first failure")
+
+// runTestJobs runs jobs on up to parallel workers. Results are emitted in
+// job order from the calling goroutine, so formatters and the summary never
+// see concurrent calls and output stays deterministic.
+func runTestJobs(jobs []testJob, parallel int, run func(testJob) ([]TestResult, error), emit func([]TestResult) error) error {
+	workers := parallel
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	if workers <= 1 {
+		for _, job := range jobs {
+			results, err := run(job)
+			if err != nil {
 				return err
 			}
-			if err := pq.Plan.WriteResultSets(odir.path); err != nil {
+			if err := emit(results); err != nil {
 				return err
 			}
+		}
+		return nil
+	}
 
-			policies := GetPoliciesConfig()
-			for _, r := range pq.Plan.CompareResultSetsToResults(s.RegressDir, edir) {
-				ApplyPolicies(&r, policies)
-				summary.AddResult(r)
-				if err := formatter.AddResult(r, w); err != nil {
-					return err
-				}
+	type outcome struct {
+		results []TestResult
+		err     error
+	}
+	outcomes := make([]chan outcome, len(jobs))
+	for i := range outcomes {
+		outcomes[i] = make(chan outcome, 1)
+	}
+
+	next := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		defer close(next)
+		for i := range jobs {
+			select {
+			case next <- i:
+			case <-done:
+				return
 			}
+		}
+	}()
 
-			if !opts.NoBaseline && hasBaselines(pq.Query, bdir, pq.Plan.Names) {
-				for _, r := range pq.Plan.CompareBaselinesToResults(context.Background(), bdir, tx, DefaultCostThresholdPercent) {
-					ApplyPolicies(&r, policies)
-					summary.AddResult(r)
-					if err := formatter.AddResult(r, w); err != nil {
-						return err
-					}
-				}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results, err := run(jobs[i])
+				outcomes[i] <- outcome{results, err}
 			}
-			return nil
-		}); err != nil {
-			return nil, err
+		}()
+	}
+
+	var err error
+	for i := range jobs {
+		o := <-outcomes[i]
+		if err = o.err; err == nil {
+			err = emit(o.results)
+		}
+		if err != nil {
+			break
 		}
+	}
+	close(done)
+	wg.Wait()
+	return err
+}
 
-		if timedOut {
-			r := TestResult{
-				Name:      pq.Query.Name,
-				Type:      "timeout",
-				Status:    "failed",
-				Error:     fmt.Sprintf("query did not complete within %s (statement_timeout)", timeout),
-				QueryFile: pq.SQLPath,
+// runTestJob executes a single planned query in its own transaction and
+// returns the output and baseline comparisons for it
+func (s *Suite) runTestJob(ctx context.Context, db *sql.DB, job testJob, commit bool) ([]TestResult, error) {
+	pq := job.pq
+	timeout := resolveTimeout(pq.Query)
+	var timedOut bool
+	var results []TestResult
+
+	if err := s.runInTransaction(db, commit, func(tx *sql.Tx) error {
+		if err := applyStatementTimeout(ctx, tx, timeout); err != nil {
+			return err
+		}
+		pq.Plan.CheckTypes = s.checkTypes
+		// after_each runs once the baselines are compared, so the cost
+		// checks EXPLAIN in the state the query ran in
+		if err := pq.Plan.execute(ctx, tx, false); err != nil {
+			// timeout = divergence, not a fatal error: record and continue
+			if isTimeoutError(err) {
+				timedOut = true
+				return nil
 			}
-			summary.AddResult(r)
-			if err := formatter.AddResult(r, w); err != nil {
-				return nil, err
+			return err
+		}
+		if err := pq.Plan.WriteResultSets(job.outDir); err != nil {
+			return err
+		}
+		if err := pq.Plan.WriteAnalyses(job.analyzeDir); err != nil {
+			return err
+		}
+
+		policies := GetPoliciesConfig()
+		for _, r := range pq.Plan.CompareResultSetsToResults(s.RegressDir, job.expectDir) {
+			r.AnalyzeFile = pq.Plan.analyzeFile(r.BindingName)
+			ApplyPolicies(&r, policies)
+			results = append(results, r)
+		}
+
+		if !job.noBaseline && hasBaselines(pq.Query, job.baseDir, pq.Plan.Names) {
+			for _, r := range pq.Plan.CompareBaselinesToResults(ctx, job.baseDir, tx, DefaultCostThresholdPercent) {
+				ApplyPolicies(&r, policies)
+				results = append(results, r)
 			}
+		} else if !job.noBaseline && IsAutoBaselineEnabled() {
+			results = append(results, pq.Plan.createAutoBaselines(ctx, job.baseDir, tx)...)
 		}
+		return pq.Plan.runHooks(ctx, tx, "after_each", pq.Plan.AfterEach)
+	}); err != nil {
+		return nil, err
 	}
 
-	if err := formatter.Finish(summary, w); err != nil {
-		return nil, err
+	if timedOut {
+		results = append(results, TestResult{
+			Name:      pq.Query.Name,
+			Type:      "timeout",
+			Status:    "failed",
+			Error:     fmt.Sprintf("query did not complete within %s (statement_timeout)", timeout),
+			QueryFile: pq.SQLPath,
+		})
 	}
-	return summary, nil
+	line := pq.Query.startLine()
+	for i := range results {
+		results[i].QueryLine = line
+	}
+	return results, nil
 }
 
 // runInTransaction executes fn within a transaction, rolling back on error or if commit is false
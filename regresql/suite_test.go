@@ -0,0 +1,174 @@
+package regresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunTestJobsPreservesOrder(t *testing.T) {
+	jobs := make([]testJob, 20)
+	for i := range jobs {
+		jobs[i] = testJob{outDir: fmt.Sprintf("q%02d", i)}
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	run := func(job testJob) ([]TestResult, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		// later jobs finish first, so ordering must come from runTestJobs
+		var idx int
+		fmt.Sscanf(job.outDir, "q%d", &idx)
+		time.Sleep(time.Duration(len(jobs)-idx) * time.Millisecond)
+		return []TestResult{{Name: job.outDir}}, nil
+	}
+
+	for _, parallel := range []int{0, 1, 4} {
+		var got []string
+		emit := func(results []TestResult) error {
+			for _, r := range results {
+				got = append(got, r.Name)
+			}
+			return nil
+		}
+		maxInFlight.Store(0)
+		if err := runTestJobs(jobs, parallel, run, emit); err != nil {
+			t.Fatalf("parallel=%d: unexpected error: %v", parallel, err)
+		}
+		if len(got) != len(jobs) {
+			t.Fatalf("parallel=%d: got %d results, want %d", parallel, len(got), len(jobs))
+		}
+		for i, name := range got {
+			if name != jobs[i].outDir {
+				t.Errorf("parallel=%d: result %d = %s, want %s", parallel, i, name, jobs[i].outDir)
+			}
+		}
+		if parallel > 1 && maxInFlight.Load() > int32(parallel) {
+			t.Errorf("parallel=%d: %d jobs ran at once", parallel, maxInFlight.Load())
+		}
+	}
+}
+
+func TestRunTestJobsStopsOnError(t *testing.T) {
+	jobs := make([]testJob, 50)
+	for i := range jobs {
+		jobs[i] = testJob{outDir: fmt.Sprintf("q%02d", i)}
+	}
+
+	boom := errors.New("boom")
+	run := func(job testJob) ([]TestResult, error) {
+		if job.outDir == "q03" {
+			return nil, boom
+		}
+		return []TestResult{{Name: job.outDir}}, nil
+	}
+
+	var emitted int
+	emit := func(results []TestResult) error {
+		emitted += len(results)
+		return nil
+	}
+
+	if err := runTestJobs(jobs, 4, run, emit); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if emitted != 3 {
+		t.Errorf("emitted %d results before the failure, want 3", emitted)
+	}
+}
+
+func TestRunTestJobsFailFast(t *testing.T) {
+	jobs := make([]testJob, 20)
+	for i := range jobs {
+		jobs[i] = testJob{outDir: fmt.Sprintf("q%02d", i)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := func(job testJob) ([]TestResult, error) {
+		switch job.outDir {
+		case "q00", "q01":
+			return []TestResult{{Name: job.outDir, Status: "passed"}}, nil
+		case "q02":
+			return []TestResult{{Name: job.outDir, Status: "failed"}}, nil
+		}
+		// slow queries only end when the stop cancels them
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	summary := NewTestSummary()
+	emit := func(results []TestResult) error {
+		for _, r := range results {
+			summary.AddResult(r)
+			if summary.Failed > 0 {
+				cancel()
+				return errStopTests
+			}
+		}
+		return nil
+	}
+
+	if err := runTestJobs(jobs, 4, run, emit); !errors.Is(err, errStopTests) {
+		t.Fatalf("err = %v, want errStopTests", err)
+	}
+	if summary.Total != 3 || summary.Failed != 1 {
+		t.Errorf("summary = %d total, %d failed; want 3 total, 1 failed", summary.Total, summary.Failed)
+	}
+}
+
+func TestRunTestJobAfterEachFollowsCostCheck(t *testing.T) {
+	db, log := openRecordingDB(t)
+	q, err := NewQueryFromString("orders", "SELECT * FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Path = "orders.sql"
+
+	dir := t.TempDir()
+	baseline := newBaseline(q.Name, map[string]any{"total_cost": 5.0}, nil, false)
+	if err := saveBaseline(getBaselinePath(q, dir, ""), &baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &Plan{Query: q, AfterEach: []SQLSpec{{Inline: "RESET enable_seqscan"}}}
+	job := testJob{
+		pq:        &PlannedQuery{Query: q, Plan: plan},
+		outDir:    filepath.Join(dir, "out"),
+		expectDir: filepath.Join(dir, "expected"),
+		baseDir:   dir,
+	}
+	if err := os.MkdirAll(job.outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := &Suite{RegressDir: dir}
+	if _, err := s.runTestJob(context.Background(), db, job, false); err != nil {
+		t.Fatalf("runTestJob() error = %v", err)
+	}
+
+	stmts := log.Statements()
+	explain, reset := -1, -1
+	for i, stmt := range stmts {
+		switch {
+		case strings.HasPrefix(stmt, "EXPLAIN"):
+			explain = i
+		case stmt == "RESET enable_seqscan":
+			reset = i
+		}
+	}
+	if explain < 0 || reset < explain || stmts[len(stmts)-1] != "ROLLBACK" {
+		t.Errorf("after_each must run after the cost check's EXPLAIN, in the transaction: %q", stmts)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/mndrix/tap-go"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 func (p *Plan) CompareResultSets(regressDir, expectedDir string, t *tap.T) {
@@ -71,6 +72,9 @@ func toFloat64(val any) float64 {
 }
 
 func loadResultSet(filename string) (*ResultSet, error) {
+	if isPsqlResultFile(filename) {
+		return LoadResultSet(filename)
+	}
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -111,14 +115,25 @@ func (p *Plan) CompareResultSetsToResults(regressDir, expectedDir string) []Test
 
 		// Apply per-query diff options if available
 		queryDiffConfig := diffConfig
+		var assertColumns []string
 		if p.Query != nil {
 			opts := p.Query.GetRegressQLOptions()
-			if opts.DiffFloatTolerance > 0 {
-				queryDiffConfig = &DiffConfig{
-					FloatTolerance: opts.DiffFloatTolerance,
-					MaxSamples:     diffConfig.MaxSamples,
+			if opts.DiffFloatTolerance > 0 || len(opts.ColumnFloatTolerances) > 0 || len(opts.IgnoreColumns) > 0 || opts.IgnoreColumnOrder {
+				cfg := *diffConfig
+				if opts.DiffFloatTolerance > 0 {
+					cfg.FloatTolerance = opts.DiffFloatTolerance
 				}
+				cfg.ColumnTolerances = opts.ColumnFloatTolerances
+				cfg.IgnoreColumns = opts.IgnoreColumns
+				cfg.IgnoreColumnOrder = cfg.IgnoreColumnOrder || opts.IgnoreColumnOrder
+				queryDiffConfig = &cfg
 			}
+			assertColumns = opts.AssertColumns
+		}
+		if p.CheckTypes {
+			cfg := *queryDiffConfig
+			cfg.CheckTypes = true
+			queryDiffConfig = &cfg
 		}
 
 		result := TestResult{
@@ -129,6 +144,15 @@ func (p *Plan) CompareResultSetsToResults(regressDir, expectedDir string) []Test
 			BindingsFile: p.Path,
 			BindingName:  bindingName,
 			Parameters:   bindings,
+			ActualFile:   actualRS.Filename,
+			ExpectedFile: expectedFilename,
+		}
+
+		// Expected files are found in either format, so switching a query
+		// to format=psql doesn't fail it until 'regresql update' is run
+		if !fileExists(expectedFilename) && fileExists(alternateResultFile(expectedFilename)) {
+			expectedFilename = alternateResultFile(expectedFilename)
+			result.ExpectedFile = expectedFilename
 		}
 
 		// Check if expected file exists - mark as pending if missing
@@ -154,16 +178,18 @@ func (p *Plan) CompareResultSetsToResults(regressDir, expectedDir string) []Test
 			} else {
 				result.Status = "passed"
 			}
+		} else if expectedRS, actualRS, err := projectResultSets(expectedRS, comparableResultSet(&actualRS, expectedFilename), assertColumns); err != nil {
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("assert_columns: %s", err.Error())
 		} else {
 			// Perform semantic comparison
-			structuredDiff := CompareResultSets(expectedRS, &actualRS, queryDiffConfig)
+			structuredDiff := CompareResultSets(expectedRS, actualRS, queryDiffConfig)
 			result.StructuredDiff = structuredDiff
 
 			if !structuredDiff.Identical {
 				result.Status = "failed"
 				// Also generate text diff for backward compatibility
-				textDiff, _ := DiffFiles(expectedFilename, actualRS.Filename, 3)
-				result.Diff = textDiff
+				result.Diff = resultTextDiff(expectedFilename, actualRS)
 			} else {
 				result.Status = "passed"
 			}
@@ -176,6 +202,54 @@ func (p *Plan) CompareResultSetsToResults(regressDir, expectedDir string) []Test
 	return results
 }
 
+// comparableResultSet returns the actual result set as values read back
+// from the expected file's format: psql tables hold strings only
+func comparableResultSet(actual *ResultSet, expectedFilename string) *ResultSet {
+	if isPsqlResultFile(expectedFilename) {
+		return actual.psqlText()
+	}
+	return actual
+}
+
+// resultTextDiff diffs the expected file with the actual file, or with the
+// actual result rendered in the expected file's format when their formats
+// differ
+func resultTextDiff(expectedFilename string, actual *ResultSet) string {
+	if isPsqlResultFile(expectedFilename) == isPsqlResultFile(actual.Filename) {
+		diff, _ := DiffFiles(expectedFilename, actual.Filename, 3)
+		return diff
+	}
+	expectedLines, err := readLines(expectedFilename)
+	if err != nil {
+		return ""
+	}
+	var rendered string
+	if isPsqlResultFile(expectedFilename) {
+		rendered = actual.FormatPsql()
+	} else {
+		data, _ := json.MarshalIndent(actual, "", "  ")
+		rendered = string(data)
+	}
+	return DiffLines(expectedFilename, actual.Filename, expectedLines, difflib.SplitLines(rendered), 3)
+}
+
+// projectResultSets reduces both result sets to the assert_columns of the
+// query, leaving them unchanged when it has none
+func projectResultSets(expected, actual *ResultSet, columns []string) (*ResultSet, *ResultSet, error) {
+	if len(columns) == 0 {
+		return expected, actual, nil
+	}
+	expected, err := expected.Project(columns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("expected %s", err)
+	}
+	actual, err = actual.Project(columns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("actual %s", err)
+	}
+	return expected, actual, nil
+}
+
 func (p *Plan) CompareBaselinesToResults(ctx context.Context, baselineDir string, q Querier, thresholdPercent float64) []TestResult {
 	if len(p.Query.Args) == 0 {
 		return []TestResult{p.compareBaseline(ctx, baselineDir, "", nil, q, thresholdPercent)}
@@ -195,10 +269,13 @@ func (p *Plan) compareBaseline(ctx context.Context, baselineDir, bindingName str
 	testName := strings.TrimSuffix(filepath.Base(baselinePath), ".json") + ".cost"
 
 	result := TestResult{
-		Name:       testName,
-		Type:       "cost",
-		Threshold:  thresholdPercent,
-		Parameters: bindings,
+		Name:         testName,
+		Type:         "cost",
+		Threshold:    thresholdPercent,
+		QueryFile:    p.Query.Path,
+		BindingsFile: p.Path,
+		BindingName:  bindingName,
+		Parameters:   bindings,
 	}
 
 	baseline, err := LoadBaseline(baselinePath)
@@ -233,6 +310,7 @@ func (p *Plan) compareBaseline(ctx context.Context, baselineDir, bindingName str
 	var isOk, bufferOk bool
 	var percentageIncrease float64
 	improvementThreshold := GetImprovementThreshold()
+	opts := p.Query.GetRegressQLOptions()
 
 	if useBufferComparison {
 		actualBuffers := explainPlan.Plan.SharedHitBlocks + explainPlan.Plan.SharedReadBlocks + explainPlan.Plan.LocalHitBlocks + explainPlan.Plan.LocalReadBlocks
@@ -298,8 +376,8 @@ func (p *Plan) compareBaseline(ctx context.Context, baselineDir, bindingName str
 		testName = strings.TrimSuffix(filepath.Base(baselinePath), ".json") + ".buffers"
 	} else {
 		actualCost := explainPlan.Plan.TotalCost
-		baselineCost := toFloat64(baseline.Plan["total_cost"])
-		costThreshold := GetCostThreshold()
+		baselineCost := costBaseline(baseline, baselinePath)
+		costThreshold := opts.costThreshold(GetCostThreshold())
 
 		isOk, percentageIncrease = CompareCost(actualCost, baselineCost, costThreshold)
 
@@ -331,12 +409,11 @@ func (p *Plan) compareBaseline(ctx context.Context, baselineDir, bindingName str
 		}
 	}
 
-	opts := p.Query.GetRegressQLOptions()
 	costInfo := PlanCostInfo{
 		TotalCost:    explainPlan.Plan.TotalCost,
 		TotalBuffers: explainPlan.Plan.SharedHitBlocks + explainPlan.Plan.SharedReadBlocks + explainPlan.Plan.LocalHitBlocks + explainPlan.Plan.LocalReadBlocks,
 	}
-	result.PlanWarnings = DetectPlanQualityIssues(currentSig, opts, GetIgnoredSeqScanTables(), GetCriticalTables(), costInfo)
+	result.PlanWarnings = DetectPlanQualityIssues(&explainPlan.Plan, opts, GetIgnoredSeqScanTables(), GetCriticalTables(), costInfo)
 
 	if useBufferComparison {
 		qErrorNamed := false
@@ -0,0 +1,234 @@
+package regresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type (
+	// TestRunDiff compares two test runs saved with --format json
+	TestRunDiff struct {
+		Regressions   []TestResult // failed in the second run, not in the first
+		Improvements  []TestResult // failed in the first run, passed in the second
+		StatusChanges []StatusChange
+		CostChanges   []CostChange
+	}
+
+	// StatusChange is a test whose status changed other than from or to
+	// failed; Before or After is empty when the test ran only once
+	StatusChange struct {
+		Name   string
+		Type   string
+		Before string
+		After  string
+	}
+
+	// CostChange is the plan cost of a cost test in both runs
+	CostChange struct {
+		Name          string
+		BeforeCost    float64
+		AfterCost     float64
+		ChangePercent float64
+	}
+
+	// testRunFile is the part of the JSON formatter output DiffTestRuns needs
+	testRunFile struct {
+		Tests []struct {
+			Name            string           `json:"name"`
+			Type            string           `json:"type"`
+			Status          string           `json:"status"`
+			Duration        float64          `json:"duration"`
+			Error           string           `json:"error"`
+			Expected        *testRunFileCost `json:"expected"`
+			Actual          *testRunFileCost `json:"actual"`
+			PercentIncrease float64          `json:"percent_increase"`
+		} `json:"tests"`
+	}
+
+	testRunFileCost struct {
+		TotalCost float64 `json:"total_cost"`
+	}
+)
+
+// ReadTestRun loads the results of `regresql test --format json --output`
+func ReadTestRun(path string) ([]TestResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test run: %w", err)
+	}
+	var run testRunFile
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse test run %s: %w (expected the output of --format json)", path, err)
+	}
+
+	results := make([]TestResult, 0, len(run.Tests))
+	for _, t := range run.Tests {
+		r := TestResult{
+			Name:            t.Name,
+			Type:            t.Type,
+			Status:          t.Status,
+			Duration:        t.Duration,
+			Error:           t.Error,
+			PercentIncrease: t.PercentIncrease,
+		}
+		if t.Expected != nil {
+			r.ExpectedCost = t.Expected.TotalCost
+		}
+		if t.Actual != nil {
+			r.ActualCost = t.Actual.TotalCost
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// DiffTestRuns matches the tests of two runs by name and type and reports
+// what changed from before to after
+func DiffTestRuns(before, after []TestResult) *TestRunDiff {
+	key := func(r TestResult) string { return r.Type + "\x00" + r.Name }
+	old := make(map[string]TestResult, len(before))
+	for _, r := range before {
+		old[key(r)] = r
+	}
+
+	diff := &TestRunDiff{}
+	seen := make(map[string]bool, len(after))
+	for _, r := range after {
+		k := key(r)
+		seen[k] = true
+		prev, existed := old[k]
+
+		switch {
+		case r.Status == "failed" && (!existed || prev.Status != "failed"):
+			diff.Regressions = append(diff.Regressions, r)
+		case existed && prev.Status == "failed" && r.Status == "passed":
+			diff.Improvements = append(diff.Improvements, r)
+		case !existed || prev.Status != r.Status:
+			diff.StatusChanges = append(diff.StatusChanges, StatusChange{
+				Name: r.Name, Type: r.Type, Before: prev.Status, After: r.Status,
+			})
+		}
+
+		if existed && r.Type == "cost" && prev.ActualCost > 0 && r.ActualCost > 0 && prev.ActualCost != r.ActualCost {
+			diff.CostChanges = append(diff.CostChanges, CostChange{
+				Name:          r.Name,
+				BeforeCost:    prev.ActualCost,
+				AfterCost:     r.ActualCost,
+				ChangePercent: costChangePercent(prev.ActualCost, r.ActualCost),
+			})
+		}
+	}
+	for _, r := range before {
+		if !seen[key(r)] {
+			diff.StatusChanges = append(diff.StatusChanges, StatusChange{
+				Name: r.Name, Type: r.Type, Before: r.Status,
+			})
+		}
+	}
+
+	// largest increase first
+	sort.SliceStable(diff.CostChanges, func(i, j int) bool {
+		return diff.CostChanges[i].ChangePercent > diff.CostChanges[j].ChangePercent
+	})
+	return diff
+}
+
+// Empty reports whether the runs had the same outcome and costs
+func (d *TestRunDiff) Empty() bool {
+	return len(d.Regressions) == 0 && len(d.Improvements) == 0 &&
+		len(d.StatusChanges) == 0 && len(d.CostChanges) == 0
+}
+
+// Render writes the diff as a console table, or as GitHub-flavored Markdown
+// for a pull request comment when format is "markdown"
+func (d *TestRunDiff) Render(w io.Writer, format string) error {
+	switch format {
+	case "", "table":
+		return d.renderTable(w)
+	case "markdown":
+		return d.renderMarkdown(w)
+	default:
+		return fmt.Errorf("unknown format %q (use table or markdown)", format)
+	}
+}
+
+func (d *TestRunDiff) summary() string {
+	return fmt.Sprintf("%d regressed, %d fixed, %d other status changes, %d cost changes",
+		len(d.Regressions), len(d.Improvements), len(d.StatusChanges), len(d.CostChanges))
+}
+
+func (d *TestRunDiff) renderTable(w io.Writer) error {
+	if d.Empty() {
+		fmt.Fprintln(w, "No differences between the two runs")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range d.Regressions {
+		fmt.Fprintf(tw, "  ✗ REGRESSED\t%s\t%s\t%s\n", r.Name, r.Type, firstLine(r.Error))
+	}
+	for _, r := range d.Improvements {
+		fmt.Fprintf(tw, "  ✓ FIXED\t%s\t%s\t\n", r.Name, r.Type)
+	}
+	for _, c := range d.StatusChanges {
+		fmt.Fprintf(tw, "  ~ CHANGED\t%s\t%s\t%s → %s\n", c.Name, c.Type, runStatus(c.Before), runStatus(c.After))
+	}
+	for _, c := range d.CostChanges {
+		fmt.Fprintf(tw, "  Δ COST\t%s\tcost\t%.2f → %.2f (%+.1f%%)\n", c.Name, c.BeforeCost, c.AfterCost, c.ChangePercent)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  %s\n", d.summary())
+	return nil
+}
+
+func (d *TestRunDiff) renderMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "## regresql test run diff\n\n**%s**\n\n", d.summary())
+	if d.Empty() {
+		return nil
+	}
+
+	fmt.Fprintln(w, "| change | test | type | detail |")
+	fmt.Fprintln(w, "|---|---|---|---|")
+	for _, r := range d.Regressions {
+		fmt.Fprintf(w, "| ✗ regressed | `%s` | %s | %s |\n", r.Name, r.Type, markdownCell(firstLine(r.Error)))
+	}
+	for _, r := range d.Improvements {
+		fmt.Fprintf(w, "| ✓ fixed | `%s` | %s | — |\n", r.Name, r.Type)
+	}
+	for _, c := range d.StatusChanges {
+		fmt.Fprintf(w, "| ~ changed | `%s` | %s | %s → %s |\n", c.Name, c.Type, runStatus(c.Before), runStatus(c.After))
+	}
+	for _, c := range d.CostChanges {
+		fmt.Fprintf(w, "| Δ cost | `%s` | cost | %.2f → %.2f (%+.1f%%) |\n", c.Name, c.BeforeCost, c.AfterCost, c.ChangePercent)
+	}
+	return nil
+}
+
+func runStatus(status string) string {
+	if status == "" {
+		return "absent"
+	}
+	return status
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// markdownCell escapes text for a Markdown table cell
+func markdownCell(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return strings.ReplaceAll(s, "|", `\|`)
+}
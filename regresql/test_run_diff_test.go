@@ -0,0 +1,130 @@
+package regresql
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffTestRuns(t *testing.T) {
+	before := []TestResult{
+		{Name: "orders_by_user", Type: "output", Status: "passed"},
+		{Name: "orders_by_user", Type: "cost", Status: "passed", ActualCost: 100},
+		{Name: "top_products", Type: "output", Status: "failed"},
+		{Name: "report", Type: "output", Status: "passed"},
+		{Name: "dropped", Type: "output", Status: "passed"},
+		{Name: "stable", Type: "cost", Status: "passed", ActualCost: 50},
+	}
+	after := []TestResult{
+		{Name: "orders_by_user", Type: "output", Status: "failed", Error: "rows differ\nmore"},
+		{Name: "orders_by_user", Type: "cost", Status: "passed", ActualCost: 150},
+		{Name: "top_products", Type: "output", Status: "passed"},
+		{Name: "report", Type: "output", Status: "skipped"},
+		{Name: "added", Type: "output", Status: "pending"},
+		{Name: "stable", Type: "cost", Status: "passed", ActualCost: 50},
+	}
+
+	diff := DiffTestRuns(before, after)
+
+	if len(diff.Regressions) != 1 || diff.Regressions[0].Name != "orders_by_user" || diff.Regressions[0].Type != "output" {
+		t.Errorf("Regressions = %+v, want orders_by_user output", diff.Regressions)
+	}
+	if len(diff.Improvements) != 1 || diff.Improvements[0].Name != "top_products" {
+		t.Errorf("Improvements = %+v, want top_products", diff.Improvements)
+	}
+	wantChanges := []StatusChange{
+		{Name: "report", Type: "output", Before: "passed", After: "skipped"},
+		{Name: "added", Type: "output", After: "pending"},
+		{Name: "dropped", Type: "output", Before: "passed"},
+	}
+	if len(diff.StatusChanges) != len(wantChanges) {
+		t.Fatalf("StatusChanges = %+v, want %+v", diff.StatusChanges, wantChanges)
+	}
+	for i, want := range wantChanges {
+		if diff.StatusChanges[i] != want {
+			t.Errorf("StatusChanges[%d] = %+v, want %+v", i, diff.StatusChanges[i], want)
+		}
+	}
+	if len(diff.CostChanges) != 1 || diff.CostChanges[0].ChangePercent != 50 {
+		t.Errorf("CostChanges = %+v, want orders_by_user +50%%", diff.CostChanges)
+	}
+}
+
+func TestDiffTestRunsIdentical(t *testing.T) {
+	run := []TestResult{{Name: "q", Type: "output", Status: "passed"}}
+	diff := DiffTestRuns(run, run)
+	if !diff.Empty() {
+		t.Errorf("DiffTestRuns() of identical runs = %+v, want empty", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.Render(&buf, "table"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No differences") {
+		t.Errorf("Render() = %q", buf.String())
+	}
+}
+
+func TestTestRunDiffRenderMarkdown(t *testing.T) {
+	diff := DiffTestRuns(
+		[]TestResult{{Name: "q", Type: "cost", Status: "passed", ActualCost: 10}},
+		[]TestResult{{Name: "q", Type: "cost", Status: "failed", Error: "cost a|b", ActualCost: 20}},
+	)
+
+	var buf bytes.Buffer
+	if err := diff.Render(&buf, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"**1 regressed, 0 fixed, 0 other status changes, 1 cost changes**",
+		"| ✗ regressed | `q` | cost | cost a\\|b |",
+		"| Δ cost | `q` | cost | 10.00 → 20.00 (+100.0%) |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := diff.Render(&buf, "xml"); err == nil {
+		t.Error("Render() with an unknown format expected error")
+	}
+}
+
+func TestReadTestRun(t *testing.T) {
+	results := []TestResult{
+		{Name: "q.1", Type: "output", Status: "failed", Error: "rows differ", Duration: 0.5},
+		{Name: "q.1", Type: "cost", Status: "passed", ExpectedCost: 10, ActualCost: 12, PercentIncrease: 20},
+	}
+
+	var buf bytes.Buffer
+	f := &JSONFormatter{}
+	f.Start(&buf)
+	for _, r := range results {
+		f.AddResult(r, &buf)
+	}
+	if err := f.Finish(&TestSummary{Total: 2, Passed: 1, Failed: 1}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadTestRun(path)
+	if err != nil {
+		t.Fatalf("ReadTestRun() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ReadTestRun() = %d results, want 2", len(got))
+	}
+	if got[0].Name != "q.1" || got[0].Status != "failed" || got[0].Error != "rows differ" {
+		t.Errorf("output result = %+v", got[0])
+	}
+	if got[1].ExpectedCost != 10 || got[1].ActualCost != 12 || got[1].PercentIncrease != 20 {
+		t.Errorf("cost result = %+v", got[1])
+	}
+}
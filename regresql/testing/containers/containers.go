@@ -0,0 +1,123 @@
+/*
+Package containers runs a RegreSQL suite against a throwaway PostgreSQL
+container, so tests need no shared database:
+
+	func TestQueries(t *testing.T) {
+		containers.NewContainerSuite(t, containers.ContainerOptions{
+			Root:       "..",
+			SchemaPath: "db/schema.sql",
+			Fixtures:   []string{"db/seed.sql", "db/users.csv"},
+		}).Run()
+	}
+
+The container is started with testcontainers-go, so a Docker-compatible
+runtime must be available. It is terminated when the test finishes.
+*/
+package containers
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/boringsql/regresql/v2/regresql"
+	"github.com/boringsql/regresql/v2/regresql/regresqltest"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// DefaultPostgresVersion is the postgres image tag used when none is given
+const DefaultPostgresVersion = "16"
+
+// ContainerOptions describes the database to start. Paths are relative to
+// Root. SchemaPath and Fixtures default to snapshot.schema and
+// snapshot.fixtures from regresql/regress.yaml.
+type ContainerOptions struct {
+	Root            string
+	PostgresVersion string   // postgres image tag, e.g. "17" or "16-alpine"
+	InitScripts     []string // .sql/.sh scripts run by the image entrypoint, before the schema
+	SchemaPath      string   // plain SQL schema file
+	Fixtures        []string // SQL and CSV fixtures, applied after the schema
+}
+
+// NewContainerSuite starts a postgres container, loads the schema and
+// fixtures and returns a suite connected to it. Any failure is fatal to t.
+func NewContainerSuite(t *testing.T, opts ContainerOptions) *regresqltest.TestSuite {
+	t.Helper()
+
+	opts = withConfigDefaults(t, opts)
+
+	scripts := make([]string, 0, len(opts.InitScripts)+1)
+	for _, s := range opts.InitScripts {
+		scripts = append(scripts, resolve(opts.Root, s))
+	}
+	if opts.SchemaPath != "" {
+		scripts = append(scripts, resolve(opts.Root, opts.SchemaPath))
+	}
+
+	ctr, err := postgres.Run(t.Context(), "postgres:"+opts.PostgresVersion,
+		postgres.WithDatabase("regresql"),
+		postgres.WithUsername("regresql"),
+		postgres.WithPassword("regresql"),
+		postgres.WithOrderedInitScripts(scripts...),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("regresql: failed to start postgres container: %s", err)
+	}
+
+	pguri, err := ctr.ConnectionString(t.Context(), "sslmode=disable")
+	if err != nil {
+		t.Fatalf("regresql: %s", err)
+	}
+
+	if len(opts.Fixtures) > 0 {
+		db, err := sql.Open("pgx", pguri)
+		if err != nil {
+			t.Fatalf("regresql: failed to connect to container: %s", err)
+		}
+		defer db.Close()
+
+		nullValue := ""
+		if cfg, err := regresql.ReadConfig(opts.Root); err == nil {
+			nullValue = regresql.GetSnapshotCSVNullValue(cfg.Snapshot)
+		}
+		if _, err := regresql.ApplyFixtures(db, opts.Root, opts.Fixtures, nullValue); err != nil {
+			t.Fatalf("regresql: %s", err)
+		}
+	}
+
+	return regresqltest.NewSuiteFromT(t, opts.Root, pguri)
+}
+
+// withConfigDefaults fills unset options from the project config
+func withConfigDefaults(t *testing.T, opts ContainerOptions) ContainerOptions {
+	t.Helper()
+
+	if opts.Root == "" {
+		opts.Root = "."
+	}
+	if opts.PostgresVersion == "" {
+		opts.PostgresVersion = DefaultPostgresVersion
+	}
+
+	cfg, err := regresql.ReadConfig(opts.Root)
+	if err != nil {
+		t.Fatalf("regresql: %s", err)
+	}
+	if opts.SchemaPath == "" {
+		opts.SchemaPath = regresql.GetSnapshotSchema(cfg.Snapshot)
+	}
+	if len(opts.Fixtures) == 0 {
+		opts.Fixtures = regresql.GetSnapshotFixtures(cfg.Snapshot)
+	}
+	return opts
+}
+
+func resolve(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,72 @@
+package containers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "regresql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "regresql", "regress.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestWithConfigDefaults(t *testing.T) {
+	root := writeConfig(t, "pguri: postgres://localhost/test\nsnapshot:\n  schema: db/schema.sql\n  fixtures:\n    - db/users.csv\n")
+
+	opts := withConfigDefaults(t, ContainerOptions{Root: root})
+	if opts.PostgresVersion != DefaultPostgresVersion {
+		t.Errorf("PostgresVersion = %q, want %q", opts.PostgresVersion, DefaultPostgresVersion)
+	}
+	if opts.SchemaPath != "db/schema.sql" {
+		t.Errorf("SchemaPath = %q", opts.SchemaPath)
+	}
+	if len(opts.Fixtures) != 1 || opts.Fixtures[0] != "db/users.csv" {
+		t.Errorf("Fixtures = %v", opts.Fixtures)
+	}
+
+	opts = withConfigDefaults(t, ContainerOptions{
+		Root:            root,
+		PostgresVersion: "17",
+		SchemaPath:      "other.sql",
+		Fixtures:        []string{"seed.sql"},
+	})
+	if opts.PostgresVersion != "17" || opts.SchemaPath != "other.sql" || opts.Fixtures[0] != "seed.sql" {
+		t.Errorf("explicit options overridden: %+v", opts)
+	}
+}
+
+func TestNewContainerSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a postgres container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	root := writeConfig(t, "pguri: postgres://localhost/unused\n")
+	write := func(rel, content string) {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("schema.sql", "create table users (id int primary key, name text);\n")
+	write("users.csv", "id,name\n1,alice\n2,bob\n")
+	write("users.sql", "-- name: count_users\nselect count(*) from users;\n")
+
+	suite := NewContainerSuite(t, ContainerOptions{
+		Root:       root,
+		SchemaPath: "schema.sql",
+		Fixtures:   []string{"users.csv"},
+	})
+	if suite == nil {
+		t.Fatal("NewContainerSuite returned nil")
+	}
+}
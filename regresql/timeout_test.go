@@ -154,6 +154,37 @@ func TestGetRegressQLOptions_ParsesTimeout(t *testing.T) {
 	}
 }
 
+func TestGetRegressQLOptions_CostThreshold(t *testing.T) {
+	cases := []struct {
+		name     string
+		metadata string
+		want     float64
+	}{
+		{"equals form", "cost_threshold=5.0", 5},
+		{"colon form among options", "nobaseline, cost_threshold:50", 50},
+		{"invalid value ignored", "cost_threshold=lots", 0},
+		{"unset", "notest", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := queryWithMetadata(t, "-- name: q\n-- regresql: "+tc.metadata+"\nselect 1;\n")
+			if got := q.GetRegressQLOptions().CostThreshold; got != tc.want {
+				t.Errorf("CostThreshold = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// a strict per-query threshold fails a 6% increase the global 20% allows
+	q := queryWithMetadata(t, "-- name: hot\n-- regresql: cost_threshold=5.0\nselect 1;\n")
+	threshold := q.GetRegressQLOptions().costThreshold(20)
+	if ok, _ := CompareCost(106, 100, threshold); ok {
+		t.Errorf("CompareCost with per-query threshold %.1f passed a 6%% increase", threshold)
+	}
+	if ok, _ := CompareCost(106, 100, RegressQLOptions{}.costThreshold(20)); !ok {
+		t.Error("CompareCost with global threshold should pass a 6% increase")
+	}
+}
+
 func TestIsTimeoutError(t *testing.T) {
 	// This classifier is the gate for the entire "treat timeout as a divergence"
 	// behaviour: only SQLSTATE 57014 (query_canceled, which is what
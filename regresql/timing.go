@@ -87,3 +87,9 @@ func medianOf(xs []float64) float64 {
 	}
 	return (s[m-1] + s[m]) / 2
 }
+
+// nearestRank returns the p-th percentile of sorted by the nearest-rank method
+func nearestRank(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
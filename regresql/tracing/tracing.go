@@ -0,0 +1,85 @@
+// Package tracing sets up optional OpenTelemetry tracing for regresql.
+//
+// regresql records spans for query execution, fixture loading and snapshot
+// builds through the global OpenTelemetry tracer provider. Until Init is
+// called that provider is a no-op, so tracing costs nothing when disabled.
+// Programs embedding regresql (for example Go tests using regresqltest)
+// only need this package to turn it on:
+//
+//	shutdown, err := tracing.Init(ctx)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer shutdown(ctx)
+//
+// Spans are exported over OTLP/HTTP, configured with the standard
+// OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME environment variables.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of regresql spans
+const TracerName = "github.com/boringsql/regresql"
+
+// Enabled reports whether an OTLP endpoint is configured in the environment
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init installs a global tracer provider exporting to the configured OTLP
+// endpoint (http://localhost:4318 when none is set). Spans are exported as
+// they end, so a run that exits early still delivers the spans recorded so
+// far. The returned function flushes and shuts the provider down.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName()),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func serviceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return "regresql"
+}
+
+// Start begins a span named name as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End finishes span, marking it failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, parent := Start(context.Background(), "regresql.test")
+	_, child := Start(ctx, "regresql.query.execute", attribute.String("regresql.query.name", "by_id"))
+	End(child, errors.New("relation does not exist"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	query := spans[0]
+	if query.Name() != "regresql.query.execute" || query.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("query span %q is not a child of the test span", query.Name())
+	}
+	if query.Status().Code != codes.Error || query.Status().Description != "relation does not exist" {
+		t.Errorf("query span status = %+v, want error", query.Status())
+	}
+	if got := query.Attributes(); len(got) != 1 || got[0].Value.AsString() != "by_id" {
+		t.Errorf("query span attributes = %v", got)
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Error("successful span marked as error")
+	}
+}
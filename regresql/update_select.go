@@ -0,0 +1,134 @@
+package regresql
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LastTestFailuresFile in regresql/out/ lists the expected files of the
+// output tests that failed in the last `regresql test` run, one per line and
+// relative to the regresql directory, for `regresql update --failed`
+const LastTestFailuresFile = ".last-test-failures"
+
+// writeLastTestFailures records the failed output tests of a run, replacing
+// the previous record
+func writeLastTestFailures(regressDir string, results []TestResult) error {
+	var b strings.Builder
+	for _, r := range results {
+		if r.Type != "output" || r.Status != "failed" || r.ExpectedFile == "" {
+			continue
+		}
+		rel, err := filepath.Rel(regressDir, r.ExpectedFile)
+		if err != nil {
+			return err
+		}
+		b.WriteString(filepath.ToSlash(rel) + "\n")
+	}
+	outDir := filepath.Join(regressDir, "out")
+	if err := ensureDir(outDir); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, LastTestFailuresFile), []byte(b.String()), 0o644)
+}
+
+// readLastTestFailures returns the expected files recorded by
+// writeLastTestFailures, relative to the regresql directory
+func readLastTestFailures(regressDir string) (map[string]bool, error) {
+	path := filepath.Join(regressDir, "out", LastTestFailuresFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no record of failed tests in %s, run 'regresql test' first", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	failed := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			failed[line] = true
+		}
+	}
+	return failed, scanner.Err()
+}
+
+// updateSelection narrows `regresql update` to single queries, bindings or
+// expected files; the zero value selects everything
+type updateSelection struct {
+	Query    string          // query name
+	Binding  string          // binding name, only matching queries with parameters
+	Expected map[string]bool // expected files relative to the regresql directory, nil for all
+}
+
+// bindings returns the indexes of the plan's bindings the selection covers;
+// queries without parameters have the single index 0
+func (sel updateSelection) bindings(p *Plan, regressDir, expectedDir string) []int {
+	if sel.Query != "" && p.Query.Name != sel.Query {
+		return nil
+	}
+	count := len(p.Names)
+	if len(p.Query.Args) == 0 {
+		if sel.Binding != "" {
+			return nil
+		}
+		count = 1
+	}
+
+	var out []int
+	for i := range count {
+		if sel.Binding != "" && p.Names[i] != sel.Binding {
+			continue
+		}
+		if sel.Expected != nil {
+			path := getResultSetPath(p, expectedDir, i)
+			if !sel.hasExpected(regressDir, path) && !sel.hasExpected(regressDir, alternateResultFile(path)) {
+				continue
+			}
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+// hasExpected reports whether the expected file at path is selected
+func (sel updateSelection) hasExpected(regressDir, path string) bool {
+	rel, err := filepath.Rel(regressDir, path)
+	return err == nil && sel.Expected[filepath.ToSlash(rel)]
+}
+
+// keepResultSets drops the executed result sets, and their bindings, that
+// aren't at the given indexes. Bindings are narrowed after execution, so
+// keyset pagination still chains through the earlier pages.
+func (p *Plan) keepResultSets(indexes []int) {
+	if len(p.ResultSets) == len(indexes) {
+		return
+	}
+	var (
+		names    []string
+		bindings []map[string]any
+		results  []ResultSet
+		analyses []CapturedAnalyze
+	)
+	for i := range p.ResultSets {
+		if !slices.Contains(indexes, i) {
+			continue
+		}
+		if i < len(p.Names) {
+			names = append(names, p.Names[i])
+		}
+		if i < len(p.Bindings) {
+			bindings = append(bindings, p.Bindings[i])
+		}
+		if i < len(p.Analyses) {
+			analyses = append(analyses, p.Analyses[i])
+		}
+		results = append(results, p.ResultSets[i])
+	}
+	p.Names, p.Bindings, p.ResultSets, p.Analyses = names, bindings, results, analyses
+}
@@ -0,0 +1,84 @@
+package regresql
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLastTestFailures(t *testing.T) {
+	regressDir := t.TempDir()
+	expected := filepath.Join(regressDir, "expected", "sql")
+	results := []TestResult{
+		{Type: "output", Status: "failed", ExpectedFile: filepath.Join(expected, "orders.1.json")},
+		{Type: "output", Status: "passed", ExpectedFile: filepath.Join(expected, "orders.2.json")},
+		{Type: "cost", Status: "failed"},
+	}
+
+	if _, err := readLastTestFailures(regressDir); err == nil {
+		t.Error("expected an error before any test run")
+	}
+	if err := writeLastTestFailures(regressDir, results); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := readLastTestFailures(regressDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || !failed["expected/sql/orders.1.json"] {
+		t.Errorf("failed = %v, want only expected/sql/orders.1.json", failed)
+	}
+}
+
+func TestUpdateSelectionBindings(t *testing.T) {
+	regressDir := t.TempDir()
+	expectedDir := filepath.Join(regressDir, "expected", "sql")
+
+	q := testQuery(t, "orders", "sql/orders.sql")
+	q.Args = []string{"id"}
+	p := &Plan{Query: q, Path: "plans/sql/orders.yaml", Names: []string{"1", "2", "3"}}
+	noArgs := &Plan{Query: testQuery(t, "count", "sql/count.sql"), Path: "plans/sql/count.yaml"}
+
+	tests := []struct {
+		name string
+		sel  updateSelection
+		plan *Plan
+		want []int
+	}{
+		{"all", updateSelection{}, p, []int{0, 1, 2}},
+		{"query", updateSelection{Query: "orders"}, p, []int{0, 1, 2}},
+		{"other query", updateSelection{Query: "count"}, p, nil},
+		{"binding", updateSelection{Binding: "2"}, p, []int{1}},
+		{"binding of a query without parameters", updateSelection{Binding: "2"}, noArgs, nil},
+		{"query without parameters", updateSelection{}, noArgs, []int{0}},
+		{"failed", updateSelection{Expected: map[string]bool{"expected/sql/orders.3.json": true}}, p, []int{2}},
+	}
+	for _, tt := range tests {
+		got := tt.sel.bindings(tt.plan, regressDir, expectedDir)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: bindings = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: bindings = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPlanKeepResultSets(t *testing.T) {
+	p := &Plan{
+		Names:      []string{"page_1", "page_2", "page_3"},
+		Bindings:   []map[string]any{{"p": 1}, {"p": 2}, {"p": 3}},
+		ResultSets: []ResultSet{{Filename: "1"}, {Filename: "2"}, {Filename: "3"}},
+	}
+	p.keepResultSets([]int{2})
+
+	if !equalStrings(p.Names, []string{"page_3"}) || len(p.Bindings) != 1 || p.Bindings[0]["p"] != 3 {
+		t.Errorf("Names = %v, Bindings = %v", p.Names, p.Bindings)
+	}
+	if len(p.ResultSets) != 1 || p.ResultSets[0].Filename != "3" {
+		t.Errorf("ResultSets = %v", p.ResultSets)
+	}
+}
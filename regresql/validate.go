@@ -149,5 +149,12 @@ func validateSnapshotPaths(root string, snap *SnapshotConfig) []ValidationIssue
 		}
 	}
 
+	if err := ValidateMigrationsNaming(snap.MigrationsNaming); err != nil {
+		issues = append(issues, ValidationIssue{
+			Field:   "snapshot.migrations_naming",
+			Message: err.Error(),
+		})
+	}
+
 	return issues
 }
@@ -0,0 +1,238 @@
+package regresql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	WatchOptions struct {
+		Root      string
+		NoRestore bool
+		Interval  time.Duration // polling interval, defaults to 500ms
+		Color     bool
+		NoColor   bool
+		Verbose   bool
+	}
+
+	// watchTarget narrows a test cycle to the queries affected by a change
+	watchTarget struct {
+		paths     []string
+		runFilter string
+	}
+)
+
+const (
+	defaultWatchInterval = 500 * time.Millisecond
+	watchDebounce        = 100 * time.Millisecond
+)
+
+// Watch runs the whole suite once, then polls SQL and plan files and re-runs
+// only the queries affected by each change. It returns when ctx is cancelled.
+func Watch(ctx context.Context, opts WatchOptions) error {
+	cfg, err := ReadConfig(opts.Root)
+	if err != nil {
+		return err
+	}
+	SetGlobalConfig(cfg)
+
+	maybeRestore(cfg, opts.Root, opts.NoRestore, "", "")
+
+	if err := TestConnectionString(cfg.PgUri); err != nil {
+		return err
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	runWatchCycle(cfg, opts, watchTarget{})
+
+	state := watchScan(opts.Root, cfg.Ignore)
+	fmt.Printf("\nWatching %s for changes (Ctrl+C to stop)\n", opts.Root)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next := watchScan(opts.Root, cfg.Ignore)
+		changed := watchChanges(state, next)
+		if len(changed) == 0 {
+			continue
+		}
+
+		// debounce: editors often write a file several times per save
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(watchDebounce):
+			}
+			settled := watchScan(opts.Root, cfg.Ignore)
+			more := watchChanges(next, settled)
+			next = settled
+			if len(more) == 0 {
+				break
+			}
+			changed = append(changed, more...)
+		}
+		state = next
+
+		target := watchTargetFor(opts.Root, changed)
+		if len(target.paths) == 0 {
+			continue
+		}
+		fmt.Printf("\nChanged: %s\n", strings.Join(relPaths(opts.Root, changed), ", "))
+		runWatchCycle(cfg, opts, target)
+	}
+}
+
+// runWatchCycle runs the targeted queries and prints a one-line summary;
+// errors are reported but never stop the watch loop
+func runWatchCycle(cfg config, opts WatchOptions, target watchTarget) {
+	suite := Walk(opts.Root, cfg.Ignore)
+	suite.SetPathFilters(target.paths)
+	suite.SetRunFilter(target.runFilter)
+
+	formatter := &ConsoleFormatter{}
+	formatter.SetOptions(ConsoleOptions{
+		Color:   opts.Color,
+		NoColor: opts.NoColor,
+		Verbose: opts.Verbose,
+	})
+
+	start := time.Now()
+	summary, err := suite.testQueries(cfg.PgUri, formatter, "", false)
+	stamp := time.Now().Format("15:04:05")
+	if err != nil {
+		fmt.Printf("[%s] Error: %s\n", stamp, err)
+		return
+	}
+	fmt.Printf("[%s] %d passed, %d failed, %d skipped, %d pending (%.2fs)\n",
+		stamp, summary.Passed, summary.Failed, summary.Skipped, summary.Pending, time.Since(start).Seconds())
+}
+
+// watchScan records modification times of query files and plan files
+func watchScan(root string, ignorePatterns []string) map[string]time.Time {
+	files := make(map[string]time.Time)
+
+	ignoreMatcher, err := LoadIgnoreFile(root)
+	if err != nil {
+		ignoreMatcher = NewIgnoreMatcher(root, nil)
+	}
+	ignoreMatcher.patterns = append(ignoreMatcher.patterns, ignorePatterns...)
+
+	// ShouldIgnore skips the regresql/ directory, plans are scanned below
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ignoreMatcher.ShouldIgnore(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".sql" {
+			files[path] = info.ModTime()
+		}
+		return nil
+	})
+
+	planDir := filepath.Join(root, "regresql", "plans")
+	filepath.Walk(planDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".yaml" {
+			files[path] = info.ModTime()
+		}
+		return nil
+	})
+
+	return files
+}
+
+// watchChanges lists files added, modified or removed between two scans
+func watchChanges(before, after map[string]time.Time) []string {
+	var changed []string
+	for path, mtime := range after {
+		if prev, ok := before[path]; !ok || !prev.Equal(mtime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchTargetFor maps changed files to suite path filters. A change to a
+// single plan file narrows the run further to that plan's query.
+func watchTargetFor(root string, changed []string) watchTarget {
+	planDir := filepath.Join(root, "regresql", "plans")
+
+	var target watchTarget
+	seen := make(map[string]bool)
+	var planQuery string
+
+	for _, path := range changed {
+		var rel string
+		if relPlan, err := filepath.Rel(planDir, path); err == nil && !strings.HasPrefix(relPlan, "..") {
+			if _, err := os.Stat(path); err != nil {
+				continue // plan removed, nothing left to run
+			}
+			pq, err := loadPlannedQuery(root, path)
+			if err != nil {
+				fmt.Printf("Warning: cannot load plan %s: %s\n", relPlan, err)
+				continue
+			}
+			rel = pq.RelPath
+			planQuery = pq.Query.Name
+		} else {
+			r, err := filepath.Rel(root, path)
+			if err != nil {
+				continue
+			}
+			rel = r
+		}
+
+		if !seen[rel] {
+			seen[rel] = true
+			target.paths = append(target.paths, rel)
+		}
+	}
+
+	if len(changed) == 1 && planQuery != "" {
+		target.runFilter = "^" + regexp.QuoteMeta(planQuery) + "$"
+	}
+	return target
+}
+
+func relPaths(root string, paths []string) []string {
+	rel := make([]string, len(paths))
+	for i, p := range paths {
+		if r, err := filepath.Rel(root, p); err == nil {
+			rel[i] = r
+		} else {
+			rel[i] = p
+		}
+	}
+	return rel
+}
@@ -0,0 +1,75 @@
+package regresql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchChanges(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	t1 := time.Unix(2000, 0)
+
+	before := map[string]time.Time{"a.sql": t0, "b.sql": t0, "gone.sql": t0}
+	after := map[string]time.Time{"a.sql": t0, "b.sql": t1, "new.sql": t1}
+
+	got := watchChanges(before, after)
+	want := []string{"b.sql", "gone.sql", "new.sql"}
+	if !equalStrings(got, want) {
+		t.Errorf("watchChanges = %v, want %v", got, want)
+	}
+
+	if got := watchChanges(after, after); len(got) != 0 {
+		t.Errorf("watchChanges on identical scans = %v, want none", got)
+	}
+}
+
+func TestWatchTargetFor(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	sqlPath := write("orders/orders.sql", "-- name: get_order\nselect 1;\n\n-- name: list_orders\nselect 2;\n")
+	planPath := write("regresql/plans/orders/orders_get_order.yaml", "{}\n")
+	otherSQL := write("users.sql", "select 1;\n")
+
+	t.Run("sql change targets the file", func(t *testing.T) {
+		target := watchTargetFor(root, []string{sqlPath})
+		if !equalStrings(target.paths, []string{filepath.Join("orders", "orders.sql")}) {
+			t.Errorf("paths = %v", target.paths)
+		}
+		if target.runFilter != "" {
+			t.Errorf("runFilter = %q, want none", target.runFilter)
+		}
+	})
+
+	t.Run("single plan change targets its query", func(t *testing.T) {
+		target := watchTargetFor(root, []string{planPath})
+		if !equalStrings(target.paths, []string{filepath.Join("orders", "orders.sql")}) {
+			t.Errorf("paths = %v", target.paths)
+		}
+		if target.runFilter != "^get_order$" {
+			t.Errorf("runFilter = %q, want ^get_order$", target.runFilter)
+		}
+	})
+
+	t.Run("mixed changes fall back to files", func(t *testing.T) {
+		target := watchTargetFor(root, []string{planPath, otherSQL})
+		want := []string{filepath.Join("orders", "orders.sql"), "users.sql"}
+		if !equalStrings(target.paths, want) {
+			t.Errorf("paths = %v, want %v", target.paths, want)
+		}
+		if target.runFilter != "" {
+			t.Errorf("runFilter = %q, want none", target.runFilter)
+		}
+	})
+}